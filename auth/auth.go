@@ -6,13 +6,18 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/oauth2"
 
+	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/gorilla/websocket"
+	"github.com/gravitl/netmaker/config"
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/logic"
 	"github.com/gravitl/netmaker/logic/pro/netcache"
@@ -44,17 +49,29 @@ type OAuthUser struct {
 	Login             string `json:"login" bson:"login"`
 	UserPrincipalName string `json:"userPrincipalName" bson:"userPrincipalName"`
 	AccessToken       string `json:"accesstoken" bson:"accesstoken"`
+	// Groups - groups of the user at the identity provider, nil if they were not fetched
+	Groups []string `json:"-" bson:"-"`
+}
+
+// provider - an sso provider users can sign in through
+type provider struct {
+	name      string
+	kind      string
+	namespace bool
+	config    *oauth2.Config
+	verifier  *oidc.IDTokenVerifier
+	functions map[string]interface{}
 }
 
 var (
-	auth_provider *oauth2.Config
-	upgrader      = websocket.Upgrader{}
+	providers       = map[string]*provider{}
+	defaultProvider *provider
+	upgrader        = websocket.Upgrader{}
+	providerPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
 )
 
-func getCurrentAuthFunctions() map[string]interface{} {
-	var authInfo = servercfg.GetAuthProviderInfo()
-	var authProvider = authInfo[0]
-	switch authProvider {
+func getAuthFunctions(kind string) map[string]interface{} {
+	switch kind {
 	case google_provider_name:
 		return google_functions
 	case azure_ad_provider_name:
@@ -68,18 +85,21 @@ func getCurrentAuthFunctions() map[string]interface{} {
 	}
 }
 
-// InitializeAuthProvider - initializes the auth provider if any is present
-func InitializeAuthProvider() string {
-	var functions = getCurrentAuthFunctions()
-	if functions == nil {
-		return ""
-	}
-	var _, err = fetchPassValue(logic.RandomString(64))
+// InitializeAuthProviders - initializes the configured sso providers, returning the names of those initialized.
+// The first one, the provider set by AUTH_PROVIDER if any, is used when a sign-in names none.
+func InitializeAuthProviders() []string {
+	var names = []string{}
+	var settings, err = servercfg.GetAuthProviders()
 	if err != nil {
 		logger.Log(0, err.Error())
-		return ""
 	}
-	var authInfo = servercfg.GetAuthProviderInfo()
+	if len(settings) == 0 {
+		return names
+	}
+	if _, err = fetchPassValue(logic.RandomString(64)); err != nil {
+		logger.Log(0, err.Error())
+		return names
+	}
 	var serverConn = servercfg.GetAPIHost()
 	if strings.Contains(serverConn, "localhost") || strings.Contains(serverConn, "127.0.0.1") {
 		serverConn = "http://" + serverConn
@@ -89,26 +109,66 @@ func InitializeAuthProvider() string {
 		logger.Log(1, "external OAuth detected, proceeding with https redirect: ("+serverConn+")")
 	}
 
-	if authInfo[0] == "oidc" {
-		functions[init_provider].(func(string, string, string, string))(serverConn+"/api/oauth/callback", authInfo[1], authInfo[2], authInfo[3])
-		return authInfo[0]
+	for _, setting := range settings {
+		if err = validateAuthProvider(setting); err != nil {
+			logger.Log(0, "skipping OAuth provider", setting.Name+":", err.Error())
+			continue
+		}
+		var p = &provider{
+			name:      setting.Name,
+			kind:      setting.Type,
+			namespace: setting.Namespace,
+			functions: getAuthFunctions(setting.Type),
+		}
+		p.functions[init_provider].(func(*provider, string, config.AuthProvider))(p, serverConn+"/api/oauth/callback", setting)
+		if p.config == nil {
+			continue
+		}
+		providers[p.name] = p
+		if defaultProvider == nil {
+			defaultProvider = p
+		}
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// ListAuthProviders - the sso providers users can sign in through
+func ListAuthProviders() []models.SSOProvider {
+	var list = []models.SSOProvider{}
+	for _, p := range providers {
+		list = append(list, models.SSOProvider{
+			Name:    p.name,
+			Type:    p.kind,
+			Default: p == defaultProvider,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// DefaultAuthProvider - the name of the sso provider used when a sign-in names none, empty if none is configured
+func DefaultAuthProvider() string {
+	if defaultProvider == nil {
+		return ""
 	}
+	return defaultProvider.name
+}
 
-	functions[init_provider].(func(string, string, string))(serverConn+"/api/oauth/callback", authInfo[1], authInfo[2])
-	return authInfo[0]
+// IsAuthProvider - checks if an sso provider of the given name is configured
+func IsAuthProvider(name string) bool {
+	return providers[name] != nil
 }
 
 // HandleAuthCallback - handles oauth callback
 // Note: not included in API reference as part of the OAuth process itself.
 func HandleAuthCallback(w http.ResponseWriter, r *http.Request) {
-	if auth_provider == nil {
+	if defaultProvider == nil {
 		handleOauthNotConfigured(w)
 		return
 	}
-	var functions = getCurrentAuthFunctions()
-	if functions == nil {
-		return
-	}
 	state, _ := getStateAndCode(r)
 	_, err := netcache.Get(state) // if in netcache proceeed with node registration login
 	if err == nil || errors.Is(err, netcache.ErrExpired) {
@@ -123,32 +183,41 @@ func HandleAuthCallback(w http.ResponseWriter, r *http.Request) {
 			logger.Log(1, "invalid state length: ", fmt.Sprintf("%d", len(state)))
 		}
 	} else { // handle normal login
-		functions[handle_callback].(func(http.ResponseWriter, *http.Request))(w, r)
+		var p = defaultProvider
+		if ssoState, err := logic.GetState(state); err == nil {
+			p = getProvider(ssoState.Provider)
+		}
+		if p == nil {
+			handleOauthNotConfigured(w)
+			return
+		}
+		p.functions[handle_callback].(func(*provider, http.ResponseWriter, *http.Request))(p, w, r)
 	}
 }
 
 // swagger:route GET /api/oauth/login nodes HandleAuthLogin
 //
-// Handles OAuth login.
+// Handles OAuth login through the sso provider named by the provider query parameter, or the default one.
 //
 //			Schemes: https
 //
 //			Security:
 //	  		oauth
 func HandleAuthLogin(w http.ResponseWriter, r *http.Request) {
-	if auth_provider == nil {
+	if defaultProvider == nil {
 		handleOauthNotConfigured(w)
 		return
 	}
-	var functions = getCurrentAuthFunctions()
-	if functions == nil {
+	var p = getProvider(r.URL.Query().Get("provider"))
+	if p == nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(fmt.Errorf("unknown sso provider %s", r.URL.Query().Get("provider")), "badrequest"))
 		return
 	}
 	if servercfg.GetFrontendURL() == "" {
 		handleOauthNotConfigured(w)
 		return
 	}
-	functions[handle_login].(func(http.ResponseWriter, *http.Request))(w, r)
+	p.functions[handle_login].(func(*provider, http.ResponseWriter, *http.Request))(p, w, r)
 }
 
 // IsOauthUser - returns
@@ -174,7 +243,15 @@ func HandleHeadlessSSO(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
-	req := &netcache.CValue{User: "", Pass: ""}
+	// an empty provider is cached as is, the register page then lets the user pick one when several are configured
+	providerName := r.URL.Query().Get("provider")
+	if getProvider(providerName) == nil {
+		if err = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")); err != nil {
+			logger.Log(0, "error during message writing:", err.Error())
+		}
+		return
+	}
+	req := &netcache.CValue{User: "", Pass: "", Provider: providerName}
 	stateStr := logic.RandomString(headless_signin_length)
 	if err = netcache.Set(stateStr, req); err != nil {
 		logger.Log(0, "Failed to process sso request -", err.Error())
@@ -186,12 +263,6 @@ func HandleHeadlessSSO(w http.ResponseWriter, r *http.Request) {
 	defer close(answer)
 	defer close(timeout)
 
-	if auth_provider == nil {
-		if err = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")); err != nil {
-			logger.Log(0, "error during message writing:", err.Error())
-		}
-		return
-	}
 	redirectUrl = fmt.Sprintf("https://%s/api/oauth/register/%s", servercfg.GetAPIConnString(), stateStr)
 	if err = conn.WriteMessage(websocket.TextMessage, []byte(redirectUrl)); err != nil {
 		logger.Log(0, "error during message writing:", err.Error())
@@ -269,6 +340,115 @@ func addUser(email string) error {
 	return nil
 }
 
+// getProvider - the sso provider of the given name, the default one if no name is given
+func getProvider(name string) *provider {
+	if name == "" {
+		return defaultProvider
+	}
+	return providers[name]
+}
+
+// getCachedProvider - the sso provider a host or headless sign-in cached under its state goes through
+func getCachedProvider(state string) *provider {
+	if cached, err := netcache.Get(state); err == nil {
+		return getProvider(cached.Provider)
+	}
+	return defaultProvider
+}
+
+// userName - the netmaker user of an identity at the provider, prefixed with the provider name if it is namespaced
+func (p *provider) userName(identity string) string {
+	if p.namespace && identity != "" {
+		return p.name + "-" + identity
+	}
+	return identity
+}
+
+// signInSSOUser - creates a user signing in through a provider for the first time, linking it to the
+// provider, or checks that an existing user may sign in through it. Then re-evaluates its mapped access.
+func signInSSOUser(p *provider, username string, user *OAuthUser) error {
+	if _, err := logic.GetUser(username); err != nil { // user must not exist, so try to make one
+		if err = addUser(username); err != nil {
+			return err
+		}
+		if err = logic.LinkSSOUser(username, p.name, ""); err != nil {
+			return err
+		}
+	} else if err = checkSSOUserLink(p, username); err != nil {
+		return err
+	}
+	applySSOMappings(p, username, user)
+	return nil
+}
+
+// checkSSOUserLink - checks that an existing user may sign in through a provider
+func checkSSOUserLink(p *provider, username string) error {
+	if logic.IsSSOUserLinked(username, p.name, defaultProvider.name) {
+		return nil
+	}
+	return fmt.Errorf("%w %s", errSSOUserNotLinked, p.name)
+}
+
+// validateAuthProvider - checks the settings of an sso provider before it is initialized
+func validateAuthProvider(setting config.AuthProvider) error {
+	switch {
+	case !providerPattern.MatchString(setting.Name):
+		return errors.New("name may only contain lowercase letters, digits and dashes")
+	case providers[setting.Name] != nil:
+		return errors.New("name is used by another provider")
+	case setting.Name == logic.LDAPProviderName:
+		return errors.New("name is reserved for the ldap directory")
+	case getAuthFunctions(setting.Type) == nil:
+		return fmt.Errorf("unknown type %q", setting.Type)
+	case setting.ClientID == "" || setting.ClientSecret == "":
+		return errors.New("client id and secret are required")
+	case setting.Type == oidc_provider_name && setting.OIDCIssuer == "":
+		return errors.New("oidc issuer is required")
+	}
+	return nil
+}
+
+// applySSOMappings - re-evaluates the access mapped to the groups of a user logging in through a provider
+func applySSOMappings(p *provider, username string, user *OAuthUser) {
+	if err := logic.ApplySSOMappings(p.name, username, user.Groups); err != nil {
+		logger.Log(0, "failed to apply sso mappings to user", username, err.Error())
+	}
+}
+
+// claimValues - values of a token claim holding either a list of strings or a single string
+func claimValues(claim interface{}) []string {
+	values := []string{}
+	switch claim := claim.(type) {
+	case string:
+		values = append(values, claim)
+	case []interface{}:
+		for _, value := range claim {
+			if value, ok := value.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// fetchProviderJSON - decodes the response of an identity provider api
+func fetchProviderJSON(client *http.Client, url, authorization string, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	response, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
 func fetchPassValue(newValue string) (string, error) {
 
 	type valueHolder struct {
@@ -300,6 +480,18 @@ func fetchPassValue(newValue string) (string, error) {
 	return string(b64CurrentValue), nil
 }
 
+// redirectSSOLogin - sends a user signed in through sso back to the frontend with a one time code
+// for the tokens of its session, the tokens themselves never appear in the url
+func redirectSSOLogin(w http.ResponseWriter, r *http.Request, login models.SuccessfulUserLoginResponse) {
+	code, err := logic.CreateUserLoginCode(login.SessionID)
+	if err != nil {
+		logger.Log(0, "failed to create login code for user", login.UserName, err.Error())
+		handleSSOSignInError(w, err)
+		return
+	}
+	http.Redirect(w, r, servercfg.GetFrontendURL()+"/login?code="+url.QueryEscape(code)+"&user="+url.QueryEscape(login.UserName), http.StatusPermanentRedirect)
+}
+
 func getStateAndCode(r *http.Request) (string, string) {
 	var state, code string
 	if r.FormValue("state") != "" && r.FormValue("code") != "" {
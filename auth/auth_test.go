@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/config"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/logic/pro/netcache"
+	"github.com/matryer/is"
+	"golang.org/x/oauth2"
+)
+
+// setTestProviders - configures providers of the given names, the first one being the default, which record
+// the providers their callbacks went through instead of talking to an identity provider
+func setTestProviders(t *testing.T, names ...string) *[]string {
+	previous, previousDefault := providers, defaultProvider
+	t.Cleanup(func() {
+		providers, defaultProvider = previous, previousDefault
+	})
+	callbacks := []string{}
+	providers = map[string]*provider{}
+	defaultProvider = nil
+	for _, name := range names {
+		p := &provider{
+			name: name,
+			kind: oidc_provider_name,
+			config: &oauth2.Config{
+				ClientID: name,
+				Endpoint: oauth2.Endpoint{AuthURL: "https://" + name + ".example.com/auth"},
+			},
+			functions: map[string]interface{}{
+				handle_callback: func(p *provider, w http.ResponseWriter, r *http.Request) {
+					callbacks = append(callbacks, p.name)
+				},
+			},
+		}
+		providers[name] = p
+		if defaultProvider == nil {
+			defaultProvider = p
+		}
+	}
+	return &callbacks
+}
+
+func TestValidateAuthProvider(t *testing.T) {
+	setTestProviders(t, "taken")
+	valid := config.AuthProvider{Name: "contractors", Type: google_provider_name, ClientID: "id", ClientSecret: "secret"}
+	tests := []struct {
+		name   string
+		change func(*config.AuthProvider)
+		valid  bool
+	}{
+		{name: "valid", change: func(*config.AuthProvider) {}, valid: true},
+		{name: "uppercase name", change: func(s *config.AuthProvider) { s.Name = "Contractors" }},
+		{name: "name with spaces", change: func(s *config.AuthProvider) { s.Name = "the contractors" }},
+		{name: "empty name", change: func(s *config.AuthProvider) { s.Name = "" }},
+		{name: "name of another provider", change: func(s *config.AuthProvider) { s.Name = "taken" }},
+		{name: "name of the ldap directory", change: func(s *config.AuthProvider) { s.Name = logic.LDAPProviderName }},
+		{name: "unknown type", change: func(s *config.AuthProvider) { s.Type = "saml" }},
+		{name: "missing client id", change: func(s *config.AuthProvider) { s.ClientID = "" }},
+		{name: "missing client secret", change: func(s *config.AuthProvider) { s.ClientSecret = "" }},
+		{name: "oidc without issuer", change: func(s *config.AuthProvider) { s.Type = oidc_provider_name }},
+		{name: "oidc", change: func(s *config.AuthProvider) {
+			s.Type = oidc_provider_name
+			s.OIDCIssuer = "https://issuer.example.com"
+		}, valid: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			is := is.New(t)
+			setting := valid
+			test.change(&setting)
+			err := validateAuthProvider(setting)
+			is.Equal(err == nil, test.valid)
+		})
+	}
+}
+
+func TestProviderUserName(t *testing.T) {
+	is := is.New(t)
+	shared := &provider{name: "azure-ad"}
+	namespaced := &provider{name: "contractors", namespace: true}
+	is.Equal(shared.userName("alice@example.com"), "alice@example.com")
+	is.Equal(namespaced.userName("alice@example.com"), "contractors-alice@example.com")
+	is.Equal(namespaced.userName(""), "")
+}
+
+func TestRegisterHostSSO(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	register := func(state, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/oauth/register/"+state+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"regKey": state})
+		rec := httptest.NewRecorder()
+		RegisterHostSSO(rec, req)
+		return rec
+	}
+	cache := func(t *testing.T, provider string) string {
+		state := logic.RandomString(node_signin_length)
+		if err := netcache.Set(state, &netcache.CValue{Provider: provider}); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { netcache.Del(state) })
+		return state
+	}
+
+	t.Run("cached provider", func(t *testing.T) {
+		is := is.New(t)
+		setTestProviders(t, "azure-ad", "contractors")
+		rec := register(cache(t, "contractors"), "")
+		is.Equal(rec.Code, http.StatusSeeOther)
+		is.True(strings.HasPrefix(rec.Header().Get("Location"), "https://contractors.example.com/auth"))
+	})
+	t.Run("provider picked on the register page", func(t *testing.T) {
+		is := is.New(t)
+		setTestProviders(t, "azure-ad", "contractors")
+		state := cache(t, "")
+		rec := register(state, "?provider=contractors")
+		is.Equal(rec.Code, http.StatusSeeOther)
+		is.True(strings.HasPrefix(rec.Header().Get("Location"), "https://contractors.example.com/auth"))
+		// the callback signs in through the picked provider
+		is.Equal(getCachedProvider(state).name, "contractors")
+	})
+	t.Run("several providers to choose from", func(t *testing.T) {
+		is := is.New(t)
+		setTestProviders(t, "azure-ad", "contractors")
+		rec := register(cache(t, ""), "")
+		is.Equal(rec.Code, http.StatusOK)
+		is.True(strings.Contains(rec.Body.String(), "provider=contractors"))
+		is.True(strings.Contains(rec.Body.String(), "provider=azure-ad"))
+	})
+	t.Run("single provider", func(t *testing.T) {
+		is := is.New(t)
+		setTestProviders(t, "azure-ad")
+		rec := register(cache(t, ""), "")
+		is.Equal(rec.Code, http.StatusSeeOther)
+		is.True(strings.HasPrefix(rec.Header().Get("Location"), "https://azure-ad.example.com/auth"))
+	})
+	t.Run("unknown provider", func(t *testing.T) {
+		is := is.New(t)
+		setTestProviders(t, "azure-ad", "contractors")
+		rec := register(cache(t, ""), "?provider=okta")
+		is.Equal(rec.Code, http.StatusBadRequest)
+	})
+}
+
+func TestHandleAuthCallback(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	callback := func(state string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/oauth/callback?state="+state+"&code=code", nil)
+		rec := httptest.NewRecorder()
+		HandleAuthCallback(rec, req)
+		return rec
+	}
+	login := func(t *testing.T, provider string) string {
+		state := logic.RandomString(user_signin_length)
+		if err := logic.SetState(state, provider); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { database.DeleteRecord(database.SSO_STATE_CACHE, state) })
+		return state
+	}
+
+	t.Run("provider of the login", func(t *testing.T) {
+		is := is.New(t)
+		callbacks := setTestProviders(t, "azure-ad", "contractors")
+		callback(login(t, "contractors"))
+		is.Equal(*callbacks, []string{"contractors"})
+	})
+	t.Run("unknown state goes through the default provider", func(t *testing.T) {
+		is := is.New(t)
+		callbacks := setTestProviders(t, "azure-ad", "contractors")
+		callback(logic.RandomString(user_signin_length))
+		is.Equal(*callbacks, []string{"azure-ad"})
+	})
+	t.Run("provider removed since the login", func(t *testing.T) {
+		is := is.New(t)
+		callbacks := setTestProviders(t, "azure-ad")
+		rec := callback(login(t, "contractors"))
+		is.Equal(rec.Code, http.StatusInternalServerError)
+		is.Equal(len(*callbacks), 0)
+	})
+	t.Run("no providers", func(t *testing.T) {
+		is := is.New(t)
+		setTestProviders(t)
+		rec := callback(login(t, "azure-ad"))
+		is.Equal(rec.Code, http.StatusInternalServerError)
+	})
+}
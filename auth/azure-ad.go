@@ -7,6 +7,8 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gravitl/netmaker/config"
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/logic"
 	"github.com/gravitl/netmaker/models"
@@ -25,46 +27,50 @@ var azure_ad_functions = map[string]interface{}{
 
 // == handle azure ad authentication here ==
 
-func initAzureAD(redirectURL string, clientID string, clientSecret string) {
-	auth_provider = &oauth2.Config{
+func initAzureAD(p *provider, redirectURL string, settings config.AuthProvider) {
+	p.config = &oauth2.Config{
 		RedirectURL:  redirectURL,
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
+		ClientID:     settings.ClientID,
+		ClientSecret: settings.ClientSecret,
 		Scopes:       []string{"User.Read"},
-		Endpoint:     microsoft.AzureADEndpoint(servercfg.GetAzureTenant()),
+		Endpoint:     microsoft.AzureADEndpoint(settings.AzureTenant),
+	}
+	if servercfg.IsSSOGroupSyncEnabled() {
+		// the groups and app roles of a user come with its id token
+		p.config.Scopes = append(p.config.Scopes, "openid")
 	}
 }
 
-func handleAzureLogin(w http.ResponseWriter, r *http.Request) {
+func handleAzureLogin(p *provider, w http.ResponseWriter, r *http.Request) {
 	var oauth_state_string = logic.RandomString(user_signin_length)
-	if auth_provider == nil {
+	if p.config == nil {
 		handleOauthNotConfigured(w)
 		return
 	}
 
-	if err := logic.SetState(oauth_state_string); err != nil {
+	if err := logic.SetState(oauth_state_string, p.name); err != nil {
 		handleOauthNotConfigured(w)
 		return
 	}
 
-	var url = auth_provider.AuthCodeURL(oauth_state_string)
+	var url = p.config.AuthCodeURL(oauth_state_string)
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
-func handleAzureCallback(w http.ResponseWriter, r *http.Request) {
+func handleAzureCallback(p *provider, w http.ResponseWriter, r *http.Request) {
 
 	var rState, rCode = getStateAndCode(r)
-	var content, err = getAzureUserInfo(rState, rCode)
+	var content, err = getAzureUserInfo(p, rState, rCode)
 	if err != nil {
 		logger.Log(1, "error when getting user info from azure:", err.Error())
 		handleOauthNotConfigured(w)
 		return
 	}
-	_, err = logic.GetUser(content.UserPrincipalName)
-	if err != nil { // user must not exists, so try to make one
-		if err = addUser(content.UserPrincipalName); err != nil {
-			return
-		}
+	var username = p.userName(content.UserPrincipalName)
+	if err = signInSSOUser(p, username, content); err != nil {
+		logger.Log(1, "could not sign in user", username, "through", p.name+":", err.Error())
+		handleSSOSignInError(w, err)
+		return
 	}
 	var newPass, fetchErr = fetchPassValue("")
 	if fetchErr != nil {
@@ -72,26 +78,26 @@ func handleAzureCallback(w http.ResponseWriter, r *http.Request) {
 	}
 	// send a netmaker jwt token
 	var authRequest = models.UserAuthParams{
-		UserName: content.UserPrincipalName,
+		UserName: username,
 		Password: newPass,
 	}
 
-	var jwt, jwtErr = logic.VerifyAuthRequest(authRequest)
+	var login, jwtErr = logic.VerifyOAuthRequest(authRequest, r)
 	if jwtErr != nil {
 		logger.Log(1, "could not parse jwt for user", authRequest.UserName)
 		return
 	}
 
-	logger.Log(1, "completed azure OAuth sigin in for", content.UserPrincipalName)
-	http.Redirect(w, r, servercfg.GetFrontendURL()+"/login?login="+jwt+"&user="+content.UserPrincipalName, http.StatusPermanentRedirect)
+	logger.Log(1, "completed azure OAuth sigin in for", username)
+	redirectSSOLogin(w, r, login)
 }
 
-func getAzureUserInfo(state string, code string) (*OAuthUser, error) {
+func getAzureUserInfo(p *provider, state string, code string) (*OAuthUser, error) {
 	oauth_state_string, isValid := logic.IsStateValid(state)
 	if (!isValid || state != oauth_state_string) && !isStateCached(state) {
 		return nil, fmt.Errorf("invalid oauth state")
 	}
-	var token, err = auth_provider.Exchange(context.Background(), code)
+	var token, err = p.config.Exchange(context.Background(), code)
 	if err != nil {
 		return nil, fmt.Errorf("code exchange failed: %s", err.Error())
 	}
@@ -119,9 +125,32 @@ func getAzureUserInfo(state string, code string) (*OAuthUser, error) {
 		return nil, fmt.Errorf("failed parsing email from response data: %s", err.Error())
 	}
 	userInfo.AccessToken = string(data)
+	if servercfg.IsSSOGroupSyncEnabled() {
+		userInfo.Groups = getAzureGroups(token)
+	}
 	return userInfo, nil
 }
 
+// getAzureGroups - group object ids and app roles of the id token of a user, the token comes straight
+// from the token endpoint so its signature is not checked
+func getAzureGroups(token *oauth2.Token) []string {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		logger.Log(0, "azure ad returned no id token, groups of the user are unknown")
+		return nil
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(rawIDToken, claims); err != nil {
+		logger.Log(0, "failed to parse azure ad id token:", err.Error())
+		return nil
+	}
+	if _, ok := claims["_claim_names"]; ok {
+		logger.Log(0, "user is in too many groups to be listed in its azure ad id token, emit only the groups assigned to the application")
+		return nil
+	}
+	return append(claimValues(claims["groups"]), claimValues(claims["roles"])...)
+}
+
 func verifyAzureUser(token *oauth2.Token) bool {
 	return token.Valid()
 }
@@ -1,6 +1,12 @@
 package auth
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+)
+
+// errSSOUserNotLinked - an existing user signed in through an sso provider it is not linked to
+var errSSOUserNotLinked = errors.New("user is not linked to sso provider")
 
 // == define error HTML here ==
 const oauthNotConfigured = `<!DOCTYPE html><html>
@@ -10,9 +16,35 @@ const oauthNotConfigured = `<!DOCTYPE html><html>
 </body>
 </html>`
 
+const oauthUserNotLinked = `<!DOCTYPE html><html>
+<body>
+<h3>Your Netmaker user cannot sign in through this provider.</h3>
+<p>Please sign in through the provider your user was created with, or ask an admin to link your user to this one.</p>
+</body>
+</html>`
+
+const oauthSignInFailed = `<!DOCTYPE html><html>
+<body>
+<h3>Your Netmaker server could not sign you in.</h3>
+<p>Please try again or contact your admin.</p>
+</body>
+</html>`
+
 // handleOauthNotConfigured - returns an appropriate html page when oauth is not configured on netmaker server but an oauth login was attempted
 func handleOauthNotConfigured(response http.ResponseWriter) {
 	response.Header().Set("Content-Type", "text/html; charset=utf-8")
 	response.WriteHeader(http.StatusInternalServerError)
 	response.Write([]byte(oauthNotConfigured))
 }
+
+// handleSSOSignInError - returns an appropriate html page when a user authenticated by an sso provider could not be signed in
+func handleSSOSignInError(response http.ResponseWriter, err error) {
+	response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if errors.Is(err, errSSOUserNotLinked) {
+		response.WriteHeader(http.StatusForbidden)
+		response.Write([]byte(oauthUserNotLinked))
+		return
+	}
+	response.WriteHeader(http.StatusInternalServerError)
+	response.Write([]byte(oauthSignInFailed))
+}
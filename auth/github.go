@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/gravitl/netmaker/config"
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/logic"
 	"github.com/gravitl/netmaker/models"
@@ -25,46 +26,49 @@ var github_functions = map[string]interface{}{
 
 // == handle github authentication here ==
 
-func initGithub(redirectURL string, clientID string, clientSecret string) {
-	auth_provider = &oauth2.Config{
+func initGithub(p *provider, redirectURL string, settings config.AuthProvider) {
+	p.config = &oauth2.Config{
 		RedirectURL:  redirectURL,
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
+		ClientID:     settings.ClientID,
+		ClientSecret: settings.ClientSecret,
 		Scopes:       []string{},
 		Endpoint:     github.Endpoint,
 	}
+	if servercfg.IsSSOGroupSyncEnabled() {
+		p.config.Scopes = append(p.config.Scopes, "read:org")
+	}
 }
 
-func handleGithubLogin(w http.ResponseWriter, r *http.Request) {
+func handleGithubLogin(p *provider, w http.ResponseWriter, r *http.Request) {
 	var oauth_state_string = logic.RandomString(user_signin_length)
-	if auth_provider == nil {
+	if p.config == nil {
 		handleOauthNotConfigured(w)
 		return
 	}
 
-	if err := logic.SetState(oauth_state_string); err != nil {
+	if err := logic.SetState(oauth_state_string, p.name); err != nil {
 		handleOauthNotConfigured(w)
 		return
 	}
 
-	var url = auth_provider.AuthCodeURL(oauth_state_string)
+	var url = p.config.AuthCodeURL(oauth_state_string)
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
-func handleGithubCallback(w http.ResponseWriter, r *http.Request) {
+func handleGithubCallback(p *provider, w http.ResponseWriter, r *http.Request) {
 
 	var rState, rCode = getStateAndCode(r)
-	var content, err = getGithubUserInfo(rState, rCode)
+	var content, err = getGithubUserInfo(p, rState, rCode)
 	if err != nil {
 		logger.Log(1, "error when getting user info from github:", err.Error())
 		handleOauthNotConfigured(w)
 		return
 	}
-	_, err = logic.GetUser(content.Login)
-	if err != nil { // user must not exist, so try to make one
-		if err = addUser(content.Login); err != nil {
-			return
-		}
+	var username = p.userName(content.Login)
+	if err = signInSSOUser(p, username, content); err != nil {
+		logger.Log(1, "could not sign in user", username, "through", p.name+":", err.Error())
+		handleSSOSignInError(w, err)
+		return
 	}
 	var newPass, fetchErr = fetchPassValue("")
 	if fetchErr != nil {
@@ -72,26 +76,26 @@ func handleGithubCallback(w http.ResponseWriter, r *http.Request) {
 	}
 	// send a netmaker jwt token
 	var authRequest = models.UserAuthParams{
-		UserName: content.Login,
+		UserName: username,
 		Password: newPass,
 	}
 
-	var jwt, jwtErr = logic.VerifyAuthRequest(authRequest)
+	var login, jwtErr = logic.VerifyOAuthRequest(authRequest, r)
 	if jwtErr != nil {
 		logger.Log(1, "could not parse jwt for user", authRequest.UserName)
 		return
 	}
 
-	logger.Log(1, "completed github OAuth sigin in for", content.Login)
-	http.Redirect(w, r, servercfg.GetFrontendURL()+"/login?login="+jwt+"&user="+content.Login, http.StatusPermanentRedirect)
+	logger.Log(1, "completed github OAuth sigin in for", username)
+	redirectSSOLogin(w, r, login)
 }
 
-func getGithubUserInfo(state string, code string) (*OAuthUser, error) {
+func getGithubUserInfo(p *provider, state string, code string) (*OAuthUser, error) {
 	oauth_state_string, isValid := logic.IsStateValid(state)
 	if (!isValid || state != oauth_state_string) && !isStateCached(state) {
 		return nil, fmt.Errorf("invalid oauth state")
 	}
-	var token, err = auth_provider.Exchange(context.Background(), code)
+	var token, err = p.config.Exchange(context.Background(), code)
 	if err != nil {
 		return nil, fmt.Errorf("code exchange failed: %s", err.Error())
 	}
@@ -123,9 +127,51 @@ func getGithubUserInfo(state string, code string) (*OAuthUser, error) {
 		return nil, fmt.Errorf("failed parsing email from response data: %s", err.Error())
 	}
 	userInfo.AccessToken = string(data)
+	if servercfg.IsSSOGroupSyncEnabled() {
+		if userInfo.Groups, err = getGithubGroups(httpClient, token); err != nil {
+			logger.Log(0, "failed to fetch github orgs and teams of", userInfo.Login, err.Error())
+		}
+	}
 	return userInfo, nil
 }
 
+// getGithubGroups - orgs of a user as "org" and its teams as "org/team"
+func getGithubGroups(client *http.Client, token *oauth2.Token) ([]string, error) {
+	groups := []string{}
+	for page := 1; ; page++ {
+		var orgs []struct {
+			Login string `json:"login"`
+		}
+		if err := fetchProviderJSON(client, fmt.Sprintf("https://api.github.com/user/orgs?per_page=100&page=%d", page), "token "+token.AccessToken, &orgs); err != nil {
+			return nil, err
+		}
+		for _, org := range orgs {
+			groups = append(groups, org.Login)
+		}
+		if len(orgs) < 100 {
+			break
+		}
+	}
+	for page := 1; ; page++ {
+		var teams []struct {
+			Slug         string `json:"slug"`
+			Organization struct {
+				Login string `json:"login"`
+			} `json:"organization"`
+		}
+		if err := fetchProviderJSON(client, fmt.Sprintf("https://api.github.com/user/teams?per_page=100&page=%d", page), "token "+token.AccessToken, &teams); err != nil {
+			return nil, err
+		}
+		for _, team := range teams {
+			groups = append(groups, team.Organization.Login+"/"+team.Slug)
+		}
+		if len(teams) < 100 {
+			break
+		}
+	}
+	return groups, nil
+}
+
 func verifyGithubUser(token *oauth2.Token) bool {
 	return token.Valid()
 }
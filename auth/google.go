@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/gravitl/netmaker/config"
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/logic"
 	"github.com/gravitl/netmaker/models"
@@ -26,47 +28,50 @@ var google_functions = map[string]interface{}{
 
 // == handle google authentication here ==
 
-func initGoogle(redirectURL string, clientID string, clientSecret string) {
-	auth_provider = &oauth2.Config{
+func initGoogle(p *provider, redirectURL string, settings config.AuthProvider) {
+	p.config = &oauth2.Config{
 		RedirectURL:  redirectURL,
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
+		ClientID:     settings.ClientID,
+		ClientSecret: settings.ClientSecret,
 		Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email"},
 		Endpoint:     google.Endpoint,
 	}
+	if servercfg.IsSSOGroupSyncEnabled() {
+		p.config.Scopes = append(p.config.Scopes, "https://www.googleapis.com/auth/cloud-identity.groups.readonly")
+	}
 }
 
-func handleGoogleLogin(w http.ResponseWriter, r *http.Request) {
+func handleGoogleLogin(p *provider, w http.ResponseWriter, r *http.Request) {
 	var oauth_state_string = logic.RandomString(user_signin_length)
-	if auth_provider == nil {
+	if p.config == nil {
 		handleOauthNotConfigured(w)
 		return
 	}
 
-	if err := logic.SetState(oauth_state_string); err != nil {
+	if err := logic.SetState(oauth_state_string, p.name); err != nil {
 		handleOauthNotConfigured(w)
 		return
 	}
 
-	var url = auth_provider.AuthCodeURL(oauth_state_string)
+	var url = p.config.AuthCodeURL(oauth_state_string)
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
-func handleGoogleCallback(w http.ResponseWriter, r *http.Request) {
+func handleGoogleCallback(p *provider, w http.ResponseWriter, r *http.Request) {
 
 	var rState, rCode = getStateAndCode(r)
 
-	var content, err = getGoogleUserInfo(rState, rCode)
+	var content, err = getGoogleUserInfo(p, rState, rCode)
 	if err != nil {
 		logger.Log(1, "error when getting user info from google:", err.Error())
 		handleOauthNotConfigured(w)
 		return
 	}
-	_, err = logic.GetUser(content.Email)
-	if err != nil { // user must not exists, so try to make one
-		if err = addUser(content.Email); err != nil {
-			return
-		}
+	var username = p.userName(content.Email)
+	if err = signInSSOUser(p, username, content); err != nil {
+		logger.Log(1, "could not sign in user", username, "through", p.name+":", err.Error())
+		handleSSOSignInError(w, err)
+		return
 	}
 	var newPass, fetchErr = fetchPassValue("")
 	if fetchErr != nil {
@@ -74,26 +79,26 @@ func handleGoogleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 	// send a netmaker jwt token
 	var authRequest = models.UserAuthParams{
-		UserName: content.Email,
+		UserName: username,
 		Password: newPass,
 	}
 
-	var jwt, jwtErr = logic.VerifyAuthRequest(authRequest)
+	var login, jwtErr = logic.VerifyOAuthRequest(authRequest, r)
 	if jwtErr != nil {
 		logger.Log(1, "could not parse jwt for user", authRequest.UserName)
 		return
 	}
 
-	logger.Log(1, "completed google OAuth sigin in for", content.Email)
-	http.Redirect(w, r, fmt.Sprintf("%s/login?login=%s&user=%s", servercfg.GetFrontendURL(), jwt, content.Email), http.StatusPermanentRedirect)
+	logger.Log(1, "completed google OAuth sigin in for", username)
+	redirectSSOLogin(w, r, login)
 }
 
-func getGoogleUserInfo(state string, code string) (*OAuthUser, error) {
+func getGoogleUserInfo(p *provider, state string, code string) (*OAuthUser, error) {
 	oauth_state_string, isValid := logic.IsStateValid(state)
 	if (!isValid || state != oauth_state_string) && !isStateCached(state) {
 		return nil, fmt.Errorf("invalid oauth state")
 	}
-	var token, err = auth_provider.Exchange(context.Background(), code)
+	var token, err = p.config.Exchange(context.Background(), code)
 	if err != nil {
 		return nil, fmt.Errorf("code exchange failed: %s", err.Error())
 	}
@@ -119,9 +124,42 @@ func getGoogleUserInfo(state string, code string) (*OAuthUser, error) {
 		return nil, fmt.Errorf("failed parsing email from response data: %s", err.Error())
 	}
 	userInfo.AccessToken = string(data)
+	if servercfg.IsSSOGroupSyncEnabled() {
+		if userInfo.Groups, err = getGoogleGroups(client, token, userInfo.Email); err != nil {
+			logger.Log(0, "failed to fetch google groups of", userInfo.Email, err.Error())
+		}
+	}
 	return userInfo, nil
 }
 
+// getGoogleGroups - emails of the google groups a user is a direct or indirect member of
+func getGoogleGroups(client *http.Client, token *oauth2.Token, email string) ([]string, error) {
+	groups := []string{}
+	query := url.Values{}
+	query.Set("query", fmt.Sprintf("member_key_id == '%s' && 'cloudidentity.googleapis.com/groups.discussion_forum' in labels", email))
+	for {
+		var result struct {
+			Memberships []struct {
+				GroupKey struct {
+					ID string `json:"id"`
+				} `json:"groupKey"`
+			} `json:"memberships"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := fetchProviderJSON(client, "https://cloudidentity.googleapis.com/v1/groups/-/memberships:searchTransitiveGroups?"+query.Encode(), "Bearer "+token.AccessToken, &result); err != nil {
+			return nil, err
+		}
+		for _, membership := range result.Memberships {
+			groups = append(groups, membership.GroupKey.ID)
+		}
+		if result.NextPageToken == "" {
+			break
+		}
+		query.Set("pageToken", result.NextPageToken)
+	}
+	return groups, nil
+}
+
 func verifyGoogleUser(token *oauth2.Token) bool {
 	return token.Valid()
 }
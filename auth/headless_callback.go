@@ -13,16 +13,16 @@ import (
 
 // HandleHeadlessSSOCallback - handle OAuth callback for headless logins such as Netmaker CLI
 func HandleHeadlessSSOCallback(w http.ResponseWriter, r *http.Request) {
-	functions := getCurrentAuthFunctions()
-	if functions == nil {
+	state, code := getStateAndCode(r)
+	p := getCachedProvider(state)
+	if p == nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte("bad conf"))
 		logger.Log(0, "Missing Oauth config in HandleHeadlessSSOCallback")
 		return
 	}
-	state, code := getStateAndCode(r)
 
-	userClaims, err := functions[get_user_info].(func(string, string) (*OAuthUser, error))(state, code)
+	userClaims, err := p.functions[get_user_info].(func(*provider, string, string) (*OAuthUser, error))(p, state, code)
 	if err != nil {
 		logger.Log(0, "error when getting user info from callback:", err.Error())
 		w.WriteHeader(http.StatusBadRequest)
@@ -50,43 +50,44 @@ func HandleHeadlessSSOCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = logic.GetUser(userClaims.getUserName())
-	if err != nil { // user must not exists, so try to make one
-		if err = addUser(userClaims.getUserName()); err != nil {
-			logger.Log(1, "could not create new user: ", userClaims.getUserName())
-			return
-		}
+	username := p.userName(userClaims.getUserName())
+	if err = signInSSOUser(p, username, userClaims); err != nil {
+		logger.Log(1, "could not sign in user", username, "through", p.name+":", err.Error())
+		response := returnErrTemplate(username, "user cannot sign in through "+p.name, state, reqKeyIf)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write(response)
+		return
 	}
 	newPass, fetchErr := fetchPassValue("")
 	if fetchErr != nil {
 		return
 	}
-	jwt, jwtErr := logic.VerifyAuthRequest(models.UserAuthParams{
-		UserName: userClaims.getUserName(),
+	login, jwtErr := logic.VerifyOAuthRequest(models.UserAuthParams{
+		UserName: username,
 		Password: newPass,
-	})
+	}, r)
 	if jwtErr != nil {
-		logger.Log(1, "could not parse jwt for user", userClaims.getUserName())
+		logger.Log(1, "could not parse jwt for user", username)
 		return
 	}
 
-	logger.Log(1, "headless SSO login by user:", userClaims.getUserName())
+	logger.Log(1, "headless SSO login by user:", username)
 
 	// Send OK to user in the browser
 	var response bytes.Buffer
 	if err := ssoCallbackTemplate.Execute(&response, ssoCallbackTemplateConfig{
-		User: userClaims.getUserName(),
+		User: username,
 		Verb: "Authenticated",
 	}); err != nil {
 		logger.Log(0, "Could not render SSO callback template ", err.Error())
-		response := returnErrTemplate(userClaims.getUserName(), "Could not render SSO callback template", state, reqKeyIf)
+		response := returnErrTemplate(username, "Could not render SSO callback template", state, reqKeyIf)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(response)
 	} else {
 		w.WriteHeader(http.StatusOK)
 		w.Write(response.Bytes())
 	}
-	reqKeyIf.Pass = fmt.Sprintf("JWT: %s", jwt)
+	reqKeyIf.Pass = fmt.Sprintf("JWT: %s", login.AuthToken)
 	if err = netcache.Set(state, reqKeyIf); err != nil {
 		logger.Log(0, "failed to set netcache for user", reqKeyIf.User, "-", err.Error())
 	}
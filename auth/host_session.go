@@ -22,7 +22,7 @@ import (
 // via SSO mechanism by OAuth2 protocol flow.
 // This triggers a session start and it is managed by the flow implemented here and callback
 // When this method finishes - the auth flow has finished either OK or by timeout or any other error occured
-func SessionHandler(conn *websocket.Conn) {
+func SessionHandler(conn *websocket.Conn, sourceIP string) {
 	defer conn.Close()
 	// If reached here we have a session from user to handle...
 	messageType, message, err := conn.ReadMessage()
@@ -48,6 +48,7 @@ func SessionHandler(conn *websocket.Conn) {
 	req.ALL = registerMessage.JoinAll
 	req.Pass = ""
 	req.User = registerMessage.User
+	req.Provider = registerMessage.Provider
 	if len(req.User) > 0 && len(registerMessage.Password) == 0 {
 		logger.Log(0, "invalid host registration attempted")
 		return
@@ -73,11 +74,13 @@ func SessionHandler(conn *websocket.Conn) {
 				logger.Log(0, "error during message writing:", err.Error())
 			}
 		}
-		_, err := logic.VerifyAuthRequest(models.UserAuthParams{
+		_, err := logic.VerifyUserLogin(models.UserAuthParams{
 			UserName: registerMessage.User,
 			Password: registerMessage.Password,
-		})
+			TOTP:     registerMessage.TOTP,
+		}, sourceIP)
 		if err != nil {
+			logger.Log(0, "host registration by user", registerMessage.User, "failed:", err.Error())
 			err = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 			if err != nil {
 				logger.Log(0, "error during message writing:", err.Error())
@@ -91,7 +94,7 @@ func SessionHandler(conn *websocket.Conn) {
 			return
 		}
 	} else { // handle SSO / OAuth
-		if auth_provider == nil {
+		if getProvider(registerMessage.Provider) == nil {
 			err = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 			if err != nil {
 				logger.Log(0, "error during message writing:", err.Error())
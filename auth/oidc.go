@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gravitl/netmaker/config"
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/logic"
 	"github.com/gravitl/netmaker/models"
@@ -24,60 +25,58 @@ var oidc_functions = map[string]interface{}{
 	verify_user:     verifyOIDCUser,
 }
 
-var oidc_verifier *oidc.IDTokenVerifier
-
 // == handle OIDC authentication here ==
 
-func initOIDC(redirectURL string, clientID string, clientSecret string, issuer string) {
+func initOIDC(p *provider, redirectURL string, settings config.AuthProvider) {
 	ctx, cancel := context.WithTimeout(context.Background(), OIDC_TIMEOUT)
 	defer cancel()
 
-	provider, err := oidc.NewProvider(ctx, issuer)
+	issuer, err := oidc.NewProvider(ctx, settings.OIDCIssuer)
 	if err != nil {
-		logger.Log(1, "error when initializing OIDC provider with issuer \""+issuer+"\"", err.Error())
+		logger.Log(1, "error when initializing OIDC provider with issuer \""+settings.OIDCIssuer+"\"", err.Error())
 		return
 	}
 
-	oidc_verifier = provider.Verifier(&oidc.Config{ClientID: clientID})
-	auth_provider = &oauth2.Config{
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
+	p.verifier = issuer.Verifier(&oidc.Config{ClientID: settings.ClientID})
+	p.config = &oauth2.Config{
+		ClientID:     settings.ClientID,
+		ClientSecret: settings.ClientSecret,
 		RedirectURL:  redirectURL,
-		Endpoint:     provider.Endpoint(),
+		Endpoint:     issuer.Endpoint(),
 		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
 	}
 }
 
-func handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+func handleOIDCLogin(p *provider, w http.ResponseWriter, r *http.Request) {
 	var oauth_state_string = logic.RandomString(user_signin_length)
-	if auth_provider == nil {
+	if p.config == nil {
 		handleOauthNotConfigured(w)
 		return
 	}
 
-	if err := logic.SetState(oauth_state_string); err != nil {
+	if err := logic.SetState(oauth_state_string, p.name); err != nil {
 		handleOauthNotConfigured(w)
 		return
 	}
-	var url = auth_provider.AuthCodeURL(oauth_state_string)
+	var url = p.config.AuthCodeURL(oauth_state_string)
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
-func handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+func handleOIDCCallback(p *provider, w http.ResponseWriter, r *http.Request) {
 
 	var rState, rCode = getStateAndCode(r)
 
-	var content, err = getOIDCUserInfo(rState, rCode)
+	var content, err = getOIDCUserInfo(p, rState, rCode)
 	if err != nil {
 		logger.Log(1, "error when getting user info from callback:", err.Error())
 		handleOauthNotConfigured(w)
 		return
 	}
-	_, err = logic.GetUser(content.Email)
-	if err != nil { // user must not exists, so try to make one
-		if err = addUser(content.Email); err != nil {
-			return
-		}
+	var username = p.userName(content.Email)
+	if err = signInSSOUser(p, username, content); err != nil {
+		logger.Log(1, "could not sign in user", username, "through", p.name+":", err.Error())
+		handleSSOSignInError(w, err)
+		return
 	}
 	var newPass, fetchErr = fetchPassValue("")
 	if fetchErr != nil {
@@ -85,21 +84,21 @@ func handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
 	}
 	// send a netmaker jwt token
 	var authRequest = models.UserAuthParams{
-		UserName: content.Email,
+		UserName: username,
 		Password: newPass,
 	}
 
-	var jwt, jwtErr = logic.VerifyAuthRequest(authRequest)
+	var login, jwtErr = logic.VerifyOAuthRequest(authRequest, r)
 	if jwtErr != nil {
 		logger.Log(1, "could not parse jwt for user", authRequest.UserName, jwtErr.Error())
 		return
 	}
 
-	logger.Log(1, "completed OIDC OAuth signin in for", content.Email)
-	http.Redirect(w, r, servercfg.GetFrontendURL()+"/login?login="+jwt+"&user="+content.Email, http.StatusPermanentRedirect)
+	logger.Log(1, "completed OIDC OAuth signin in for", username)
+	redirectSSOLogin(w, r, login)
 }
 
-func getOIDCUserInfo(state string, code string) (u *OAuthUser, e error) {
+func getOIDCUserInfo(p *provider, state string, code string) (u *OAuthUser, e error) {
 	oauth_state_string, isValid := logic.IsStateValid(state)
 	logger.Log(3, "using oauth state string:,", oauth_state_string)
 	logger.Log(3, "            state string:,", state)
@@ -116,7 +115,7 @@ func getOIDCUserInfo(state string, code string) (u *OAuthUser, e error) {
 	ctx, cancel := context.WithTimeout(context.Background(), OIDC_TIMEOUT)
 	defer cancel()
 
-	oauth2Token, err := auth_provider.Exchange(ctx, code)
+	oauth2Token, err := p.config.Exchange(ctx, code)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange oauth2 token using code \"%s\"", code)
 	}
@@ -126,7 +125,7 @@ func getOIDCUserInfo(state string, code string) (u *OAuthUser, e error) {
 		return nil, fmt.Errorf("failed to get raw id_token from oauth2 token")
 	}
 
-	idToken, err := oidc_verifier.Verify(ctx, rawIDToken)
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify raw id_token: \"%s\"", err.Error())
 	}
@@ -134,6 +133,11 @@ func getOIDCUserInfo(state string, code string) (u *OAuthUser, e error) {
 	u = &OAuthUser{}
 	if err := idToken.Claims(u); err != nil {
 		e = fmt.Errorf("error when claiming OIDCUser: \"%s\"", err.Error())
+	} else if servercfg.IsSSOGroupSyncEnabled() {
+		var claims map[string]interface{}
+		if err := idToken.Claims(&claims); err == nil {
+			u.Groups = claimValues(claims[servercfg.GetSSOGroupsClaim()])
+		}
 	}
 
 	return
@@ -27,17 +27,16 @@ var (
 // Listens in /oidc/callback.
 func HandleHostSSOCallback(w http.ResponseWriter, r *http.Request) {
 
-	var functions = getCurrentAuthFunctions()
-	if functions == nil {
+	state, code := getStateAndCode(r)
+	var p = getCachedProvider(state)
+	if p == nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte("bad conf"))
 		logger.Log(0, "Missing Oauth config in HandleNodeSSOCallback")
 		return
 	}
 
-	state, code := getStateAndCode(r)
-
-	var userClaims, err = functions[get_user_info].(func(string, string) (*OAuthUser, error))(state, code)
+	var userClaims, err = p.functions[get_user_info].(func(*provider, string, string) (*OAuthUser, error))(p, state, code)
 	if err != nil {
 		logger.Log(0, "error when getting user info from callback:", err.Error())
 		handleOauthNotConfigured(w)
@@ -71,12 +70,23 @@ func HandleHostSSOCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logger.Log(1, "registering host for user:", userClaims.getUserName(), reqKeyIf.Host.Name, reqKeyIf.Host.ID.String())
+	var username = p.userName(userClaims.getUserName())
+	if _, err = logic.GetUser(username); err == nil {
+		if err = checkSSOUserLink(p, username); err != nil {
+			logger.Log(0, "host registration by user", username, "failed:", err.Error())
+			response := returnErrTemplate(username, "user cannot sign in through "+p.name, state, reqKeyIf)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write(response)
+			return
+		}
+		applySSOMappings(p, username, userClaims)
+	}
+	logger.Log(1, "registering host for user:", username, reqKeyIf.Host.Name, reqKeyIf.Host.ID.String())
 
 	// Send OK to user in the browser
 	var response bytes.Buffer
 	if err := ssoCallbackTemplate.Execute(&response, ssoCallbackTemplateConfig{
-		User: userClaims.getUserName(),
+		User: username,
 		Verb: "Authenticated",
 	}); err != nil {
 		logger.Log(0, "Could not render SSO callback template ", err.Error())
@@ -88,7 +98,7 @@ func HandleHostSSOCallback(w http.ResponseWriter, r *http.Request) {
 		w.Write(response.Bytes())
 	}
 
-	reqKeyIf.User = userClaims.getUserName() // set the cached registering hosts' user
+	reqKeyIf.User = username // set the cached registering hosts' user
 	if err = netcache.Set(state, reqKeyIf); err != nil {
 		logger.Log(0, "machine failed to complete join on network,", reqKeyIf.Network, "-", err.Error())
 		return
@@ -127,10 +137,11 @@ func returnErrTemplate(uname, message, state string, ncache *netcache.CValue) []
 
 // RegisterHostSSO redirects to the IDP for authentication
 // Puts machine key in cache so the callback can retrieve it using the oidc state param
+// Lets the user pick the IDP when several are configured and the sign-in did not name one
 // Listens in /oidc/register/:regKey.
 func RegisterHostSSO(w http.ResponseWriter, r *http.Request) {
 
-	if auth_provider == nil {
+	if defaultProvider == nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte("invalid login attempt"))
 		return
@@ -144,8 +155,47 @@ func RegisterHostSSO(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("invalid login attempt"))
 		return
 	}
+	reqKeyIf, err := netcache.Get(machineKeyStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid login attempt"))
+		return
+	}
+	var providerName = reqKeyIf.Provider
+	if providerName == "" {
+		providerName = r.URL.Query().Get("provider")
+	}
+	if providerName == "" && len(providers) > 1 {
+		var response bytes.Buffer
+		if err = ssoProviderChoiceTemplate.Execute(&response, ssoProviderChoiceTemplateConfig{
+			State:     machineKeyStr,
+			Providers: ListAuthProviders(),
+		}); err != nil {
+			logger.Log(0, "Could not render SSO provider choice template ", err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("content-type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(response.Bytes())
+		return
+	}
+	var p = getProvider(providerName)
+	if p == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("unknown sso provider"))
+		return
+	}
+	if reqKeyIf.Provider != p.name { // the callback signs in through the provider cached with the state
+		reqKeyIf.Provider = p.name
+		if err = netcache.Set(machineKeyStr, reqKeyIf); err != nil {
+			logger.Log(0, "failed to set netcache for sso provider", p.name, "-", err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
 
-	http.Redirect(w, r, auth_provider.AuthCodeURL(machineKeyStr), http.StatusSeeOther)
+	http.Redirect(w, r, p.config.AuthCodeURL(machineKeyStr), http.StatusSeeOther)
 }
 
 // == private ==
@@ -1,12 +1,21 @@
 package auth
 
-import "html/template"
+import (
+	"html/template"
+
+	"github.com/gravitl/netmaker/models"
+)
 
 type ssoCallbackTemplateConfig struct {
 	User string
 	Verb string
 }
 
+type ssoProviderChoiceTemplateConfig struct {
+	State     string
+	Providers []models.SSOProvider
+}
+
 var ssoCallbackTemplate = template.Must(
 	template.New("ssocallback").Parse(`<!DOCTYPE html>
 	<html lang="en">
@@ -127,3 +136,56 @@ var ssoErrCallbackTemplate = template.Must(
 
 	</html>`),
 )
+
+var ssoProviderChoiceTemplate = template.Must(
+	template.New("ssoproviderchoice").Parse(`<!DOCTYPE html>
+	<html lang="en">
+
+	<head>
+		<meta charset="UTF-8">
+		<meta name="viewport" content="width=device-width, initial-scale=1.0, user-scalable=yes">
+		<meta http-equiv="X-UA-Compatible" content="ie=edge">
+		<title>Netmaker :: SSO Sign In</title>
+
+		<style>
+			html, body {
+				margin: 0px;
+				padding: 0px;
+			}
+			body {
+				height: 100vh;
+				overflow: hidden;
+				display: flex;
+				flex-flow: column nowrap;
+				justify-content: center;
+				align-items: center;
+			}
+			#logo {
+				width: 150px;
+			}
+			h3 {
+				margin-bottom: 3rem;
+				color: rgb(25, 135, 84);
+				font-size: xx-large;
+			}
+			a {
+				display: block;
+				margin: 0.5rem;
+			}
+		</style>
+	</head>
+
+	<body>
+		<img
+			src="https://raw.githubusercontent.com/gravitl/netmaker-docs/master/images/netmaker-github/netmaker-teal.png"
+			alt="netmaker logo"
+			id="logo"
+		>
+		<h3>Sign in with</h3>
+		{{range .Providers}}
+		<a href="/api/oauth/register/{{$.State}}?provider={{.Name}}">{{.Name}} ({{.Type}})</a>
+		{{end}}
+	</body>
+
+	</html>`),
+)
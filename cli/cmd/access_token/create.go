@@ -0,0 +1,47 @@
+package access_token
+
+import (
+	"strings"
+
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/gravitl/netmaker/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	expiration int
+	networks   string
+	resources  string
+	readOnly   bool
+)
+
+var accessTokenCreateCmd = &cobra.Command{
+	Use:   "create [NAME]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Create an access token",
+	Long:  `Create an access token, the token is only printed once`,
+	Run: func(cmd *cobra.Command, args []string) {
+		req := &models.AccessTokenRequest{
+			Name:       args[0],
+			Expiration: int64(expiration),
+			Scope: models.AccessTokenScope{
+				ReadOnly: readOnly,
+			},
+		}
+		if networks != "" {
+			req.Scope.Networks = strings.Split(networks, ",")
+		}
+		if resources != "" {
+			req.Scope.Resources = strings.Split(resources, ",")
+		}
+		functions.PrettyPrint(functions.CreateAccessToken(req))
+	},
+}
+
+func init() {
+	accessTokenCreateCmd.Flags().IntVar(&expiration, "expiration", 0, "Expiration time of the token in UNIX timestamp format")
+	accessTokenCreateCmd.Flags().StringVar(&networks, "networks", "", "Comma-separated list of networks the token is limited to")
+	accessTokenCreateCmd.Flags().StringVar(&resources, "resources", "", "Comma-separated list of resources the token is limited to (Enum:- network,node,host,ext_client,dns,enrollment_key,acl,user,server)")
+	accessTokenCreateCmd.Flags().BoolVar(&readOnly, "read_only", false, "Limit the token to read requests ?")
+	rootCmd.AddCommand(accessTokenCreateCmd)
+}
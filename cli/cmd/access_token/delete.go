@@ -0,0 +1,23 @@
+package access_token
+
+import (
+	"fmt"
+
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/spf13/cobra"
+)
+
+var accessTokenDeleteCmd = &cobra.Command{
+	Use:   "delete [TOKEN ID]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Revoke an access token",
+	Long:  `Revoke an access token`,
+	Run: func(cmd *cobra.Command, args []string) {
+		functions.RevokeAccessToken(args[0])
+		fmt.Println("Access token", args[0], "revoked")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(accessTokenDeleteCmd)
+}
@@ -0,0 +1,46 @@
+package access_token
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravitl/netmaker/cli/cmd/commons"
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/guumaster/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var accessTokenListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.NoArgs,
+	Short: "List access tokens",
+	Long:  `List access tokens`,
+	Run: func(cmd *cobra.Command, args []string) {
+		tokens := functions.GetAccessTokens()
+		switch commons.OutputFormat {
+		case commons.JsonOutput:
+			functions.PrettyPrint(tokens)
+		default:
+			formatTime := func(t time.Time) string {
+				if t.IsZero() {
+					return "never"
+				}
+				return t.Format(time.RFC3339)
+			}
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"ID", "Name", "User", "Networks", "Resources", "Read Only", "Expires", "Last Used"})
+			for _, t := range *tokens {
+				table.Append([]string{t.ID, t.Name, t.UserName, strings.Join(t.Scope.Networks, ", "),
+					strings.Join(t.Scope.Resources, ", "), strconv.FormatBool(t.Scope.ReadOnly),
+					formatTime(t.ExpiresAt), formatTime(t.LastUsedAt)})
+			}
+			table.Render()
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(accessTokenListCmd)
+}
@@ -0,0 +1,30 @@
+package audit
+
+import (
+	"fmt"
+
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/spf13/cobra"
+)
+
+var exportFormat string
+
+var auditExportCmd = &cobra.Command{
+	Use:   "export",
+	Args:  cobra.NoArgs,
+	Short: "Export audit events",
+	Long:  `Export the changes made through the api as jsonl or syslog lines, oldest first`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if exportFormat != "jsonl" && exportFormat != "syslog" {
+			cmd.PrintErrln("format must be jsonl or syslog")
+			return
+		}
+		fmt.Print(functions.ExportAuditLog(filterQuery(), exportFormat))
+	},
+}
+
+func init() {
+	addFilterFlags(auditExportCmd)
+	auditExportCmd.Flags().StringVar(&exportFormat, "format", "jsonl", "Export format: jsonl or syslog")
+	rootCmd.AddCommand(auditExportCmd)
+}
@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"net/url"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	resource string
+	actor    string
+	action   string
+	network  string
+	since    string
+	until    string
+	limit    string
+)
+
+// addFilterFlags - adds the flags narrowing a query of the audit log to a command
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&resource, "resource", "", "Resource changed, e.g. node, host or user")
+	cmd.Flags().StringVar(&actor, "actor", "", "User or host which made the changes")
+	cmd.Flags().StringVar(&action, "action", "", "Action taken: create, update or delete")
+	cmd.Flags().StringVar(&network, "network", "", "Network the changes were made in")
+	cmd.Flags().StringVar(&since, "since", "", "Start of the queried period, RFC 3339 or unix seconds")
+	cmd.Flags().StringVar(&until, "until", "", "End of the queried period, RFC 3339 or unix seconds")
+	cmd.Flags().StringVar(&limit, "limit", "", "Maximum number of most recent events")
+}
+
+// filterQuery - query parameters of the set filter flags
+func filterQuery() url.Values {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"resource": resource,
+		"actor":    actor,
+		"action":   action,
+		"network":  network,
+		"since":    since,
+		"until":    until,
+		"limit":    limit,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	return query
+}
@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gravitl/netmaker/cli/cmd/commons"
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/guumaster/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var auditListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.NoArgs,
+	Short: "List audit events",
+	Long:  `List the changes made through the api, oldest first`,
+	Run: func(cmd *cobra.Command, args []string) {
+		events := functions.GetAuditLog(filterQuery())
+		switch commons.OutputFormat {
+		case commons.JsonOutput:
+			functions.PrettyPrint(events)
+		default:
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Time", "Actor", "Action", "Resource", "Resource ID", "Network", "Source IP", "Status"})
+			for _, e := range *events {
+				table.Append([]string{e.Timestamp.Format(time.RFC3339), e.Actor, string(e.Action), string(e.Resource),
+					e.ResourceID, e.Network, e.SourceIP, strconv.Itoa(e.Status)})
+			}
+			table.Render()
+		}
+	},
+}
+
+func init() {
+	addFilterFlags(auditListCmd)
+	rootCmd.AddCommand(auditListCmd)
+}
@@ -8,11 +8,12 @@ import (
 )
 
 var (
-	endpoint  string
-	username  string
-	password  string
-	masterKey string
-	sso       bool
+	endpoint    string
+	username    string
+	password    string
+	masterKey   string
+	accessToken string
+	sso         bool
 )
 
 var contextSetCmd = &cobra.Command{
@@ -22,15 +23,16 @@ var contextSetCmd = &cobra.Command{
 	Long:  `Create a context or update an existing one`,
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := config.Context{
-			Endpoint:  endpoint,
-			Username:  username,
-			Password:  password,
-			MasterKey: masterKey,
-			SSO:       sso,
+			Endpoint:    endpoint,
+			Username:    username,
+			Password:    password,
+			MasterKey:   masterKey,
+			AccessToken: accessToken,
+			SSO:         sso,
 		}
-		if ctx.Username == "" && ctx.MasterKey == "" && !ctx.SSO {
+		if ctx.Username == "" && ctx.MasterKey == "" && ctx.AccessToken == "" && !ctx.SSO {
 			cmd.Usage()
-			log.Fatal("Either username/password or master key is required")
+			log.Fatal("Either username/password, master key or access token is required")
 		}
 		config.SetContext(args[0], ctx)
 	},
@@ -44,5 +46,6 @@ func init() {
 	contextSetCmd.MarkFlagsRequiredTogether("username", "password")
 	contextSetCmd.Flags().BoolVar(&sso, "sso", false, "Login via Single Sign On (SSO) ?")
 	contextSetCmd.Flags().StringVar(&masterKey, "master_key", "", "Master Key")
+	contextSetCmd.Flags().StringVar(&accessToken, "access_token", "", "Access Token")
 	rootCmd.AddCommand(contextSetCmd)
 }
@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyFrom string
+	historyTo   string
+	historyStep string
+)
+
+var metricsHistoryCmd = &cobra.Command{
+	Use:   "history [NETWORK NAME] [NODE ID]",
+	Args:  cobra.ExactArgs(2),
+	Short: "Retrieve the metrics history of a node",
+	Long:  `Retrieve the metrics history of a node, defaults to the last 24 hours`,
+	Run: func(cmd *cobra.Command, args []string) {
+		functions.PrettyPrint(functions.GetNodeMetricsHistory(args[0], args[1], historyFrom, historyTo, historyStep))
+	},
+}
+
+func init() {
+	metricsHistoryCmd.Flags().StringVar(&historyFrom, "from", "", "Start of the range as RFC 3339 time or unix seconds")
+	metricsHistoryCmd.Flags().StringVar(&historyTo, "to", "", "End of the range as RFC 3339 time or unix seconds")
+	metricsHistoryCmd.Flags().StringVar(&historyStep, "step", "", "Width of the returned points, e.g. 5m or 1h")
+	rootCmd.AddCommand(metricsHistoryCmd)
+}
@@ -0,0 +1,36 @@
+package role
+
+import (
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/gravitl/netmaker/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	userName string
+	group    string
+	network  string
+)
+
+var roleBindCmd = &cobra.Command{
+	Use:   "bind [ROLE ID]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Bind a role to a user or a group",
+	Long:  `Bind a role to a user or a group, on one network or on all networks`,
+	Run: func(cmd *cobra.Command, args []string) {
+		functions.PrettyPrint(functions.CreateRoleBinding(&models.RoleBinding{
+			RoleID:   args[0],
+			UserName: userName,
+			Group:    group,
+			Network:  network,
+		}))
+	},
+}
+
+func init() {
+	roleBindCmd.Flags().StringVar(&userName, "user", "", "User to bind the role to")
+	roleBindCmd.Flags().StringVar(&group, "group", "", "User group to bind the role to")
+	roleBindCmd.Flags().StringVar(&network, "network", "", "Network the binding is limited to, all networks if empty")
+	roleBindCmd.MarkFlagsMutuallyExclusive("user", "group")
+	rootCmd.AddCommand(roleBindCmd)
+}
@@ -0,0 +1,48 @@
+package role
+
+import (
+	"os"
+
+	"github.com/gravitl/netmaker/cli/cmd/commons"
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/gravitl/netmaker/models"
+	"github.com/guumaster/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var bindingsUser string
+
+var roleBindingsCmd = &cobra.Command{
+	Use:   "bindings",
+	Args:  cobra.NoArgs,
+	Short: "List role bindings",
+	Long:  `List the stored role bindings, or all bindings in effect for a user with --user`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var bindings *[]models.RoleBinding
+		if bindingsUser != "" {
+			bindings = functions.GetUserRoleBindings(bindingsUser)
+		} else {
+			bindings = functions.GetRoleBindings()
+		}
+		switch commons.OutputFormat {
+		case commons.JsonOutput:
+			functions.PrettyPrint(bindings)
+		default:
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"ID", "Role", "User", "Group", "Network"})
+			for _, b := range *bindings {
+				network := b.Network
+				if network == "" {
+					network = "all"
+				}
+				table.Append([]string{b.ID, b.RoleID, b.UserName, b.Group, network})
+			}
+			table.Render()
+		}
+	},
+}
+
+func init() {
+	roleBindingsCmd.Flags().StringVar(&bindingsUser, "user", "", "List the bindings in effect for a user")
+	rootCmd.AddCommand(roleBindingsCmd)
+}
@@ -0,0 +1,48 @@
+package role
+
+import (
+	"log"
+	"strings"
+
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/gravitl/netmaker/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	permissions string
+	description string
+)
+
+var roleCreateCmd = &cobra.Command{
+	Use:   "create [ID]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Create a custom role",
+	Long:  `Create a custom role out of resource:action permissions, e.g. --permissions node:read,dns:*`,
+	Run: func(cmd *cobra.Command, args []string) {
+		functions.PrettyPrint(functions.CreateRole(&models.Role{
+			ID:          args[0],
+			Description: description,
+			Permissions: parsePermissions(permissions),
+		}))
+	},
+}
+
+func parsePermissions(value string) []models.RbacPermission {
+	perms := []models.RbacPermission{}
+	for _, p := range strings.Split(value, ",") {
+		resource, action, found := strings.Cut(p, ":")
+		if !found {
+			log.Fatalf("invalid permission %s, expected resource:action", p)
+		}
+		perms = append(perms, models.RbacPermission{Resource: models.RbacResource(resource), Action: models.RbacAction(action)})
+	}
+	return perms
+}
+
+func init() {
+	roleCreateCmd.Flags().StringVar(&permissions, "permissions", "", "Comma-separated list of resource:action permissions (resources:- network,node,host,ext_client,dns,enrollment_key,acl,user,server,* actions:- read,create,update,delete,*)")
+	roleCreateCmd.Flags().StringVar(&description, "description", "", "Description of the role")
+	roleCreateCmd.MarkFlagRequired("permissions")
+	rootCmd.AddCommand(roleCreateCmd)
+}
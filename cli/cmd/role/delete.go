@@ -0,0 +1,23 @@
+package role
+
+import (
+	"fmt"
+
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/spf13/cobra"
+)
+
+var roleDeleteCmd = &cobra.Command{
+	Use:   "delete [ID]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Delete a custom role",
+	Long:  `Delete a custom role which is not bound to any user or group`,
+	Run: func(cmd *cobra.Command, args []string) {
+		functions.DeleteRole(args[0])
+		fmt.Println("Role", args[0], "deleted")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(roleDeleteCmd)
+}
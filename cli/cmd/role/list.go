@@ -0,0 +1,41 @@
+package role
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gravitl/netmaker/cli/cmd/commons"
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/guumaster/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var roleListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.NoArgs,
+	Short: "List roles",
+	Long:  `List the built-in and custom roles`,
+	Run: func(cmd *cobra.Command, args []string) {
+		roles := functions.GetRoles()
+		switch commons.OutputFormat {
+		case commons.JsonOutput:
+			functions.PrettyPrint(roles)
+		default:
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"ID", "Permissions", "Built-In", "Description"})
+			for _, r := range *roles {
+				permissions := []string{}
+				for _, p := range r.Permissions {
+					permissions = append(permissions, string(p.Resource)+":"+string(p.Action))
+				}
+				table.Append([]string{r.ID, strings.Join(permissions, ", "), strconv.FormatBool(r.BuiltIn), r.Description})
+			}
+			table.Render()
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(roleListCmd)
+}
@@ -0,0 +1,23 @@
+package role
+
+import (
+	"fmt"
+
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/spf13/cobra"
+)
+
+var roleUnbindCmd = &cobra.Command{
+	Use:   "unbind [BINDING ID]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Delete a role binding",
+	Long:  `Delete a role binding`,
+	Run: func(cmd *cobra.Command, args []string) {
+		functions.DeleteRoleBinding(args[0])
+		fmt.Println("Role binding", args[0], "deleted")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(roleUnbindCmd)
+}
@@ -0,0 +1,41 @@
+package sso_mapping
+
+import (
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/gravitl/netmaker/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	provider   string
+	userGroups []string
+	networks   []string
+	admin      bool
+)
+
+var ssoMappingCreateCmd = &cobra.Command{
+	Use:   "create [IDP GROUP]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Map an identity provider group",
+	Long: `Map an identity provider group to user groups, networks and admin status, applied to its members each time they log in through SSO.
+The group is a value of the groups claim for OIDC, a group object id or app role for Azure AD, a group email for Google, "org" or "org/team" for GitHub and a group dn for LDAP.
+Groups of the same name at different providers are different groups, the mapping only applies to the given provider`,
+	Run: func(cmd *cobra.Command, args []string) {
+		functions.PrettyPrint(functions.CreateSSOMapping(&models.SSOMapping{
+			Provider:   provider,
+			IdPGroup:   args[0],
+			UserGroups: userGroups,
+			Networks:   networks,
+			Admin:      admin,
+		}))
+	},
+}
+
+func init() {
+	ssoMappingCreateCmd.Flags().StringVar(&provider, "provider", "", "Name of the SSO provider of the group, ldap for groups of the LDAP directory")
+	ssoMappingCreateCmd.MarkFlagRequired("provider")
+	ssoMappingCreateCmd.Flags().StringSliceVar(&userGroups, "groups", nil, "User groups granted to members of the group")
+	ssoMappingCreateCmd.Flags().StringSliceVar(&networks, "networks", nil, "Networks granted to members of the group")
+	ssoMappingCreateCmd.Flags().BoolVar(&admin, "admin", false, "Make members of the group admins")
+	rootCmd.AddCommand(ssoMappingCreateCmd)
+}
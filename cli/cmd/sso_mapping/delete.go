@@ -0,0 +1,23 @@
+package sso_mapping
+
+import (
+	"fmt"
+
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/spf13/cobra"
+)
+
+var ssoMappingDeleteCmd = &cobra.Command{
+	Use:   "delete [MAPPING ID]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Delete an sso mapping",
+	Long:  `Delete an sso mapping, its users lose the access at their next login`,
+	Run: func(cmd *cobra.Command, args []string) {
+		functions.DeleteSSOMapping(args[0])
+		fmt.Println("SSO mapping", args[0], "deleted")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ssoMappingDeleteCmd)
+}
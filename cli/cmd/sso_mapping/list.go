@@ -0,0 +1,37 @@
+package sso_mapping
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gravitl/netmaker/cli/cmd/commons"
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/guumaster/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var ssoMappingListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.NoArgs,
+	Short: "List sso mappings",
+	Long:  `List the mappings of identity provider groups to user groups, networks and admin status`,
+	Run: func(cmd *cobra.Command, args []string) {
+		mappings := functions.GetSSOMappings()
+		switch commons.OutputFormat {
+		case commons.JsonOutput:
+			functions.PrettyPrint(mappings)
+		default:
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"ID", "Provider", "IdP Group", "User Groups", "Networks", "Admin"})
+			for _, m := range *mappings {
+				table.Append([]string{m.ID, m.Provider, m.IdPGroup, strings.Join(m.UserGroups, ", "), strings.Join(m.Networks, ", "), strconv.FormatBool(m.Admin)})
+			}
+			table.Render()
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ssoMappingListCmd)
+}
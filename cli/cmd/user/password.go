@@ -0,0 +1,32 @@
+package user
+
+import (
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/spf13/cobra"
+)
+
+var userResetPasswordCmd = &cobra.Command{
+	Use:   "reset-password [USER NAME]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Create a password reset token",
+	Long: `Create a one-time token the user can set a new password with at /api/users/adm/password-reset,
+replacing any outstanding one. The token is only shown once.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		functions.PrettyPrint(functions.CreatePasswordResetToken(args[0]))
+	},
+}
+
+var userUnlockCmd = &cobra.Command{
+	Use:   "unlock [USER NAME]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Lift the lockout of a user",
+	Long:  `Lift the lockout of a user who failed to log in too often`,
+	Run: func(cmd *cobra.Command, args []string) {
+		functions.PrettyPrint(functions.ClearUserLockout(args[0]))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(userResetPasswordCmd)
+	rootCmd.AddCommand(userUnlockCmd)
+}
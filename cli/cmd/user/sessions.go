@@ -0,0 +1,56 @@
+package user
+
+import (
+	"os"
+	"time"
+
+	"github.com/gravitl/netmaker/cli/cmd/commons"
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/guumaster/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var userSessionsCmd = &cobra.Command{
+	Use:   "sessions [USER NAME]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "List active sessions",
+	Long:  `List the active sessions of a user, or of all users when no user name is given`,
+	Run: func(cmd *cobra.Command, args []string) {
+		username := ""
+		if len(args) > 0 {
+			username = args[0]
+		}
+		sessions := functions.GetUserSessions(username)
+		switch commons.OutputFormat {
+		case commons.JsonOutput:
+			functions.PrettyPrint(sessions)
+		default:
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"ID", "User", "Created", "Refreshed", "Expires", "Source IP", "User Agent"})
+			for _, s := range *sessions {
+				table.Append([]string{s.ID, s.UserName, s.CreatedAt.Format(time.RFC3339), s.RefreshedAt.Format(time.RFC3339),
+					s.ExpiresAt.Format(time.RFC3339), s.SourceIP, s.UserAgent})
+			}
+			table.Render()
+		}
+	},
+}
+
+var userLogoutCmd = &cobra.Command{
+	Use:   "logout [USER NAME] [SESSION ID]",
+	Args:  cobra.RangeArgs(1, 2),
+	Short: "End sessions of a user",
+	Long:  `End a session of a user, or all of its sessions when no session id is given`,
+	Run: func(cmd *cobra.Command, args []string) {
+		sessionID := ""
+		if len(args) > 1 {
+			sessionID = args[1]
+		}
+		functions.PrettyPrint(functions.DeleteUserSession(args[0], sessionID))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(userSessionsCmd)
+	rootCmd.AddCommand(userLogoutCmd)
+}
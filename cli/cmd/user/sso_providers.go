@@ -0,0 +1,43 @@
+package user
+
+import (
+	"github.com/gravitl/netmaker/cli/functions"
+	"github.com/spf13/cobra"
+)
+
+var userSSOProvidersCmd = &cobra.Command{
+	Use:   "sso-providers [USER NAME]",
+	Args:  cobra.ExactArgs(1),
+	Short: "List the sso providers of a user",
+	Long: `List the sso providers a user is linked to.
+A user without links signs in through the default provider.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		functions.PrettyPrint(functions.GetUserSSOProviders(args[0]))
+	},
+}
+
+var userLinkSSOCmd = &cobra.Command{
+	Use:   "link-sso [USER NAME] [PROVIDER NAME]",
+	Args:  cobra.ExactArgs(2),
+	Short: "Let a user sign in through an sso provider",
+	Long:  `Let a user sign in through an sso provider`,
+	Run: func(cmd *cobra.Command, args []string) {
+		functions.PrettyPrint(functions.LinkUserSSOProvider(args[0], args[1]))
+	},
+}
+
+var userUnlinkSSOCmd = &cobra.Command{
+	Use:   "unlink-sso [USER NAME] [PROVIDER NAME]",
+	Args:  cobra.ExactArgs(2),
+	Short: "Stop a user from signing in through an sso provider",
+	Long:  `Stop a user from signing in through an sso provider`,
+	Run: func(cmd *cobra.Command, args []string) {
+		functions.PrettyPrint(functions.UnlinkUserSSOProvider(args[0], args[1]))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(userSSOProvidersCmd)
+	rootCmd.AddCommand(userLinkSSOCmd)
+	rootCmd.AddCommand(userUnlinkSSOCmd)
+}
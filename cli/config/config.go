@@ -11,13 +11,16 @@ import (
 
 // Context maintains configuration for interaction with Netmaker API
 type Context struct {
-	Endpoint  string `yaml:"endpoint"`
-	Username  string `yaml:"username,omitempty"`
-	Password  string `yaml:"password,omitempty"`
-	MasterKey string `yaml:"masterkey,omitempty"`
-	Current   bool   `yaml:"current,omitempty"`
-	AuthToken string `yaml:"auth_token,omitempty"`
-	SSO       bool   `yaml:"sso,omitempty"`
+	Endpoint    string `yaml:"endpoint"`
+	Username    string `yaml:"username,omitempty"`
+	Password    string `yaml:"password,omitempty"`
+	MasterKey   string `yaml:"masterkey,omitempty"`
+	AccessToken string `yaml:"access_token,omitempty"`
+	Current     bool   `yaml:"current,omitempty"`
+	AuthToken   string `yaml:"auth_token,omitempty"`
+	// RefreshToken - renews AuthToken without logging in again
+	RefreshToken string `yaml:"refresh_token,omitempty"`
+	SSO          bool   `yaml:"sso,omitempty"`
 }
 
 var (
@@ -119,6 +122,17 @@ func SetAuthToken(authToken string) {
 	}
 }
 
+// SetAuthTokens - saves the auth token and the refresh token renewing it
+func SetAuthTokens(authToken, refreshToken string) {
+	ctxName, _ := GetCurrentContext()
+	if ctx, ok := contextMap[ctxName]; ok {
+		ctx.AuthToken = authToken
+		ctx.RefreshToken = refreshToken
+		contextMap[ctxName] = ctx
+		saveContext()
+	}
+}
+
 // DeleteContext - deletes a context
 func DeleteContext(ctxName string) {
 	if _, ok := contextMap[ctxName]; ok {
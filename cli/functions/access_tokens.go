@@ -0,0 +1,22 @@
+package functions
+
+import (
+	"net/http"
+
+	"github.com/gravitl/netmaker/models"
+)
+
+// CreateAccessToken - create an access token
+func CreateAccessToken(req *models.AccessTokenRequest) *models.AccessTokenResponse {
+	return request[models.AccessTokenResponse](http.MethodPost, "/api/v1/access-tokens", req)
+}
+
+// GetAccessTokens - gets the access tokens visible to the user
+func GetAccessTokens() *[]models.AccessToken {
+	return request[[]models.AccessToken](http.MethodGet, "/api/v1/access-tokens", nil)
+}
+
+// RevokeAccessToken - revoke an access token
+func RevokeAccessToken(tokenID string) {
+	request[any](http.MethodDelete, "/api/v1/access-tokens/"+tokenID, nil)
+}
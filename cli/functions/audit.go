@@ -0,0 +1,19 @@
+package functions
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gravitl/netmaker/models"
+)
+
+// GetAuditLog - fetch the events of the audit log matching a query
+func GetAuditLog(query url.Values) *[]models.AuditEvent {
+	return request[[]models.AuditEvent](http.MethodGet, "/api/audit?"+query.Encode(), nil)
+}
+
+// ExportAuditLog - fetch the events of the audit log matching a query as jsonl or syslog lines
+func ExportAuditLog(query url.Values, format string) string {
+	query.Set("format", format)
+	return get("/api/audit?" + query.Encode())
+}
@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -86,12 +87,35 @@ func getAuthToken(ctx config.Context, force bool) string {
 		config.SetAuthToken(authToken)
 		return authToken
 	}
+	if ctx.RefreshToken != "" {
+		refreshParams := &models.RefreshTokenRequest{RefreshToken: ctx.RefreshToken}
+		if login, err := postLogin(ctx.Endpoint+"/api/users/adm/refresh", refreshParams); err == nil {
+			config.SetAuthTokens(login.AuthToken, login.RefreshToken)
+			return login.AuthToken
+		}
+	}
 	authParams := &models.UserAuthParams{UserName: ctx.Username, Password: ctx.Password}
-	payload, err := json.Marshal(authParams)
+	login, err := postLogin(ctx.Endpoint+"/api/users/adm/authenticate", authParams)
+	if err != nil && strings.Contains(err.Error(), "mfa code required") {
+		fmt.Print("MFA code: ")
+		code, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		authParams.TOTP = strings.TrimSpace(code)
+		login, err = postLogin(ctx.Endpoint+"/api/users/adm/authenticate", authParams)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
-	res, err := http.Post(ctx.Endpoint+"/api/users/adm/authenticate", "application/json", bytes.NewReader(payload))
+	config.SetAuthTokens(login.AuthToken, login.RefreshToken)
+	return login.AuthToken
+}
+
+// postLogin - sends credentials or a refresh token to the server, returning the tokens of the session
+func postLogin(url string, payload any) (*models.SuccessfulUserLoginResponse, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.Fatal(err)
+	}
+	res, err := http.Post(url, "application/json", bytes.NewReader(payloadBytes))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -100,15 +124,15 @@ func getAuthToken(ctx config.Context, force bool) string {
 		log.Fatalf("Client could not read response body: %s", err)
 	}
 	if res.StatusCode != http.StatusOK {
-		log.Fatalf("Error Status: %d Response: %s", res.StatusCode, string(resBodyBytes))
+		return nil, fmt.Errorf("Error Status: %d Response: %s", res.StatusCode, string(resBodyBytes))
 	}
-	body := new(models.SuccessResponse)
-	if err := json.Unmarshal(resBodyBytes, body); err != nil {
+	body := struct {
+		Response models.SuccessfulUserLoginResponse
+	}{}
+	if err := json.Unmarshal(resBodyBytes, &body); err != nil {
 		log.Fatalf("Error unmarshalling JSON: %s", err)
 	}
-	authToken := body.Response.(map[string]any)["AuthToken"].(string)
-	config.SetAuthToken(authToken)
-	return authToken
+	return &body.Response, nil
 }
 
 func request[T any](method, route string, payload any) *T {
@@ -135,6 +159,8 @@ func request[T any](method, route string, payload any) *T {
 	}
 	if ctx.MasterKey != "" {
 		req.Header.Set("Authorization", "Bearer "+ctx.MasterKey)
+	} else if ctx.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+ctx.AccessToken)
 	} else {
 		req.Header.Set("Authorization", "Bearer "+getAuthToken(ctx, false))
 	}
@@ -145,7 +171,7 @@ retry:
 		log.Fatalf("Client error making http request: %s", err)
 	}
 	// refresh JWT token
-	if res.StatusCode == http.StatusUnauthorized && !retried && ctx.MasterKey == "" {
+	if res.StatusCode == http.StatusUnauthorized && !retried && ctx.MasterKey == "" && ctx.AccessToken == "" {
 		req.Header.Set("Authorization", "Bearer "+getAuthToken(ctx, true))
 		retried = true
 		// TODO add a retry limit, drop goto
@@ -175,6 +201,8 @@ func get(route string) string {
 	}
 	if ctx.MasterKey != "" {
 		req.Header.Set("Authorization", "Bearer "+ctx.MasterKey)
+	} else if ctx.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+ctx.AccessToken)
 	} else {
 		req.Header.Set("Authorization", "Bearer "+getAuthToken(ctx, true))
 	}
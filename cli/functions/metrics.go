@@ -3,6 +3,7 @@ package functions
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"github.com/gravitl/netmaker/models"
 )
@@ -12,6 +13,20 @@ func GetNodeMetrics(networkName, nodeID string) *models.Metrics {
 	return request[models.Metrics](http.MethodGet, fmt.Sprintf("/api/metrics/%s/%s", networkName, nodeID), nil)
 }
 
+// GetNodeMetricsHistory - fetch the metrics history of a node, empty bounds and step use the server defaults
+func GetNodeMetricsHistory(networkName, nodeID, from, to, step string) *models.MetricsHistory {
+	query := url.Values{}
+	// an empty from still has to be sent to ask for the history instead of the latest metrics
+	query.Set("from", from)
+	if to != "" {
+		query.Set("to", to)
+	}
+	if step != "" {
+		query.Set("step", step)
+	}
+	return request[models.MetricsHistory](http.MethodGet, fmt.Sprintf("/api/metrics/%s/%s?%s", networkName, nodeID, query.Encode()), nil)
+}
+
 // GetNetworkNodeMetrics - fetch an entire network's metrics
 func GetNetworkNodeMetrics(networkName string) *models.NetworkMetrics {
 	return request[models.NetworkMetrics](http.MethodGet, "/api/metrics/"+networkName, nil)
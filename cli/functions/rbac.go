@@ -0,0 +1,42 @@
+package functions
+
+import (
+	"net/http"
+
+	"github.com/gravitl/netmaker/models"
+)
+
+// GetRoles - fetch the built-in and custom roles
+func GetRoles() *[]models.Role {
+	return request[[]models.Role](http.MethodGet, "/api/v1/roles", nil)
+}
+
+// CreateRole - create a custom role
+func CreateRole(role *models.Role) *models.Role {
+	return request[models.Role](http.MethodPost, "/api/v1/roles", role)
+}
+
+// DeleteRole - delete a custom role
+func DeleteRole(roleID string) {
+	request[any](http.MethodDelete, "/api/v1/roles/"+roleID, nil)
+}
+
+// GetRoleBindings - fetch the stored role bindings
+func GetRoleBindings() *[]models.RoleBinding {
+	return request[[]models.RoleBinding](http.MethodGet, "/api/v1/rolebindings", nil)
+}
+
+// GetUserRoleBindings - fetch the role bindings in effect for a user
+func GetUserRoleBindings(username string) *[]models.RoleBinding {
+	return request[[]models.RoleBinding](http.MethodGet, "/api/users/"+username+"/rolebindings", nil)
+}
+
+// CreateRoleBinding - bind a role to a user or a group
+func CreateRoleBinding(binding *models.RoleBinding) *models.RoleBinding {
+	return request[models.RoleBinding](http.MethodPost, "/api/v1/rolebindings", binding)
+}
+
+// DeleteRoleBinding - delete a role binding
+func DeleteRoleBinding(bindingID string) {
+	request[any](http.MethodDelete, "/api/v1/rolebindings/"+bindingID, nil)
+}
@@ -0,0 +1,22 @@
+package functions
+
+import (
+	"net/http"
+
+	"github.com/gravitl/netmaker/models"
+)
+
+// GetSSOMappings - fetch the mappings of identity provider groups
+func GetSSOMappings() *[]models.SSOMapping {
+	return request[[]models.SSOMapping](http.MethodGet, "/api/v1/sso/mappings", nil)
+}
+
+// CreateSSOMapping - map an identity provider group to user groups, networks and admin status
+func CreateSSOMapping(mapping *models.SSOMapping) *models.SSOMapping {
+	return request[models.SSOMapping](http.MethodPost, "/api/v1/sso/mappings", mapping)
+}
+
+// DeleteSSOMapping - delete a mapping of an identity provider group
+func DeleteSSOMapping(mappingID string) {
+	request[any](http.MethodDelete, "/api/v1/sso/mappings/"+mappingID, nil)
+}
@@ -35,3 +35,44 @@ func GetUser(username string) *models.User {
 func ListUsers() *[]models.ReturnUser {
 	return request[[]models.ReturnUser](http.MethodGet, "/api/users", nil)
 }
+
+// GetUserSessions - fetch the active sessions of a user, of all users if username is empty
+func GetUserSessions(username string) *[]models.UserSession {
+	if username == "" {
+		return request[[]models.UserSession](http.MethodGet, "/api/v1/sessions", nil)
+	}
+	return request[[]models.UserSession](http.MethodGet, "/api/users/"+username+"/sessions", nil)
+}
+
+// DeleteUserSession - end a session of a user, all of its sessions if sessionID is empty
+func DeleteUserSession(username, sessionID string) *models.SuccessResponse {
+	if sessionID == "" {
+		return request[models.SuccessResponse](http.MethodDelete, "/api/users/"+username+"/sessions", nil)
+	}
+	return request[models.SuccessResponse](http.MethodDelete, "/api/users/"+username+"/sessions/"+sessionID, nil)
+}
+
+// CreatePasswordResetToken - create a one-time token a user can set a new password with
+func CreatePasswordResetToken(username string) *models.PasswordResetToken {
+	return request[models.PasswordResetToken](http.MethodPost, "/api/users/"+username+"/password-reset", nil)
+}
+
+// ClearUserLockout - lift the lockout of a user who failed to log in too often
+func ClearUserLockout(username string) *models.SuccessResponse {
+	return request[models.SuccessResponse](http.MethodDelete, "/api/users/"+username+"/lockout", nil)
+}
+
+// GetUserSSOProviders - fetch the sso providers a user is linked to
+func GetUserSSOProviders(username string) *models.SSOUserLinks {
+	return request[models.SSOUserLinks](http.MethodGet, "/api/users/"+username+"/sso-providers", nil)
+}
+
+// LinkUserSSOProvider - let a user sign in through an sso provider
+func LinkUserSSOProvider(username, provider string) *models.SSOUserLinks {
+	return request[models.SSOUserLinks](http.MethodPut, "/api/users/"+username+"/sso-providers/"+provider, nil)
+}
+
+// UnlinkUserSSOProvider - stop a user from signing in through an sso provider
+func UnlinkUserSSOProvider(username, provider string) *models.SuccessResponse {
+	return request[models.SuccessResponse](http.MethodDelete, "/api/users/"+username+"/sso-providers/"+provider, nil)
+}
@@ -68,12 +68,18 @@ type ServerConfig struct {
 	MQPassword           string    `yaml:"mqpassword"`
 	MQUserName           string    `yaml:"mqusername"`
 	MetricsExporter      string    `yaml:"metrics_exporter"`
+	MetricsCollection    string    `yaml:"metrics_collection"`
 	BasicAuth            string    `yaml:"basic_auth"`
 	LicenseValue         string    `yaml:"license_value"`
 	NetmakerAccountID    string    `yaml:"netmaker_account_id"`
 	IsEE                 string    `yaml:"is_ee"`
 	StunPort             int       `yaml:"stun_port"`
 	StunList             string    `yaml:"stun_list"`
+	StunPrimaryIP        string    `yaml:"stun_primary_ip"`
+	StunAltIP            string    `yaml:"stun_alt_ip"`
+	StunAltPort          int       `yaml:"stun_alt_port"`
+	StunRateLimit        int       `yaml:"stun_rate_limit"`
+	StunAllowlist        bool      `yaml:"stun_allowlist"`
 	Proxy                string    `yaml:"proxy"`
 	DefaultProxyMode     ProxyMode `yaml:"defaultproxymode"`
 	TurnServer           string    `yaml:"turn_server"`
@@ -82,6 +88,57 @@ type ServerConfig struct {
 	TurnUserName         string    `yaml:"turn_username"`
 	TurnPassword         string    `yaml:"turn_password"`
 	UseTurn              bool      `yaml:"use_turn"`
+	EmbeddedTurn         bool      `yaml:"embedded_turn"`
+	TurnRelayIP          string    `yaml:"turn_relay_ip"`
+	TurnHostQuota        int       `yaml:"turn_host_quota"`
+	TurnMaxAllocations   int       `yaml:"turn_max_allocations"`
+	MetricsScrapeToken   string    `yaml:"metrics_scrape_token"`
+	MetricsRetention1m   int       `yaml:"metrics_retention_1m"`
+	MetricsRetention1h   int       `yaml:"metrics_retention_1h"`
+	MetricsRetention1d   int       `yaml:"metrics_retention_1d"`
+	SmtpHost             string    `yaml:"smtp_host"`
+	SmtpPort             int       `yaml:"smtp_port"`
+	SmtpUsername         string    `yaml:"smtp_username"`
+	SmtpPassword         string    `yaml:"smtp_password"`
+	SmtpSender           string    `yaml:"smtp_sender"`
+	AuditSyslog          string    `yaml:"audit_syslog"`
+	TrustedProxies       string    `yaml:"trusted_proxies"`
+	UserTokenValidity    int       `yaml:"user_token_validity"`
+	UserSessionValidity  int       `yaml:"user_session_validity"`
+	UserSessionLifetime  int       `yaml:"user_session_lifetime"`
+	MFAEnforced          string    `yaml:"mfa_enforced"`
+	SSOGroupSync         string    `yaml:"sso_group_sync"`
+	SSOGroupsClaim       string    `yaml:"sso_groups_claim"`
+	LDAPURL              string    `yaml:"ldap_url"`
+	LDAPStartTLS         string    `yaml:"ldap_start_tls"`
+	LDAPSkipTLSVerify    string    `yaml:"ldap_skip_tls_verify"`
+	LDAPBindDN           string    `yaml:"ldap_bind_dn"`
+	LDAPBindPassword     string    `yaml:"ldap_bind_password"`
+	LDAPBaseDN           string    `yaml:"ldap_base_dn"`
+	LDAPUserFilter       string    `yaml:"ldap_user_filter"`
+	LDAPGroupAttribute   string    `yaml:"ldap_group_attribute"`
+	PasswordMinLength    int       `yaml:"password_min_length"`
+	PasswordClasses      int       `yaml:"password_character_classes"`
+	PasswordHistory      int       `yaml:"password_history"`
+	LockoutThreshold     int       `yaml:"login_lockout_threshold"`
+	LockoutIPThreshold   int       `yaml:"login_lockout_ip_threshold"`
+	LockoutDuration      int       `yaml:"login_lockout_duration"`
+	ResetTokenValidity   int       `yaml:"password_reset_token_validity"`
+
+	AuthProviders []AuthProvider `yaml:"auth_providers"`
+}
+
+// AuthProvider - a named sso provider offered next to the one set by authprovider
+type AuthProvider struct {
+	Name         string `yaml:"name" json:"name"`
+	Type         string `yaml:"type" json:"type"`
+	ClientID     string `yaml:"clientid" json:"client_id"`
+	ClientSecret string `yaml:"clientsecret" json:"client_secret"`
+	OIDCIssuer   string `yaml:"oidcissuer" json:"oidc_issuer"`
+	AzureTenant  string `yaml:"azuretenant" json:"azure_tenant"`
+	// Namespace - prefixes the users of the provider with its name, keeping them apart from
+	// same-named users of other providers
+	Namespace bool `yaml:"namespace" json:"namespace"`
 }
 
 // ProxyMode - default proxy mode for server
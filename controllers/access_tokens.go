@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+)
+
+func accessTokenHandlers(r *mux.Router) {
+	r.HandleFunc("/api/v1/access-tokens", logic.Authorize(false, models.SelfResource, models.ReadAction, denyAccessTokens(http.HandlerFunc(getAccessTokens)))).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/access-tokens", logic.Authorize(false, models.SelfResource, models.CreateAction, denyAccessTokens(http.HandlerFunc(createAccessToken)))).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/access-tokens/{tokenid}", logic.Authorize(false, models.SelfResource, models.DeleteAction, denyAccessTokens(http.HandlerFunc(revokeAccessToken)))).Methods(http.MethodDelete)
+}
+
+// denyAccessTokens - access tokens cannot manage access tokens
+func denyAccessTokens(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(logic.AccessTokenHeader) != "" {
+			logic.ReturnErrorResponse(w, r, logic.FormatError(errors.New("access tokens cannot manage access tokens"), "forbidden"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// swagger:route GET /api/v1/access-tokens accessTokens getAccessTokens
+//
+// Lists the access tokens of the user, admins get the tokens of all users.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: accessTokensResponse
+func getAccessTokens(w http.ResponseWriter, r *http.Request) {
+	username := r.Header.Get("user")
+	if r.Header.Get("ismaster") == "yes" {
+		username = ""
+	}
+	tokens, err := logic.GetAccessTokens(username)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch access tokens:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// swagger:route POST /api/v1/access-tokens accessTokens createAccessToken
+//
+// Creates an access token of the user, the token is only part of this response.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: accessTokenResponse
+func createAccessToken(w http.ResponseWriter, r *http.Request) {
+	var req models.AccessTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	token, err := logic.CreateAccessToken(r.Header.Get("user"), req)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to create access token:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "created access token", token.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(token)
+}
+
+// swagger:route DELETE /api/v1/access-tokens/{tokenid} accessTokens revokeAccessToken
+//
+// Revokes an access token of the user, admins can revoke the tokens of all users.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func revokeAccessToken(w http.ResponseWriter, r *http.Request) {
+	tokenID := mux.Vars(r)["tokenid"]
+	token, err := logic.GetAccessToken(tokenID)
+	if err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	if r.Header.Get("ismaster") != "yes" && token.UserName != r.Header.Get("user") {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(errors.New("access token belongs to another user"), "forbidden"))
+		return
+	}
+	if err := logic.RevokeAccessToken(tokenID); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to revoke access token:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "revoked access token", token.Name, "of user", token.UserName)
+	logic.ReturnSuccessResponse(w, r, "revoked access token "+token.Name)
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessTokenScope(t *testing.T) {
+	createNet()
+	user := models.User{UserName: "tokenadmin", Password: "password", IsAdmin: true, Networks: []string{}, Groups: []string{}}
+	assert.Nil(t, logic.CreateUser(&user))
+	defer logic.DeleteUser(user.UserName)
+	scoped, err := logic.CreateAccessToken(user.UserName, models.AccessTokenRequest{
+		Name:  "scoped",
+		Scope: models.AccessTokenScope{Networks: []string{"skynet"}, ReadOnly: true, Resources: []string{"node"}},
+	})
+	assert.Nil(t, err)
+	unscoped, err := logic.CreateAccessToken(user.UserName, models.AccessTokenRequest{Name: "unscoped"})
+	assert.Nil(t, err)
+	defer logic.DeleteUserAccessTokens(user.UserName)
+	router := mux.NewRouter()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router.HandleFunc("/api/nodes/{network}", logic.Authorize(false, models.NodeResource, models.ReadAction, handler)).Methods(http.MethodGet)
+	router.HandleFunc("/api/nodes/{network}", logic.Authorize(false, models.NodeResource, models.CreateAction, handler)).Methods(http.MethodPost)
+	router.HandleFunc("/api/dns/{network}", logic.Authorize(false, models.DNSResource, models.ReadAction, handler)).Methods(http.MethodGet)
+	accessTokenHandlers(router)
+	serve := func(method, url, token string) int {
+		req := httptest.NewRequest(method, url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	t.Run("InScope", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/nodes/skynet", scoped.Token))
+	})
+	t.Run("ReadOnly", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/api/nodes/skynet", scoped.Token))
+	})
+	t.Run("OtherResource", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/dns/skynet", scoped.Token))
+	})
+	t.Run("OtherNetwork", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/nodes/othernet", scoped.Token))
+	})
+	t.Run("Unscoped", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/api/nodes/skynet", unscoped.Token))
+	})
+	t.Run("ManageTokens", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/v1/access-tokens", unscoped.Token))
+	})
+	t.Run("BadSecret", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/nodes/skynet", logic.ACCESS_TOKEN_PREFIX+scoped.ID+".wrong"))
+	})
+}
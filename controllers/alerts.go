@@ -0,0 +1,292 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+)
+
+func alertHandlers(r *mux.Router) {
+	r.HandleFunc("/api/v1/alerts", logic.Authorize(false, models.ServerResource, models.ReadAction, http.HandlerFunc(getAlerts))).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/alerts/rules", logic.Authorize(false, models.ServerResource, models.ReadAction, http.HandlerFunc(getAlertRules))).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/alerts/rules", logic.Authorize(false, models.ServerResource, models.CreateAction, http.HandlerFunc(createAlertRule))).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/alerts/rules/{ruleid}", logic.Authorize(false, models.ServerResource, models.UpdateAction, http.HandlerFunc(updateAlertRule))).Methods(http.MethodPut)
+	r.HandleFunc("/api/v1/alerts/rules/{ruleid}", logic.Authorize(false, models.ServerResource, models.DeleteAction, http.HandlerFunc(deleteAlertRule))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/v1/alerts/targets", logic.Authorize(false, models.ServerResource, models.ReadAction, http.HandlerFunc(getAlertTargets))).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/alerts/targets", logic.Authorize(false, models.ServerResource, models.CreateAction, http.HandlerFunc(createAlertTarget))).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/alerts/targets/{targetid}", logic.Authorize(false, models.ServerResource, models.UpdateAction, http.HandlerFunc(updateAlertTarget))).Methods(http.MethodPut)
+	r.HandleFunc("/api/v1/alerts/targets/{targetid}", logic.Authorize(false, models.ServerResource, models.DeleteAction, http.HandlerFunc(deleteAlertTarget))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/v1/alerts/targets/{targetid}/test", logic.Authorize(false, models.ServerResource, models.UpdateAction, http.HandlerFunc(testAlertTarget))).Methods(http.MethodPost)
+}
+
+// swagger:route GET /api/v1/alerts alerts getAlerts
+//
+// Lists the firing and recently resolved alerts.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: alertsResponse
+func getAlerts(w http.ResponseWriter, r *http.Request) {
+	alerts, err := logic.GetAlerts()
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch alerts:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(alerts)
+}
+
+// swagger:route GET /api/v1/alerts/rules alerts getAlertRules
+//
+// Lists the alert rules.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: alertRulesResponse
+func getAlertRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := logic.GetAlertRules()
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch alert rules:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rules)
+}
+
+// swagger:route POST /api/v1/alerts/rules alerts createAlertRule
+//
+// Creates an alert rule.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: alertRuleResponse
+func createAlertRule(w http.ResponseWriter, r *http.Request) {
+	var rule models.AlertRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	rule, err := logic.CreateAlertRule(rule)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to create alert rule:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "created alert rule", rule.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// swagger:route PUT /api/v1/alerts/rules/{ruleid} alerts updateAlertRule
+//
+// Replaces an alert rule.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: alertRuleResponse
+func updateAlertRule(w http.ResponseWriter, r *http.Request) {
+	var rule models.AlertRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	rule, err := logic.UpdateAlertRule(mux.Vars(r)["ruleid"], rule)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to update alert rule:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "updated alert rule", rule.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// swagger:route DELETE /api/v1/alerts/rules/{ruleid} alerts deleteAlertRule
+//
+// Deletes an alert rule together with its alerts.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func deleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	ruleID := mux.Vars(r)["ruleid"]
+	if err := logic.DeleteAlertRule(ruleID); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to delete alert rule:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "deleted alert rule", ruleID)
+	logic.ReturnSuccessResponse(w, r, "deleted alert rule "+ruleID)
+}
+
+// swagger:route GET /api/v1/alerts/targets alerts getAlertTargets
+//
+// Lists the notification targets of alerts.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: alertTargetsResponse
+func getAlertTargets(w http.ResponseWriter, r *http.Request) {
+	targets, err := logic.GetAlertTargets()
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch alert targets:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(targets)
+}
+
+// swagger:route POST /api/v1/alerts/targets alerts createAlertTarget
+//
+// Creates a webhook, Slack or email notification target.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: alertTargetResponse
+func createAlertTarget(w http.ResponseWriter, r *http.Request) {
+	var target models.AlertTarget
+	if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	target, err := logic.CreateAlertTarget(target)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to create alert target:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "created alert target", target.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(target)
+}
+
+// swagger:route PUT /api/v1/alerts/targets/{targetid} alerts updateAlertTarget
+//
+// Replaces a notification target.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: alertTargetResponse
+func updateAlertTarget(w http.ResponseWriter, r *http.Request) {
+	var target models.AlertTarget
+	if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	target, err := logic.UpdateAlertTarget(mux.Vars(r)["targetid"], target)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to update alert target:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "updated alert target", target.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(target)
+}
+
+// swagger:route DELETE /api/v1/alerts/targets/{targetid} alerts deleteAlertTarget
+//
+// Deletes a notification target no rule notifies anymore.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func deleteAlertTarget(w http.ResponseWriter, r *http.Request) {
+	targetID := mux.Vars(r)["targetid"]
+	if err := logic.DeleteAlertTarget(targetID); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to delete alert target:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "deleted alert target", targetID)
+	logic.ReturnSuccessResponse(w, r, "deleted alert target "+targetID)
+}
+
+// swagger:route POST /api/v1/alerts/targets/{targetid}/test alerts testAlertTarget
+//
+// Sends a test notification to a target.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func testAlertTarget(w http.ResponseWriter, r *http.Request) {
+	target, err := logic.GetAlertTarget(mux.Vars(r)["targetid"])
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch alert target:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	alert := models.Alert{
+		ID:       "test",
+		RuleName: "test",
+		Subject:  target.Name,
+		Message:  "test notification of alert target " + target.Name,
+		State:    models.AlertFiring,
+		FiredAt:  time.Now(),
+	}
+	if err := logic.SendAlertNotification(&target, &alert); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to notify alert target", target.Name, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logic.ReturnSuccessResponse(w, r, "sent test notification to "+target.Name)
+}
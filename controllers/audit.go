@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+)
+
+// defaultAuditLimit - events returned by a query of the audit log without a limit
+const defaultAuditLimit = 1000
+
+func auditHandlers(r *mux.Router) {
+	r.HandleFunc("/api/audit", logic.Authorize(false, models.ServerResource, models.ReadAction, http.HandlerFunc(getAuditLog))).Methods(http.MethodGet)
+}
+
+// swagger:route GET /api/audit audit getAuditLog
+//
+// Queries the audit log of changes made through the api, filtered by resource, actor, action, network,
+// since and until. The events are returned as json, or exported as jsonl or syslog lines with format.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: auditEventsResponse
+func getAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.AuditFilter{
+		Actor:    query.Get("actor"),
+		Action:   models.RbacAction(query.Get("action")),
+		Resource: models.RbacResource(query.Get("resource")),
+		Network:  query.Get("network"),
+		Limit:    defaultAuditLimit,
+	}
+	var err error
+	if filter.Since, err = parseAuditTime(query.Get("since")); err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	if filter.Until, err = parseAuditTime(query.Get("until")); err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 0 {
+			logic.ReturnErrorResponse(w, r, logic.FormatError(fmt.Errorf("invalid limit %s", limit), "badrequest"))
+			return
+		}
+	}
+	events, err := logic.QueryAuditLog(filter)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to query audit log:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	switch query.Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(events)
+	case "jsonl":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(w)
+		for i := range events {
+			encoder.Encode(&events[i])
+		}
+	case "syslog":
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		for i := range events {
+			line, err := logic.AuditEventToSyslog(&events[i])
+			if err != nil {
+				continue
+			}
+			fmt.Fprintln(w, line)
+		}
+	default:
+		logic.ReturnErrorResponse(w, r, logic.FormatError(fmt.Errorf("unknown format %s", query.Get("format")), "badrequest"))
+	}
+}
+
+// parseAuditTime - parses a time given as RFC 3339 or unix seconds, zero if empty
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %s, expected RFC 3339 or unix seconds", value)
+	}
+	return t, nil
+}
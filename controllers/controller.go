@@ -16,8 +16,15 @@ import (
 
 // HttpHandlers - handler functions for REST interactions
 var HttpHandlers = []interface{}{
+	prometheusHandlers,
 	nodeHandlers,
 	userHandlers,
+	userSessionHandlers,
+	userMFAHandlers,
+	userPasswordHandlers,
+	ssoMappingHandlers,
+	ssoProviderHandlers,
+	scimHandlers,
 	networkHandlers,
 	dnsHandlers,
 	fileHandlers,
@@ -27,6 +34,11 @@ var HttpHandlers = []interface{}{
 	loggerHandlers,
 	hostHandlers,
 	enrollmentKeyHandlers,
+	alertHandlers,
+	metricHandlers,
+	accessTokenHandlers,
+	rbacHandlers,
+	auditHandlers,
 	legacyHandlers,
 }
 
@@ -48,7 +60,7 @@ func HandleRESTRequests(wg *sync.WaitGroup, ctx context.Context) {
 
 	port := servercfg.GetAPIPort()
 
-	srv := &http.Server{Addr: ":" + port, Handler: handlers.CORS(originsOk, headersOk, methodsOk)(r)}
+	srv := &http.Server{Addr: ":" + port, Handler: handlers.CORS(originsOk, headersOk, methodsOk)(observeRequests(r))}
 	go func() {
 		err := srv.ListenAndServe()
 		if err != nil {
@@ -144,7 +144,7 @@ type createExtClientPathParams struct {
 	CustomExtClient models.CustomExtClient `json:"custom_ext_client"`
 }
 
-// swagger:parameters getNode updateNode deleteNode createRelay deleteRelay createEgressGateway deleteEgressGateway createIngressGateway deleteIngressGateway uncordonNode
+// swagger:parameters getNode updateNode deleteNode createRelay deleteRelay createEgressGateway deleteEgressGateway createInternetGw updateInternetGw deleteInternetGw createIngressGateway deleteIngressGateway uncordonNode
 type networkNodePathParams struct {
 	// Network
 	// in: path
@@ -252,6 +252,13 @@ type egressGatewayBodyParam struct {
 	EgressGatewayRequest models.EgressGatewayRequest `json:"egress_gateway_request"`
 }
 
+// swagger:parameters createInternetGw updateInternetGw
+type internetGatewayBodyParam struct {
+	// IDs of the nodes and ext clients routing all of their traffic through the gateway
+	// in: body
+	ClientIDs []string `json:"client_ids"`
+}
+
 // swagger:parameters authenticate
 type authParamBodyParam struct {
 	// AuthParams
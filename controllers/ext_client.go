@@ -17,19 +17,20 @@ import (
 	"github.com/gravitl/netmaker/models"
 	"github.com/gravitl/netmaker/models/promodels"
 	"github.com/gravitl/netmaker/mq"
+	"github.com/gravitl/netmaker/servercfg"
 	"github.com/skip2/go-qrcode"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
 
 func extClientHandlers(r *mux.Router) {
 
-	r.HandleFunc("/api/extclients", logic.SecurityCheck(false, http.HandlerFunc(getAllExtClients))).Methods(http.MethodGet)
-	r.HandleFunc("/api/extclients/{network}", logic.SecurityCheck(false, http.HandlerFunc(getNetworkExtClients))).Methods(http.MethodGet)
-	r.HandleFunc("/api/extclients/{network}/{clientid}", logic.SecurityCheck(false, http.HandlerFunc(getExtClient))).Methods(http.MethodGet)
-	r.HandleFunc("/api/extclients/{network}/{clientid}/{type}", logic.NetUserSecurityCheck(false, true, http.HandlerFunc(getExtClientConf))).Methods(http.MethodGet)
-	r.HandleFunc("/api/extclients/{network}/{clientid}", logic.NetUserSecurityCheck(false, true, http.HandlerFunc(updateExtClient))).Methods(http.MethodPut)
-	r.HandleFunc("/api/extclients/{network}/{clientid}", logic.NetUserSecurityCheck(false, true, http.HandlerFunc(deleteExtClient))).Methods(http.MethodDelete)
-	r.HandleFunc("/api/extclients/{network}/{nodeid}", logic.NetUserSecurityCheck(false, true, checkFreeTierLimits(clients_l, http.HandlerFunc(createExtClient)))).Methods(http.MethodPost)
+	r.HandleFunc("/api/extclients", logic.Authorize(false, models.ExtClientResource, models.ReadAction, http.HandlerFunc(getAllExtClients))).Methods(http.MethodGet)
+	r.HandleFunc("/api/extclients/{network}", logic.Authorize(false, models.ExtClientResource, models.ReadAction, http.HandlerFunc(getNetworkExtClients))).Methods(http.MethodGet)
+	r.HandleFunc("/api/extclients/{network}/{clientid}", logic.Authorize(false, models.ExtClientResource, models.ReadAction, http.HandlerFunc(getExtClient))).Methods(http.MethodGet)
+	r.HandleFunc("/api/extclients/{network}/{clientid}/{type}", logic.Authorize(false, models.ExtClientResource, models.ReadAction, http.HandlerFunc(getExtClientConf))).Methods(http.MethodGet)
+	r.HandleFunc("/api/extclients/{network}/{clientid}", logic.Authorize(false, models.ExtClientResource, models.UpdateAction, http.HandlerFunc(updateExtClient))).Methods(http.MethodPut)
+	r.HandleFunc("/api/extclients/{network}/{clientid}", logic.Authorize(false, models.ExtClientResource, models.DeleteAction, http.HandlerFunc(deleteExtClient))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/extclients/{network}/{nodeid}", logic.Authorize(false, models.ExtClientResource, models.CreateAction, checkFreeTierLimits(clients_l, http.HandlerFunc(createExtClient)))).Methods(http.MethodPost)
 }
 
 func checkIngressExists(nodeID string) bool {
@@ -225,8 +226,12 @@ func getExtClientConf(w http.ResponseWriter, r *http.Request) {
 	if network.AddressRange6 != "" {
 		newAllowedIPs += network.AddressRange6
 	}
+	usesInternetGw := false
 	if egressGatewayRanges, err := logic.GetEgressRangesOnNetwork(&client); err == nil {
 		for _, egressGatewayRange := range egressGatewayRanges {
+			if egressGatewayRange == models.IPV4_DEFAULT_ROUTE || egressGatewayRange == models.IPV6_DEFAULT_ROUTE {
+				usesInternetGw = true
+			}
 			newAllowedIPs += "," + egressGatewayRange
 		}
 	}
@@ -235,6 +240,13 @@ func getExtClientConf(w http.ResponseWriter, r *http.Request) {
 		defaultDNS = "DNS = " + client.DNS
 	} else if gwnode.IngressDNS != "" {
 		defaultDNS = "DNS = " + gwnode.IngressDNS
+	} else if usesInternetGw {
+		// the client's resolver is unreachable once the default route points to the tunnel
+		if servercfg.IsDNSMode() && servercfg.GetCoreDNSAddr() != "" {
+			defaultDNS = "DNS = " + servercfg.GetCoreDNSAddr()
+		} else {
+			defaultDNS = "DNS = " + models.DEFAULT_INTERNET_GW_DNS
+		}
 	}
 
 	defaultMTU := 1420
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"reflect"
 
 	"github.com/gorilla/mux"
@@ -19,18 +20,28 @@ import (
 )
 
 func hostHandlers(r *mux.Router) {
-	r.HandleFunc("/api/hosts", logic.SecurityCheck(false, http.HandlerFunc(getHosts))).Methods(http.MethodGet)
-	r.HandleFunc("/api/hosts/keys", logic.SecurityCheck(true, http.HandlerFunc(updateAllKeys))).Methods(http.MethodPut)
-	r.HandleFunc("/api/hosts/{hostid}/keys", logic.SecurityCheck(true, http.HandlerFunc(updateKeys))).Methods(http.MethodPut)
-	r.HandleFunc("/api/hosts/{hostid}", logic.SecurityCheck(true, http.HandlerFunc(updateHost))).Methods(http.MethodPut)
-	r.HandleFunc("/api/hosts/{hostid}", logic.SecurityCheck(true, http.HandlerFunc(deleteHost))).Methods(http.MethodDelete)
-	r.HandleFunc("/api/hosts/{hostid}/networks/{network}", logic.SecurityCheck(true, http.HandlerFunc(addHostToNetwork))).Methods(http.MethodPost)
-	r.HandleFunc("/api/hosts/{hostid}/networks/{network}", logic.SecurityCheck(true, http.HandlerFunc(deleteHostFromNetwork))).Methods(http.MethodDelete)
-	r.HandleFunc("/api/hosts/{hostid}/relay", logic.SecurityCheck(false, http.HandlerFunc(createHostRelay))).Methods(http.MethodPost)
-	r.HandleFunc("/api/hosts/{hostid}/relay", logic.SecurityCheck(false, http.HandlerFunc(deleteHostRelay))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/hosts", logic.Authorize(false, models.HostResource, models.ReadAction, http.HandlerFunc(getHosts))).Methods(http.MethodGet)
+	r.HandleFunc("/api/hosts/keys", logic.Authorize(false, models.HostResource, models.UpdateAction, http.HandlerFunc(updateAllKeys))).Methods(http.MethodPut)
+	r.HandleFunc("/api/hosts/{hostid}/keys", logic.Authorize(false, models.HostResource, models.UpdateAction, http.HandlerFunc(updateKeys))).Methods(http.MethodPut)
+	r.HandleFunc("/api/hosts/{hostid}", logic.Authorize(false, models.HostResource, models.UpdateAction, http.HandlerFunc(updateHost))).Methods(http.MethodPut)
+	r.HandleFunc("/api/hosts/{hostid}", logic.Authorize(false, models.HostResource, models.DeleteAction, http.HandlerFunc(deleteHost))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/hosts/{hostid}/networks/{network}", logic.Authorize(false, models.HostResource, models.UpdateAction, http.HandlerFunc(addHostToNetwork))).Methods(http.MethodPost)
+	r.HandleFunc("/api/hosts/{hostid}/networks/{network}", logic.Authorize(false, models.HostResource, models.UpdateAction, http.HandlerFunc(deleteHostFromNetwork))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/hosts/{hostid}/relay", logic.Authorize(false, models.HostResource, models.UpdateAction, http.HandlerFunc(createHostRelay))).Methods(http.MethodPost)
+	r.HandleFunc("/api/hosts/{hostid}/relay", logic.Authorize(false, models.HostResource, models.UpdateAction, http.HandlerFunc(deleteHostRelay))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/v1/relaygroups", logic.Authorize(false, models.HostResource, models.ReadAction, http.HandlerFunc(getRelayGroups))).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/relaygroups", logic.Authorize(false, models.HostResource, models.CreateAction, http.HandlerFunc(createRelayGroup))).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/relaygroups/{groupid}", logic.Authorize(false, models.HostResource, models.UpdateAction, http.HandlerFunc(updateRelayGroup))).Methods(http.MethodPut)
+	r.HandleFunc("/api/v1/relaygroups/{groupid}", logic.Authorize(false, models.HostResource, models.DeleteAction, http.HandlerFunc(deleteRelayGroup))).Methods(http.MethodDelete)
 	r.HandleFunc("/api/hosts/adm/authenticate", authenticateHost).Methods(http.MethodPost)
-	r.HandleFunc("/api/v1/host", authorize(true, false, "host", http.HandlerFunc(pull))).Methods(http.MethodGet)
-	r.HandleFunc("/api/v1/host/{hostid}/signalpeer", authorize(true, false, "host", http.HandlerFunc(signalPeer))).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/host", logic.Authorize(true, models.HostResource, models.ReadAction, http.HandlerFunc(pull))).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/host/{hostid}/signalpeer", logic.Authorize(true, models.HostResource, models.UpdateAction, http.HandlerFunc(signalPeer))).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/host/{hostid}/punch", logic.Authorize(true, models.HostResource, models.UpdateAction, http.HandlerFunc(punchPeer))).Methods(http.MethodPost)
+	r.HandleFunc("/api/hosts/{hostid}/punch", logic.Authorize(false, models.HostResource, models.UpdateAction, http.HandlerFunc(punchPeer))).Methods(http.MethodPost)
+	r.HandleFunc("/api/hosts/{hostid}/paths", logic.Authorize(false, models.HostResource, models.ReadAction, http.HandlerFunc(getHostPeerPaths))).Methods(http.MethodGet)
+	r.HandleFunc("/api/hosts/{hostid}/endpoints", logic.Authorize(false, models.HostResource, models.ReadAction, http.HandlerFunc(getHostPeerEndpoints))).Methods(http.MethodGet)
+	r.HandleFunc("/api/hosts/{hostid}/endpoints/{peerhostid}", logic.Authorize(false, models.HostResource, models.UpdateAction, http.HandlerFunc(pinPeerEndpoint))).Methods(http.MethodPut)
+	r.HandleFunc("/api/hosts/{hostid}/endpoints/{peerhostid}", logic.Authorize(false, models.HostResource, models.UpdateAction, http.HandlerFunc(unpinPeerEndpoint))).Methods(http.MethodDelete)
 	r.HandleFunc("/api/v1/auth-register/host", socketHandler)
 }
 
@@ -102,7 +113,7 @@ func getHosts(w http.ResponseWriter, r *http.Request) {
 //				200: pull
 func pull(w http.ResponseWriter, r *http.Request) {
 
-	hostID := r.Header.Get(hostIDHeader) // return JSON/API formatted keys
+	hostID := r.Header.Get(logic.HostIDHeader) // return JSON/API formatted keys
 	if len(hostID) == 0 {
 		logger.Log(0, "no host authorized to pull")
 		logic.ReturnErrorResponse(w, r, logic.FormatError(fmt.Errorf("no host authorized to pull"), "internal"))
@@ -180,6 +191,12 @@ func updateHost(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if updateRelay || newHost.IsRelay != currHost.IsRelay {
+		if err := logic.CheckHostNotInRelayGroup(currHost.ID.String()); err != nil {
+			logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+			return
+		}
+	}
 	logic.UpdateHost(newHost, currHost) // update the in memory struct values
 	if err = logic.UpsertHost(newHost); err != nil {
 		logger.Log(0, r.Header.Get("user"), "failed to update a host:", err.Error())
@@ -269,6 +286,13 @@ func deleteHost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	changed, err := logic.RemoveHostFromRelayGroups(hostid)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to remove host from relay groups:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	go publishRelayGroupChanges(changed)
 	if err = logic.RemoveHost(currHost); err != nil {
 		logger.Log(0, r.Header.Get("user"), "failed to delete a host:", err.Error())
 		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
@@ -553,6 +577,200 @@ func signalPeer(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(signal)
 }
 
+// swagger:route POST /api/v1/host/{hostid}/punch hosts punchPeer
+//
+// Schedule a hole punch between a host and a peer.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: punchSession
+func punchPeer(w http.ResponseWriter, r *http.Request) {
+	var params = mux.Vars(r)
+	hostid := params["hostid"]
+	host, err := logic.GetHost(hostid)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to get host:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	var req models.PunchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	peer, err := logic.GetHost(req.PeerHostID)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to get peer host:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	session, err := logic.CreatePunchSession(host, peer)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to create punch session:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	if err := mq.PublishPunchSession(session); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to publish punch session:", err.Error())
+		logic.ExpirePunchSession(session.ID)
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	logger.Log(2, r.Header.Get("user"), "scheduled punch between", host.Name, "and", peer.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(session)
+}
+
+// swagger:route GET /api/hosts/{hostid}/paths hosts getHostPeerPaths
+//
+// Lists the paths found between a host and its peers.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: peerPaths
+func getHostPeerPaths(w http.ResponseWriter, r *http.Request) {
+	hostid := mux.Vars(r)["hostid"]
+	if _, err := logic.GetHost(hostid); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to get host:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	paths, err := logic.GetHostPeerPaths(hostid)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch peer paths:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(paths)
+}
+
+// swagger:route GET /api/hosts/{hostid}/endpoints hosts getHostPeerEndpoints
+//
+// Lists the endpoints a host uses to reach its peers.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: peerEndpoints
+func getHostPeerEndpoints(w http.ResponseWriter, r *http.Request) {
+	hostid := mux.Vars(r)["hostid"]
+	if _, err := logic.GetHost(hostid); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to get host:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	selections, err := logic.GetHostPeerEndpoints(hostid)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch peer endpoints:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(selections)
+}
+
+// swagger:route PUT /api/hosts/{hostid}/endpoints/{peerhostid} hosts pinPeerEndpoint
+//
+// Pins the endpoint a host uses to reach a peer host.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: peerEndpoint
+func pinPeerEndpoint(w http.ResponseWriter, r *http.Request) {
+	var params = mux.Vars(r)
+	host, err := logic.GetHost(params["hostid"])
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to get host:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	peerHost, err := logic.GetHost(params["peerhostid"])
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to get peer host:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	var pin models.PeerEndpointPin
+	if err := json.NewDecoder(r.Body).Decode(&pin); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	endpoint, err := netip.ParseAddrPort(pin.Endpoint)
+	if err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	selection, err := logic.PinPeerEndpoint(host.ID.String(), peerHost.ID.String(), endpoint)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to pin peer endpoint:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	go func() {
+		if err := mq.PublishSingleHostPeerUpdate(context.Background(), host, nil, nil); err != nil {
+			logger.Log(0, "failed to publish peer update after pinning an endpoint", host.ID.String(), err.Error())
+		}
+	}()
+	logger.Log(1, r.Header.Get("user"), "pinned endpoint", endpoint.String(), "of", peerHost.Name, "for", host.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(selection)
+}
+
+// swagger:route DELETE /api/hosts/{hostid}/endpoints/{peerhostid} hosts unpinPeerEndpoint
+//
+// Hands the endpoint a host uses to reach a peer host back to the automatic selection.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: okResponse
+func unpinPeerEndpoint(w http.ResponseWriter, r *http.Request) {
+	var params = mux.Vars(r)
+	host, err := logic.GetHost(params["hostid"])
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to get host:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	if err := logic.UnpinPeerEndpoint(host.ID.String(), params["peerhostid"]); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to unpin peer endpoint:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	go func() {
+		if err := mq.PublishSingleHostPeerUpdate(context.Background(), host, nil, nil); err != nil {
+			logger.Log(0, "failed to publish peer update after unpinning an endpoint", host.ID.String(), err.Error())
+		}
+	}()
+	logger.Log(1, r.Header.Get("user"), "unpinned endpoint of", params["peerhostid"], "for", host.Name)
+	logic.ReturnSuccessResponse(w, r, "unpinned endpoint of peer "+params["peerhostid"])
+}
+
 // swagger:route POST /api/hosts/keys host updateAllKeys
 //
 // Update keys for a network.
@@ -6,10 +6,11 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
 )
 
 func legacyHandlers(r *mux.Router) {
-	r.HandleFunc("/api/v1/legacy/nodes", logic.SecurityCheck(true, http.HandlerFunc(wipeLegacyNodes))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/v1/legacy/nodes", logic.Authorize(false, models.NodeResource, models.DeleteAction, http.HandlerFunc(wipeLegacyNodes))).Methods(http.MethodDelete)
 }
 
 // swagger:route DELETE /api/v1/legacy/nodes nodes wipeLegacyNodes
@@ -8,10 +8,11 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
 )
 
 func loggerHandlers(r *mux.Router) {
-	r.HandleFunc("/api/logs", logic.SecurityCheck(true, http.HandlerFunc(getLogs))).Methods(http.MethodGet)
+	r.HandleFunc("/api/logs", logic.Authorize(false, models.ServerResource, models.ReadAction, http.HandlerFunc(getLogs))).Methods(http.MethodGet)
 }
 
 func getLogs(w http.ResponseWriter, r *http.Request) {
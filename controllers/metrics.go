@@ -1,8 +1,11 @@
-package ee_controllers
+package controller
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gravitl/netmaker/database"
@@ -11,15 +14,24 @@ import (
 	"github.com/gravitl/netmaker/models"
 )
 
-// MetricHandlers - How we handle EE Metrics
-func MetricHandlers(r *mux.Router) {
-	r.HandleFunc("/api/metrics/{network}/{nodeid}", logic.SecurityCheck(true, http.HandlerFunc(getNodeMetrics))).Methods(http.MethodGet)
-	r.HandleFunc("/api/metrics/{network}", logic.SecurityCheck(true, http.HandlerFunc(getNetworkNodesMetrics))).Methods(http.MethodGet)
-	r.HandleFunc("/api/metrics", logic.SecurityCheck(true, http.HandlerFunc(getAllMetrics))).Methods(http.MethodGet)
-	r.HandleFunc("/api/metrics-ext/{network}", logic.SecurityCheck(true, http.HandlerFunc(getNetworkExtMetrics))).Methods(http.MethodGet)
+func metricHandlers(r *mux.Router) {
+	r.HandleFunc("/api/metrics/{network}/{nodeid}", logic.Authorize(false, models.NodeResource, models.ReadAction, http.HandlerFunc(getNodeMetrics))).Methods(http.MethodGet)
+	r.HandleFunc("/api/metrics/{network}", logic.Authorize(false, models.NodeResource, models.ReadAction, http.HandlerFunc(getNetworkNodesMetrics))).Methods(http.MethodGet)
+	r.HandleFunc("/api/metrics", logic.Authorize(false, models.ServerResource, models.ReadAction, http.HandlerFunc(getAllMetrics))).Methods(http.MethodGet)
+	r.HandleFunc("/api/metrics-ext/{network}", logic.Authorize(false, models.ExtClientResource, models.ReadAction, http.HandlerFunc(getNetworkExtMetrics))).Methods(http.MethodGet)
 }
 
-// get the metrics of a given node
+// swagger:route GET /api/metrics/{network}/{nodeid} metrics getNodeMetrics
+//
+// Get the metrics of a node, the history of the metrics if from, to or step are given.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: nodeMetricsResponse
 func getNodeMetrics(w http.ResponseWriter, r *http.Request) {
 	// set header.
 	w.Header().Set("Content-Type", "application/json")
@@ -28,6 +40,11 @@ func getNodeMetrics(w http.ResponseWriter, r *http.Request) {
 	nodeID := params["nodeid"]
 
 	logger.Log(1, r.Header.Get("user"), "requested fetching metrics for node", nodeID, "on network", params["network"])
+	query := r.URL.Query()
+	if query.Has("from") || query.Has("to") || query.Has("step") {
+		getNodeMetricsHistory(w, r, nodeID)
+		return
+	}
 	metrics, err := logic.GetMetrics(nodeID)
 	if err != nil {
 		logger.Log(1, r.Header.Get("user"), "failed to fetch metrics of node", nodeID, err.Error())
@@ -40,7 +57,67 @@ func getNodeMetrics(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(metrics)
 }
 
-// get the metrics of all nodes in given network
+// getNodeMetricsHistory - answers a range query on the metrics history of a node,
+// from and to are RFC 3339 times or unix seconds defaulting to the last 24 hours,
+// step is a duration such as 5m or seconds
+func getNodeMetricsHistory(w http.ResponseWriter, r *http.Request, nodeID string) {
+	query := r.URL.Query()
+	to, err := parseMetricsTime(query.Get("to"), time.Now())
+	if err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	from, err := parseMetricsTime(query.Get("from"), to.Add(-time.Hour*24))
+	if err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	var step time.Duration
+	if stepParam := query.Get("step"); stepParam != "" {
+		if seconds, err := strconv.Atoi(stepParam); err == nil {
+			step = time.Duration(seconds) * time.Second
+		} else if step, err = time.ParseDuration(stepParam); err != nil {
+			logic.ReturnErrorResponse(w, r, logic.FormatError(fmt.Errorf("invalid step %s", stepParam), "badrequest"))
+			return
+		}
+	}
+	history, err := logic.GetMetricsHistory(nodeID, from, to, step)
+	if err != nil {
+		logger.Log(1, r.Header.Get("user"), "failed to fetch metrics history of node", nodeID, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "fetched metrics history for node", nodeID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
+// parseMetricsTime - parses an RFC 3339 time or unix seconds, def if empty
+func parseMetricsTime(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %s", value)
+	}
+	return t, nil
+}
+
+// swagger:route GET /api/metrics/{network} metrics getNetworkNodesMetrics
+//
+// Get the metrics of all nodes in a network.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: networkMetricsResponse
 func getNetworkNodesMetrics(w http.ResponseWriter, r *http.Request) {
 	// set header.
 	w.Header().Set("Content-Type", "application/json")
@@ -74,7 +151,17 @@ func getNetworkNodesMetrics(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(networkMetrics)
 }
 
-// get the metrics for ext clients on a given network
+// swagger:route GET /api/metrics-ext/{network} metrics getNetworkExtMetrics
+//
+// Get the metrics of the external clients in a network.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: extMetricsResponse
 func getNetworkExtMetrics(w http.ResponseWriter, r *http.Request) {
 	// set header.
 	w.Header().Set("Content-Type", "application/json")
@@ -132,7 +219,17 @@ func getNetworkExtMetrics(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(networkMetrics.Connectivity)
 }
 
-// get Metrics of all nodes on server, lots of data
+// swagger:route GET /api/metrics metrics getAllMetrics
+//
+// Get the metrics of all nodes on the server, lots of data.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: networkMetricsResponse
 func getAllMetrics(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	logger.Log(1, r.Header.Get("user"), "requested fetching all metrics")
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNodeMetricsHistory(t *testing.T) {
+	t.Setenv("MASTER_KEY", "secretkey")
+	nodeID := uuid.New().String()
+	peerID := uuid.New().String()
+	defer logic.DeleteMetricsHistory(nodeID)
+	start := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	for i := 0; i < 3; i++ {
+		metrics := models.Metrics{
+			Connectivity: map[string]models.Metric{
+				peerID: {NodeName: "peer", Connected: true, Latency: 10, TotalReceived: int64(1000 * (i + 1))},
+			},
+		}
+		assert.Nil(t, logic.RecordMetricsHistory(nodeID, "skynet", &metrics, start.Add(time.Minute*time.Duration(i))))
+	}
+	router := mux.NewRouter()
+	metricHandlers(router)
+	serve := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/metrics/skynet/"+nodeID+"?"+query, nil)
+		req.Header.Set("Authorization", "Bearer secretkey")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	t.Run("Range", func(t *testing.T) {
+		w := serve(fmt.Sprintf("from=%d&to=%d&step=1m", start.Unix(), start.Add(time.Minute*3).Unix()))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var history models.MetricsHistory
+		assert.Nil(t, json.NewDecoder(w.Body).Decode(&history))
+		assert.Equal(t, models.MetricsMinute, history.Resolution)
+		assert.Equal(t, 3, len(history.Points))
+		assert.Equal(t, int64(1000), history.Points[2].Peers[peerID].Received)
+	})
+	t.Run("RFC3339", func(t *testing.T) {
+		w := serve("from=" + start.Format(time.RFC3339) + "&to=" + start.Add(time.Minute*2).Format(time.RFC3339) + "&step=60")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var history models.MetricsHistory
+		assert.Nil(t, json.NewDecoder(w.Body).Decode(&history))
+		assert.Equal(t, 2, len(history.Points))
+	})
+	t.Run("InvalidStep", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve("step=often").Code)
+	})
+	t.Run("InvalidTime", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve("from=yesterday").Code)
+	})
+	t.Run("EmptyRange", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(fmt.Sprintf("from=%d&to=%d", start.Unix(), start.Unix())).Code)
+	})
+	t.Run("Unauthorized", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/metrics/skynet/"+nodeID+"?step=1m", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
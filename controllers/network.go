@@ -19,13 +19,14 @@ import (
 )
 
 func networkHandlers(r *mux.Router) {
-	r.HandleFunc("/api/networks", logic.SecurityCheck(false, http.HandlerFunc(getNetworks))).Methods(http.MethodGet)
-	r.HandleFunc("/api/networks", logic.SecurityCheck(true, checkFreeTierLimits(networks_l, http.HandlerFunc(createNetwork)))).Methods(http.MethodPost)
-	r.HandleFunc("/api/networks/{networkname}", logic.SecurityCheck(false, http.HandlerFunc(getNetwork))).Methods(http.MethodGet)
-	r.HandleFunc("/api/networks/{networkname}", logic.SecurityCheck(true, http.HandlerFunc(deleteNetwork))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/networks", logic.Authorize(false, models.NetworkResource, models.ReadAction, http.HandlerFunc(getNetworks))).Methods(http.MethodGet)
+	r.HandleFunc("/api/networks", logic.Authorize(false, models.NetworkResource, models.CreateAction, checkFreeTierLimits(networks_l, http.HandlerFunc(createNetwork)))).Methods(http.MethodPost)
+	r.HandleFunc("/api/networks/{networkname}", logic.Authorize(false, models.NetworkResource, models.ReadAction, http.HandlerFunc(getNetwork))).Methods(http.MethodGet)
+	r.HandleFunc("/api/networks/{networkname}", logic.Authorize(false, models.NetworkResource, models.DeleteAction, http.HandlerFunc(deleteNetwork))).Methods(http.MethodDelete)
 	// ACLs
-	r.HandleFunc("/api/networks/{networkname}/acls", logic.SecurityCheck(true, http.HandlerFunc(updateNetworkACL))).Methods(http.MethodPut)
-	r.HandleFunc("/api/networks/{networkname}/acls", logic.SecurityCheck(true, http.HandlerFunc(getNetworkACL))).Methods(http.MethodGet)
+	r.HandleFunc("/api/networks/{networkname}/acls", logic.Authorize(false, models.AclResource, models.UpdateAction, http.HandlerFunc(updateNetworkACL))).Methods(http.MethodPut)
+	r.HandleFunc("/api/networks/{networkname}/acls", logic.Authorize(false, models.AclResource, models.ReadAction, http.HandlerFunc(getNetworkACL))).Methods(http.MethodGet)
+	r.HandleFunc("/api/networks/{networkname}/topology", logic.Authorize(false, models.NetworkResource, models.ReadAction, http.HandlerFunc(getNetworkTopology))).Methods(http.MethodGet)
 }
 
 // swagger:route GET /api/networks networks getNetworks
@@ -183,6 +184,43 @@ func getNetworkACL(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(networkACL)
 }
 
+// swagger:route GET /api/networks/{networkname}/topology networks getNetworkTopology
+//
+// Get the graph of nodes, ext clients and egress ranges of a network with the connectivity of its edges,
+// as Graphviz DOT with format=dot or an Accept header of text/vnd.graphviz.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: networkTopologyResponse
+func getNetworkTopology(w http.ResponseWriter, r *http.Request) {
+	netname := mux.Vars(r)["networkname"]
+	if _, err := logic.GetNetwork(netname); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch network", netname, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	topology, err := logic.GetNetworkTopology(netname)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to build topology of network", netname, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	logger.Log(2, r.Header.Get("user"), "fetched topology of network", netname)
+	if r.URL.Query().Get("format") == "dot" || strings.Contains(r.Header.Get("Accept"), "text/vnd.graphviz") {
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(logic.TopologyToDOT(&topology)))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(topology)
+}
+
 // swagger:route DELETE /api/networks/{networkname} networks deleteNetwork
 //
 // Delete a network.  Will not delete if there are any nodes that belong to the network.
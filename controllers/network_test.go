@@ -2,10 +2,14 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/gravitl/netmaker/database"
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/logic"
@@ -25,6 +29,8 @@ var netHost models.Host
 func TestMain(m *testing.M) {
 	database.InitializeDatabase()
 	defer database.CloseDB()
+	// failed logins of earlier runs must not lock out the test requests
+	database.DeleteAllRecords(database.LOGIN_ATTEMPTS_TABLE_NAME)
 	logic.CreateAdmin(&models.User{
 		UserName: "admin",
 		Password: "password",
@@ -86,32 +92,110 @@ func TestDeleteNetwork(t *testing.T) {
 	})
 }
 
-func TestSecurityCheck(t *testing.T) {
-	//these seem to work but not sure it the tests are really testing the functionality
-
+func TestAuthorize(t *testing.T) {
 	os.Setenv("MASTER_KEY", "secretkey")
-	t.Run("NoNetwork", func(t *testing.T) {
-		networks, username, err := logic.UserPermissions(false, "", "Bearer secretkey")
-		assert.Nil(t, err)
-		t.Log(networks, username)
+	deleteAllNetworks()
+	createNet()
+	defer deleteAllNetworks()
+	user := models.User{UserName: "rbacuser", Password: "password", Networks: []string{}, Groups: []string{}}
+	assert.Nil(t, logic.CreateUser(&user))
+	defer logic.DeleteUser(user.UserName)
+	role := models.Role{
+		ID:          "dns-editor",
+		Permissions: []models.RbacPermission{{Resource: models.DNSResource, Action: models.AllActions}},
+	}
+	assert.Nil(t, logic.CreateRole(&role))
+	defer logic.DeleteRole(role.ID)
+	binding := models.RoleBinding{RoleID: role.ID, UserName: user.UserName, Network: "skynet"}
+	assert.Nil(t, logic.CreateRoleBinding(&binding))
+	defer logic.DeleteRoleBinding(binding.ID)
+	login, err := logic.CreateUserSession(&user, "127.0.0.1", "test")
+	assert.Nil(t, err)
+	userToken := login.AuthToken
+
+	var networksHeader, userHeader, hostHeader string
+	router := mux.NewRouter()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		networksHeader = r.Header.Get("networks")
+		userHeader = r.Header.Get("user")
+		hostHeader = r.Header.Get(logic.HostIDHeader)
+		w.WriteHeader(http.StatusOK)
+	})
+	router.HandleFunc("/api/v1/host", logic.Authorize(true, models.HostResource, models.ReadAction, handler)).Methods(http.MethodGet)
+	router.HandleFunc("/api/dns/{network}", logic.Authorize(false, models.DNSResource, models.CreateAction, handler)).Methods(http.MethodPost)
+	router.HandleFunc("/api/dns", logic.Authorize(false, models.DNSResource, models.ReadAction, handler)).Methods(http.MethodGet)
+	router.HandleFunc("/api/dns/adm/pushdns", logic.Authorize(false, models.DNSResource, models.UpdateAction, handler)).Methods(http.MethodPost)
+	router.HandleFunc("/api/nodes/{network}", logic.Authorize(false, models.NodeResource, models.ReadAction, handler)).Methods(http.MethodGet)
+	serverHandlers(router)
+	serveWithHeaders := func(method, url, token string, headers map[string]string) int {
+		req := httptest.NewRequest(method, url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	serve := func(method, url, token string) int {
+		return serveWithHeaders(method, url, token, nil)
+	}
+	t.Run("MasterKey", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/nodes/skynet", "secretkey"))
+		assert.Equal(t, fmt.Sprintf("[%q]", logic.ALL_NETWORK_ACCESS), networksHeader)
+	})
+	t.Run("BadToken", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/nodes/skynet", "badkey"))
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/nodes/skynet", ""))
+	})
+	t.Run("BoundNetwork", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/api/dns/skynet", userToken))
+	})
+	t.Run("OtherNetwork", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/api/dns/othernet", userToken))
+	})
+	t.Run("OtherResource", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/nodes/skynet", userToken))
 	})
-	t.Run("WithNetwork", func(t *testing.T) {
-		networks, username, err := logic.UserPermissions(false, "skynet", "Bearer secretkey")
+	t.Run("ReadWithoutNetwork", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/dns", userToken))
+		assert.Equal(t, `["skynet"]`, networksHeader)
+	})
+	t.Run("WriteWithoutNetwork", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/api/dns/adm/pushdns", userToken))
+	})
+	t.Run("HostToken", func(t *testing.T) {
+		hostToken, err := logic.CreateJWT("authorizehost", "", "")
 		assert.Nil(t, err)
-		t.Log(networks, username)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/v1/host", hostToken))
+		assert.Equal(t, "authorizehost", hostHeader)
+		// a token with a forged signature is no host
+		tampered := hostToken[:len(hostToken)-2] + "A" + hostToken[len(hostToken)-1:]
+		if tampered == hostToken {
+			tampered = hostToken[:len(hostToken)-2] + "B" + hostToken[len(hostToken)-1:]
+		}
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/v1/host", tampered))
 	})
-	t.Run("BadNet", func(t *testing.T) {
-		t.Skip()
-		networks, username, err := logic.UserPermissions(false, "badnet", "Bearer secretkey")
-		assert.NotNil(t, err)
-		t.Log(err)
-		t.Log(networks, username)
+	t.Run("SpoofedIdentityHeaders", func(t *testing.T) {
+		spoofed := map[string]string{logic.HostIDHeader: "victimhost", "user": "admin", "networks": `["othernet"]`}
+		assert.Equal(t, http.StatusForbidden, serveWithHeaders(http.MethodGet, "/api/v1/host", userToken, spoofed))
+		assert.Equal(t, http.StatusOK, serveWithHeaders(http.MethodPost, "/api/dns/skynet", userToken, spoofed))
+		assert.Equal(t, "", hostHeader)
+		assert.Equal(t, user.UserName, userHeader)
+		assert.Equal(t, `["skynet"]`, networksHeader)
 	})
-	t.Run("BadToken", func(t *testing.T) {
-		networks, username, err := logic.UserPermissions(false, "skynet", "Bearer badkey")
-		assert.NotNil(t, err)
-		t.Log(err)
-		t.Log(networks, username)
+	t.Run("EndedSessionOnHostRoute", func(t *testing.T) {
+		ended, err := logic.CreateUserSession(&user, "127.0.0.1", "test")
+		assert.Nil(t, err)
+		assert.Nil(t, logic.DeleteUserSession(ended.SessionID))
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/v1/host", ended.AuthToken))
+		assert.Equal(t, "", hostHeader)
+	})
+	t.Run("ServerInfo", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/server/getserverinfo", userToken))
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/server/turn/stats", userToken))
 	})
 }
 
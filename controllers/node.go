@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/gravitl/netmaker/database"
@@ -19,22 +18,23 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-var hostIDHeader = "host-id"
-
 func nodeHandlers(r *mux.Router) {
 
-	r.HandleFunc("/api/nodes", authorize(false, false, "user", http.HandlerFunc(getAllNodes))).Methods(http.MethodGet)
-	r.HandleFunc("/api/nodes/{network}", authorize(false, true, "network", http.HandlerFunc(getNetworkNodes))).Methods(http.MethodGet)
-	r.HandleFunc("/api/nodes/{network}/{nodeid}", authorize(true, true, "node", http.HandlerFunc(getNode))).Methods(http.MethodGet)
-	r.HandleFunc("/api/nodes/{network}/{nodeid}", authorize(false, true, "node", http.HandlerFunc(updateNode))).Methods(http.MethodPut)
-	r.HandleFunc("/api/nodes/{network}/{nodeid}", authorize(true, true, "node", http.HandlerFunc(deleteNode))).Methods(http.MethodDelete)
-	r.HandleFunc("/api/nodes/{network}/{nodeid}/createrelay", authorize(false, true, "user", http.HandlerFunc(createRelay))).Methods(http.MethodPost)
-	r.HandleFunc("/api/nodes/{network}/{nodeid}/deleterelay", authorize(false, true, "user", http.HandlerFunc(deleteRelay))).Methods(http.MethodDelete)
-	r.HandleFunc("/api/nodes/{network}/{nodeid}/creategateway", authorize(false, true, "user", http.HandlerFunc(createEgressGateway))).Methods(http.MethodPost)
-	r.HandleFunc("/api/nodes/{network}/{nodeid}/deletegateway", authorize(false, true, "user", http.HandlerFunc(deleteEgressGateway))).Methods(http.MethodDelete)
-	r.HandleFunc("/api/nodes/{network}/{nodeid}/createingress", logic.SecurityCheck(false, http.HandlerFunc(createIngressGateway))).Methods(http.MethodPost)
-	r.HandleFunc("/api/nodes/{network}/{nodeid}/deleteingress", logic.SecurityCheck(false, http.HandlerFunc(deleteIngressGateway))).Methods(http.MethodDelete)
-	r.HandleFunc("/api/nodes/{network}/{nodeid}", authorize(true, true, "node", http.HandlerFunc(updateNode))).Methods(http.MethodPost)
+	r.HandleFunc("/api/nodes", logic.Authorize(false, models.NodeResource, models.ReadAction, http.HandlerFunc(getAllNodes))).Methods(http.MethodGet)
+	r.HandleFunc("/api/nodes/{network}", logic.Authorize(false, models.NodeResource, models.ReadAction, http.HandlerFunc(getNetworkNodes))).Methods(http.MethodGet)
+	r.HandleFunc("/api/nodes/{network}/{nodeid}", logic.Authorize(true, models.NodeResource, models.ReadAction, http.HandlerFunc(getNode))).Methods(http.MethodGet)
+	r.HandleFunc("/api/nodes/{network}/{nodeid}", logic.Authorize(false, models.NodeResource, models.UpdateAction, http.HandlerFunc(updateNode))).Methods(http.MethodPut)
+	r.HandleFunc("/api/nodes/{network}/{nodeid}", logic.Authorize(true, models.NodeResource, models.DeleteAction, http.HandlerFunc(deleteNode))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/nodes/{network}/{nodeid}/createrelay", logic.Authorize(false, models.NodeResource, models.UpdateAction, http.HandlerFunc(createRelay))).Methods(http.MethodPost)
+	r.HandleFunc("/api/nodes/{network}/{nodeid}/deleterelay", logic.Authorize(false, models.NodeResource, models.UpdateAction, http.HandlerFunc(deleteRelay))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/nodes/{network}/{nodeid}/creategateway", logic.Authorize(false, models.NodeResource, models.UpdateAction, http.HandlerFunc(createEgressGateway))).Methods(http.MethodPost)
+	r.HandleFunc("/api/nodes/{network}/{nodeid}/deletegateway", logic.Authorize(false, models.NodeResource, models.UpdateAction, http.HandlerFunc(deleteEgressGateway))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/nodes/{network}/{nodeid}/internetgw", logic.Authorize(false, models.NodeResource, models.UpdateAction, http.HandlerFunc(createInternetGw))).Methods(http.MethodPost)
+	r.HandleFunc("/api/nodes/{network}/{nodeid}/internetgw", logic.Authorize(false, models.NodeResource, models.UpdateAction, http.HandlerFunc(updateInternetGw))).Methods(http.MethodPut)
+	r.HandleFunc("/api/nodes/{network}/{nodeid}/internetgw", logic.Authorize(false, models.NodeResource, models.UpdateAction, http.HandlerFunc(deleteInternetGw))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/nodes/{network}/{nodeid}/createingress", logic.Authorize(false, models.NodeResource, models.UpdateAction, http.HandlerFunc(createIngressGateway))).Methods(http.MethodPost)
+	r.HandleFunc("/api/nodes/{network}/{nodeid}/deleteingress", logic.Authorize(false, models.NodeResource, models.UpdateAction, http.HandlerFunc(deleteIngressGateway))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/nodes/{network}/{nodeid}", logic.Authorize(true, models.NodeResource, models.UpdateAction, http.HandlerFunc(updateNode))).Methods(http.MethodPost)
 	r.HandleFunc("/api/nodes/adm/{network}/authenticate", authenticate).Methods(http.MethodPost)
 	r.HandleFunc("/api/v1/nodes/migrate", migrate).Methods(http.MethodPost)
 }
@@ -147,127 +147,6 @@ func authenticate(response http.ResponseWriter, request *http.Request) {
 	response.Write(successJSONResponse)
 }
 
-// The middleware for most requests to the API
-// They all pass  through here first
-// This will validate the JWT (or check for master token)
-// This will also check against the authNetwork and make sure the node should be accessing that endpoint,
-// even if it's technically ok
-// This is kind of a poor man's RBAC. There's probably a better/smarter way.
-// TODO: Consider better RBAC implementations
-func authorize(hostAllowed, networkCheck bool, authNetwork string, next http.Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var errorResponse = models.ErrorResponse{
-			Code: http.StatusForbidden, Message: logic.Forbidden_Msg,
-		}
-
-		var params = mux.Vars(r)
-
-		networkexists, _ := logic.NetworkExists(params["network"])
-		//check that the request is for a valid network
-		//if (networkCheck && !networkexists) || err != nil {
-		if networkCheck && !networkexists {
-			logic.ReturnErrorResponse(w, r, errorResponse)
-			return
-		} else {
-			w.Header().Set("Content-Type", "application/json")
-
-			//get the auth token
-			bearerToken := r.Header.Get("Authorization")
-
-			var tokenSplit = strings.Split(bearerToken, " ")
-
-			//I put this in in case the user doesn't put in a token at all (in which case it's empty)
-			//There's probably a smarter way of handling this.
-			var authToken = "928rt238tghgwe@TY@$Y@#WQAEGB2FC#@HG#@$Hddd"
-
-			if len(tokenSplit) > 1 {
-				authToken = tokenSplit[1]
-			} else {
-				logic.ReturnErrorResponse(w, r, errorResponse)
-				return
-			}
-			// check if host instead of user
-			if hostAllowed {
-				// TODO --- should ensure that node is only operating on itself
-				if hostID, _, _, err := logic.VerifyHostToken(authToken); err == nil {
-					r.Header.Set(hostIDHeader, hostID)
-					// this indicates request is from a node
-					// used for failover - if a getNode comes from node, this will trigger a metrics wipe
-					next.ServeHTTP(w, r)
-					return
-				}
-			}
-
-			var isAuthorized = false
-			var nodeID = ""
-			username, networks, isadmin, errN := logic.VerifyUserToken(authToken)
-			if errN != nil {
-				logic.ReturnErrorResponse(w, r, errorResponse)
-				return
-			}
-
-			isnetadmin := isadmin
-			if errN == nil && isadmin {
-				nodeID = "mastermac"
-				isAuthorized = true
-				r.Header.Set("ismasterkey", "yes")
-			}
-			if !isadmin && params["network"] != "" {
-				if logic.StringSliceContains(networks, params["network"]) && pro.IsUserNetAdmin(params["network"], username) {
-					isnetadmin = true
-				}
-			}
-			//The mastermac (login with masterkey from config) can do everything!! May be dangerous.
-			if nodeID == "mastermac" {
-				isAuthorized = true
-				r.Header.Set("ismasterkey", "yes")
-				//for everyone else, there's poor man's RBAC. The "cases" are defined in the routes in the handlers
-				//So each route defines which access network should be allowed to access it
-			} else {
-				switch authNetwork {
-				case "all":
-					isAuthorized = true
-				case "nodes":
-					isAuthorized = (nodeID != "") || isnetadmin
-				case "network":
-					if isnetadmin {
-						isAuthorized = true
-					} else {
-						node, err := logic.GetNodeByID(nodeID)
-						if err != nil {
-							logic.ReturnErrorResponse(w, r, errorResponse)
-							return
-						}
-						isAuthorized = (node.Network == params["network"])
-					}
-				case "node":
-					if isnetadmin {
-						isAuthorized = true
-					} else {
-						isAuthorized = (nodeID == params["netid"])
-					}
-				case "host":
-				case "user":
-					isAuthorized = true
-				default:
-					isAuthorized = false
-				}
-			}
-			if !isAuthorized {
-				logic.ReturnErrorResponse(w, r, errorResponse)
-				return
-			} else {
-				//If authorized, this function passes along it's request and output to the appropriate route function.
-				if username == "" {
-					username = "(user not found)"
-				}
-				r.Header.Set("user", username)
-				next.ServeHTTP(w, r)
-			}
-		}
-	}
-}
-
 // swagger:route GET /api/nodes/{network} nodes getNetworkNodes
 //
 // Gets all nodes associated with network including pending nodes.
@@ -313,15 +192,15 @@ func getNetworkNodes(w http.ResponseWriter, r *http.Request) {
 // Not quite sure if this is necessary. Probably necessary based on front end but may want to review after iteration 1 if it's being used or not
 func getAllNodes(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	user, err := logic.GetUser(r.Header.Get("user"))
-	if err != nil && r.Header.Get("ismasterkey") != "yes" {
+	networks, err := getHeaderNetworks(r)
+	if err != nil {
 		logger.Log(0, r.Header.Get("user"),
-			"error fetching user info: ", err.Error())
+			"error unmarshalling networks: ", err.Error())
 		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
 		return
 	}
 	var nodes []models.Node
-	if user.IsAdmin || r.Header.Get("ismasterkey") == "yes" {
+	if len(networks) > 0 && networks[0] == logic.ALL_NETWORK_ACCESS {
 		nodes, err = logic.GetAllNodes()
 		if err != nil {
 			logger.Log(0, "error fetching all nodes info: ", err.Error())
@@ -329,13 +208,7 @@ func getAllNodes(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	} else {
-		nodes, err = getUsersNodes(*user)
-		if err != nil {
-			logger.Log(0, r.Header.Get("user"),
-				"error fetching nodes: ", err.Error())
-			logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
-			return
-		}
+		nodes = getNetworksNodes(networks)
 	}
 	// return all the nodes in JSON/API format
 	apiNodes := logic.GetAllNodesAPI(nodes[:])
@@ -345,17 +218,16 @@ func getAllNodes(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(apiNodes)
 }
 
-func getUsersNodes(user models.User) ([]models.Node, error) {
+func getNetworksNodes(networks []string) []models.Node {
 	var nodes []models.Node
-	var err error
-	for _, networkName := range user.Networks {
+	for _, networkName := range networks {
 		tmpNodes, err := logic.GetNetworkNodes(networkName)
 		if err != nil {
 			continue
 		}
 		nodes = append(nodes, tmpNodes...)
 	}
-	return nodes, err
+	return nodes
 }
 
 // swagger:route GET /api/nodes/{network}/{nodeid} nodes getNode
@@ -383,6 +255,11 @@ func getNode(w http.ResponseWriter, r *http.Request) {
 		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
 		return
 	}
+	// access was checked on the network of the route
+	if node.Network != params["network"] {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(fmt.Errorf("node %s is not on network %s", nodeid, params["network"]), "notfound"))
+		return
+	}
 	host, err := logic.GetHost(node.HostID.String())
 	if err != nil {
 		logger.Log(0, r.Header.Get("user"),
@@ -502,6 +379,127 @@ func deleteEgressGateway(w http.ResponseWriter, r *http.Request) {
 	runUpdates(&node, true)
 }
 
+// == INTERNET GATEWAY ==
+
+// swagger:route POST /api/nodes/{network}/{nodeid}/internetgw nodes createInternetGw
+//
+// Make a node an internet gateway (exit node) for other nodes and ext clients.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: nodeResponse
+func createInternetGw(w http.ResponseWriter, r *http.Request) {
+	handleInternetGwRequest(w, r, false)
+}
+
+// swagger:route PUT /api/nodes/{network}/{nodeid}/internetgw nodes updateInternetGw
+//
+// Update the nodes and ext clients routed through an internet gateway.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: nodeResponse
+func updateInternetGw(w http.ResponseWriter, r *http.Request) {
+	handleInternetGwRequest(w, r, true)
+}
+
+func handleInternetGwRequest(w http.ResponseWriter, r *http.Request, update bool) {
+	var params = mux.Vars(r)
+	w.Header().Set("Content-Type", "application/json")
+	nodeid := params["nodeid"]
+	netid := params["network"]
+	node, err := logic.GetNodeByID(nodeid)
+	if err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	if node.Network != netid {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(fmt.Errorf("node %s is not on network %s", nodeid, netid), "badrequest"))
+		return
+	}
+	if update && !node.IsInternetGateway() {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(fmt.Errorf("node %s is not an internet gateway", nodeid), "badrequest"))
+		return
+	}
+	var clientIDs []string
+	if err := json.NewDecoder(r.Body).Decode(&clientIDs); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	if err := logic.ValidateInetGwReq(node, clientIDs, update); err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	if err := logic.SetInternetGw(&node, clientIDs); err != nil {
+		logger.Log(0, r.Header.Get("user"),
+			fmt.Sprintf("failed to set internet gateway on node [%s] on network [%s]: %v",
+				nodeid, netid, err))
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+
+	apiNode := node.ConvertToAPINode()
+	logger.Log(1, r.Header.Get("user"), "set internet gateway on node", nodeid, "on network", netid)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(apiNode)
+	go func() {
+		mq.PublishPeerUpdate()
+	}()
+	runUpdates(&node, true)
+}
+
+// swagger:route DELETE /api/nodes/{network}/{nodeid}/internetgw nodes deleteInternetGw
+//
+// Remove the internet gateway from a node.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: nodeResponse
+func deleteInternetGw(w http.ResponseWriter, r *http.Request) {
+	var params = mux.Vars(r)
+	w.Header().Set("Content-Type", "application/json")
+	nodeid := params["nodeid"]
+	netid := params["network"]
+	node, err := logic.GetNodeByID(nodeid)
+	if err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	if node.Network != netid || !node.IsInternetGateway() {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(fmt.Errorf("node %s is not an internet gateway on network %s", nodeid, netid), "badrequest"))
+		return
+	}
+	if err := logic.UnsetInternetGw(&node); err != nil {
+		logger.Log(0, r.Header.Get("user"),
+			fmt.Sprintf("failed to delete internet gateway on node [%s] on network [%s]: %v",
+				nodeid, netid, err))
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+
+	apiNode := node.ConvertToAPINode()
+	logger.Log(1, r.Header.Get("user"), "deleted internet gateway on node", nodeid, "on network", netid)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(apiNode)
+	go func() {
+		mq.PublishPeerUpdate()
+	}()
+	runUpdates(&node, true)
+}
+
 // == INGRESS ==
 
 // swagger:route POST /api/nodes/{network}/{nodeid}/createingress nodes createIngressGateway
@@ -622,6 +620,11 @@ func updateNode(w http.ResponseWriter, r *http.Request) {
 		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
 		return
 	}
+	// access was checked on the network of the route
+	if currentNode.Network != params["network"] {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(fmt.Errorf("node %s is not on network %s", nodeid, params["network"]), "notfound"))
+		return
+	}
 
 	var newData models.ApiNode
 	// we decode our body request params
@@ -732,6 +735,11 @@ func deleteNode(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	// access was checked on the network of the route
+	if node.Network != params["network"] {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(fmt.Errorf("node %s is not on network %s", nodeid, params["network"]), "notfound"))
+		return
+	}
 	if r.Header.Get("ismaster") != "yes" {
 		username := r.Header.Get("user")
 		if username != "" && !doesUserOwnNode(username, params["network"], nodeid) {
@@ -2,9 +2,13 @@ package controller
 
 import (
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/gravitl/netmaker/database"
 	"github.com/gravitl/netmaker/logic"
 	"github.com/gravitl/netmaker/logic/acls"
@@ -73,6 +77,105 @@ func TestCreateEgressGateway(t *testing.T) {
 		assert.Equal(t, true, node.IsEgressGateway)
 		assert.Equal(t, gateway.Ranges, node.EgressGatewayRanges)
 	})
+	t.Run("Success-Per-Range-Config", func(t *testing.T) {
+		var gateway models.EgressGatewayRequest
+		gateway.Ranges = []string{"10.100.100.0/24"}
+		gateway.RangesWithConfig = []models.EgressRangeConfig{
+			{Range: "10.200.200.0/24", NatEnabled: "no", Metric: 100, AdvertiseTo: []string{"peer"}, AdvertiseToTags: []string{"cloud"}},
+		}
+		gateway.NetID = "skynet"
+		deleteAllNodes()
+		testnode := createTestNode()
+		gateway.NodeID = testnode.ID.String()
+
+		node, err := logic.CreateEgressGateway(gateway)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"10.100.100.0/24", "10.200.200.0/24"}, node.EgressGatewayRanges)
+		assert.Equal(t, "yes", node.EgressGatewayRequest.GetRangeConfig("10.100.100.0/24").NatEnabled)
+		rangeConfig := node.EgressGatewayRequest.GetRangeConfig("10.200.200.0/24")
+		assert.Equal(t, "no", rangeConfig.NatEnabled)
+		assert.Equal(t, uint32(100), rangeConfig.Metric)
+		assert.True(t, node.EgressGatewayRequest.IsRangeAdvertisedTo("10.200.200.0/24", "peer", nil))
+		assert.False(t, node.EgressGatewayRequest.IsRangeAdvertisedTo("10.200.200.0/24", "other", nil))
+		assert.True(t, node.EgressGatewayRequest.IsRangeAdvertisedTo("10.200.200.0/24", "other", []string{"onprem", "cloud"}))
+		assert.False(t, node.EgressGatewayRequest.IsRangeAdvertisedTo("10.200.200.0/24", "other", []string{"onprem"}))
+		assert.True(t, node.EgressGatewayRequest.IsRangeAdvertisedTo("10.100.100.0/24", "other", nil))
+	})
+	t.Run("Overlapping-Ranges-Mapped", func(t *testing.T) {
+		deleteAllNodes()
+		siteA := createTestNode()
+		siteB := createTestNode()
+		gatewayA := models.EgressGatewayRequest{
+			NetID:  "skynet",
+			NodeID: siteA.ID.String(),
+			RangesWithConfig: []models.EgressRangeConfig{
+				{Range: "192.168.1.0/24", VirtualRange: "10.200.1.0/24"},
+			},
+		}
+		gatewayB := models.EgressGatewayRequest{
+			NetID:  "skynet",
+			NodeID: siteB.ID.String(),
+			RangesWithConfig: []models.EgressRangeConfig{
+				{Range: "192.168.1.0/24", VirtualRange: "10.200.2.0/24"},
+			},
+		}
+		nodeA, err := logic.CreateEgressGateway(gatewayA)
+		assert.Nil(t, err)
+		rangeConfig := nodeA.EgressGatewayRequest.GetRangeConfig("192.168.1.0/24")
+		assert.Equal(t, "10.200.1.0/24", rangeConfig.AdvertisedRange())
+		_, err = logic.CreateEgressGateway(gatewayB)
+		assert.Nil(t, err)
+
+		gatewayB.RangesWithConfig[0].VirtualRange = "10.200.1.0/25"
+		_, err = logic.CreateEgressGateway(gatewayB)
+		assert.EqualError(t, err, "virtual range 10.200.1.0/25 must have the same size as egress range 192.168.1.0/24")
+		gatewayB.RangesWithConfig[0] = models.EgressRangeConfig{Range: "192.168.2.0/24", VirtualRange: "10.200.1.0/24"}
+		_, err = logic.CreateEgressGateway(gatewayB)
+		assert.ErrorContains(t, err, "virtual range 10.200.1.0/24 overlaps range 10.200.1.0/24 advertised by node")
+	})
+	t.Run("Overlapping-Ranges-Unmapped", func(t *testing.T) {
+		deleteAllNodes()
+		siteA := createTestNode()
+		siteB := createTestNode()
+		_, err := logic.CreateEgressGateway(models.EgressGatewayRequest{
+			NetID:  "skynet",
+			NodeID: siteA.ID.String(),
+			Ranges: []string{"192.168.0.0/16"},
+		})
+		assert.Nil(t, err)
+		// the same range on another gateway is high availability
+		_, err = logic.CreateEgressGateway(models.EgressGatewayRequest{
+			NetID:  "skynet",
+			NodeID: siteB.ID.String(),
+			Ranges: []string{"192.168.0.0/16"},
+		})
+		assert.Nil(t, err)
+		_, err = logic.CreateEgressGateway(models.EgressGatewayRequest{
+			NetID:  "skynet",
+			NodeID: siteB.ID.String(),
+			Ranges: []string{"192.168.1.0/24"},
+		})
+		assert.ErrorContains(t, err, "egress range 192.168.1.0/24 overlaps range 192.168.0.0/16 advertised by node")
+		_, err = logic.CreateEgressGateway(models.EgressGatewayRequest{
+			NetID:  "skynet",
+			NodeID: siteB.ID.String(),
+			Ranges: []string{"172.16.0.0/16", "172.16.1.0/24"},
+		})
+		assert.EqualError(t, err, "egress range 172.16.0.0/16 overlaps range 172.16.1.0/24 of the same gateway")
+	})
+	t.Run("Invalid-Range-Nat", func(t *testing.T) {
+		var gateway models.EgressGatewayRequest
+		gateway.RangesWithConfig = []models.EgressRangeConfig{
+			{Range: "10.200.200.0/24", NatEnabled: "maybe"},
+		}
+		gateway.NetID = "skynet"
+		deleteAllNodes()
+		testnode := createTestNode()
+		gateway.NodeID = testnode.ID.String()
+
+		_, err := logic.CreateEgressGateway(gateway)
+		assert.EqualError(t, err, "natenabled of egress range 10.200.200.0/24 must be yes or no")
+	})
 
 }
 func TestDeleteEgressGateway(t *testing.T) {
@@ -216,6 +319,32 @@ func TestNodeACLs(t *testing.T) {
 	deleteAllNodes()
 }
 
+func TestNodeRouteNetwork(t *testing.T) {
+	t.Setenv("MASTER_KEY", "secretkey")
+	createNet()
+	node := createTestNode()
+	defer database.DeleteRecord(database.NODES_TABLE_NAME, node.ID.String())
+	router := mux.NewRouter()
+	nodeHandlers(router)
+	serve := func(method, url string) int {
+		req := httptest.NewRequest(method, url, strings.NewReader("{}"))
+		req.Header.Set("Authorization", "Bearer secretkey")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	t.Run("OtherNetwork", func(t *testing.T) {
+		for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+			assert.Equal(t, http.StatusNotFound, serve(method, "/api/nodes/othernet/"+node.ID.String()), method)
+		}
+		_, err := logic.GetNodeByID(node.ID.String())
+		assert.Nil(t, err)
+	})
+	t.Run("NodeNetwork", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/nodes/skynet/"+node.ID.String()))
+	})
+}
+
 func deleteAllNodes() {
 	database.DeleteAllRecords(database.NODES_TABLE_NAME)
 }
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/servercfg"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var apiRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "netmaker",
+	Name:      "api_request_duration_seconds",
+	Help:      "Duration of API requests by route.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"route", "method", "code"})
+
+func prometheusHandlers(r *mux.Router) {
+	r.Handle("/metrics", scrapeAuth(promhttp.Handler())).Methods(http.MethodGet)
+}
+
+// scrapeAuth - lets requests carrying the configured scrape token through,
+// prometheus does not hold a user account so /metrics has its own token
+func scrapeAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := servercfg.GetMetricsScrapeToken()
+		if token == "" {
+			logic.ReturnErrorResponse(w, r, logic.FormatError(errors.New("metrics scraping is disabled, set METRICS_SCRAPE_TOKEN"), "forbidden"))
+			return
+		}
+		bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			logic.ReturnErrorResponse(w, r, logic.FormatError(errors.New("invalid scrape token"), "unauthorized"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// observeRequests - wraps the router recording the duration of requests by route template,
+// the template keeps ids out of the labels, requests matching no route are recorded as unmatched
+func observeRequests(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unmatched"
+		var match mux.RouteMatch
+		if router.Match(r, &match) && match.Route != nil {
+			if template, err := match.Route.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		router.ServeHTTP(recorder, r)
+		apiRequestDuration.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusRecorder - keeps the status code written to a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Hijack - lets websocket handlers take over the connection
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	s.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrapeAuth(t *testing.T) {
+	r := mux.NewRouter()
+	prometheusHandlers(r)
+	scrape := func(token string) int {
+		request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		response := httptest.NewRecorder()
+		r.ServeHTTP(response, request)
+		return response.Code
+	}
+	t.Run("DisabledWithoutToken", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, scrape("anything"))
+	})
+	os.Setenv("METRICS_SCRAPE_TOKEN", "scrape-secret")
+	defer os.Unsetenv("METRICS_SCRAPE_TOKEN")
+	t.Run("MissingToken", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, scrape(""))
+	})
+	t.Run("WrongToken", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, scrape("wrong"))
+	})
+	t.Run("ValidToken", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, scrape("scrape-secret"))
+	})
+	t.Run("UserTokenIsNotEnough", func(t *testing.T) {
+		token, err := logic.CreateUserJWT("admin", "", []string{}, true)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusUnauthorized, scrape(token))
+	})
+}
+
+func TestObserveRequests(t *testing.T) {
+	r := mux.NewRouter()
+	r.HandleFunc("/api/things/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}).Methods(http.MethodGet)
+	handler := observeRequests(r)
+	serve := func(path string) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	t.Run("RouteTemplate", func(t *testing.T) {
+		serve("/api/things/1")
+		serve("/api/things/2")
+		// ids share the series of the template
+		assert.True(t, apiRequestDuration.DeleteLabelValues("/api/things/{id}", http.MethodGet, "418"))
+		assert.False(t, apiRequestDuration.DeleteLabelValues("/api/things/1", http.MethodGet, "418"))
+	})
+	t.Run("Unmatched", func(t *testing.T) {
+		serve("/api/unknown/route")
+		assert.True(t, apiRequestDuration.DeleteLabelValues("unmatched", http.MethodGet, "404"))
+	})
+}
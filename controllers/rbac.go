@@ -0,0 +1,251 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+)
+
+func rbacHandlers(r *mux.Router) {
+	r.HandleFunc("/api/v1/roles", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getRoles))).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/roles", logic.Authorize(false, models.UserResource, models.CreateAction, http.HandlerFunc(createRole))).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/roles/{roleid}", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getRole))).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/roles/{roleid}", logic.Authorize(false, models.UserResource, models.UpdateAction, http.HandlerFunc(updateRole))).Methods(http.MethodPut)
+	r.HandleFunc("/api/v1/roles/{roleid}", logic.Authorize(false, models.UserResource, models.DeleteAction, http.HandlerFunc(deleteRole))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/v1/rolebindings", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getRoleBindings))).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/rolebindings", logic.Authorize(false, models.UserResource, models.CreateAction, http.HandlerFunc(createRoleBinding))).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/rolebindings/{bindingid}", logic.Authorize(false, models.UserResource, models.DeleteAction, http.HandlerFunc(deleteRoleBinding))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/users/{username}/rolebindings", logic.Authorize(false, models.SelfResource, models.ReadAction, logic.ContinueIfUserMatch(http.HandlerFunc(getUserRoleBindings)))).Methods(http.MethodGet)
+}
+
+// swagger:route GET /api/v1/roles rbac getRoles
+//
+// Lists the built-in and custom roles.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: rolesResponse
+func getRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := logic.GetRoles()
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch roles:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(roles)
+}
+
+// swagger:route GET /api/v1/roles/{roleid} rbac getRole
+//
+// Gets a role.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: roleResponse
+func getRole(w http.ResponseWriter, r *http.Request) {
+	role, err := logic.GetRole(mux.Vars(r)["roleid"])
+	if err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "notfound"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(role)
+}
+
+// swagger:route POST /api/v1/roles rbac createRole
+//
+// Creates a custom role.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: roleResponse
+func createRole(w http.ResponseWriter, r *http.Request) {
+	var role models.Role
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	if err := logic.CreateRole(&role); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to create role:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "created role", role.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(role)
+}
+
+// swagger:route PUT /api/v1/roles/{roleid} rbac updateRole
+//
+// Updates the description and permissions of a custom role.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: roleResponse
+func updateRole(w http.ResponseWriter, r *http.Request) {
+	var role models.Role
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	role.ID = mux.Vars(r)["roleid"]
+	if err := logic.UpdateRole(&role); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to update role:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "updated role", role.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(role)
+}
+
+// swagger:route DELETE /api/v1/roles/{roleid} rbac deleteRole
+//
+// Deletes a custom role which is not bound to any user or group.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func deleteRole(w http.ResponseWriter, r *http.Request) {
+	roleID := mux.Vars(r)["roleid"]
+	if err := logic.DeleteRole(roleID); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to delete role:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "deleted role", roleID)
+	logic.ReturnSuccessResponse(w, r, "deleted role "+roleID)
+}
+
+// swagger:route GET /api/v1/rolebindings rbac getRoleBindings
+//
+// Lists the role bindings of users and groups.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: roleBindingsResponse
+func getRoleBindings(w http.ResponseWriter, r *http.Request) {
+	bindings, err := logic.GetRoleBindings()
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch role bindings:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bindings)
+}
+
+// swagger:route POST /api/v1/rolebindings rbac createRoleBinding
+//
+// Binds a role to a user or a group, on one network or on all networks.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: roleBindingResponse
+func createRoleBinding(w http.ResponseWriter, r *http.Request) {
+	var binding models.RoleBinding
+	if err := json.NewDecoder(r.Body).Decode(&binding); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	if err := logic.CreateRoleBinding(&binding); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to create role binding:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "bound role", binding.RoleID, "to", binding.UserName+binding.Group)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(binding)
+}
+
+// swagger:route DELETE /api/v1/rolebindings/{bindingid} rbac deleteRoleBinding
+//
+// Deletes a role binding.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func deleteRoleBinding(w http.ResponseWriter, r *http.Request) {
+	bindingID := mux.Vars(r)["bindingid"]
+	if err := logic.DeleteRoleBinding(bindingID); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to delete role binding:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "deleted role binding", bindingID)
+	logic.ReturnSuccessResponse(w, r, "deleted role binding "+bindingID)
+}
+
+// swagger:route GET /api/users/{username}/rolebindings rbac getUserRoleBindings
+//
+// Lists the role bindings in effect for a user, including those of its groups, admin flag and network access levels.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: roleBindingsResponse
+func getUserRoleBindings(w http.ResponseWriter, r *http.Request) {
+	user, err := logic.GetUser(mux.Vars(r)["username"])
+	if err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "notfound"))
+		return
+	}
+	bindings, err := logic.GetUserRoleBindings(user)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch role bindings of user", user.UserName, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bindings)
+}
@@ -167,6 +167,10 @@ func deleteHostRelay(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	var params = mux.Vars(r)
 	hostid := params["hostid"]
+	if err := logic.CheckHostNotInRelayGroup(hostid); err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
 	relayHost, relayed, err := logic.DeleteHostRelay(hostid)
 	if err != nil {
 		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
@@ -197,3 +201,130 @@ func deleteHostRelay(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(apiHostData)
 }
+
+// swagger:route GET /api/v1/relaygroups hosts getRelayGroups
+//
+// Lists all relay groups.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: relayGroupsResponse
+func getRelayGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := logic.GetRelayGroups()
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch relay groups:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(groups)
+}
+
+// swagger:route POST /api/v1/relaygroups hosts createRelayGroup
+//
+// Create a relay group, members are assigned to the relay hosts automatically.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: relayGroupResponse
+func createRelayGroup(w http.ResponseWriter, r *http.Request) {
+	var req models.RelayGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	group, changed, err := logic.CreateRelayGroup(req)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to create relay group", req.Name, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "created relay group", group.Name)
+	go publishRelayGroupChanges(changed)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(group)
+}
+
+// swagger:route PUT /api/v1/relaygroups/{groupid} hosts updateRelayGroup
+//
+// Update the relay hosts and members of a relay group.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: relayGroupResponse
+func updateRelayGroup(w http.ResponseWriter, r *http.Request) {
+	var req models.RelayGroupRequest
+	groupID := mux.Vars(r)["groupid"]
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	group, changed, err := logic.UpdateRelayGroup(groupID, req)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to update relay group", groupID, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "updated relay group", group.Name)
+	go publishRelayGroupChanges(changed)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(group)
+}
+
+// swagger:route DELETE /api/v1/relaygroups/{groupid} hosts deleteRelayGroup
+//
+// Delete a relay group, its members are no longer relayed.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func deleteRelayGroup(w http.ResponseWriter, r *http.Request) {
+	groupID := mux.Vars(r)["groupid"]
+	changed, err := logic.DeleteRelayGroup(groupID)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to delete relay group", groupID, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "deleted relay group", groupID)
+	go publishRelayGroupChanges(changed)
+	logic.ReturnSuccessResponse(w, r, "deleted relay group "+groupID)
+}
+
+// publishRelayGroupChanges - informs the hosts changed by a relay group operation and updates peers
+func publishRelayGroupChanges(changed []models.Host) {
+	for i := range changed {
+		if err := mq.HostUpdate(&models.HostUpdate{
+			Action: models.UpdateHost,
+			Host:   changed[i],
+		}); err != nil {
+			logger.Log(0, "failed to send host update: ", changed[i].ID.String(), err.Error())
+		}
+	}
+	if len(changed) > 0 {
+		if err := mq.PublishPeerUpdate(); err != nil {
+			logger.Log(0, "fail to publish peer update: ", err.Error())
+		}
+	}
+}
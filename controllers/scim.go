@@ -0,0 +1,720 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/mq"
+)
+
+// scimFilterRegex - the equality filters identity providers look up users and groups with, e.g. userName eq "alice"
+var scimFilterRegex = regexp.MustCompile(`(?i)^\s*([a-z.]+)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// scimMemberFilterRegex - patch path selecting a member of a group, e.g. members[value eq "alice"]
+var scimMemberFilterRegex = regexp.MustCompile(`(?i)^members\[value eq "((?:[^"\\]|\\.)*)"\]$`)
+
+func scimHandlers(r *mux.Router) {
+	r.HandleFunc("/scim/v2/ServiceProviderConfig", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getSCIMServiceProviderConfig))).Methods(http.MethodGet)
+	r.HandleFunc("/scim/v2/Users", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getSCIMUsers))).Methods(http.MethodGet)
+	r.HandleFunc("/scim/v2/Users", logic.Authorize(false, models.UserResource, models.CreateAction, http.HandlerFunc(createSCIMUser))).Methods(http.MethodPost)
+	r.HandleFunc("/scim/v2/Users/{username}", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getSCIMUser))).Methods(http.MethodGet)
+	r.HandleFunc("/scim/v2/Users/{username}", logic.Authorize(false, models.UserResource, models.UpdateAction, http.HandlerFunc(replaceSCIMUser))).Methods(http.MethodPut)
+	r.HandleFunc("/scim/v2/Users/{username}", logic.Authorize(false, models.UserResource, models.UpdateAction, http.HandlerFunc(patchSCIMUser))).Methods(http.MethodPatch)
+	r.HandleFunc("/scim/v2/Users/{username}", logic.Authorize(false, models.UserResource, models.DeleteAction, http.HandlerFunc(deleteSCIMUser))).Methods(http.MethodDelete)
+	r.HandleFunc("/scim/v2/Groups", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getSCIMGroups))).Methods(http.MethodGet)
+	r.HandleFunc("/scim/v2/Groups", logic.Authorize(false, models.UserResource, models.CreateAction, http.HandlerFunc(createSCIMGroup))).Methods(http.MethodPost)
+	r.HandleFunc("/scim/v2/Groups/{groupname}", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getSCIMGroup))).Methods(http.MethodGet)
+	r.HandleFunc("/scim/v2/Groups/{groupname}", logic.Authorize(false, models.UserResource, models.UpdateAction, http.HandlerFunc(replaceSCIMGroup))).Methods(http.MethodPut)
+	r.HandleFunc("/scim/v2/Groups/{groupname}", logic.Authorize(false, models.UserResource, models.UpdateAction, http.HandlerFunc(patchSCIMGroup))).Methods(http.MethodPatch)
+	r.HandleFunc("/scim/v2/Groups/{groupname}", logic.Authorize(false, models.UserResource, models.DeleteAction, http.HandlerFunc(deleteSCIMGroup))).Methods(http.MethodDelete)
+}
+
+// swagger:route GET /scim/v2/ServiceProviderConfig scim getSCIMServiceProviderConfig
+//
+// Describes the scim features supported by the server.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: scimServiceProviderConfigResponse
+func getSCIMServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	supported := func(supported bool) map[string]bool { return map[string]bool{"supported": supported} }
+	returnSCIMResponse(w, http.StatusOK, map[string]any{
+		"schemas":        []string{models.SCIMServiceProviderConfigSchema},
+		"patch":          supported(true),
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": 1000},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "Access Token",
+			"description": "Personal access token of an admin with the users scope",
+			"primary":     true,
+		}},
+	})
+}
+
+// swagger:route GET /scim/v2/Users scim getSCIMUsers
+//
+// Lists users, optionally filtered with an equality filter on userName, externalId or id.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: scimListResponse
+func getSCIMUsers(w http.ResponseWriter, r *http.Request) {
+	attribute, value, err := parseSCIMFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		returnSCIMError(w, http.StatusBadRequest, "invalidFilter", err.Error())
+		return
+	}
+	users, err := logic.GetSCIMUsers()
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch scim users:", err.Error())
+		returnSCIMError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	resources := []any{}
+	for _, user := range users {
+		switch attribute {
+		case "":
+		case "username", "id":
+			if !strings.EqualFold(user.UserName, value) {
+				continue
+			}
+		case "externalid":
+			if user.ExternalID != value {
+				continue
+			}
+		default:
+			returnSCIMError(w, http.StatusBadRequest, "invalidFilter", "users can only be filtered by userName, externalId or id")
+			return
+		}
+		resources = append(resources, user)
+	}
+	returnSCIMList(w, r, resources)
+}
+
+// swagger:route GET /scim/v2/Users/{username} scim getSCIMUser
+//
+// Gets a user.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: scimUserResponse
+func getSCIMUser(w http.ResponseWriter, r *http.Request) {
+	user, err := logic.GetSCIMUser(mux.Vars(r)["username"])
+	if err != nil {
+		returnSCIMError(w, http.StatusNotFound, "", "user not found")
+		return
+	}
+	returnSCIMResponse(w, http.StatusOK, user)
+}
+
+// swagger:route POST /scim/v2/Users scim createSCIMUser
+//
+// Provisions a user, which is given a random password unless one is set.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				201: scimUserResponse
+func createSCIMUser(w http.ResponseWriter, r *http.Request) {
+	var user models.SCIMUser
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		returnSCIMError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	if _, err := logic.GetUser(user.UserName); err == nil {
+		returnSCIMError(w, http.StatusConflict, "uniqueness", "user "+user.UserName+" already exists")
+		return
+	}
+	if err := logic.CreateSCIMUser(&user); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to provision user", user.UserName, err.Error())
+		returnSCIMError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	if !*user.Active {
+		disableUserExtClients(user.UserName)
+	}
+	logger.Log(1, r.Header.Get("user"), "provisioned user", user.UserName)
+	returnSCIMResponse(w, http.StatusCreated, user)
+}
+
+// swagger:route PUT /scim/v2/Users/{username} scim replaceSCIMUser
+//
+// Replaces the external id and active state of a user, deactivated users cannot log in
+// and lose their network access and ext clients.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: scimUserResponse
+func replaceSCIMUser(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	var user models.SCIMUser
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		returnSCIMError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	if user.UserName != "" && user.UserName != username {
+		returnSCIMError(w, http.StatusBadRequest, "mutability", "users cannot be renamed")
+		return
+	}
+	updateSCIMUser(w, r, username, user.ExternalID, user.Active == nil || *user.Active)
+}
+
+// swagger:route PATCH /scim/v2/Users/{username} scim patchSCIMUser
+//
+// Changes the external id or active state of a user, other attributes are ignored.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: scimUserResponse
+func patchSCIMUser(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	current, err := logic.GetSCIMUser(username)
+	if err != nil {
+		returnSCIMError(w, http.StatusNotFound, "", "user not found")
+		return
+	}
+	var patch models.SCIMPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		returnSCIMError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	externalID, active := "", *current.Active
+	for _, operation := range patch.Operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" {
+			continue
+		}
+		values := map[string]json.RawMessage{}
+		if operation.Path == "" {
+			if err := json.Unmarshal(operation.Value, &values); err != nil {
+				returnSCIMError(w, http.StatusBadRequest, "invalidValue", err.Error())
+				return
+			}
+		} else {
+			values[operation.Path] = operation.Value
+		}
+		for attribute, value := range values {
+			switch strings.ToLower(attribute) {
+			case "active":
+				if active, err = parseSCIMBool(value); err != nil {
+					returnSCIMError(w, http.StatusBadRequest, "invalidValue", err.Error())
+					return
+				}
+			case "externalid":
+				if err := json.Unmarshal(value, &externalID); err != nil {
+					returnSCIMError(w, http.StatusBadRequest, "invalidValue", err.Error())
+					return
+				}
+			}
+		}
+	}
+	updateSCIMUser(w, r, username, externalID, active)
+}
+
+// swagger:route DELETE /scim/v2/Users/{username} scim deleteSCIMUser
+//
+// Deprovisions a user, deleting its ext clients and network users.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				204: successResponse
+func deleteSCIMUser(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	if _, err := logic.GetUser(username); err != nil {
+		returnSCIMError(w, http.StatusNotFound, "", "user not found")
+		return
+	}
+	deleteUserExtClients(username)
+	if _, err := logic.DeleteUser(username); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to deprovision user", username, err.Error())
+		returnSCIMError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "deprovisioned user", username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:route GET /scim/v2/Groups scim getSCIMGroups
+//
+// Lists user groups with their members, optionally filtered with an equality filter on displayName or id.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: scimListResponse
+func getSCIMGroups(w http.ResponseWriter, r *http.Request) {
+	attribute, value, err := parseSCIMFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		returnSCIMError(w, http.StatusBadRequest, "invalidFilter", err.Error())
+		return
+	}
+	if attribute != "" && attribute != "displayname" && attribute != "id" {
+		returnSCIMError(w, http.StatusBadRequest, "invalidFilter", "groups can only be filtered by displayName or id")
+		return
+	}
+	groups, err := logic.GetSCIMGroups()
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch scim groups:", err.Error())
+		returnSCIMError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	resources := []any{}
+	for _, group := range groups {
+		if attribute == "" || group.DisplayName == value {
+			resources = append(resources, group)
+		}
+	}
+	returnSCIMList(w, r, resources)
+}
+
+// swagger:route GET /scim/v2/Groups/{groupname} scim getSCIMGroup
+//
+// Gets a user group with its members.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: scimGroupResponse
+func getSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := logic.GetSCIMGroup(mux.Vars(r)["groupname"])
+	if err != nil {
+		returnSCIMError(w, http.StatusNotFound, "", err.Error())
+		return
+	}
+	returnSCIMResponse(w, http.StatusOK, group)
+}
+
+// swagger:route POST /scim/v2/Groups scim createSCIMGroup
+//
+// Provisions a user group with its members.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				201: scimGroupResponse
+func createSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	var group models.SCIMGroup
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		returnSCIMError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	if _, err := logic.GetSCIMGroup(group.DisplayName); err == nil {
+		returnSCIMError(w, http.StatusConflict, "uniqueness", "group "+group.DisplayName+" already exists")
+		return
+	}
+	if err := logic.CreateSCIMGroup(&group); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to provision group", group.DisplayName, err.Error())
+		returnSCIMError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "provisioned group", group.DisplayName)
+	returnSCIMResponse(w, http.StatusCreated, group)
+}
+
+// swagger:route PUT /scim/v2/Groups/{groupname} scim replaceSCIMGroup
+//
+// Replaces the name and members of a user group.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: scimGroupResponse
+func replaceSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["groupname"]
+	if _, err := logic.GetSCIMGroup(name); err != nil {
+		returnSCIMError(w, http.StatusNotFound, "", err.Error())
+		return
+	}
+	var group models.SCIMGroup
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		returnSCIMError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	members := []string{}
+	for _, member := range group.Members {
+		members = append(members, member.Value)
+	}
+	if err := logic.SetSCIMGroupMembers(name, members); err != nil {
+		returnSCIMError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	if group.DisplayName != "" && group.DisplayName != name {
+		if err := logic.RenameSCIMGroup(name, group.DisplayName); err != nil {
+			returnSCIMError(w, http.StatusBadRequest, "invalidValue", err.Error())
+			return
+		}
+		name = strings.TrimSpace(group.DisplayName)
+	}
+	logger.Log(1, r.Header.Get("user"), "replaced group", name)
+	returnSCIMGroup(w, name)
+}
+
+// swagger:route PATCH /scim/v2/Groups/{groupname} scim patchSCIMGroup
+//
+// Adds, removes or replaces members of a user group, or renames it.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: scimGroupResponse
+func patchSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["groupname"]
+	if _, err := logic.GetSCIMGroup(name); err != nil {
+		returnSCIMError(w, http.StatusNotFound, "", err.Error())
+		return
+	}
+	var patch models.SCIMPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		returnSCIMError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	for _, operation := range patch.Operations {
+		var err error
+		if name, err = patchSCIMGroupOperation(name, operation); err != nil {
+			returnSCIMError(w, http.StatusBadRequest, "invalidValue", err.Error())
+			return
+		}
+	}
+	logger.Log(1, r.Header.Get("user"), "patched group", name)
+	returnSCIMGroup(w, name)
+}
+
+// swagger:route DELETE /scim/v2/Groups/{groupname} scim deleteSCIMGroup
+//
+// Deprovisions a user group, its members lose the access it gave them.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				204: successResponse
+func deleteSCIMGroup(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["groupname"]
+	if _, err := logic.GetSCIMGroup(name); err != nil {
+		returnSCIMError(w, http.StatusNotFound, "", err.Error())
+		return
+	}
+	if err := logic.DeleteSCIMGroup(name); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to deprovision group", name, err.Error())
+		returnSCIMError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "deprovisioned group", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// patchSCIMGroupOperation - applies an operation of a group patch, returning the (new) name of the group
+func patchSCIMGroupOperation(name string, operation models.SCIMPatchOperation) (string, error) {
+	op := strings.ToLower(operation.Op)
+	if match := scimMemberFilterRegex.FindStringSubmatch(operation.Path); match != nil {
+		if op != "remove" {
+			return name, fmt.Errorf("unsupported %s of %s", op, operation.Path)
+		}
+		return name, logic.RemoveSCIMGroupMembers(name, []string{match[1]})
+	}
+	values := map[string]json.RawMessage{}
+	if operation.Path == "" {
+		if err := json.Unmarshal(operation.Value, &values); err != nil {
+			return name, err
+		}
+	} else {
+		values[operation.Path] = operation.Value
+	}
+	for attribute, value := range values {
+		switch strings.ToLower(attribute) {
+		case "members":
+			var members []models.SCIMMember
+			if len(value) > 0 {
+				if err := json.Unmarshal(value, &members); err != nil {
+					return name, err
+				}
+			}
+			usernames := []string{}
+			for _, member := range members {
+				usernames = append(usernames, member.Value)
+			}
+			var err error
+			switch {
+			case op == "add":
+				err = logic.AddSCIMGroupMembers(name, usernames)
+			case op == "remove" && len(value) > 0:
+				err = logic.RemoveSCIMGroupMembers(name, usernames)
+			default:
+				// replace, or remove of all members
+				err = logic.SetSCIMGroupMembers(name, usernames)
+			}
+			if err != nil {
+				return name, err
+			}
+		case "displayname":
+			var newName string
+			if err := json.Unmarshal(value, &newName); err != nil {
+				return name, err
+			}
+			if err := logic.RenameSCIMGroup(name, newName); err != nil {
+				return name, err
+			}
+			name = strings.TrimSpace(newName)
+		}
+	}
+	return name, nil
+}
+
+// updateSCIMUser - applies the external id and active state of a put or patch of a user
+func updateSCIMUser(w http.ResponseWriter, r *http.Request, username, externalID string, active bool) {
+	wasActive := logic.IsUserActive(username)
+	if err := logic.UpdateSCIMUser(username, externalID, active); err != nil {
+		returnSCIMError(w, http.StatusNotFound, "", err.Error())
+		return
+	}
+	if wasActive && !active {
+		disableUserExtClients(username)
+		logger.Log(1, r.Header.Get("user"), "deactivated user", username)
+	} else if !wasActive && active {
+		enableUserExtClients(username)
+		logger.Log(1, r.Header.Get("user"), "activated user", username)
+	}
+	user, err := logic.GetSCIMUser(username)
+	if err != nil {
+		returnSCIMError(w, http.StatusNotFound, "", err.Error())
+		return
+	}
+	returnSCIMResponse(w, http.StatusOK, user)
+}
+
+// disableUserExtClients - disables the ext clients of a user deactivated by its identity provider,
+// remembering them to be enabled again when the user is activated
+func disableUserExtClients(username string) {
+	clients, err := logic.GetAllExtClients()
+	if err != nil {
+		logger.Log(0, "failed to fetch ext clients of user", username, err.Error())
+		return
+	}
+	disabled := []string{}
+	for i := range clients {
+		if clients[i].OwnerID != username || !clients[i].Enabled {
+			continue
+		}
+		key, err := logic.GetRecordKey(clients[i].ClientID, clients[i].Network)
+		if err != nil {
+			continue
+		}
+		update := models.CustomExtClient{ClientID: clients[i].ClientID, Enabled: false}
+		if _, err := logic.UpdateExtClient(&clients[i], &update); err != nil {
+			logger.Log(0, "failed to disable ext client", update.ClientID, "of user", username, err.Error())
+			continue
+		}
+		disabled = append(disabled, key)
+	}
+	if len(disabled) == 0 {
+		return
+	}
+	if err := logic.SetSCIMDisabledExtClients(username, disabled); err != nil {
+		logger.Log(0, "failed to record disabled ext clients of user", username, err.Error())
+	}
+	publishUserExtClientsUpdate(username)
+}
+
+// enableUserExtClients - enables the ext clients disabled when a user was deactivated, clients the user
+// had disabled itself stay disabled
+func enableUserExtClients(username string) {
+	keys, err := logic.TakeSCIMDisabledExtClients(username)
+	if err != nil {
+		logger.Log(0, "failed to fetch disabled ext clients of user", username, err.Error())
+		return
+	}
+	if len(keys) == 0 {
+		return
+	}
+	clients, err := logic.GetAllExtClients()
+	if err != nil {
+		logger.Log(0, "failed to fetch ext clients of user", username, err.Error())
+		return
+	}
+	enabled := false
+	for i := range clients {
+		key, err := logic.GetRecordKey(clients[i].ClientID, clients[i].Network)
+		if err != nil || clients[i].OwnerID != username || clients[i].Enabled || !logic.StringSliceContains(keys, key) {
+			continue
+		}
+		update := models.CustomExtClient{ClientID: clients[i].ClientID, Enabled: true}
+		if _, err := logic.UpdateExtClient(&clients[i], &update); err != nil {
+			logger.Log(0, "failed to enable ext client", update.ClientID, "of user", username, err.Error())
+			continue
+		}
+		enabled = true
+	}
+	if enabled {
+		publishUserExtClientsUpdate(username)
+	}
+}
+
+func publishUserExtClientsUpdate(username string) {
+	go func() {
+		if err := mq.PublishPeerUpdate(); err != nil {
+			logger.Log(1, "error publishing peer update after updating ext clients of user", username, err.Error())
+		}
+	}()
+}
+
+// deleteUserExtClients - deletes the ext clients of a user deprovisioned by its identity provider
+func deleteUserExtClients(username string) {
+	clients, err := logic.GetAllExtClients()
+	if err != nil {
+		logger.Log(0, "failed to fetch ext clients of user", username, err.Error())
+		return
+	}
+	for i := range clients {
+		if clients[i].OwnerID != username {
+			continue
+		}
+		client := clients[i]
+		if err := logic.DeleteExtClient(client.Network, client.ClientID); err != nil {
+			logger.Log(0, "failed to delete ext client", client.ClientID, "of user", username, err.Error())
+			continue
+		}
+		go func() {
+			if err := mq.PublishDeletedClientPeerUpdate(&client); err != nil {
+				logger.Log(1, "error setting ext peers after deleting client", client.ClientID, err.Error())
+			}
+			if err := mq.PublishDeleteExtClientDNS(&client); err != nil {
+				logger.Log(1, "error publishing dns update for extclient deletion", err.Error())
+			}
+		}()
+	}
+}
+
+// parseSCIMFilter - splits an equality filter into its lower cased attribute and value, empty without filter
+func parseSCIMFilter(filter string) (string, string, error) {
+	if filter == "" {
+		return "", "", nil
+	}
+	match := scimFilterRegex.FindStringSubmatch(filter)
+	if match == nil {
+		return "", "", fmt.Errorf("unsupported filter %s, only eq filters are supported", filter)
+	}
+	var value string
+	if err := json.Unmarshal([]byte(`"`+match[2]+`"`), &value); err != nil {
+		return "", "", err
+	}
+	return strings.ToLower(match[1]), value, nil
+}
+
+// parseSCIMBool - reads a boolean some identity providers send as string
+func parseSCIMBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(strings.ToLower(s))
+}
+
+func returnSCIMGroup(w http.ResponseWriter, name string) {
+	group, err := logic.GetSCIMGroup(name)
+	if err != nil {
+		returnSCIMError(w, http.StatusNotFound, "", err.Error())
+		return
+	}
+	returnSCIMResponse(w, http.StatusOK, group)
+}
+
+// returnSCIMList - writes the page of resources selected by the startIndex and count parameters
+func returnSCIMList(w http.ResponseWriter, r *http.Request, resources []any) {
+	startIndex, err := strconv.Atoi(r.URL.Query().Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count < 0 {
+		count = len(resources)
+	}
+	page := []any{}
+	if startIndex <= len(resources) {
+		page = resources[startIndex-1:]
+		if count < len(page) {
+			page = page[:count]
+		}
+	}
+	returnSCIMResponse(w, http.StatusOK, models.SCIMListResponse{
+		Schemas:      []string{models.SCIMListResponseSchema},
+		TotalResults: len(resources),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	})
+}
+
+func returnSCIMError(w http.ResponseWriter, status int, scimType, detail string) {
+	returnSCIMResponse(w, status, models.SCIMError{
+		Schemas:  []string{models.SCIMErrorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+func returnSCIMResponse(w http.ResponseWriter, status int, response any) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
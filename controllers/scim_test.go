@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSCIMFilter(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Filter    string
+		Attribute string
+		Value     string
+		Err       bool
+	}{
+		{Name: "empty", Filter: ""},
+		{Name: "username", Filter: `userName eq "alice@example.com"`, Attribute: "username", Value: "alice@example.com"},
+		{Name: "operator case", Filter: `displayName EQ "Dev Ops"`, Attribute: "displayname", Value: "Dev Ops"},
+		{Name: "escaped quote", Filter: `externalId eq "a\"b"`, Attribute: "externalid", Value: `a"b`},
+		{Name: "unsupported operator", Filter: `userName sw "a"`, Err: true},
+		{Name: "unquoted value", Filter: `userName eq alice`, Err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			attribute, value, err := parseSCIMFilter(tt.Filter)
+			if tt.Err {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.Attribute, attribute)
+			assert.Equal(t, tt.Value, value)
+		})
+	}
+}
+
+func TestParseSCIMBool(t *testing.T) {
+	for raw, want := range map[string]bool{`true`: true, `false`: false, `"False"`: false, `"True"`: true} {
+		got, err := parseSCIMBool(json.RawMessage(raw))
+		assert.Nil(t, err)
+		assert.Equal(t, want, got, raw)
+	}
+	_, err := parseSCIMBool(json.RawMessage(`"maybe"`))
+	assert.NotNil(t, err)
+}
+
+func TestSCIMUserExtClients(t *testing.T) {
+	createNet()
+	active := true
+	user := models.SCIMUser{UserName: "scimclients", Active: &active}
+	assert.Nil(t, logic.CreateSCIMUser(&user))
+	t.Cleanup(func() {
+		logic.DeleteUser(user.UserName)
+		database.DeleteRecord(database.SCIM_USERS_TABLE_NAME, user.UserName)
+	})
+	for _, client := range []models.ExtClient{
+		{ClientID: "scimclient-on", Network: "skynet", OwnerID: user.UserName, Enabled: true},
+		{ClientID: "scimclient-off", Network: "skynet", OwnerID: user.UserName, Enabled: false},
+	} {
+		client := client
+		assert.Nil(t, logic.CreateExtClient(&client))
+		t.Cleanup(func() { logic.DeleteExtClient(client.Network, client.ClientID) })
+	}
+	enabled := func(clientID string) bool {
+		client, err := logic.GetExtClient(clientID, "skynet")
+		assert.Nil(t, err)
+		return client.Enabled
+	}
+	update := func(active bool) {
+		w := httptest.NewRecorder()
+		updateSCIMUser(w, httptest.NewRequest(http.MethodPut, "/scim/v2/Users/"+user.UserName, nil), user.UserName, "", active)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	t.Run("Deactivate", func(t *testing.T) {
+		update(false)
+		assert.False(t, enabled("scimclient-on"))
+		assert.False(t, enabled("scimclient-off"))
+	})
+	t.Run("Activate", func(t *testing.T) {
+		update(true)
+		assert.True(t, enabled("scimclient-on"))
+		// clients disabled before the deactivation stay disabled
+		assert.False(t, enabled("scimclient-off"))
+		state, err := logic.GetSCIMUserState(user.UserName)
+		assert.Nil(t, err)
+		assert.Empty(t, state.DisabledExtClients)
+	})
+}
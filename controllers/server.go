@@ -2,8 +2,8 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/gravitl/netmaker/database"
@@ -11,6 +11,7 @@ import (
 	"github.com/gravitl/netmaker/models"
 	"github.com/gravitl/netmaker/mq"
 	"github.com/gravitl/netmaker/servercfg"
+	turnserver "github.com/gravitl/netmaker/turn-server"
 )
 
 func serverHandlers(r *mux.Router) {
@@ -19,9 +20,10 @@ func serverHandlers(r *mux.Router) {
 		resp.WriteHeader(http.StatusOK)
 		resp.Write([]byte("Server is up and running!!"))
 	}))
-	r.HandleFunc("/api/server/getconfig", allowUsers(http.HandlerFunc(getConfig))).Methods(http.MethodGet)
-	r.HandleFunc("/api/server/getserverinfo", authorize(true, false, "node", http.HandlerFunc(getServerInfo))).Methods(http.MethodGet)
+	r.HandleFunc("/api/server/getconfig", logic.Authorize(false, models.SelfResource, models.ReadAction, http.HandlerFunc(getConfig))).Methods(http.MethodGet)
+	r.HandleFunc("/api/server/getserverinfo", logic.Authorize(true, models.SelfResource, models.ReadAction, http.HandlerFunc(getServerInfo))).Methods(http.MethodGet)
 	r.HandleFunc("/api/server/status", http.HandlerFunc(getStatus)).Methods(http.MethodGet)
+	r.HandleFunc("/api/server/turn/stats", logic.Authorize(false, models.ServerResource, models.ReadAction, http.HandlerFunc(getTurnStats))).Methods(http.MethodGet)
 }
 
 // swagger:route GET /api/server/status server getStatus
@@ -52,30 +54,6 @@ func getStatus(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(&currentServerStatus)
 }
 
-// allowUsers - allow all authenticated (valid) users - only used by getConfig, may be able to remove during refactor
-func allowUsers(next http.Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var errorResponse = models.ErrorResponse{
-			Code: http.StatusUnauthorized, Message: logic.Unauthorized_Msg,
-		}
-		bearerToken := r.Header.Get("Authorization")
-		var tokenSplit = strings.Split(bearerToken, " ")
-		var authToken = ""
-		if len(tokenSplit) < 2 {
-			logic.ReturnErrorResponse(w, r, errorResponse)
-			return
-		} else {
-			authToken = tokenSplit[1]
-		}
-		user, _, _, err := logic.VerifyUserToken(authToken)
-		if err != nil || user == "" {
-			logic.ReturnErrorResponse(w, r, errorResponse)
-			return
-		}
-		next.ServeHTTP(w, r)
-	}
-}
-
 // swagger:route GET /api/server/getserverinfo server getServerInfo
 //
 // Get the server configuration.
@@ -122,3 +100,23 @@ func getConfig(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(scfg)
 	//w.WriteHeader(http.StatusOK)
 }
+
+// swagger:route GET /api/server/turn/stats server getTurnStats
+//
+// Get the allocation stats of the embedded turn server.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: turnStatsResponse
+func getTurnStats(w http.ResponseWriter, r *http.Request) {
+	if !servercfg.IsUsingEmbeddedTurn() {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(errors.New("embedded turn server is not enabled"), "badrequest"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(turnserver.GetStats())
+}
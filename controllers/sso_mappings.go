@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+)
+
+func ssoMappingHandlers(r *mux.Router) {
+	r.HandleFunc("/api/v1/sso/mappings", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getSSOMappings))).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/sso/mappings", logic.Authorize(false, models.UserResource, models.CreateAction, http.HandlerFunc(createSSOMapping))).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/sso/mappings/{mappingid}", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getSSOMapping))).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/sso/mappings/{mappingid}", logic.Authorize(false, models.UserResource, models.UpdateAction, http.HandlerFunc(updateSSOMapping))).Methods(http.MethodPut)
+	r.HandleFunc("/api/v1/sso/mappings/{mappingid}", logic.Authorize(false, models.UserResource, models.DeleteAction, http.HandlerFunc(deleteSSOMapping))).Methods(http.MethodDelete)
+}
+
+// swagger:route GET /api/v1/sso/mappings user getSSOMappings
+//
+// Lists the mappings of identity provider groups to user groups, networks and admin status.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: ssoMappingsResponse
+func getSSOMappings(w http.ResponseWriter, r *http.Request) {
+	mappings, err := logic.GetSSOMappings()
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch sso mappings:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(mappings)
+}
+
+// swagger:route GET /api/v1/sso/mappings/{mappingid} user getSSOMapping
+//
+// Gets a mapping of an identity provider group.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: ssoMappingResponse
+func getSSOMapping(w http.ResponseWriter, r *http.Request) {
+	mapping, err := logic.GetSSOMapping(mux.Vars(r)["mappingid"])
+	if err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "notfound"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(mapping)
+}
+
+// swagger:route POST /api/v1/sso/mappings user createSSOMapping
+//
+// Maps a group of an sso provider to user groups, networks and admin status, applied to
+// its members when they log in through that provider.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: ssoMappingResponse
+func createSSOMapping(w http.ResponseWriter, r *http.Request) {
+	var mapping models.SSOMapping
+	if err := json.NewDecoder(r.Body).Decode(&mapping); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	if err := logic.CreateSSOMapping(&mapping); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to create sso mapping:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "mapped identity provider group", mapping.IdPGroup)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(mapping)
+}
+
+// swagger:route PUT /api/v1/sso/mappings/{mappingid} user updateSSOMapping
+//
+// Replaces a mapping of an identity provider group.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: ssoMappingResponse
+func updateSSOMapping(w http.ResponseWriter, r *http.Request) {
+	var mapping models.SSOMapping
+	if err := json.NewDecoder(r.Body).Decode(&mapping); err != nil {
+		logger.Log(0, r.Header.Get("user"), "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	mapping.ID = mux.Vars(r)["mappingid"]
+	if err := logic.UpdateSSOMapping(&mapping); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to update sso mapping:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "updated sso mapping", mapping.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(mapping)
+}
+
+// swagger:route DELETE /api/v1/sso/mappings/{mappingid} user deleteSSOMapping
+//
+// Deletes a mapping of an identity provider group, its users lose the access at their next login.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func deleteSSOMapping(w http.ResponseWriter, r *http.Request) {
+	mappingID := mux.Vars(r)["mappingid"]
+	if err := logic.DeleteSSOMapping(mappingID); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to delete sso mapping:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "deleted sso mapping", mappingID)
+	logic.ReturnSuccessResponse(w, r, "deleted sso mapping "+mappingID)
+}
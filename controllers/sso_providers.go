@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/auth"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+)
+
+func ssoProviderHandlers(r *mux.Router) {
+	r.HandleFunc("/api/oauth/providers", getSSOProviders).Methods(http.MethodGet)
+	r.HandleFunc("/api/users/{username}/sso-providers", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getUserSSOProviders))).Methods(http.MethodGet)
+	r.HandleFunc("/api/users/{username}/sso-providers/{provider}", logic.Authorize(false, models.UserResource, models.UpdateAction, http.HandlerFunc(linkUserSSOProvider))).Methods(http.MethodPut)
+	r.HandleFunc("/api/users/{username}/sso-providers/{provider}", logic.Authorize(false, models.UserResource, models.UpdateAction, http.HandlerFunc(unlinkUserSSOProvider))).Methods(http.MethodDelete)
+}
+
+// swagger:route GET /api/oauth/providers user getSSOProviders
+//
+// Lists the sso providers users can sign in through with /api/oauth/login?provider=.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: ssoProvidersResponse
+func getSSOProviders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(auth.ListAuthProviders())
+}
+
+// swagger:route GET /api/users/{username}/sso-providers user getUserSSOProviders
+//
+// Lists the sso providers a user is linked to. A user without links signs in through the default provider.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: ssoUserLinksResponse
+func getUserSSOProviders(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	if _, err := logic.GetUser(username); err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "notfound"))
+		return
+	}
+	links, err := logic.GetSSOUserLinks(username)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch sso providers of user", username, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(links)
+}
+
+// swagger:route PUT /api/users/{username}/sso-providers/{provider} user linkUserSSOProvider
+//
+// Lets a user sign in through another sso provider.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: ssoUserLinksResponse
+func linkUserSSOProvider(w http.ResponseWriter, r *http.Request) {
+	username, provider := mux.Vars(r)["username"], mux.Vars(r)["provider"]
+	if !auth.IsAuthProvider(provider) {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(fmt.Errorf("unknown sso provider %s", provider), "badrequest"))
+		return
+	}
+	user, err := logic.GetUser(username)
+	if err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "notfound"))
+		return
+	}
+	if auth.IsOauthUser(user) != nil {
+		err := fmt.Errorf("user %s does not sign in through sso", username)
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	if err = logic.LinkSSOUser(username, provider, auth.DefaultAuthProvider()); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to link user", username, "to sso provider", provider, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	links, err := logic.GetSSOUserLinks(username)
+	if err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "linked user", username, "to sso provider", provider)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(links)
+}
+
+// swagger:route DELETE /api/users/{username}/sso-providers/{provider} user unlinkUserSSOProvider
+//
+// Stops a user from signing in through an sso provider. A user left without links signs in through the default provider.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func unlinkUserSSOProvider(w http.ResponseWriter, r *http.Request) {
+	username, provider := mux.Vars(r)["username"], mux.Vars(r)["provider"]
+	if _, err := logic.GetUser(username); err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "notfound"))
+		return
+	}
+	if err := logic.UnlinkSSOUser(username, provider); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to unlink user", username, "from sso provider", provider, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "unlinked user", username, "from sso provider", provider)
+	logic.ReturnSuccessResponse(w, r, "unlinked "+username+" from sso provider "+provider)
+}
@@ -24,13 +24,13 @@ func userHandlers(r *mux.Router) {
 	r.HandleFunc("/api/users/adm/hasadmin", hasAdmin).Methods(http.MethodGet)
 	r.HandleFunc("/api/users/adm/createadmin", createAdmin).Methods(http.MethodPost)
 	r.HandleFunc("/api/users/adm/authenticate", authenticateUser).Methods(http.MethodPost)
-	r.HandleFunc("/api/users/{username}", logic.SecurityCheck(false, logic.ContinueIfUserMatch(http.HandlerFunc(updateUser)))).Methods(http.MethodPut)
-	r.HandleFunc("/api/users/networks/{username}", logic.SecurityCheck(true, http.HandlerFunc(updateUserNetworks))).Methods(http.MethodPut)
-	r.HandleFunc("/api/users/{username}/adm", logic.SecurityCheck(true, http.HandlerFunc(updateUserAdm))).Methods(http.MethodPut)
-	r.HandleFunc("/api/users/{username}", logic.SecurityCheck(true, checkFreeTierLimits(users_l, http.HandlerFunc(createUser)))).Methods(http.MethodPost)
-	r.HandleFunc("/api/users/{username}", logic.SecurityCheck(true, http.HandlerFunc(deleteUser))).Methods(http.MethodDelete)
-	r.HandleFunc("/api/users/{username}", logic.SecurityCheck(false, logic.ContinueIfUserMatch(http.HandlerFunc(getUser)))).Methods(http.MethodGet)
-	r.HandleFunc("/api/users", logic.SecurityCheck(true, http.HandlerFunc(getUsers))).Methods(http.MethodGet)
+	r.HandleFunc("/api/users/{username}", logic.Authorize(false, models.SelfResource, models.UpdateAction, logic.ContinueIfUserMatch(http.HandlerFunc(updateUser)))).Methods(http.MethodPut)
+	r.HandleFunc("/api/users/networks/{username}", logic.Authorize(false, models.UserResource, models.UpdateAction, http.HandlerFunc(updateUserNetworks))).Methods(http.MethodPut)
+	r.HandleFunc("/api/users/{username}/adm", logic.Authorize(false, models.UserResource, models.UpdateAction, http.HandlerFunc(updateUserAdm))).Methods(http.MethodPut)
+	r.HandleFunc("/api/users/{username}", logic.Authorize(false, models.UserResource, models.CreateAction, checkFreeTierLimits(users_l, http.HandlerFunc(createUser)))).Methods(http.MethodPost)
+	r.HandleFunc("/api/users/{username}", logic.Authorize(false, models.UserResource, models.DeleteAction, http.HandlerFunc(deleteUser))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/users/{username}", logic.Authorize(false, models.SelfResource, models.ReadAction, logic.ContinueIfUserMatch(http.HandlerFunc(getUser)))).Methods(http.MethodGet)
+	r.HandleFunc("/api/users", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getUsers))).Methods(http.MethodGet)
 	r.HandleFunc("/api/oauth/login", auth.HandleAuthLogin).Methods(http.MethodGet)
 	r.HandleFunc("/api/oauth/callback", auth.HandleAuthCallback).Methods(http.MethodGet)
 	r.HandleFunc("/api/oauth/headless", auth.HandleHeadlessSSO)
@@ -72,7 +72,7 @@ func authenticateUser(response http.ResponseWriter, request *http.Request) {
 		return
 	}
 	username := authRequest.UserName
-	jwt, err := logic.VerifyAuthRequest(authRequest)
+	login, err := logic.VerifyAuthRequest(authRequest, request)
 	if err != nil {
 		logger.Log(0, username, "user validation failed: ",
 			err.Error())
@@ -80,7 +80,7 @@ func authenticateUser(response http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	if jwt == "" {
+	if login.AuthToken == "" {
 		// very unlikely that err is !nil and no jwt returned, but handle it anyways.
 		logger.Log(0, username, "jwt token is empty")
 		logic.ReturnErrorResponse(response, request, logic.FormatError(errors.New("no token returned"), "internal"))
@@ -88,12 +88,9 @@ func authenticateUser(response http.ResponseWriter, request *http.Request) {
 	}
 
 	var successResponse = models.SuccessResponse{
-		Code:    http.StatusOK,
-		Message: "W1R3: Device " + username + " Authorized",
-		Response: models.SuccessfulUserLoginResponse{
-			AuthToken: jwt,
-			UserName:  username,
-		},
+		Code:     http.StatusOK,
+		Message:  "W1R3: Device " + username + " Authorized",
+		Response: login,
 	}
 	// Send back the JWT
 	successJSONResponse, jsonError := json.Marshal(successResponse)
@@ -220,6 +217,10 @@ func createAdmin(w http.ResponseWriter, r *http.Request) {
 		logic.ReturnErrorResponse(w, r, logic.FormatError(fmt.Errorf("basic auth is disabled"), "badrequest"))
 		return
 	}
+	if err = logic.ValidatePassword(admin.UserName, admin.Password); err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
 
 	err = logic.CreateAdmin(&admin)
 	if err != nil {
@@ -230,6 +231,7 @@ func createAdmin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logger.Log(1, admin.UserName, "was made a new admin")
+	logic.AuditRequest(r, admin.UserName, models.CreateAction, models.UserResource, admin.UserName)
 	json.NewEncoder(w).Encode(admin)
 }
 
@@ -255,6 +257,12 @@ func createUser(w http.ResponseWriter, r *http.Request) {
 		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
 		return
 	}
+	// users created through the api log in with their password, ldap users are created on their first login
+	user.LDAPDN = ""
+	if err = logic.ValidatePassword(user.UserName, user.Password); err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
 
 	err = logic.CreateUser(&user)
 	if err != nil {
@@ -489,7 +497,7 @@ func socketHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// Start handling the session
-	go auth.SessionHandler(conn)
+	go auth.SessionHandler(conn, logic.RequestSourceIP(r))
 }
 
 // getHeaderNetworks returns a slice of networks parsed form the request header.
@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+	"github.com/skip2/go-qrcode"
+)
+
+func userMFAHandlers(r *mux.Router) {
+	r.HandleFunc("/api/users/adm/mfa/enroll", enrollUserMFA).Methods(http.MethodPost)
+	r.HandleFunc("/api/users/adm/mfa/confirm", confirmUserMFA).Methods(http.MethodPost)
+	r.HandleFunc("/api/users/adm/mfa/recovery-codes", regenerateMFARecoveryCodes).Methods(http.MethodPost)
+	r.HandleFunc("/api/users/adm/mfa/disable", disableUserMFA).Methods(http.MethodPost)
+	r.HandleFunc("/api/users/{username}/mfa", logic.Authorize(false, models.SelfResource, models.ReadAction, logic.ContinueIfUserMatch(http.HandlerFunc(getUserMFAStatus)))).Methods(http.MethodGet)
+	r.HandleFunc("/api/users/{username}/mfa", logic.Authorize(false, models.UserResource, models.DeleteAction, http.HandlerFunc(resetUserMFA))).Methods(http.MethodDelete)
+}
+
+// swagger:route POST /api/users/adm/mfa/enroll user enrollUserMFA
+//
+// Creates a totp secret for the user whose credentials are given, with a qr code of it for authenticator apps
+// and recovery codes. The enrollment takes effect once confirmed with a code.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: mfaEnrollmentResponse
+func enrollUserMFA(w http.ResponseWriter, r *http.Request) {
+	authRequest, ok := decodeMFACredentials(w, r)
+	if !ok {
+		return
+	}
+	enrollment, err := logic.EnrollUserMFA(authRequest.UserName)
+	if err != nil {
+		logger.Log(0, authRequest.UserName, "failed to enroll mfa:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	enrollment.QRCode, err = qrcode.Encode(enrollment.URI, qrcode.Medium, 220)
+	if err != nil {
+		logger.Log(1, authRequest.UserName, "failed to encode qr code: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	logger.Log(1, authRequest.UserName, "started mfa enrollment")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// swagger:route POST /api/users/adm/mfa/confirm user confirmUserMFA
+//
+// Enables the pending mfa enrollment of the user whose credentials are given with a code of its authenticator.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func confirmUserMFA(w http.ResponseWriter, r *http.Request) {
+	authRequest, ok := decodeMFACredentials(w, r)
+	if !ok {
+		return
+	}
+	sourceIP := logic.RequestSourceIP(r)
+	err := logic.CheckLoginLockout(authRequest.UserName, sourceIP)
+	if err == nil {
+		err = logic.ConfirmUserMFA(authRequest.UserName, authRequest.TOTP)
+		// codes of a pending enrollment are guessed as well
+		if errors.Is(err, logic.ErrMFAInvalidCode) {
+			logic.RecordLoginFailure(authRequest.UserName, sourceIP)
+		}
+	}
+	if err != nil {
+		logger.Log(0, authRequest.UserName, "failed to confirm mfa:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logic.AuditRequest(r, authRequest.UserName, models.UpdateAction, models.UserResource, authRequest.UserName)
+	logger.Log(1, authRequest.UserName, "enabled mfa")
+	logic.ReturnSuccessResponse(w, r, "mfa enabled for "+authRequest.UserName)
+}
+
+// swagger:route POST /api/users/adm/mfa/recovery-codes user regenerateMFARecoveryCodes
+//
+// Replaces the recovery codes of the user whose credentials and mfa code are given.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: mfaRecoveryCodesResponse
+func regenerateMFARecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	authRequest, ok := decodeMFACredentials(w, r)
+	if !ok || !verifyMFACode(w, r, authRequest) {
+		return
+	}
+	codes, err := logic.RegenerateMFARecoveryCodes(authRequest.UserName)
+	if err != nil {
+		logger.Log(0, authRequest.UserName, "failed to regenerate mfa recovery codes:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logic.AuditRequest(r, authRequest.UserName, models.UpdateAction, models.UserResource, authRequest.UserName)
+	logger.Log(1, authRequest.UserName, "regenerated mfa recovery codes")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(codes)
+}
+
+// swagger:route POST /api/users/adm/mfa/disable user disableUserMFA
+//
+// Disables mfa of the user whose credentials and mfa code are given.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func disableUserMFA(w http.ResponseWriter, r *http.Request) {
+	authRequest, ok := decodeMFACredentials(w, r)
+	if !ok || !verifyMFACode(w, r, authRequest) {
+		return
+	}
+	if err := logic.DisableUserMFA(authRequest.UserName); err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logic.AuditRequest(r, authRequest.UserName, models.UpdateAction, models.UserResource, authRequest.UserName)
+	logger.Log(1, authRequest.UserName, "disabled mfa")
+	logic.ReturnSuccessResponse(w, r, "mfa disabled for "+authRequest.UserName)
+}
+
+// swagger:route GET /api/users/{username}/mfa user getUserMFAStatus
+//
+// Gets whether a user has mfa enabled.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: mfaStatusResponse
+func getUserMFAStatus(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	if _, err := logic.GetUser(username); err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "notfound"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(logic.GetUserMFAStatus(username))
+}
+
+// swagger:route DELETE /api/users/{username}/mfa user resetUserMFA
+//
+// Removes the mfa enrollment of a user who lost its authenticator and recovery codes.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func resetUserMFA(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	if err := logic.DisableUserMFA(username); err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "reset mfa of user", username)
+	logic.ReturnSuccessResponse(w, r, "mfa reset for "+username)
+}
+
+// decodeMFACredentials - decodes and checks the password of a request managing the mfa of a local user
+func decodeMFACredentials(w http.ResponseWriter, r *http.Request) (models.UserAuthParams, bool) {
+	var authRequest models.UserAuthParams
+	if !servercfg.IsBasicAuthEnabled() {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(errors.New("basic auth is disabled"), "badrequest"))
+		return authRequest, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&authRequest); err != nil {
+		logger.Log(0, "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return authRequest, false
+	}
+	if _, err := logic.VerifyUserPassword(authRequest, logic.RequestSourceIP(r)); err != nil {
+		logger.Log(0, authRequest.UserName, "user validation failed: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "unauthorized"))
+		return authRequest, false
+	}
+	return authRequest, true
+}
+
+// verifyMFACode - checks the mfa code of a request whose credentials were checked, wrong codes count
+// towards the login lockout
+func verifyMFACode(w http.ResponseWriter, r *http.Request, authRequest models.UserAuthParams) bool {
+	user, err := logic.GetUser(authRequest.UserName)
+	if err == nil {
+		err = logic.VerifyUserSecondFactor(user, authRequest.TOTP, logic.RequestSourceIP(r))
+	}
+	if err != nil {
+		logger.Log(0, authRequest.UserName, "mfa validation failed: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "unauthorized"))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/auth"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+func userPasswordHandlers(r *mux.Router) {
+	r.HandleFunc("/api/users/adm/password-reset", resetUserPassword).Methods(http.MethodPost)
+	r.HandleFunc("/api/users/{username}/password-reset", logic.Authorize(false, models.UserResource, models.UpdateAction, http.HandlerFunc(createPasswordResetToken))).Methods(http.MethodPost)
+	r.HandleFunc("/api/users/{username}/lockout", logic.Authorize(false, models.UserResource, models.UpdateAction, http.HandlerFunc(clearUserLockout))).Methods(http.MethodDelete)
+}
+
+// swagger:route POST /api/users/{username}/password-reset user createPasswordResetToken
+//
+// Creates a one-time token the user can set a new password with, replacing any outstanding one.
+// The token is only returned once.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: passwordResetTokenResponse
+func createPasswordResetToken(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	user, err := logic.GetUser(username)
+	if err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "notfound"))
+		return
+	}
+	if auth.IsOauthUser(user) == nil {
+		err := fmt.Errorf("cannot reset the password of oauth user %s", username)
+		logger.Log(0, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "forbidden"))
+		return
+	}
+	token, err := logic.CreatePasswordResetToken(username)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to create password reset token for user", username, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "created password reset token for user", username)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(token)
+}
+
+// swagger:route POST /api/users/adm/password-reset user resetUserPassword
+//
+// Sets a new password of a user with the one-time token an admin created for it.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func resetUserPassword(w http.ResponseWriter, r *http.Request) {
+	if !servercfg.IsBasicAuthEnabled() {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(errors.New("basic auth is disabled"), "badrequest"))
+		return
+	}
+	var request models.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Log(0, "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	sourceIP := logic.RequestSourceIP(r)
+	// guessing tokens counts towards the lockout of the source ip like guessing passwords
+	if err := logic.CheckLoginLockout("", sourceIP); err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "unauthorized"))
+		return
+	}
+	if err := logic.ResetUserPassword(request); err != nil {
+		logger.Log(0, "failed to reset password of user", request.UserName, err.Error())
+		if errors.Is(err, logic.ErrInvalidPasswordResetToken) {
+			logic.RecordLoginFailure("", sourceIP)
+			logic.ReturnErrorResponse(w, r, logic.FormatError(err, "unauthorized"))
+			return
+		}
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, "password of user", request.UserName, "was reset")
+	logic.AuditRequest(r, request.UserName, models.UpdateAction, models.UserResource, request.UserName)
+	logic.ReturnSuccessResponse(w, r, "password of "+request.UserName+" was reset")
+}
+
+// swagger:route DELETE /api/users/{username}/lockout user clearUserLockout
+//
+// Lifts the lockout of a user who failed to log in too often.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func clearUserLockout(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	if _, err := logic.GetUser(username); err != nil {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "notfound"))
+		return
+	}
+	logic.ClearLoginLockout(username)
+	logger.Log(1, r.Header.Get("user"), "lifted the lockout of user", username)
+	logic.ReturnSuccessResponse(w, r, "lockout of "+username+" lifted")
+}
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+)
+
+func userSessionHandlers(r *mux.Router) {
+	r.HandleFunc("/api/users/adm/refresh", refreshUserSession).Methods(http.MethodPost)
+	r.HandleFunc("/api/users/adm/exchange", exchangeUserLoginCode).Methods(http.MethodPost)
+	r.HandleFunc("/api/users/{username}/sessions", logic.Authorize(false, models.SelfResource, models.ReadAction, logic.ContinueIfUserMatch(http.HandlerFunc(getUserSessions)))).Methods(http.MethodGet)
+	r.HandleFunc("/api/users/{username}/sessions", logic.Authorize(false, models.SelfResource, models.DeleteAction, logic.ContinueIfUserMatch(http.HandlerFunc(deleteUserSessions)))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/users/{username}/sessions/{sessionid}", logic.Authorize(false, models.SelfResource, models.DeleteAction, logic.ContinueIfUserMatch(http.HandlerFunc(deleteUserSession)))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/v1/sessions", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getAllUserSessions))).Methods(http.MethodGet)
+}
+
+// swagger:route POST /api/users/adm/refresh user refreshUserSession
+//
+// Exchanges a refresh token for a new access token and refresh token of the same session.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func refreshUserSession(w http.ResponseWriter, r *http.Request) {
+	var request models.RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Log(0, "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	login, err := logic.RefreshUserSession(request.RefreshToken)
+	if err != nil {
+		logger.Log(1, "failed to refresh user session:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "unauthorized"))
+		return
+	}
+	logger.Log(2, login.UserName, "refreshed session", login.SessionID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.SuccessResponse{
+		Code:     http.StatusOK,
+		Message:  "W1R3: Device " + login.UserName + " Authorized",
+		Response: login,
+	})
+}
+
+// swagger:route POST /api/users/adm/exchange user exchangeUserLoginCode
+//
+// Exchanges the one time code handed out after an sso login for the tokens of its session.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func exchangeUserLoginCode(w http.ResponseWriter, r *http.Request) {
+	var request models.LoginCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Log(0, "error decoding request body: ", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "badrequest"))
+		return
+	}
+	login, err := logic.ExchangeUserLoginCode(request.Code)
+	if err != nil {
+		logger.Log(1, "failed to exchange login code:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "unauthorized"))
+		return
+	}
+	logger.Log(2, login.UserName, "exchanged login code of session", login.SessionID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.SuccessResponse{
+		Code:     http.StatusOK,
+		Message:  "W1R3: Device " + login.UserName + " Authorized",
+		Response: login,
+	})
+}
+
+// swagger:route GET /api/users/{username}/sessions user getUserSessions
+//
+// Lists the active sessions of a user.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: userSessionsResponse
+func getUserSessions(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	sessions, err := logic.GetUserSessions(username)
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch sessions of user", username, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// swagger:route GET /api/v1/sessions user getAllUserSessions
+//
+// Lists the active sessions of all users.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: userSessionsResponse
+func getAllUserSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := logic.GetUserSessions("")
+	if err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to fetch user sessions:", err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// swagger:route DELETE /api/users/{username}/sessions/{sessionid} user deleteUserSession
+//
+// Ends a session of a user, its tokens stop working immediately.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func deleteUserSession(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	session, err := logic.GetUserSession(params["sessionid"])
+	if err != nil || session.UserName != params["username"] {
+		logic.ReturnErrorResponse(w, r, logic.FormatError(errors.New("session not found"), "notfound"))
+		return
+	}
+	if err := logic.DeleteUserSession(session.ID); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to end session", session.ID, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "ended session", session.ID, "of user", session.UserName)
+	logic.ReturnSuccessResponse(w, r, "ended session "+session.ID)
+}
+
+// swagger:route DELETE /api/users/{username}/sessions user deleteUserSessions
+//
+// Ends all sessions of a user, logging it out everywhere.
+//
+//			Schemes: https
+//
+//			Security:
+//	  		oauth
+//
+//			Responses:
+//				200: successResponse
+func deleteUserSessions(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	if err := logic.DeleteUserSessions(username); err != nil {
+		logger.Log(0, r.Header.Get("user"), "failed to end sessions of user", username, err.Error())
+		logic.ReturnErrorResponse(w, r, logic.FormatError(err, "internal"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "ended all sessions of user", username)
+	logic.ReturnSuccessResponse(w, r, "ended all sessions of user "+username)
+}
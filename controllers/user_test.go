@@ -1,10 +1,16 @@
 package controller
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/gravitl/netmaker/database"
 	"github.com/gravitl/netmaker/logic"
 	"github.com/gravitl/netmaker/models"
 )
@@ -258,27 +264,31 @@ func TestUpdateUser(t *testing.T) {
 
 func TestVerifyAuthRequest(t *testing.T) {
 	deleteAllUsers(t)
+	t.Cleanup(func() {
+		database.DeleteAllRecords(database.LOGIN_ATTEMPTS_TABLE_NAME)
+	})
 	user := models.User{UserName: "admin", Password: "password", Networks: nil, IsAdmin: true, Groups: nil}
 	var authRequest models.UserAuthParams
+	request := httptest.NewRequest(http.MethodPost, "/api/users/adm/authenticate", nil)
 	t.Run("EmptyUserName", func(t *testing.T) {
 		authRequest.UserName = ""
 		authRequest.Password = "Password"
-		jwt, err := logic.VerifyAuthRequest(authRequest)
-		assert.Equal(t, "", jwt)
+		login, err := logic.VerifyAuthRequest(authRequest, request)
+		assert.Equal(t, "", login.AuthToken)
 		assert.EqualError(t, err, "username can't be empty")
 	})
 	t.Run("EmptyPassword", func(t *testing.T) {
 		authRequest.UserName = "admin"
 		authRequest.Password = ""
-		jwt, err := logic.VerifyAuthRequest(authRequest)
-		assert.Equal(t, "", jwt)
+		login, err := logic.VerifyAuthRequest(authRequest, request)
+		assert.Equal(t, "", login.AuthToken)
 		assert.EqualError(t, err, "password can't be empty")
 	})
 	t.Run("NonExistantUser", func(t *testing.T) {
 		authRequest.UserName = "admin"
 		authRequest.Password = "password"
-		jwt, err := logic.VerifyAuthRequest(authRequest)
-		assert.Equal(t, "", jwt)
+		login, err := logic.VerifyAuthRequest(authRequest, request)
+		assert.Equal(t, "", login.AuthToken)
 		assert.EqualError(t, err, "error retrieving user from db: could not find any records")
 	})
 	t.Run("Non-Admin", func(t *testing.T) {
@@ -289,8 +299,8 @@ func TestVerifyAuthRequest(t *testing.T) {
 			t.Error(err)
 		}
 		authRequest := models.UserAuthParams{UserName: "nonadmin", Password: "somepass"}
-		jwt, err := logic.VerifyAuthRequest(authRequest)
-		assert.NotNil(t, jwt)
+		login, err := logic.VerifyAuthRequest(authRequest, request)
+		assert.NotEmpty(t, login.AuthToken)
 		assert.Nil(t, err)
 	})
 	t.Run("WrongPassword", func(t *testing.T) {
@@ -299,14 +309,45 @@ func TestVerifyAuthRequest(t *testing.T) {
 			t.Error(err)
 		}
 		authRequest := models.UserAuthParams{UserName: "admin", Password: "badpass"}
-		jwt, err := logic.VerifyAuthRequest(authRequest)
-		assert.Equal(t, "", jwt)
+		login, err := logic.VerifyAuthRequest(authRequest, request)
+		assert.Equal(t, "", login.AuthToken)
 		assert.EqualError(t, err, "incorrect credentials")
 	})
 	t.Run("Success", func(t *testing.T) {
 		authRequest := models.UserAuthParams{UserName: "admin", Password: "password"}
-		jwt, err := logic.VerifyAuthRequest(authRequest)
+		login, err := logic.VerifyAuthRequest(authRequest, request)
 		assert.Nil(t, err)
-		assert.NotNil(t, jwt)
+		assert.NotEmpty(t, login.AuthToken)
 	})
 }
+
+func TestDisableUserMFALockout(t *testing.T) {
+	deleteAllUsers(t)
+	t.Setenv("LOGIN_LOCKOUT_THRESHOLD", "3")
+	t.Cleanup(func() {
+		database.DeleteAllRecords(database.LOGIN_ATTEMPTS_TABLE_NAME)
+		database.DeleteRecord(database.USER_MFA_TABLE_NAME, "mfalock")
+	})
+	user := models.User{UserName: "mfalock", Password: "password", Groups: []string{}}
+	assert.Nil(t, logic.CreateUser(&user))
+	mfa, _ := json.Marshal(&models.UserMFA{UserName: user.UserName, Secret: "JBSWY3DPEHPK3PXP", Enabled: true})
+	assert.Nil(t, database.Insert(user.UserName, string(mfa), database.USER_MFA_TABLE_NAME))
+	router := mux.NewRouter()
+	userMFAHandlers(router)
+	disable := func(code string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(&models.UserAuthParams{UserName: user.UserName, Password: "password", TOTP: code})
+		req := httptest.NewRequest(http.MethodPost, "/api/users/adm/mfa/disable", strings.NewReader(string(body)))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	for i := 0; i < 3; i++ {
+		w := disable("wrong-code")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), logic.ErrMFAInvalidCode.Error())
+	}
+	w := disable("wrong-code")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), logic.ErrLoginLockedOut.Error())
+	assert.True(t, logic.GetUserMFAStatus(user.UserName).Enabled)
+}
@@ -61,6 +61,50 @@ const (
 	ENROLLMENT_KEYS_TABLE_NAME = "enrollmentkeys"
 	// HOST_ACTIONS_TABLE_NAME - table name for enrollmentkeys
 	HOST_ACTIONS_TABLE_NAME = "hostactions"
+	// RELAY_GROUPS_TABLE_NAME - table name for relay groups
+	RELAY_GROUPS_TABLE_NAME = "relaygroups"
+	// TURN_HOSTS_TABLE_NAME - table name for hosts registered with the embedded turn server
+	TURN_HOSTS_TABLE_NAME = "turnhosts"
+	// PEER_PATHS_TABLE_NAME - table name for the paths found between pairs of hosts
+	PEER_PATHS_TABLE_NAME = "peerpaths"
+	// PEER_ENDPOINTS_TABLE_NAME - table name for the endpoints hosts use to reach their peers
+	PEER_ENDPOINTS_TABLE_NAME = "peerendpoints"
+	// METRICS_HISTORY_1M_TABLE_NAME - table name for the metrics history of nodes at 1 minute resolution
+	METRICS_HISTORY_1M_TABLE_NAME = "metricshistory1m"
+	// METRICS_HISTORY_1H_TABLE_NAME - table name for the metrics history of nodes at 1 hour resolution
+	METRICS_HISTORY_1H_TABLE_NAME = "metricshistory1h"
+	// METRICS_HISTORY_1D_TABLE_NAME - table name for the metrics history of nodes at 1 day resolution
+	METRICS_HISTORY_1D_TABLE_NAME = "metricshistory1d"
+	// ALERT_RULES_TABLE_NAME - table name for alert rules
+	ALERT_RULES_TABLE_NAME = "alertrules"
+	// ALERT_TARGETS_TABLE_NAME - table name for alert notification targets
+	ALERT_TARGETS_TABLE_NAME = "alerttargets"
+	// ALERTS_TABLE_NAME - table name for firing and recently resolved alerts
+	ALERTS_TABLE_NAME = "alerts"
+	// ACCESS_TOKENS_TABLE_NAME - table name for api access tokens of users
+	ACCESS_TOKENS_TABLE_NAME = "accesstokens"
+	// ROLES_TABLE_NAME - table name for custom rbac roles
+	ROLES_TABLE_NAME = "roles"
+	// ROLE_BINDINGS_TABLE_NAME - table name for bindings of rbac roles to users and groups
+	ROLE_BINDINGS_TABLE_NAME = "rolebindings"
+	// AUDIT_LOG_TABLE_NAME - table name for the audit log of changes made through the api
+	AUDIT_LOG_TABLE_NAME = "auditlog"
+	// USER_SESSIONS_TABLE_NAME - table name for the sessions of logged in users
+	USER_SESSIONS_TABLE_NAME = "usersessions"
+	// USER_MFA_TABLE_NAME - table name for the totp second factors of users
+	USER_MFA_TABLE_NAME = "usermfa"
+	// SSO_MAPPINGS_TABLE_NAME - table name for the mappings of identity provider groups to netmaker access
+	SSO_MAPPINGS_TABLE_NAME = "ssomappings"
+	// SCIM_USERS_TABLE_NAME - table name for the provisioning state of users managed through scim
+	SCIM_USERS_TABLE_NAME = "scimusers"
+	// LOGIN_ATTEMPTS_TABLE_NAME - table name for the failed logins of users and source ips
+	LOGIN_ATTEMPTS_TABLE_NAME = "loginattempts"
+	// PASSWORD_HISTORY_TABLE_NAME - table name for the hashes of the previous passwords of users
+	PASSWORD_HISTORY_TABLE_NAME = "passwordhistory"
+	// PASSWORD_RESETS_TABLE_NAME - table name for the outstanding password reset tokens of users
+	PASSWORD_RESETS_TABLE_NAME = "passwordresets"
+	// SSO_USER_LINKS_TABLE_NAME - table name for the sso providers users sign in through
+	SSO_USER_LINKS_TABLE_NAME = "ssouserlinks"
 
 	// == ERROR CONSTS ==
 	// NO_RECORD - no singular result found
@@ -83,6 +127,8 @@ const (
 	DELETE_ALL = "deleteall"
 	// FETCH_ALL - fetch table contents const
 	FETCH_ALL = "fetchall"
+	// FETCH_RANGE - fetch records in a key range const
+	FETCH_RANGE = "fetchrange"
 	// CLOSE_DB - graceful close of db const
 	CLOSE_DB = "closedb"
 	// isconnected
@@ -144,6 +190,28 @@ func createTables() {
 	createTable(HOSTS_TABLE_NAME)
 	createTable(ENROLLMENT_KEYS_TABLE_NAME)
 	createTable(HOST_ACTIONS_TABLE_NAME)
+	createTable(RELAY_GROUPS_TABLE_NAME)
+	createTable(TURN_HOSTS_TABLE_NAME)
+	createTable(PEER_PATHS_TABLE_NAME)
+	createTable(PEER_ENDPOINTS_TABLE_NAME)
+	createTable(METRICS_HISTORY_1M_TABLE_NAME)
+	createTable(METRICS_HISTORY_1H_TABLE_NAME)
+	createTable(METRICS_HISTORY_1D_TABLE_NAME)
+	createTable(ALERT_RULES_TABLE_NAME)
+	createTable(ALERT_TARGETS_TABLE_NAME)
+	createTable(ALERTS_TABLE_NAME)
+	createTable(ACCESS_TOKENS_TABLE_NAME)
+	createTable(ROLES_TABLE_NAME)
+	createTable(ROLE_BINDINGS_TABLE_NAME)
+	createTable(AUDIT_LOG_TABLE_NAME)
+	createTable(USER_SESSIONS_TABLE_NAME)
+	createTable(USER_MFA_TABLE_NAME)
+	createTable(SSO_MAPPINGS_TABLE_NAME)
+	createTable(SCIM_USERS_TABLE_NAME)
+	createTable(LOGIN_ATTEMPTS_TABLE_NAME)
+	createTable(PASSWORD_HISTORY_TABLE_NAME)
+	createTable(PASSWORD_RESETS_TABLE_NAME)
+	createTable(SSO_USER_LINKS_TABLE_NAME)
 }
 
 func createTable(tableName string) error {
@@ -159,6 +227,7 @@ func IsJSONString(value string) bool {
 
 // Insert - inserts object into db
 func Insert(key string, value string, tableName string) error {
+	defer observeCall("insert", tableName, time.Now())
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
 	if key != "" && value != "" && IsJSONString(value) {
@@ -170,6 +239,7 @@ func Insert(key string, value string, tableName string) error {
 
 // InsertPeer - inserts peer into db
 func InsertPeer(key string, value string) error {
+	defer observeCall("insert", PEERS_TABLE_NAME, time.Now())
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
 	if key != "" && value != "" && IsJSONString(value) {
@@ -181,6 +251,7 @@ func InsertPeer(key string, value string) error {
 
 // DeleteRecord - deletes a record from db
 func DeleteRecord(tableName string, key string) error {
+	defer observeCall("delete", tableName, time.Now())
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
 	return getCurrentDB()[DELETE].(func(string, string) error)(tableName, key)
@@ -188,6 +259,7 @@ func DeleteRecord(tableName string, key string) error {
 
 // DeleteAllRecords - removes a table and remakes
 func DeleteAllRecords(tableName string) error {
+	defer observeCall("delete_all", tableName, time.Now())
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
 	err := getCurrentDB()[DELETE_ALL].(func(string) error)(tableName)
@@ -215,11 +287,20 @@ func FetchRecord(tableName string, key string) (string, error) {
 
 // FetchRecords - fetches all records in given table
 func FetchRecords(tableName string) (map[string]string, error) {
+	defer observeCall("fetch", tableName, time.Now())
 	dbMutex.RLock()
 	defer dbMutex.RUnlock()
 	return getCurrentDB()[FETCH_ALL].(func(string) (map[string]string, error))(tableName)
 }
 
+// FetchRecordsInRange - fetches the records of a table with keys from fromKey up to, not including, toKey
+func FetchRecordsInRange(tableName, fromKey, toKey string) (map[string]string, error) {
+	defer observeCall("fetch_range", tableName, time.Now())
+	dbMutex.RLock()
+	defer dbMutex.RUnlock()
+	return getCurrentDB()[FETCH_RANGE].(func(string, string, string) (map[string]string, error))(tableName, fromKey, toKey)
+}
+
 // initializeUUID - create a UUID record for server if none exists
 func initializeUUID() error {
 	records, err := FetchRecords(SERVER_UUID_TABLE_NAME)
@@ -0,0 +1,20 @@
+package database
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var callDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "netmaker",
+	Name:      "database_call_duration_seconds",
+	Help:      "Duration of database calls including the wait for the database lock.",
+	Buckets:   []float64{.0005, .001, .005, .01, .025, .05, .1, .25, .5, 1},
+}, []string{"operation", "table"})
+
+// observeCall - records the duration of a database call started at start
+func observeCall(operation, tableName string, start time.Time) {
+	callDuration.WithLabelValues(operation, tableName).Observe(time.Since(start).Seconds())
+}
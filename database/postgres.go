@@ -21,6 +21,7 @@ var PG_FUNCTIONS = map[string]interface{}{
 	DELETE:       pgDeleteRecord,
 	DELETE_ALL:   pgDeleteAllRecords,
 	FETCH_ALL:    pgFetchRecords,
+	FETCH_RANGE:  pgFetchRecordsInRange,
 	CLOSE_DB:     pgCloseDB,
 	isConnected:  pgIsConnected,
 }
@@ -133,6 +134,25 @@ func pgFetchRecords(tableName string) (map[string]string, error) {
 	return records, nil
 }
 
+func pgFetchRecordsInRange(tableName, fromKey, toKey string) (map[string]string, error) {
+	row, err := PGDB.Query("SELECT * FROM "+tableName+" WHERE key >= $1 AND key < $2 ORDER BY key", fromKey, toKey)
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]string)
+	defer row.Close()
+	for row.Next() {
+		var key string
+		var value string
+		row.Scan(&key, &value)
+		records[key] = value
+	}
+	if len(records) == 0 {
+		return nil, errors.New(NO_RECORDS)
+	}
+	return records, nil
+}
+
 func pgCloseDB() {
 	PGDB.Close()
 }
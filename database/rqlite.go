@@ -19,6 +19,7 @@ var RQLITE_FUNCTIONS = map[string]interface{}{
 	DELETE:       rqliteDeleteRecord,
 	DELETE_ALL:   rqliteDeleteAllRecords,
 	FETCH_ALL:    rqliteFetchRecords,
+	FETCH_RANGE:  rqliteFetchRecordsInRange,
 	CLOSE_DB:     rqliteCloseDB,
 	isConnected:  rqliteConnected,
 }
@@ -102,6 +103,24 @@ func rqliteFetchRecords(tableName string) (map[string]string, error) {
 	return records, nil
 }
 
+func rqliteFetchRecordsInRange(tableName, fromKey, toKey string) (map[string]string, error) {
+	row, err := RQliteDatabase.QueryOne("SELECT * FROM " + tableName + " WHERE key >= \"" + fromKey + "\" AND key < \"" + toKey + "\" ORDER BY key")
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]string)
+	for row.Next() {
+		var key string
+		var value string
+		row.Scan(&key, &value)
+		records[key] = value
+	}
+	if len(records) == 0 {
+		return nil, errors.New(NO_RECORDS)
+	}
+	return records, nil
+}
+
 func rqliteCloseDB() {
 	RQliteDatabase.Close()
 }
@@ -24,6 +24,7 @@ var SQLITE_FUNCTIONS = map[string]interface{}{
 	DELETE:       sqliteDeleteRecord,
 	DELETE_ALL:   sqliteDeleteAllRecords,
 	FETCH_ALL:    sqliteFetchRecords,
+	FETCH_RANGE:  sqliteFetchRecordsInRange,
 	CLOSE_DB:     sqliteCloseDB,
 	isConnected:  sqliteConnected,
 }
@@ -133,6 +134,25 @@ func sqliteFetchRecords(tableName string) (map[string]string, error) {
 	return records, nil
 }
 
+func sqliteFetchRecordsInRange(tableName, fromKey, toKey string) (map[string]string, error) {
+	row, err := SqliteDB.Query("SELECT * FROM "+tableName+" WHERE key >= ? AND key < ? ORDER BY key", fromKey, toKey)
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]string)
+	defer row.Close()
+	for row.Next() {
+		var key string
+		var value string
+		row.Scan(&key, &value)
+		records[key] = value
+	}
+	if len(records) == 0 {
+		return nil, errors.New(NO_RECORDS)
+	}
+	return records, nil
+}
+
 func sqliteCloseDB() {
 	SqliteDB.Close()
 }
@@ -15,13 +15,13 @@ import (
 )
 
 func NetworkUsersHandlers(r *mux.Router) {
-	r.HandleFunc("/api/networkusers", logic.SecurityCheck(true, http.HandlerFunc(getAllNetworkUsers))).Methods(http.MethodGet)
-	r.HandleFunc("/api/networkusers/{network}", logic.SecurityCheck(true, http.HandlerFunc(getNetworkUsers))).Methods(http.MethodGet)
-	r.HandleFunc("/api/networkusers/{network}/{networkuser}", logic.SecurityCheck(true, http.HandlerFunc(getNetworkUser))).Methods(http.MethodGet)
-	r.HandleFunc("/api/networkusers/{network}", logic.SecurityCheck(true, http.HandlerFunc(createNetworkUser))).Methods(http.MethodPost)
-	r.HandleFunc("/api/networkusers/{network}", logic.SecurityCheck(true, http.HandlerFunc(updateNetworkUser))).Methods(http.MethodPut)
-	r.HandleFunc("/api/networkusers/data/{networkuser}/me", logic.NetUserSecurityCheck(false, false, http.HandlerFunc(getNetworkUserData))).Methods(http.MethodGet)
-	r.HandleFunc("/api/networkusers/{network}/{networkuser}", logic.SecurityCheck(true, http.HandlerFunc(deleteNetworkUser))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/networkusers", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getAllNetworkUsers))).Methods(http.MethodGet)
+	r.HandleFunc("/api/networkusers/{network}", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getNetworkUsers))).Methods(http.MethodGet)
+	r.HandleFunc("/api/networkusers/{network}/{networkuser}", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getNetworkUser))).Methods(http.MethodGet)
+	r.HandleFunc("/api/networkusers/{network}", logic.Authorize(false, models.UserResource, models.CreateAction, http.HandlerFunc(createNetworkUser))).Methods(http.MethodPost)
+	r.HandleFunc("/api/networkusers/{network}", logic.Authorize(false, models.UserResource, models.UpdateAction, http.HandlerFunc(updateNetworkUser))).Methods(http.MethodPut)
+	r.HandleFunc("/api/networkusers/data/{networkuser}/me", logic.Authorize(false, models.SelfResource, models.ReadAction, logic.ContinueIfUserMatch(http.HandlerFunc(getNetworkUserData)))).Methods(http.MethodGet)
+	r.HandleFunc("/api/networkusers/{network}/{networkuser}", logic.Authorize(false, models.UserResource, models.DeleteAction, http.HandlerFunc(deleteNetworkUser))).Methods(http.MethodDelete)
 }
 
 // == RETURN TYPES ==
@@ -10,13 +10,14 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/gravitl/netmaker/logic/pro"
+	"github.com/gravitl/netmaker/models"
 	"github.com/gravitl/netmaker/models/promodels"
 )
 
 func UserGroupsHandlers(r *mux.Router) {
-	r.HandleFunc("/api/usergroups", logic.SecurityCheck(true, http.HandlerFunc(getUserGroups))).Methods(http.MethodGet)
-	r.HandleFunc("/api/usergroups/{usergroup}", logic.SecurityCheck(true, http.HandlerFunc(createUserGroup))).Methods(http.MethodPost)
-	r.HandleFunc("/api/usergroups/{usergroup}", logic.SecurityCheck(true, http.HandlerFunc(deleteUserGroup))).Methods(http.MethodDelete)
+	r.HandleFunc("/api/usergroups", logic.Authorize(false, models.UserResource, models.ReadAction, http.HandlerFunc(getUserGroups))).Methods(http.MethodGet)
+	r.HandleFunc("/api/usergroups/{usergroup}", logic.Authorize(false, models.UserResource, models.CreateAction, http.HandlerFunc(createUserGroup))).Methods(http.MethodPost)
+	r.HandleFunc("/api/usergroups/{usergroup}", logic.Authorize(false, models.UserResource, models.DeleteAction, http.HandlerFunc(deleteUserGroup))).Methods(http.MethodDelete)
 }
 
 func getUserGroups(w http.ResponseWriter, r *http.Request) {
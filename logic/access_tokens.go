@@ -0,0 +1,257 @@
+package logic
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+)
+
+const (
+	// ACCESS_TOKEN_PREFIX - prefix telling access tokens apart from user jwts
+	ACCESS_TOKEN_PREFIX = "nmat_"
+	// accessTokenUseInterval - time between two writes of the last use of a token
+	accessTokenUseInterval = time.Minute
+)
+
+// accessTokenResources - resources a token can be scoped to
+var accessTokenResources = []models.RbacResource{
+	models.NetworkResource,
+	models.NodeResource,
+	models.HostResource,
+	models.ExtClientResource,
+	models.DNSResource,
+	models.EnrollmentKeyResource,
+	models.AclResource,
+	models.UserResource,
+	models.ServerResource,
+}
+
+var accessTokenMutex = &sync.Mutex{}
+
+// CreateAccessToken - creates an access token of a user, the returned value is not stored and cannot be fetched again
+func CreateAccessToken(username string, req models.AccessTokenRequest) (models.AccessTokenResponse, error) {
+	var response models.AccessTokenResponse
+	user, err := GetUser(username)
+	if err != nil {
+		return response, err
+	}
+	if req.Name == "" {
+		return response, errors.New("access token name cannot be empty")
+	}
+	token := models.AccessToken{
+		ID:        uuid.New().String(),
+		Name:      req.Name,
+		UserName:  username,
+		Scope:     req.Scope,
+		CreatedAt: time.Now(),
+	}
+	if req.Expiration > 0 {
+		token.ExpiresAt = time.Unix(req.Expiration, 0)
+		if !token.ExpiresAt.After(token.CreatedAt) {
+			return response, errors.New("expiration of the access token has to be in the future")
+		}
+	}
+	if token.Scope.Networks == nil {
+		token.Scope.Networks = []string{}
+	}
+	if token.Scope.Resources == nil {
+		token.Scope.Resources = []string{}
+	}
+	for _, network := range token.Scope.Networks {
+		if exists, err := NetworkExists(network); err != nil || !exists {
+			return response, fmt.Errorf("network %s does not exist", network)
+		}
+		if !user.IsAdmin && !StringSliceContains(user.Networks, network) {
+			return response, fmt.Errorf("user %s has no access to network %s", username, network)
+		}
+	}
+	for _, resource := range token.Scope.Resources {
+		if !isAccessTokenResource(resource) {
+			return response, fmt.Errorf("unknown resource %s", resource)
+		}
+	}
+	secret := RandomString(40)
+	if secret == "" {
+		return response, errors.New("failed to generate access token")
+	}
+	token.SecretHash = hashAccessTokenSecret(secret)
+	if err := saveAccessToken(&token); err != nil {
+		return response, err
+	}
+	response.AccessToken = token
+	response.SecretHash = ""
+	response.Token = ACCESS_TOKEN_PREFIX + token.ID + "." + secret
+	return response, nil
+}
+
+// GetAccessToken - fetches an access token
+func GetAccessToken(id string) (models.AccessToken, error) {
+	var token models.AccessToken
+	record, err := database.FetchRecord(database.ACCESS_TOKENS_TABLE_NAME, id)
+	if err != nil {
+		return token, err
+	}
+	err = json.Unmarshal([]byte(record), &token)
+	return token, err
+}
+
+// GetAccessTokens - fetches the access tokens of a user, of all users if username is empty,
+// the secret hashes are left out
+func GetAccessTokens(username string) ([]models.AccessToken, error) {
+	tokens := []models.AccessToken{}
+	records, err := database.FetchRecords(database.ACCESS_TOKENS_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return tokens, err
+	}
+	for _, record := range records {
+		var token models.AccessToken
+		if err := json.Unmarshal([]byte(record), &token); err != nil {
+			continue
+		}
+		if username != "" && token.UserName != username {
+			continue
+		}
+		token.SecretHash = ""
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+// RevokeAccessToken - deletes an access token, it stops working immediately
+func RevokeAccessToken(id string) error {
+	if _, err := GetAccessToken(id); err != nil {
+		return err
+	}
+	return database.DeleteRecord(database.ACCESS_TOKENS_TABLE_NAME, id)
+}
+
+// DeleteUserAccessTokens - revokes the access tokens of a deleted user
+func DeleteUserAccessTokens(username string) error {
+	tokens, err := GetAccessTokens(username)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if err := database.DeleteRecord(database.ACCESS_TOKENS_TABLE_NAME, token.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsAccessToken - checks if a bearer token is an access token rather than a jwt
+func IsAccessToken(tokenString string) bool {
+	return strings.HasPrefix(tokenString, ACCESS_TOKEN_PREFIX)
+}
+
+// VerifyAccessToken - checks an access token and records its use
+func VerifyAccessToken(tokenString string) (*models.AccessToken, error) {
+	id, secret, found := strings.Cut(strings.TrimPrefix(tokenString, ACCESS_TOKEN_PREFIX), ".")
+	if !IsAccessToken(tokenString) || !found {
+		return nil, Unauthorized_Err
+	}
+	token, err := GetAccessToken(id)
+	if err != nil {
+		return nil, Unauthorized_Err
+	}
+	if subtle.ConstantTimeCompare([]byte(token.SecretHash), []byte(hashAccessTokenSecret(secret))) != 1 {
+		return nil, Unauthorized_Err
+	}
+	now := time.Now()
+	if !token.ExpiresAt.IsZero() && now.After(token.ExpiresAt) {
+		return nil, errors.New("access token expired")
+	}
+	if now.Sub(token.LastUsedAt) >= accessTokenUseInterval {
+		accessTokenMutex.Lock()
+		// the token may have been revoked meanwhile
+		if _, err := GetAccessToken(id); err == nil {
+			token.LastUsedAt = now
+			if err := saveAccessToken(&token); err != nil {
+				logger.Log(1, "failed to record use of access token", token.Name, err.Error())
+			}
+		}
+		accessTokenMutex.Unlock()
+	}
+	return &token, nil
+}
+
+// CheckAccessTokenScope - checks that an action on a resource of a network lies within the scope of an access token,
+// network is empty for requests not bound to one
+func CheckAccessTokenScope(token *models.AccessToken, resource models.RbacResource, action models.RbacAction, network string) error {
+	if token.Scope.ReadOnly && action != models.ReadAction {
+		return errors.New("access token is read only")
+	}
+	if len(token.Scope.Resources) > 0 {
+		scoped := resource
+		if scoped == models.SelfResource {
+			// the own user of the token
+			scoped = models.UserResource
+		}
+		if !StringSliceContains(token.Scope.Resources, string(scoped)) {
+			return fmt.Errorf("access token has no access to %s", resource)
+		}
+	}
+	if len(token.Scope.Networks) > 0 && network != "" && !StringSliceContains(token.Scope.Networks, network) {
+		return fmt.Errorf("access token has no access to network %s", network)
+	}
+	return nil
+}
+
+// verifyAccessTokenUser - user an access token acts as, with the networks and admin rights narrowed to its scope
+func verifyAccessTokenUser(tokenString string) (username string, networks []string, isadmin bool, err error) {
+	token, err := VerifyAccessToken(tokenString)
+	if err != nil {
+		return "", nil, false, err
+	}
+	user, err := GetUser(token.UserName)
+	if err != nil {
+		return "", nil, false, err
+	}
+	if len(token.Scope.Networks) == 0 {
+		return user.UserName, user.Networks, user.IsAdmin, nil
+	}
+	networks = []string{}
+	for _, network := range token.Scope.Networks {
+		if user.IsAdmin || StringSliceContains(user.Networks, network) {
+			networks = append(networks, network)
+		}
+	}
+	return user.UserName, networks, false, nil
+}
+
+func isAccessTokenResource(resource string) bool {
+	for _, r := range accessTokenResources {
+		if string(r) == resource {
+			return true
+		}
+	}
+	return false
+}
+
+func hashAccessTokenSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func saveAccessToken(token *models.AccessToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return database.Insert(token.ID, string(data), database.ACCESS_TOKENS_TABLE_NAME)
+}
@@ -0,0 +1,109 @@
+package logic
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestAccessTokens(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	user := models.User{UserName: "tokenuser", IsAdmin: true, Networks: []string{}}
+	data, _ := json.Marshal(&user)
+	if err := database.Insert(user.UserName, string(data), database.USERS_TABLE_NAME); err != nil {
+		t.Fatal(err)
+	}
+	defer database.DeleteRecord(database.USERS_TABLE_NAME, user.UserName)
+	defer DeleteUserAccessTokens(user.UserName)
+
+	t.Run("create and verify", func(t *testing.T) {
+		is := is.New(t)
+		created, err := CreateAccessToken(user.UserName, models.AccessTokenRequest{Name: "ci"})
+		is.NoErr(err)
+		is.True(IsAccessToken(created.Token))
+		is.Equal(created.SecretHash, "")
+		username, _, isadmin, err := VerifyUserToken(created.Token)
+		is.NoErr(err)
+		is.Equal(username, user.UserName)
+		is.True(isadmin)
+		tokens, err := GetAccessTokens(user.UserName)
+		is.NoErr(err)
+		is.Equal(len(tokens), 1)
+		is.True(!tokens[0].LastUsedAt.IsZero())
+		is.Equal(tokens[0].SecretHash, "")
+	})
+	t.Run("wrong secret", func(t *testing.T) {
+		is := is.New(t)
+		created, err := CreateAccessToken(user.UserName, models.AccessTokenRequest{Name: "wrong"})
+		is.NoErr(err)
+		_, err = VerifyAccessToken(ACCESS_TOKEN_PREFIX + created.ID + ".notthesecret")
+		is.True(err != nil)
+	})
+	t.Run("revoked", func(t *testing.T) {
+		is := is.New(t)
+		created, err := CreateAccessToken(user.UserName, models.AccessTokenRequest{Name: "revoked"})
+		is.NoErr(err)
+		is.NoErr(RevokeAccessToken(created.ID))
+		_, _, _, err = VerifyUserToken(created.Token)
+		is.True(err != nil)
+	})
+	t.Run("expired", func(t *testing.T) {
+		is := is.New(t)
+		created, err := CreateAccessToken(user.UserName, models.AccessTokenRequest{Name: "expired", Expiration: time.Now().Add(time.Hour).Unix()})
+		is.NoErr(err)
+		token, err := GetAccessToken(created.ID)
+		is.NoErr(err)
+		token.ExpiresAt = time.Now().Add(-time.Minute)
+		is.NoErr(saveAccessToken(&token))
+		_, err = VerifyAccessToken(created.Token)
+		is.True(err != nil)
+	})
+	t.Run("invalid requests", func(t *testing.T) {
+		is := is.New(t)
+		_, err := CreateAccessToken(user.UserName, models.AccessTokenRequest{})
+		is.True(err != nil)
+		_, err = CreateAccessToken(user.UserName, models.AccessTokenRequest{Name: "past", Expiration: time.Now().Add(-time.Hour).Unix()})
+		is.True(err != nil)
+		_, err = CreateAccessToken(user.UserName, models.AccessTokenRequest{Name: "bogus", Scope: models.AccessTokenScope{Resources: []string{"bogus"}}})
+		is.True(err != nil)
+		_, err = CreateAccessToken("nobody", models.AccessTokenRequest{Name: "nobody"})
+		is.True(err != nil)
+	})
+}
+
+func TestCheckAccessTokenScope(t *testing.T) {
+	token := &models.AccessToken{
+		Scope: models.AccessTokenScope{
+			Networks:  []string{"skynet"},
+			ReadOnly:  true,
+			Resources: []string{"node", "ext_client"},
+		},
+	}
+	cases := []struct {
+		name     string
+		resource models.RbacResource
+		action   models.RbacAction
+		network  string
+		allowed  bool
+	}{
+		{"read nodes", models.NodeResource, models.ReadAction, "skynet", true},
+		{"read ext clients", models.ExtClientResource, models.ReadAction, "skynet", true},
+		{"read without network", models.NodeResource, models.ReadAction, "", true},
+		{"write nodes", models.NodeResource, models.UpdateAction, "skynet", false},
+		{"other network", models.NodeResource, models.ReadAction, "othernet", false},
+		{"other resource", models.DNSResource, models.ReadAction, "skynet", false},
+		{"own user needs the user resource", models.SelfResource, models.ReadAction, "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			err := CheckAccessTokenScope(token, tc.resource, tc.action, tc.network)
+			is.Equal(err == nil, tc.allowed)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package logic
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+var (
+	// alertHTTPClient - client used to deliver webhook and Slack notifications
+	alertHTTPClient = &http.Client{Timeout: time.Second * 10}
+	// alertSMTPTimeout - time an email notification may take, smtp.SendMail has no timeout of its own
+	alertSMTPTimeout = time.Second * 30
+)
+
+// SendAlertNotification - delivers an alert to a target
+func SendAlertNotification(target *models.AlertTarget, alert *models.Alert) error {
+	switch target.Type {
+	case models.WebhookTarget:
+		return postAlert(target.URL, alert)
+	case models.SlackTarget:
+		return postAlert(target.URL, map[string]string{
+			"text": alertSummary(alert),
+		})
+	case models.EmailTarget:
+		return mailAlert(target.Emails, alert)
+	default:
+		return fmt.Errorf("unknown alert target type %s", target.Type)
+	}
+}
+
+func postAlert(url string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := alertHTTPClient.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("target responded with %s", resp.Status)
+	}
+	return nil
+}
+
+func mailAlert(to []string, alert *models.Alert) error {
+	host := servercfg.GetSmtpHost()
+	if host == "" {
+		return errors.New("no SMTP server configured")
+	}
+	var auth smtp.Auth
+	if username, password := servercfg.GetSmtpCredentials(); username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	sender := servercfg.GetSmtpSender()
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", sender)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: [netmaker] %s\r\n", alertSummary(alert))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "Rule: %s\r\nState: %s\r\nSubject: %s\r\nNetwork: %s\r\nFired at: %s\r\n\r\n%s\r\n",
+		alert.RuleName, alert.State, alert.Subject, alert.Network, alert.FiredAt.Format(time.RFC3339), alert.Message)
+	addr := net.JoinHostPort(host, strconv.Itoa(servercfg.GetSmtpPort()))
+	return sendMail(addr, host, auth, sender, to, []byte(msg.String()))
+}
+
+// sendMail - smtp.SendMail bounded by alertSMTPTimeout
+func sendMail(addr, host string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	conn, err := net.DialTimeout("tcp", addr, alertSMTPTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(alertSMTPTimeout)); err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("SMTP server does not support authentication")
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// alertSummary - one line description of an alert
+func alertSummary(alert *models.Alert) string {
+	return fmt.Sprintf("[%s] %s: %s", strings.ToUpper(string(alert.State)), alert.RuleName, alert.Message)
+}
@@ -0,0 +1,459 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+)
+
+const (
+	// ALERT_EVALUATION_INTERVAL - time between two evaluations of the alert rules
+	ALERT_EVALUATION_INTERVAL = time.Minute
+	// ALERT_RESOLVED_RETENTION - time resolved alerts are kept
+	ALERT_RESOLVED_RETENTION = time.Hour * 24 * 7
+	// default window in minutes the connectivity of peers is averaged over
+	defaultConnectivityWindow = 60
+)
+
+var alertMutex = &sync.Mutex{}
+
+// firingSubject - a subject a rule fires for with the message describing it
+type firingSubject struct {
+	network string
+	message string
+}
+
+// alertNotification - an alert to send to the targets of its rule
+type alertNotification struct {
+	rule  models.AlertRule
+	alert models.Alert
+}
+
+// CreateAlertRule - creates an alert rule
+func CreateAlertRule(rule models.AlertRule) (models.AlertRule, error) {
+	rule.ID = uuid.New().String()
+	if err := validateAlertRule(&rule); err != nil {
+		return rule, err
+	}
+	return rule, saveAlertRecord(database.ALERT_RULES_TABLE_NAME, rule.ID, &rule)
+}
+
+// UpdateAlertRule - replaces an alert rule, alerts of the rule are kept
+func UpdateAlertRule(ruleID string, rule models.AlertRule) (models.AlertRule, error) {
+	if _, err := GetAlertRule(ruleID); err != nil {
+		return rule, err
+	}
+	rule.ID = ruleID
+	if err := validateAlertRule(&rule); err != nil {
+		return rule, err
+	}
+	return rule, saveAlertRecord(database.ALERT_RULES_TABLE_NAME, rule.ID, &rule)
+}
+
+// DeleteAlertRule - deletes an alert rule together with its alerts
+func DeleteAlertRule(ruleID string) error {
+	if _, err := GetAlertRule(ruleID); err != nil {
+		return err
+	}
+	alertMutex.Lock()
+	defer alertMutex.Unlock()
+	alerts, err := GetAlerts()
+	if err != nil {
+		return err
+	}
+	for _, alert := range alerts {
+		if alert.RuleID == ruleID {
+			if err := database.DeleteRecord(database.ALERTS_TABLE_NAME, alert.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return database.DeleteRecord(database.ALERT_RULES_TABLE_NAME, ruleID)
+}
+
+// GetAlertRule - fetches an alert rule
+func GetAlertRule(ruleID string) (models.AlertRule, error) {
+	var rule models.AlertRule
+	record, err := database.FetchRecord(database.ALERT_RULES_TABLE_NAME, ruleID)
+	if err != nil {
+		return rule, err
+	}
+	err = json.Unmarshal([]byte(record), &rule)
+	return rule, err
+}
+
+// GetAlertRules - fetches all alert rules
+func GetAlertRules() ([]models.AlertRule, error) {
+	rules := []models.AlertRule{}
+	records, err := database.FetchRecords(database.ALERT_RULES_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return rules, err
+	}
+	for _, record := range records {
+		var rule models.AlertRule
+		if err := json.Unmarshal([]byte(record), &rule); err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// CreateAlertTarget - creates a notification target
+func CreateAlertTarget(target models.AlertTarget) (models.AlertTarget, error) {
+	target.ID = uuid.New().String()
+	if err := validateAlertTarget(&target); err != nil {
+		return target, err
+	}
+	return target, saveAlertRecord(database.ALERT_TARGETS_TABLE_NAME, target.ID, &target)
+}
+
+// UpdateAlertTarget - replaces a notification target
+func UpdateAlertTarget(targetID string, target models.AlertTarget) (models.AlertTarget, error) {
+	if _, err := GetAlertTarget(targetID); err != nil {
+		return target, err
+	}
+	target.ID = targetID
+	if err := validateAlertTarget(&target); err != nil {
+		return target, err
+	}
+	return target, saveAlertRecord(database.ALERT_TARGETS_TABLE_NAME, target.ID, &target)
+}
+
+// DeleteAlertTarget - deletes a notification target, fails while rules still notify it
+func DeleteAlertTarget(targetID string) error {
+	if _, err := GetAlertTarget(targetID); err != nil {
+		return err
+	}
+	rules, err := GetAlertRules()
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if StringSliceContains(rule.Targets, targetID) {
+			return fmt.Errorf("target is used by alert rule %s", rule.Name)
+		}
+	}
+	return database.DeleteRecord(database.ALERT_TARGETS_TABLE_NAME, targetID)
+}
+
+// GetAlertTarget - fetches a notification target
+func GetAlertTarget(targetID string) (models.AlertTarget, error) {
+	var target models.AlertTarget
+	record, err := database.FetchRecord(database.ALERT_TARGETS_TABLE_NAME, targetID)
+	if err != nil {
+		return target, err
+	}
+	err = json.Unmarshal([]byte(record), &target)
+	return target, err
+}
+
+// GetAlertTargets - fetches all notification targets
+func GetAlertTargets() ([]models.AlertTarget, error) {
+	targets := []models.AlertTarget{}
+	records, err := database.FetchRecords(database.ALERT_TARGETS_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return targets, err
+	}
+	for _, record := range records {
+		var target models.AlertTarget
+		if err := json.Unmarshal([]byte(record), &target); err != nil {
+			continue
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// GetAlerts - fetches the firing and recently resolved alerts
+func GetAlerts() ([]models.Alert, error) {
+	alerts := []models.Alert{}
+	records, err := database.FetchRecords(database.ALERTS_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return alerts, err
+	}
+	for _, record := range records {
+		var alert models.Alert
+		if err := json.Unmarshal([]byte(record), &alert); err != nil {
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// ManageAlerts - evaluates the alert rules every ALERT_EVALUATION_INTERVAL
+func ManageAlerts(ctx context.Context) {
+	ticker := time.NewTicker(ALERT_EVALUATION_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := EvaluateAlertRules(time.Now()); err != nil {
+				logger.Log(0, "failed to evaluate alert rules", err.Error())
+			}
+		}
+	}
+}
+
+// EvaluateAlertRules - checks every enabled rule, fires alerts for new subjects, resolves the ones
+// which no longer match and notifies the targets of the rule, an alert is only notified again
+// after the repeat interval of its rule
+func EvaluateAlertRules(now time.Time) error {
+	alertMutex.Lock()
+	notifications, err := updateAlerts(now)
+	alertMutex.Unlock()
+	// targets can be slow to answer, notify once the alerts are stored without holding the lock
+	for i := range notifications {
+		notifyAlert(&notifications[i].rule, &notifications[i].alert)
+	}
+	return err
+}
+
+// updateAlerts - stores the alerts of the current evaluation and returns the ones to notify
+func updateAlerts(now time.Time) ([]alertNotification, error) {
+	notifications := []alertNotification{}
+	rules, err := GetAlertRules()
+	if err != nil {
+		return notifications, err
+	}
+	alerts, err := GetAlerts()
+	if err != nil {
+		return notifications, err
+	}
+	existing := make(map[string]models.Alert)
+	for _, alert := range alerts {
+		existing[alert.ID] = alert
+	}
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		firing, err := evaluateAlertRule(&rule, now)
+		if err != nil {
+			logger.Log(1, "failed to evaluate alert rule", rule.Name, err.Error())
+			// keep the alerts of the rule as they are until it can be evaluated again
+			for id, alert := range existing {
+				if alert.RuleID == rule.ID {
+					delete(existing, id)
+				}
+			}
+			continue
+		}
+		for subject, fired := range firing {
+			id := alertID(rule.ID, subject)
+			alert, ok := existing[id]
+			delete(existing, id)
+			if !ok || alert.State == models.AlertResolved {
+				alert = models.Alert{
+					ID:       id,
+					RuleID:   rule.ID,
+					RuleName: rule.Name,
+					Type:     rule.Type,
+					Subject:  subject,
+					State:    models.AlertFiring,
+					FiredAt:  now,
+				}
+			} else if rule.RepeatInterval <= 0 || now.Sub(alert.LastNotified) < time.Duration(rule.RepeatInterval)*time.Minute {
+				continue
+			}
+			alert.Network = fired.network
+			alert.Message = fired.message
+			alert.LastNotified = now
+			if err := saveAlertRecord(database.ALERTS_TABLE_NAME, alert.ID, &alert); err != nil {
+				return notifications, err
+			}
+			notifications = append(notifications, alertNotification{rule: rule, alert: alert})
+		}
+	}
+	for _, alert := range existing {
+		if alert.State == models.AlertResolved {
+			if now.Sub(alert.ResolvedAt) > ALERT_RESOLVED_RETENTION {
+				if err := database.DeleteRecord(database.ALERTS_TABLE_NAME, alert.ID); err != nil {
+					return notifications, err
+				}
+			}
+			continue
+		}
+		alert.State = models.AlertResolved
+		alert.ResolvedAt = now
+		alert.LastNotified = now
+		if err := saveAlertRecord(database.ALERTS_TABLE_NAME, alert.ID, &alert); err != nil {
+			return notifications, err
+		}
+		if rule, err := GetAlertRule(alert.RuleID); err == nil && rule.Enabled {
+			notifications = append(notifications, alertNotification{rule: rule, alert: alert})
+		}
+	}
+	return notifications, nil
+}
+
+// evaluateAlertRule - returns the subjects a rule currently fires for
+func evaluateAlertRule(rule *models.AlertRule, now time.Time) (map[string]firingSubject, error) {
+	firing := make(map[string]firingSubject)
+	switch rule.Type {
+	case models.EnrollmentKeyExpiryRule:
+		keys, err := GetAllEnrollmentKeys()
+		if err != nil {
+			return firing, err
+		}
+		for _, key := range keys {
+			if key.Expiration.IsZero() || key.Unlimited || !now.Before(key.Expiration) {
+				continue
+			}
+			if rule.Network != "" && !StringSliceContains(key.Networks, rule.Network) {
+				continue
+			}
+			left := key.Expiration.Sub(now)
+			if left.Hours() > rule.Threshold {
+				continue
+			}
+			// the value of a key is its secret, alerts are stored and sent to third parties
+			subject := enrollmentKeyRef(key.Value)
+			firing[subject] = firingSubject{
+				network: rule.Network,
+				message: fmt.Sprintf("enrollment key %s (tags %v) expires in %s", subject, key.Tags, left.Round(time.Minute)),
+			}
+		}
+		return firing, nil
+	}
+	nodes, err := GetAllNodes()
+	if err != nil {
+		return firing, err
+	}
+	for _, node := range nodes {
+		if node.PendingDelete || (rule.Network != "" && node.Network != rule.Network) {
+			continue
+		}
+		name := node.ID.String()
+		if host, err := GetHost(node.HostID.String()); err == nil {
+			name = host.Name
+		}
+		silent := now.Sub(node.LastCheckIn)
+		switch rule.Type {
+		case models.NodeCheckInRule:
+			if node.Connected && silent.Minutes() > rule.Threshold {
+				firing[node.ID.String()] = firingSubject{
+					network: node.Network,
+					message: fmt.Sprintf("node %s on network %s has not checked in for %s", name, node.Network, silent.Round(time.Minute)),
+				}
+			}
+		case models.EgressDownRule:
+			if !node.IsEgressGateway {
+				continue
+			}
+			if !node.Connected || silent.Minutes() > rule.Threshold {
+				firing[node.ID.String()] = firingSubject{
+					network: node.Network,
+					message: fmt.Sprintf("egress gateway %s on network %s is down, last check in %s ago", name, node.Network, silent.Round(time.Minute)),
+				}
+			}
+		case models.PeerConnectivityRule:
+			window := time.Duration(rule.Window) * time.Minute
+			history, err := GetMetricsHistory(node.ID.String(), now.Add(-window), now, window)
+			if err != nil || len(history.Points) == 0 {
+				continue
+			}
+			for peerID, peer := range history.Points[0].Peers {
+				if peer.Samples == 0 {
+					continue
+				}
+				percent := 100 * float64(peer.ConnectedSamples) / float64(peer.Samples)
+				if percent >= rule.Threshold {
+					continue
+				}
+				firing[node.ID.String()+"/"+peerID] = firingSubject{
+					network: node.Network,
+					message: fmt.Sprintf("%s was connected to %s %.1f%% of the last %s on network %s", name, peer.NodeName, percent, window, node.Network),
+				}
+			}
+		}
+	}
+	return firing, nil
+}
+
+// notifyAlert - sends an alert to the targets of its rule, failures are logged
+func notifyAlert(rule *models.AlertRule, alert *models.Alert) {
+	for _, targetID := range rule.Targets {
+		target, err := GetAlertTarget(targetID)
+		if err != nil {
+			logger.Log(1, "failed to fetch alert target", targetID, err.Error())
+			continue
+		}
+		if err := SendAlertNotification(&target, alert); err != nil {
+			logger.Log(1, "failed to notify", target.Name, "of alert", alert.RuleName, err.Error())
+		}
+	}
+}
+
+func validateAlertRule(rule *models.AlertRule) error {
+	if rule.Name == "" {
+		return errors.New("alert rule name cannot be empty")
+	}
+	switch rule.Type {
+	case models.NodeCheckInRule, models.EgressDownRule, models.EnrollmentKeyExpiryRule:
+	case models.PeerConnectivityRule:
+		if rule.Threshold > 100 {
+			return errors.New("connectivity threshold is a percentage")
+		}
+		if rule.Window <= 0 {
+			rule.Window = defaultConnectivityWindow
+		}
+	default:
+		return fmt.Errorf("unknown alert rule type %s", rule.Type)
+	}
+	if rule.Threshold <= 0 {
+		return errors.New("alert rule threshold has to be positive")
+	}
+	if rule.Targets == nil {
+		rule.Targets = []string{}
+	}
+	for _, targetID := range rule.Targets {
+		if _, err := GetAlertTarget(targetID); err != nil {
+			return fmt.Errorf("unknown alert target %s", targetID)
+		}
+	}
+	return nil
+}
+
+func validateAlertTarget(target *models.AlertTarget) error {
+	if target.Name == "" {
+		return errors.New("alert target name cannot be empty")
+	}
+	switch target.Type {
+	case models.WebhookTarget, models.SlackTarget:
+		if target.URL == "" {
+			return errors.New("url of the alert target cannot be empty")
+		}
+	case models.EmailTarget:
+		if len(target.Emails) == 0 {
+			return errors.New("alert target needs at least one email address")
+		}
+	default:
+		return fmt.Errorf("unknown alert target type %s", target.Type)
+	}
+	return nil
+}
+
+func saveAlertRecord(table, key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return database.Insert(key, string(data), table)
+}
+
+// alertID - deduplication key of the alerts of a rule
+func alertID(ruleID, subject string) string {
+	return ruleID + "-" + subject
+}
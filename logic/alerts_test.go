@@ -0,0 +1,250 @@
+package logic
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestAlertRules(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	var mu sync.Mutex
+	received := []models.Alert{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert models.Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, alert)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+	notified := func() []models.Alert {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]models.Alert{}, received...)
+	}
+	now := time.Now()
+	node := models.Node{}
+	node.ID = uuid.New()
+	node.Network = "alerttest"
+	node.Connected = true
+	node.LastCheckIn = now.Add(-time.Minute * 30)
+	if err := upsertNode(&node); err != nil {
+		t.Fatal(err)
+	}
+	defer database.DeleteRecord(database.NODES_TABLE_NAME, node.ID.String())
+	target, err := CreateAlertTarget(models.AlertTarget{Name: "hook", Type: models.WebhookTarget, URL: webhook.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DeleteAlertTarget(target.ID)
+	rule, err := CreateAlertRule(models.AlertRule{
+		Name:      "checkin",
+		Type:      models.NodeCheckInRule,
+		Enabled:   true,
+		Network:   "alerttest",
+		Threshold: 10,
+		Targets:   []string{target.ID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DeleteAlertRule(rule.ID)
+
+	t.Run("fires for silent node", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(EvaluateAlertRules(now))
+		sent := notified()
+		is.Equal(len(sent), 1)
+		is.Equal(sent[0].State, models.AlertFiring)
+		is.Equal(sent[0].Subject, node.ID.String())
+		is.Equal(sent[0].Network, "alerttest")
+	})
+	t.Run("deduplicated while firing", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(EvaluateAlertRules(now.Add(time.Minute)))
+		is.Equal(len(notified()), 1)
+	})
+	t.Run("resolved after check in", func(t *testing.T) {
+		is := is.New(t)
+		node.LastCheckIn = now.Add(time.Minute * 2)
+		is.NoErr(upsertNode(&node))
+		is.NoErr(EvaluateAlertRules(now.Add(time.Minute * 2)))
+		sent := notified()
+		is.Equal(len(sent), 2)
+		is.Equal(sent[1].State, models.AlertResolved)
+		alerts, err := GetAlerts()
+		is.NoErr(err)
+		found := false
+		for _, alert := range alerts {
+			if alert.RuleID == rule.ID {
+				found = true
+				is.Equal(alert.State, models.AlertResolved)
+			}
+		}
+		is.True(found)
+	})
+	t.Run("target in use", func(t *testing.T) {
+		is := is.New(t)
+		is.True(DeleteAlertTarget(target.ID) != nil)
+	})
+}
+
+func TestAlertValidation(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	t.Run("unknown rule type", func(t *testing.T) {
+		is := is.New(t)
+		_, err := CreateAlertRule(models.AlertRule{Name: "x", Type: "bogus", Threshold: 1})
+		is.True(err != nil)
+	})
+	t.Run("threshold required", func(t *testing.T) {
+		is := is.New(t)
+		_, err := CreateAlertRule(models.AlertRule{Name: "x", Type: models.NodeCheckInRule})
+		is.True(err != nil)
+	})
+	t.Run("connectivity is a percentage", func(t *testing.T) {
+		is := is.New(t)
+		_, err := CreateAlertRule(models.AlertRule{Name: "x", Type: models.PeerConnectivityRule, Threshold: 150})
+		is.True(err != nil)
+	})
+	t.Run("unknown target", func(t *testing.T) {
+		is := is.New(t)
+		_, err := CreateAlertRule(models.AlertRule{Name: "x", Type: models.NodeCheckInRule, Threshold: 5, Targets: []string{"missing"}})
+		is.True(err != nil)
+	})
+	t.Run("webhook without url", func(t *testing.T) {
+		is := is.New(t)
+		_, err := CreateAlertTarget(models.AlertTarget{Name: "x", Type: models.WebhookTarget})
+		is.True(err != nil)
+	})
+	t.Run("email without addresses", func(t *testing.T) {
+		is := is.New(t)
+		_, err := CreateAlertTarget(models.AlertTarget{Name: "x", Type: models.EmailTarget})
+		is.True(err != nil)
+	})
+}
+
+func TestEmailNotification(t *testing.T) {
+	is := is.New(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	is.NoErr(err)
+	defer listener.Close()
+	mail := make(chan string, 1)
+	go serveTestSMTP(listener, mail)
+	t.Setenv("SMTP_HOST", "127.0.0.1")
+	t.Setenv("SMTP_PORT", strconv.Itoa(listener.Addr().(*net.TCPAddr).Port))
+	t.Setenv("SMTP_SENDER", "alerts@example.com")
+	target := models.AlertTarget{Name: "ops", Type: models.EmailTarget, Emails: []string{"ops@example.com"}}
+	alert := models.Alert{RuleName: "checkin", Message: "node is silent", State: models.AlertFiring}
+	is.NoErr(SendAlertNotification(&target, &alert))
+	select {
+	case body := <-mail:
+		is.True(strings.Contains(body, "To: ops@example.com"))
+		is.True(strings.Contains(body, "node is silent"))
+	case <-time.After(time.Second * 5):
+		t.Fatal("no mail received")
+	}
+}
+
+func TestEmailNotificationTimeout(t *testing.T) {
+	is := is.New(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	is.NoErr(err)
+	defer listener.Close()
+	// accept the connection but never greet
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second * 5)
+		}
+	}()
+	t.Setenv("SMTP_HOST", "127.0.0.1")
+	t.Setenv("SMTP_PORT", strconv.Itoa(listener.Addr().(*net.TCPAddr).Port))
+	timeout := alertSMTPTimeout
+	alertSMTPTimeout = time.Millisecond * 200
+	defer func() { alertSMTPTimeout = timeout }()
+	target := models.AlertTarget{Name: "ops", Type: models.EmailTarget, Emails: []string{"ops@example.com"}}
+	started := time.Now()
+	is.True(SendAlertNotification(&target, &models.Alert{RuleName: "checkin"}) != nil)
+	is.True(time.Since(started) < time.Second*2)
+}
+
+func TestEnrollmentKeyExpiryAlert(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	is := is.New(t)
+	now := time.Now()
+	key, err := CreateEnrollmentKey(0, now.Add(time.Hour), []string{"alerttest"}, []string{"ci"}, false)
+	is.NoErr(err)
+	defer DeleteEnrollmentKey(key.Value)
+	rule := models.AlertRule{ID: "expiry", Type: models.EnrollmentKeyExpiryRule, Threshold: 24}
+	firing, err := evaluateAlertRule(&rule, now)
+	is.NoErr(err)
+	subject := enrollmentKeyRef(key.Value)
+	fired, ok := firing[subject]
+	is.True(ok)
+	// the secret value of the key never ends up in alerts
+	is.True(!strings.Contains(subject, key.Value))
+	is.True(!strings.Contains(fired.message, key.Value))
+	is.True(strings.Contains(fired.message, "ci"))
+}
+
+// serveTestSMTP - accepts a single mail without authentication and hands its data to mail
+func serveTestSMTP(listener net.Listener, mail chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			mail <- data.String()
+			reply("250 ok")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
@@ -0,0 +1,451 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic/acls/nodeacls"
+	"github.com/gravitl/netmaker/logic/pro"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/models/promodels"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+const (
+	// auditBodyLimit - bytes of a response kept to record a created object
+	auditBodyLimit = 64 * 1024
+	// auditSyslogPriority - facility log audit (13), severity notice (5)
+	auditSyslogPriority = 13*8 + 5
+	auditRedacted       = "[redacted]"
+)
+
+// auditSecretFields - fields never written to the audit log
+var auditSecretFields = []string{"password", "hostpass", "privatekey", "private_key", "secret_hash", "token", "mqpassword", "secret",
+	"refresh_hash", "previous_refresh_hash", "refreshtoken", "authtoken", "totp", "recovery_codes", "value"}
+
+// auditSnapshots - fetchers of the object a route changes, by the route variable naming it, in order of precedence
+var auditSnapshots = []struct {
+	variable string
+	fetch    func(vars map[string]string) (any, error)
+}{
+	{"nodeid", func(vars map[string]string) (any, error) { return GetNodeByID(vars["nodeid"]) }},
+	{"clientid", func(vars map[string]string) (any, error) { return GetExtClient(vars["clientid"], vars["network"]) }},
+	{"domain", func(vars map[string]string) (any, error) { return getAuditDNSEntry(vars["network"], vars["domain"]) }},
+	{"hostid", func(vars map[string]string) (any, error) { return GetHost(vars["hostid"]) }},
+	{"groupid", func(vars map[string]string) (any, error) { return GetRelayGroup(vars["groupid"]) }},
+	{"keyID", func(vars map[string]string) (any, error) { return GetEnrollmentKey(vars["keyID"]) }},
+	{"roleid", func(vars map[string]string) (any, error) { return GetRole(vars["roleid"]) }},
+	{"bindingid", func(vars map[string]string) (any, error) {
+		return database.FetchRecord(database.ROLE_BINDINGS_TABLE_NAME, vars["bindingid"])
+	}},
+	{"tokenid", func(vars map[string]string) (any, error) { return GetAccessToken(vars["tokenid"]) }},
+	{"sessionid", func(vars map[string]string) (any, error) { return GetUserSession(vars["sessionid"]) }},
+	{"mappingid", func(vars map[string]string) (any, error) { return GetSSOMapping(vars["mappingid"]) }},
+	{"ruleid", func(vars map[string]string) (any, error) { return GetAlertRule(vars["ruleid"]) }},
+	{"targetid", func(vars map[string]string) (any, error) { return GetAlertTarget(vars["targetid"]) }},
+	{"networkuser", func(vars map[string]string) (any, error) {
+		return pro.GetNetworkUser(vars["network"], promodels.NetworkUserID(vars["networkuser"]))
+	}},
+	{"username", func(vars map[string]string) (any, error) { return GetReturnUser(vars["username"]) }},
+	{"networkname", func(vars map[string]string) (any, error) { return GetNetwork(vars["networkname"]) }},
+}
+
+// RecordAuditEvent - appends an event to the audit log and forwards it to the syslog server if one is configured
+func RecordAuditEvent(event *models.AuditEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := database.Insert(event.ID, string(data), database.AUDIT_LOG_TABLE_NAME); err != nil {
+		return err
+	}
+	if address := servercfg.GetAuditSyslog(); address != "" {
+		go func(event models.AuditEvent) {
+			if err := forwardAuditEvent(address, &event); err != nil {
+				logger.Log(1, "failed to forward audit event", event.ID, "to syslog:", err.Error())
+			}
+		}(*event)
+	}
+	return nil
+}
+
+// AuditRequest - records a successful change made by a request which does not go through Authorize,
+// e.g. the registration of a host with an enrollment key
+func AuditRequest(r *http.Request, actor string, action models.RbacAction, resource models.RbacResource, resourceID string) {
+	event := models.AuditEvent{
+		Actor:      actor,
+		Action:     action,
+		Resource:   resource,
+		ResourceID: resourceID,
+		SourceIP:   RequestSourceIP(r),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Status:     http.StatusOK,
+	}
+	// the path may carry a secret, e.g. an enrollment token
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			event.Path = template
+		}
+	}
+	if err := RecordAuditEvent(&event); err != nil {
+		logger.Log(0, "failed to record audit event of", actor, string(action), string(resource), err.Error())
+	}
+}
+
+// QueryAuditLog - fetches the events of the audit log matching a filter, oldest first
+func QueryAuditLog(filter models.AuditFilter) ([]models.AuditEvent, error) {
+	events := []models.AuditEvent{}
+	records, err := database.FetchRecords(database.AUDIT_LOG_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return events, err
+	}
+	for _, record := range records {
+		var event models.AuditEvent
+		if err := json.Unmarshal([]byte(record), &event); err != nil {
+			continue
+		}
+		if (filter.Actor != "" && event.Actor != filter.Actor) ||
+			(filter.Action != "" && event.Action != filter.Action) ||
+			(filter.Resource != "" && event.Resource != filter.Resource) ||
+			(filter.Network != "" && event.Network != filter.Network) ||
+			(!filter.Since.IsZero() && event.Timestamp.Before(filter.Since)) ||
+			(!filter.Until.IsZero() && event.Timestamp.After(filter.Until)) {
+			continue
+		}
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[len(events)-filter.Limit:]
+	}
+	return events, nil
+}
+
+// AuditEventToSyslog - renders an audit event as an RFC 5424 syslog message
+func AuditEventToSyslog(event *models.AuditEvent) (string, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return fmt.Sprintf("<%d>1 %s %s netmaker - %s - %s", auditSyslogPriority,
+		event.Timestamp.UTC().Format(time.RFC3339Nano), hostname, event.Action, data), nil
+}
+
+// RequestSourceIP - address a request came from, the X-Forwarded-For and X-Real-Ip headers are only
+// trusted if the request was passed on by one of the TRUSTED_PROXIES
+func RequestSourceIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remote = ip
+	}
+	proxies := trustedProxies()
+	if !isTrustedProxy(remote, proxies) {
+		return remote
+	}
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		// every proxy appends the address it got the request from, the first untrusted one from the right is the client
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if i == 0 || !isTrustedProxy(hop, proxies) {
+				return hop
+			}
+		}
+	}
+	if realIP := r.Header.Get("X-Real-Ip"); realIP != "" {
+		return strings.TrimSpace(realIP)
+	}
+	return remote
+}
+
+// trustedProxies - parses the TRUSTED_PROXIES setting, single addresses become /32 or /128 prefixes
+func trustedProxies() []netip.Prefix {
+	prefixes := []netip.Prefix{}
+	for _, proxy := range servercfg.GetTrustedProxies() {
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(proxy); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		} else {
+			logger.Log(1, "ignoring invalid trusted proxy", proxy)
+		}
+	}
+	return prefixes
+}
+
+func isTrustedProxy(ip string, proxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveAudited - serves a request authorized for an action on a resource, recording it in the audit log
+// unless it only reads
+func serveAudited(resource models.RbacResource, action models.RbacAction, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	if action == models.ReadAction {
+		next.ServeHTTP(w, r)
+		return
+	}
+	vars := mux.Vars(r)
+	event := models.AuditEvent{
+		Timestamp: time.Now(),
+		Actor:     r.Header.Get("user"),
+		Action:    action,
+		Resource:  resource,
+		Network:   vars["networkname"],
+		SourceIP:  RequestSourceIP(r),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+	}
+	// the identity headers are set by Authorize from verified credentials, an authenticated user
+	// is recorded over a host
+	if hostID := r.Header.Get(HostIDHeader); hostID != "" && event.Actor == "" {
+		event.Actor = "host:" + hostID
+	}
+	if event.Network == "" {
+		event.Network = vars["network"]
+	}
+	if resource == models.SelfResource && vars["username"] != "" {
+		event.Resource = models.UserResource
+	}
+	var fetch func(vars map[string]string) (any, error)
+	if resource == models.AclResource {
+		fetch = func(vars map[string]string) (any, error) {
+			return nodeacls.FetchAllACLs(nodeacls.NetworkID(vars["networkname"]))
+		}
+		event.ResourceID = vars["networkname"]
+	} else {
+		for _, snapshot := range auditSnapshots {
+			if vars[snapshot.variable] != "" {
+				fetch = snapshot.fetch
+				event.ResourceID = vars[snapshot.variable]
+				break
+			}
+		}
+	}
+	if vars["keyID"] != "" {
+		// the id of an enrollment key is its secret value
+		event.ResourceID = enrollmentKeyRef(vars["keyID"])
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				event.Path = template
+			}
+		}
+	}
+	var before any
+	if fetch != nil && action != models.CreateAction {
+		if object, err := fetch(vars); err == nil {
+			before = object
+		}
+	}
+	recorder := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(recorder, r)
+	event.Status = recorder.status
+
+	var after any
+	if recorder.status < http.StatusMultipleChoices {
+		if action == models.CreateAction {
+			var created any
+			// route variables of creations name the parent, e.g. the ingress of an ext client
+			event.ResourceID = ""
+			if err := json.Unmarshal(recorder.body.Bytes(), &created); err == nil {
+				after = created
+				event.ResourceID = auditObjectID(created)
+			}
+		} else if fetch != nil && action != models.DeleteAction {
+			if object, err := fetch(vars); err == nil {
+				after = object
+			}
+		}
+	}
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+	if beforeFields != nil {
+		event.Before, _ = json.Marshal(beforeFields)
+	}
+	if afterFields != nil {
+		event.After, _ = json.Marshal(afterFields)
+	}
+	event.Diff = auditDiff(beforeFields, afterFields)
+	if err := RecordAuditEvent(&event); err != nil {
+		logger.Log(0, "failed to record audit event of", event.Actor, string(event.Action), string(event.Resource), err.Error())
+	}
+}
+
+// auditResponseWriter - keeps the status and the start of the body of a response
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if remaining := auditBodyLimit - w.body.Len(); remaining > 0 {
+		if len(b) > remaining {
+			w.body.Write(b[:remaining])
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// auditFields - json representation of an object with its secrets redacted
+func auditFields(object any) any {
+	if object == nil {
+		return nil
+	}
+	data, ok := object.(string)
+	if !ok {
+		raw, err := json.Marshal(object)
+		if err != nil {
+			return nil
+		}
+		data = string(raw)
+	}
+	var fields any
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return nil
+	}
+	return redactAuditFields(fields)
+}
+
+func redactAuditFields(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if StringSliceContains(auditSecretFields, strings.ToLower(key)) {
+				v[key] = auditRedacted
+				continue
+			}
+			v[key] = redactAuditFields(field)
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactAuditFields(v[i])
+		}
+	}
+	return value
+}
+
+// auditDiff - top level fields differing between two objects, nil unless both are objects
+func auditDiff(before, after any) map[string]models.AuditChange {
+	beforeMap, ok := before.(map[string]any)
+	if !ok {
+		return nil
+	}
+	afterMap, ok := after.(map[string]any)
+	if !ok {
+		return nil
+	}
+	diff := make(map[string]models.AuditChange)
+	for key, value := range beforeMap {
+		if !reflect.DeepEqual(value, afterMap[key]) {
+			diff[key] = models.AuditChange{Before: value, After: afterMap[key]}
+		}
+	}
+	for key, value := range afterMap {
+		if _, ok := beforeMap[key]; !ok {
+			diff[key] = models.AuditChange{After: value}
+		}
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// auditObjectID - id of an object created through the api, read from the response
+func auditObjectID(created any) string {
+	fields, ok := created.(map[string]any)
+	if !ok {
+		return ""
+	}
+	for _, key := range []string{"id", "ID", "clientid", "netid", "username", "name"} {
+		if id, ok := fields[key].(string); ok && id != "" {
+			return id
+		}
+	}
+	// enrollment keys are named by their secret value, only a hash of it is recorded
+	if value, ok := fields["value"].(string); ok && value != "" {
+		return enrollmentKeyRef(value)
+	}
+	return ""
+}
+
+func getAuditDNSEntry(network, domain string) (models.DNSEntry, error) {
+	entries, err := GetCustomDNS(network)
+	if err != nil {
+		return models.DNSEntry{}, err
+	}
+	for _, entry := range entries {
+		if entry.Name == domain {
+			return entry, nil
+		}
+	}
+	return models.DNSEntry{}, fmt.Errorf("dns entry %s not found", domain)
+}
+
+// forwardAuditEvent - sends an audit event to a syslog server given as network://host:port
+func forwardAuditEvent(address string, event *models.AuditEvent) error {
+	target, err := url.Parse(address)
+	if err != nil {
+		return err
+	}
+	message, err := AuditEventToSyslog(event)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout(target.Scheme, target.Host, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if strings.HasPrefix(target.Scheme, "tcp") {
+		// octet counting framing of RFC 6587
+		message = fmt.Sprintf("%d %s", len(message), message)
+	}
+	_, err = conn.Write([]byte(message))
+	return err
+}
@@ -0,0 +1,245 @@
+package logic
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestQueryAuditLog(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	database.DeleteAllRecords(database.AUDIT_LOG_TABLE_NAME)
+	defer database.DeleteAllRecords(database.AUDIT_LOG_TABLE_NAME)
+	is := is.New(t)
+	start := time.Now().Add(-time.Hour)
+	is.NoErr(RecordAuditEvent(&models.AuditEvent{Timestamp: start, Actor: "alice", Action: models.CreateAction, Resource: models.NodeResource, Network: "skynet"}))
+	is.NoErr(RecordAuditEvent(&models.AuditEvent{Timestamp: start.Add(time.Minute), Actor: "bob", Action: models.UpdateAction, Resource: models.NodeResource, Network: "skynet"}))
+	is.NoErr(RecordAuditEvent(&models.AuditEvent{Timestamp: start.Add(2 * time.Minute), Actor: "alice", Action: models.DeleteAction, Resource: models.DNSResource, Network: "other"}))
+
+	t.Run("all", func(t *testing.T) {
+		is := is.New(t)
+		events, err := QueryAuditLog(models.AuditFilter{})
+		is.NoErr(err)
+		is.Equal(len(events), 3)
+		is.Equal(events[0].Actor, "alice")
+		is.Equal(events[2].Action, models.DeleteAction)
+		is.True(events[0].ID != "")
+	})
+	t.Run("filters", func(t *testing.T) {
+		is := is.New(t)
+		events, err := QueryAuditLog(models.AuditFilter{Actor: "alice"})
+		is.NoErr(err)
+		is.Equal(len(events), 2)
+		events, err = QueryAuditLog(models.AuditFilter{Resource: models.NodeResource, Action: models.UpdateAction})
+		is.NoErr(err)
+		is.Equal(len(events), 1)
+		is.Equal(events[0].Actor, "bob")
+		events, err = QueryAuditLog(models.AuditFilter{Network: "other"})
+		is.NoErr(err)
+		is.Equal(len(events), 1)
+		events, err = QueryAuditLog(models.AuditFilter{Since: start.Add(30 * time.Second), Until: start.Add(90 * time.Second)})
+		is.NoErr(err)
+		is.Equal(len(events), 1)
+		is.Equal(events[0].Actor, "bob")
+	})
+	t.Run("limit keeps newest", func(t *testing.T) {
+		is := is.New(t)
+		events, err := QueryAuditLog(models.AuditFilter{Limit: 2})
+		is.NoErr(err)
+		is.Equal(len(events), 2)
+		is.Equal(events[0].Actor, "bob")
+	})
+}
+
+func TestAuditFields(t *testing.T) {
+	t.Run("redaction", func(t *testing.T) {
+		is := is.New(t)
+		fields := auditFields(map[string]any{
+			"username": "alice",
+			"password": "hunter2",
+			"nested":   []any{map[string]any{"PrivateKey": "key"}},
+		}).(map[string]any)
+		is.Equal(fields["username"], "alice")
+		is.Equal(fields["password"], auditRedacted)
+		is.Equal(fields["nested"].([]any)[0].(map[string]any)["PrivateKey"], auditRedacted)
+	})
+	t.Run("diff", func(t *testing.T) {
+		is := is.New(t)
+		before := auditFields(map[string]any{"name": "a", "port": 51821, "gone": true})
+		after := auditFields(map[string]any{"name": "a", "port": 51822, "added": "x"})
+		diff := auditDiff(before, after)
+		is.Equal(len(diff), 3)
+		is.Equal(diff["port"].Before, float64(51821))
+		is.Equal(diff["port"].After, float64(51822))
+		is.Equal(diff["gone"].After, nil)
+		is.Equal(diff["added"].After, "x")
+		is.Equal(auditDiff(before, before), nil)
+		is.Equal(auditDiff(nil, after), nil)
+	})
+}
+
+func TestAuditEventToSyslog(t *testing.T) {
+	is := is.New(t)
+	event := models.AuditEvent{ID: "1", Timestamp: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), Actor: "alice", Action: models.UpdateAction, Resource: models.NodeResource}
+	line, err := AuditEventToSyslog(&event)
+	is.NoErr(err)
+	is.True(strings.HasPrefix(line, "<109>1 2023-01-02T03:04:05Z "))
+	message := line[strings.Index(line, "{"):]
+	var decoded models.AuditEvent
+	is.NoErr(json.Unmarshal([]byte(message), &decoded))
+	is.Equal(decoded.Actor, "alice")
+}
+
+func TestServeAudited(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	database.DeleteAllRecords(database.AUDIT_LOG_TABLE_NAME)
+	defer database.DeleteAllRecords(database.AUDIT_LOG_TABLE_NAME)
+	// httptest requests come from 192.0.2.1
+	t.Setenv("TRUSTED_PROXIES", "192.0.2.1, 10.0.0.0/24")
+	router := mux.NewRouter()
+	audited := func(resource models.RbacResource, action models.RbacAction, next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			serveAudited(resource, action, next, w, r)
+		}
+	}
+	router.HandleFunc("/api/v1/roles", audited(models.UserResource, models.ReadAction, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/roles", audited(models.UserResource, models.CreateAction, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"created-id","token":"secret"}`))
+	})).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/roles/{roleid}", audited(models.UserResource, models.UpdateAction, func(w http.ResponseWriter, r *http.Request) {
+		role, _ := GetRole(mux.Vars(r)["roleid"])
+		role.Description = "changed"
+		if err := UpdateRole(&role); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})).Methods(http.MethodPut)
+	router.HandleFunc("/api/v1/enrollment-keys", audited(models.EnrollmentKeyResource, models.CreateAction, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"value":"secretkeyvalue","tags":["ci"]}`))
+	})).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/enrollment-keys/{keyID}", audited(models.EnrollmentKeyResource, models.DeleteAction, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).Methods(http.MethodDelete)
+	router.HandleFunc("/api/v1/hosts/{hostid}", audited(models.HostResource, models.UpdateAction, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).Methods(http.MethodPut)
+	serveAs := func(method, path string, headers map[string]string) {
+		req := httptest.NewRequest(method, path, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		req.Header.Set("X-Forwarded-For", "10.1.2.3, 10.0.0.1")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	serve := func(method, path string) {
+		serveAs(method, path, map[string]string{"user": "alice"})
+	}
+
+	t.Run("reads are not recorded", func(t *testing.T) {
+		is := is.New(t)
+		serve(http.MethodGet, "/api/v1/roles")
+		events, err := QueryAuditLog(models.AuditFilter{})
+		is.NoErr(err)
+		is.Equal(len(events), 0)
+	})
+	t.Run("create", func(t *testing.T) {
+		is := is.New(t)
+		serve(http.MethodPost, "/api/v1/roles")
+		events, err := QueryAuditLog(models.AuditFilter{Action: models.CreateAction})
+		is.NoErr(err)
+		is.Equal(len(events), 1)
+		is.Equal(events[0].Actor, "alice")
+		is.Equal(events[0].SourceIP, "10.1.2.3")
+		is.Equal(events[0].ResourceID, "created-id")
+		is.Equal(events[0].Status, http.StatusOK)
+		is.True(!strings.Contains(string(events[0].After), "secret"))
+	})
+	t.Run("update", func(t *testing.T) {
+		is := is.New(t)
+		role := models.Role{ID: "audited", Description: "original", Permissions: []models.RbacPermission{{Resource: models.DNSResource, Action: models.ReadAction}}}
+		is.NoErr(CreateRole(&role))
+		defer DeleteRole(role.ID)
+		serve(http.MethodPut, "/api/v1/roles/audited")
+		events, err := QueryAuditLog(models.AuditFilter{Action: models.UpdateAction})
+		is.NoErr(err)
+		is.Equal(len(events), 1)
+		is.Equal(events[0].ResourceID, "audited")
+		is.Equal(events[0].Diff["description"].Before, "original")
+		is.Equal(events[0].Diff["description"].After, "changed")
+	})
+	t.Run("enrollment keys are not named by their value", func(t *testing.T) {
+		is := is.New(t)
+		serve(http.MethodPost, "/api/v1/enrollment-keys")
+		serve(http.MethodDelete, "/api/v1/enrollment-keys/secretkeyvalue")
+		events, err := QueryAuditLog(models.AuditFilter{Resource: models.EnrollmentKeyResource})
+		is.NoErr(err)
+		is.Equal(len(events), 2)
+		for _, event := range events {
+			is.Equal(event.ResourceID, enrollmentKeyRef("secretkeyvalue"))
+			data, err := json.Marshal(event)
+			is.NoErr(err)
+			is.True(!strings.Contains(string(data), "secretkeyvalue"))
+		}
+	})
+	t.Run("actor", func(t *testing.T) {
+		is := is.New(t)
+		serveAs(http.MethodPut, "/api/v1/hosts/hostid", map[string]string{HostIDHeader: "hostid"})
+		serveAs(http.MethodPut, "/api/v1/hosts/hostid", map[string]string{HostIDHeader: "hostid", "user": "bob"})
+		events, err := QueryAuditLog(models.AuditFilter{Resource: models.HostResource})
+		is.NoErr(err)
+		is.Equal(len(events), 2)
+		is.Equal(events[0].Actor, "host:hostid")
+		is.Equal(events[1].Actor, "bob")
+	})
+}
+
+func TestRequestSourceIP(t *testing.T) {
+	request := func(remote, forwarded, realIP string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		if realIP != "" {
+			req.Header.Set("X-Real-Ip", realIP)
+		}
+		return req
+	}
+	t.Run("headers ignored without trusted proxies", func(t *testing.T) {
+		is := is.New(t)
+		t.Setenv("TRUSTED_PROXIES", "")
+		is.Equal(RequestSourceIP(request("203.0.113.5:4000", "198.51.100.1", "198.51.100.2")), "203.0.113.5")
+	})
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.2,172.16.0.0/12")
+	t.Run("untrusted remote", func(t *testing.T) {
+		is := is.New(t)
+		is.Equal(RequestSourceIP(request("203.0.113.5:4000", "198.51.100.1", "")), "203.0.113.5")
+	})
+	t.Run("forwarded by a trusted proxy", func(t *testing.T) {
+		is := is.New(t)
+		is.Equal(RequestSourceIP(request("10.0.0.2:4000", "198.51.100.1", "")), "198.51.100.1")
+	})
+	t.Run("spoofed hops left of the client", func(t *testing.T) {
+		is := is.New(t)
+		is.Equal(RequestSourceIP(request("10.0.0.2:4000", "1.2.3.4, 198.51.100.1, 172.16.5.5", "")), "198.51.100.1")
+	})
+	t.Run("real ip of a trusted proxy", func(t *testing.T) {
+		is := is.New(t)
+		is.Equal(RequestSourceIP(request("[::ffff:10.0.0.2]:4000", "", "198.51.100.3")), "198.51.100.3")
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -99,12 +100,6 @@ func CreateUser(user *models.User) error {
 	// set password to encrypted password
 	user.Password = string(hash)
 
-	tokenString, _ := CreateProUserJWT(user.UserName, user.Networks, user.Groups, user.IsAdmin)
-	if tokenString == "" {
-		// logic.ReturnErrorResponse(w, r, errorResponse)
-		return err
-	}
-
 	SetUserDefaults(user)
 
 	// connect db
@@ -123,29 +118,7 @@ func CreateUser(user *models.User) error {
 		currentNets = []models.Network{}
 	}
 	for i := range currentNets {
-		newUser := promodels.NetworkUser{
-			ID:      promodels.NetworkUserID(user.UserName),
-			Clients: []string{},
-			Nodes:   []string{},
-		}
-
-		pro.AddProNetDefaults(&currentNets[i])
-		if pro.IsUserAllowed(&currentNets[i], user.UserName, user.Groups) {
-			newUser.AccessLevel = currentNets[i].ProSettings.DefaultAccessLevel
-			newUser.ClientLimit = currentNets[i].ProSettings.DefaultUserClientLimit
-			newUser.NodeLimit = currentNets[i].ProSettings.DefaultUserNodeLimit
-		} else {
-			newUser.AccessLevel = pro.NO_ACCESS
-			newUser.ClientLimit = 0
-			newUser.NodeLimit = 0
-		}
-
-		// legacy
-		if StringSliceContains(user.Networks, currentNets[i].NetID) {
-			if !servercfg.Is_EE {
-				newUser.AccessLevel = pro.NET_ADMIN
-			}
-		}
+		newUser := defaultNetworkUser(&currentNets[i], user)
 		userErr := pro.CreateNetworkUser(&currentNets[i], &newUser)
 		if userErr != nil {
 			logger.Log(0, "failed to add network user data on network", currentNets[i].NetID, "for user", user.UserName)
@@ -156,6 +129,34 @@ func CreateUser(user *models.User) error {
 	return nil
 }
 
+// defaultNetworkUser - network user of a user on a network it has just been given access to
+func defaultNetworkUser(network *models.Network, user *models.User) promodels.NetworkUser {
+	newUser := promodels.NetworkUser{
+		ID:      promodels.NetworkUserID(user.UserName),
+		Clients: []string{},
+		Nodes:   []string{},
+	}
+
+	pro.AddProNetDefaults(network)
+	if pro.IsUserAllowed(network, user.UserName, user.Groups) {
+		newUser.AccessLevel = network.ProSettings.DefaultAccessLevel
+		newUser.ClientLimit = network.ProSettings.DefaultUserClientLimit
+		newUser.NodeLimit = network.ProSettings.DefaultUserNodeLimit
+	} else {
+		newUser.AccessLevel = pro.NO_ACCESS
+		newUser.ClientLimit = 0
+		newUser.NodeLimit = 0
+	}
+
+	// legacy
+	if StringSliceContains(user.Networks, network.NetID) {
+		if !servercfg.Is_EE {
+			newUser.AccessLevel = pro.NET_ADMIN
+		}
+	}
+	return newUser
+}
+
 // CreateAdmin - creates an admin user
 func CreateAdmin(admin *models.User) error {
 	hasadmin, err := HasAdmin()
@@ -169,33 +170,59 @@ func CreateAdmin(admin *models.User) error {
 	return CreateUser(admin)
 }
 
-// VerifyAuthRequest - verifies an auth request
-func VerifyAuthRequest(authRequest models.UserAuthParams) (string, error) {
+// VerifyUserCredentials - checks the username and password of an auth request
+func VerifyUserCredentials(authRequest models.UserAuthParams) (*models.User, error) {
 	var result models.User
 	if authRequest.UserName == "" {
-		return "", errors.New("username can't be empty")
+		return nil, errors.New("username can't be empty")
 	} else if authRequest.Password == "" {
-		return "", errors.New("password can't be empty")
+		return nil, errors.New("password can't be empty")
 	}
 	// Search DB for node with Mac Address. Ignore pending nodes (they should not be able to authenticate with API until approved).
 	record, err := database.FetchRecord(database.USERS_TABLE_NAME, authRequest.UserName)
 	if err != nil {
-		return "", errors.New("error retrieving user from db: " + err.Error())
+		// users unknown to netmaker are created on their first ldap login
+		if database.IsEmptyRecord(err) && servercfg.IsLDAPEnabled() {
+			return VerifyLDAPCredentials(authRequest.UserName, authRequest.Password)
+		}
+		return nil, errors.New("error retrieving user from db: " + err.Error())
 	}
 	if err = json.Unmarshal([]byte(record), &result); err != nil {
-		return "", errors.New("error unmarshalling user json: " + err.Error())
+		return nil, errors.New("error unmarshalling user json: " + err.Error())
+	}
+	if servercfg.IsLDAPEnabled() && IsLDAPUser(&result) {
+		return VerifyLDAPCredentials(authRequest.UserName, authRequest.Password)
 	}
 
 	// compare password from request to stored password in database
 	// might be able to have a common hash (certificates?) and compare those so that a password isn't passed in in plain text...
 	// TODO: Consider a way of hashing the password client side before sending, or using certificates
 	if err = bcrypt.CompareHashAndPassword([]byte(result.Password), []byte(authRequest.Password)); err != nil {
-		return "", errors.New("incorrect credentials")
+		return nil, errors.New("incorrect credentials")
+	}
+	if !IsUserActive(result.UserName) {
+		return nil, ErrUserDeactivated
+	}
+	return &result, nil
+}
+
+// VerifyAuthRequest - verifies the password and second factor of an auth request and opens a session for the user of the request
+func VerifyAuthRequest(authRequest models.UserAuthParams, r *http.Request) (models.SuccessfulUserLoginResponse, error) {
+	user, err := VerifyUserLogin(authRequest, RequestSourceIP(r))
+	if err != nil {
+		return models.SuccessfulUserLoginResponse{}, err
 	}
+	return CreateUserSession(user, RequestSourceIP(r), r.UserAgent())
+}
 
-	// Create a new JWT for the node
-	tokenString, _ := CreateProUserJWT(authRequest.UserName, result.Networks, result.Groups, result.IsAdmin)
-	return tokenString, nil
+// VerifyOAuthRequest - verifies the auth request of a user signed in through an oauth provider and opens
+// a session for it, a second factor is left to the provider
+func VerifyOAuthRequest(authRequest models.UserAuthParams, r *http.Request) (models.SuccessfulUserLoginResponse, error) {
+	user, err := VerifyUserCredentials(authRequest)
+	if err != nil {
+		return models.SuccessfulUserLoginResponse{}, err
+	}
+	return CreateUserSession(user, RequestSourceIP(r), r.UserAgent())
 }
 
 // UpdateUserNetworks - updates the networks of a given user
@@ -211,35 +238,7 @@ func UpdateUserNetworks(newNetworks, newGroups []string, isadmin bool, currentUs
 		currentUser.IsAdmin = true
 		currentUser.Networks = nil
 	} else {
-		// == PRO ==
-		currentUser.Groups = newGroups
-		for _, n := range newNetworks {
-			if !StringSliceContains(currentUser.Networks, n) {
-				// make net admin of any network not previously assigned
-				pro.MakeNetAdmin(n, currentUser.UserName)
-			}
-		}
-		// Compare networks, find networks not in previous
-		for _, n := range currentUser.Networks {
-			if !StringSliceContains(newNetworks, n) {
-				// if user was removed from a network, re-assign access to net default level
-				if network, err := GetNetwork(n); err == nil {
-					if network.ProSettings != nil {
-						ok := pro.AssignAccessLvl(n, currentUser.UserName, network.ProSettings.DefaultAccessLevel)
-						if ok {
-							logger.Log(0, "changed", currentUser.UserName, "access level on network", network.NetID, "to", fmt.Sprintf("%d", network.ProSettings.DefaultAccessLevel))
-						}
-					}
-				}
-			}
-		}
-
-		if err := AdjustGroupPermissions(currentUser); err != nil {
-			logger.Log(0, "failed to update user", currentUser.UserName, "after group update", err.Error())
-		}
-		// == END PRO ==
-
-		currentUser.Networks = newNetworks
+		adjustUserNetworks(currentUser, newNetworks, newGroups)
 	}
 
 	userChange := models.User{
@@ -255,6 +254,48 @@ func UpdateUserNetworks(newNetworks, newGroups []string, isadmin bool, currentUs
 	return err
 }
 
+// adjustUserNetworks - sets the networks and groups of a non admin user, making it net admin of networks
+// it was added to and dropping it to the default access level of networks it was removed from
+func adjustUserNetworks(currentUser *models.ReturnUser, newNetworks, newGroups []string) {
+	// == PRO ==
+	currentUser.Groups = newGroups
+	for _, n := range newNetworks {
+		if !StringSliceContains(currentUser.Networks, n) {
+			// make net admin of any network not previously assigned
+			pro.MakeNetAdmin(n, currentUser.UserName)
+		}
+	}
+	// Compare networks, find networks not in previous
+	for _, n := range currentUser.Networks {
+		if !StringSliceContains(newNetworks, n) {
+			// if user was removed from a network, re-assign access to net default level
+			if network, err := GetNetwork(n); err == nil {
+				if network.ProSettings != nil {
+					ok := pro.AssignAccessLvl(n, currentUser.UserName, network.ProSettings.DefaultAccessLevel)
+					if ok {
+						logger.Log(0, "changed", currentUser.UserName, "access level on network", network.NetID, "to", fmt.Sprintf("%d", network.ProSettings.DefaultAccessLevel))
+					}
+				}
+			}
+		}
+	}
+
+	if err := AdjustGroupPermissions(currentUser); err != nil {
+		logger.Log(0, "failed to update user", currentUser.UserName, "after group update", err.Error())
+	}
+	// == END PRO ==
+	currentUser.Networks = newNetworks
+}
+
+// saveUser - stores a user record as is, unlike UpdateUser it can clear the networks and groups of a user
+func saveUser(user *models.User) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	return database.Insert(user.UserName, string(data), database.USERS_TABLE_NAME)
+}
+
 // UpdateUser - updates a given user
 func UpdateUser(userchange, user *models.User) (*models.User, error) {
 	// check if user exists
@@ -273,7 +314,12 @@ func UpdateUser(userchange, user *models.User) (*models.User, error) {
 	if len(userchange.Groups) > 0 {
 		user.Groups = userchange.Groups
 	}
+	previousHash := ""
 	if userchange.Password != "" {
+		if err := checkNewPassword(user, userchange.Password); err != nil {
+			return &models.User{}, err
+		}
+		previousHash = user.Password
 		// encrypt that password so we never see it again
 		hash, err := bcrypt.GenerateFromPassword([]byte(userchange.Password), 5)
 
@@ -305,6 +351,7 @@ func UpdateUser(userchange, user *models.User) (*models.User, error) {
 	if err = database.Insert(user.UserName, string(data), database.USERS_TABLE_NAME); err != nil {
 		return &models.User{}, err
 	}
+	recordPasswordHistory(user.UserName, previousHash)
 	logger.Log(1, "updated user", queryUser)
 	return user, nil
 }
@@ -339,6 +386,26 @@ func DeleteUser(user string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	if err = DeleteUserAccessTokens(user); err != nil {
+		logger.Log(0, "failed to revoke access tokens of user", user, err.Error())
+	}
+	if err = DeleteUserRoleBindings(user); err != nil {
+		logger.Log(0, "failed to delete role bindings of user", user, err.Error())
+	}
+	if err = DeleteUserSessions(user); err != nil {
+		logger.Log(0, "failed to end sessions of user", user, err.Error())
+	}
+	if _, err = GetUserMFA(user); err == nil {
+		if err = DisableUserMFA(user); err != nil {
+			logger.Log(0, "failed to remove mfa of user", user, err.Error())
+		}
+	}
+	if err = database.DeleteRecord(database.SCIM_USERS_TABLE_NAME, user); err != nil && !database.IsEmptyRecord(err) {
+		logger.Log(0, "failed to remove scim state of user", user, err.Error())
+	}
+	deletePasswordRecords(user)
+	ClearLoginLockout(user)
+	deleteSSOUserLinks(user)
 
 	// == pro - remove user from all network user instances ==
 	currentNets, err := GetNetworks()
@@ -392,10 +459,11 @@ func GetState(state string) (*models.SsoState, error) {
 	return &s, nil
 }
 
-// SetState - sets a state with new expiration
-func SetState(state string) error {
+// SetState - sets a state of a sign-in through an sso provider with new expiration
+func SetState(state, provider string) error {
 	s := models.SsoState{
 		Value:      state,
+		Provider:   provider,
 		Expiration: time.Now().Add(models.DefaultExpDuration),
 	}
 
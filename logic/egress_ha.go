@@ -0,0 +1,219 @@
+package logic
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+)
+
+// EGRESS_HA_CHECKIN_TIMEOUT - time without a check-in after which an egress gateway is considered down
+const EGRESS_HA_CHECKIN_TIMEOUT = time.Minute * 5
+
+var (
+	egressHAMutex = &sync.Mutex{}
+	// activeEgressGateways - network -> advertised egress range -> id of the node currently routing the range
+	activeEgressGateways = make(map[string]map[string]string)
+	// egressGatewayConnectivity - node id -> whether the last metrics of an egress gateway showed a connected peer,
+	// refreshed when the gateway itself checks in
+	egressGatewayConnectivity = make(map[string]bool)
+)
+
+// IsEgressGatewayHealthy - checks check-in and metrics data of an egress gateway node
+func IsEgressGatewayHealthy(node *models.Node) bool {
+	egressHAMutex.Lock()
+	defer egressHAMutex.Unlock()
+	return isEgressGatewayHealthy(node)
+}
+
+// RefreshEgressGatewayHealth - re-reads the metrics of an egress gateway that checked in or reported metrics,
+// the metrics of the other gateways are not read again when the selection is updated
+func RefreshEgressGatewayHealth(node *models.Node) {
+	if !node.IsEgressGateway {
+		forgetEgressGateway(node.ID.String())
+		return
+	}
+	connected := hasEgressConnectivity(node.ID.String())
+	egressHAMutex.Lock()
+	defer egressHAMutex.Unlock()
+	egressGatewayConnectivity[node.ID.String()] = connected
+}
+
+// isEgressGatewayHealthy - checks the health of a gateway, the caller holds egressHAMutex
+func isEgressGatewayHealthy(node *models.Node) bool {
+	if !node.IsEgressGateway || !node.Connected || node.PendingDelete || node.Action == models.NODE_DELETE {
+		return false
+	}
+	if time.Since(node.LastCheckIn) > EGRESS_HA_CHECKIN_TIMEOUT {
+		return false
+	}
+	connected, ok := egressGatewayConnectivity[node.ID.String()]
+	if !ok {
+		// not checked in since the server started
+		connected = hasEgressConnectivity(node.ID.String())
+		egressGatewayConnectivity[node.ID.String()] = connected
+	}
+	return connected
+}
+
+// hasEgressConnectivity - checks the metrics of a gateway for a connected peer
+func hasEgressConnectivity(nodeID string) bool {
+	metrics, err := GetMetrics(nodeID)
+	if err != nil || metrics == nil || len(metrics.Connectivity) == 0 {
+		return true // no metrics collected, rely on check-in only
+	}
+	for _, metric := range metrics.Connectivity {
+		if metric.Connected {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActiveEgressGateway - checks if the given node is the gateway selected to route the given advertised range.
+// Only reads the selection kept up to date by UpdateEgressHA, it runs for every peer of a peer update.
+func IsActiveEgressGateway(node *models.Node, iprange string) bool {
+	egressHAMutex.Lock()
+	defer egressHAMutex.Unlock()
+	selection, ok := activeEgressGateways[node.Network]
+	if !ok {
+		logger.Log(3, "no egress gateway selection for network", node.Network, "yet, advertising", iprange, "through every gateway")
+		return true
+	}
+	activeID, ok := selection[iprange]
+	if !ok {
+		return true
+	}
+	return activeID == node.ID.String()
+}
+
+// InitEgressHA - selects the active egress gateways of every network, called on server start
+func InitEgressHA() {
+	networks, err := GetNetworks()
+	if err != nil && !database.IsEmptyRecord(err) {
+		logger.Log(0, "failed to select egress gateways:", err.Error())
+		return
+	}
+	for _, network := range networks {
+		if _, err = UpdateEgressHA(network.NetID); err != nil {
+			logger.Log(0, "failed to select egress gateways of network", network.NetID, err.Error())
+		}
+	}
+}
+
+// egressCandidate - a gateway advertising a range with its priority for the range
+type egressCandidate struct {
+	node     models.Node
+	priority int
+}
+
+// UpdateEgressHA - (re)selects the active gateway for every egress range of a network
+// returns true if any range moved to a different gateway
+func UpdateEgressHA(network string) (bool, error) {
+	// the selection is read, computed and stored under one lock so concurrent check-ins can't overwrite each other
+	egressHAMutex.Lock()
+	defer egressHAMutex.Unlock()
+	nodes, err := GetNetworkNodes(network)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return false, err
+	}
+	candidates := make(map[string][]egressCandidate)
+	for _, node := range nodes {
+		if !node.IsEgressGateway {
+			continue
+		}
+		for _, iprange := range node.EgressGatewayRanges {
+			if isInternetGwRange(iprange) {
+				continue // internet gateways serve their own clients, they don't take over for each other
+			}
+			rangeConfig := node.EgressGatewayRequest.GetRangeConfig(iprange)
+			advertised := rangeConfig.AdvertisedRange()
+			candidates[advertised] = append(candidates[advertised], egressCandidate{node: node, priority: rangeConfig.Priority})
+		}
+	}
+	// health is checked once per gateway, not once per range
+	health := make(map[string]bool)
+	isHealthy := func(node *models.Node) bool {
+		healthy, ok := health[node.ID.String()]
+		if !ok {
+			healthy = isEgressGatewayHealthy(node)
+			health[node.ID.String()] = healthy
+		}
+		return healthy
+	}
+	previous := activeEgressGateways[network]
+	selection := make(map[string]string, len(candidates))
+	changed := false
+	for iprange, gateways := range candidates {
+		selection[iprange] = selectEgressGateway(gateways, previous[iprange], isHealthy)
+		if previous != nil && previous[iprange] != selection[iprange] {
+			logger.Log(0, "egress range", iprange, "on network", network, "moved from gateway", previous[iprange], "to", selection[iprange])
+			changed = true
+		}
+	}
+	for iprange := range previous {
+		if _, ok := selection[iprange]; !ok {
+			changed = true
+		}
+	}
+	activeEgressGateways[network] = selection
+	return changed, nil
+}
+
+// forgetEgressGateway - drops the cached health of a deleted gateway
+func forgetEgressGateway(nodeID string) {
+	egressHAMutex.Lock()
+	defer egressHAMutex.Unlock()
+	delete(egressGatewayConnectivity, nodeID)
+}
+
+// ResetEgressHA - drops the cached egress gateway selection of a network
+func ResetEgressHA(network string) {
+	egressHAMutex.Lock()
+	defer egressHAMutex.Unlock()
+	delete(activeEgressGateways, network)
+}
+
+// selectEgressGateway - picks the healthy gateway with the highest priority for a range,
+// the current gateway is kept while no healthy gateway with a higher priority exists
+func selectEgressGateway(gateways []egressCandidate, current string, isHealthy func(*models.Node) bool) string {
+	if len(gateways) == 0 {
+		return ""
+	}
+	sort.SliceStable(gateways, func(i, j int) bool {
+		if gateways[i].priority != gateways[j].priority {
+			return gateways[i].priority > gateways[j].priority
+		}
+		return gateways[i].node.ID.String() < gateways[j].node.ID.String()
+	})
+	if len(gateways) == 1 {
+		return gateways[0].node.ID.String()
+	}
+	var best *egressCandidate
+	for i := range gateways {
+		if isHealthy(&gateways[i].node) {
+			best = &gateways[i]
+			break
+		}
+	}
+	if best == nil {
+		// every gateway is down, keep routing through the current one until one recovers
+		for i := range gateways {
+			if gateways[i].node.ID.String() == current {
+				return current
+			}
+		}
+		return gateways[0].node.ID.String()
+	}
+	for i := range gateways {
+		if gateways[i].node.ID.String() == current &&
+			gateways[i].priority == best.priority &&
+			isHealthy(&gateways[i].node) {
+			return current
+		}
+	}
+	return best.node.ID.String()
+}
@@ -0,0 +1,154 @@
+package logic
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestSelectEgressGateway(t *testing.T) {
+	newGateway := func(priority int) egressCandidate {
+		node := models.Node{}
+		node.ID = uuid.New()
+		return egressCandidate{node: node, priority: priority}
+	}
+	primary := newGateway(100)
+	standby := newGateway(50)
+	peer := newGateway(50)
+	down := map[string]bool{}
+	isHealthy := func(node *models.Node) bool {
+		return !down[node.ID.String()]
+	}
+	t.Run("highest priority wins", func(t *testing.T) {
+		is := is.New(t)
+		gateways := []egressCandidate{standby, primary}
+		is.Equal(selectEgressGateway(gateways, "", isHealthy), primary.node.ID.String())
+	})
+	t.Run("failover to standby", func(t *testing.T) {
+		is := is.New(t)
+		down[primary.node.ID.String()] = true
+		defer delete(down, primary.node.ID.String())
+		gateways := []egressCandidate{primary, standby}
+		is.Equal(selectEgressGateway(gateways, primary.node.ID.String(), isHealthy), standby.node.ID.String())
+	})
+	t.Run("sticky among equal priorities", func(t *testing.T) {
+		is := is.New(t)
+		down[primary.node.ID.String()] = true
+		defer delete(down, primary.node.ID.String())
+		gateways := []egressCandidate{primary, standby, peer}
+		is.Equal(selectEgressGateway(gateways, peer.node.ID.String(), isHealthy), peer.node.ID.String())
+		is.Equal(selectEgressGateway(gateways, standby.node.ID.String(), isHealthy), standby.node.ID.String())
+	})
+	t.Run("preempt when primary recovers", func(t *testing.T) {
+		is := is.New(t)
+		gateways := []egressCandidate{primary, standby}
+		is.Equal(selectEgressGateway(gateways, standby.node.ID.String(), isHealthy), primary.node.ID.String())
+	})
+	t.Run("all down keeps current", func(t *testing.T) {
+		is := is.New(t)
+		down[primary.node.ID.String()] = true
+		down[standby.node.ID.String()] = true
+		defer delete(down, primary.node.ID.String())
+		defer delete(down, standby.node.ID.String())
+		gateways := []egressCandidate{primary, standby}
+		is.Equal(selectEgressGateway(gateways, standby.node.ID.String(), isHealthy), standby.node.ID.String())
+	})
+}
+
+func TestEgressRangePriority(t *testing.T) {
+	is := is.New(t)
+	gateway := models.EgressGatewayRequest{
+		Ranges:   []string{"10.10.0.0/16", "10.20.0.0/16", "10.30.0.0/16"},
+		Priority: 50,
+		RangesWithConfig: []models.EgressRangeConfig{
+			{Range: "10.10.0.0/16", NatEnabled: "yes", Priority: 100},
+			{Range: "10.20.0.0/16", NatEnabled: "yes"},
+		},
+	}
+	is.Equal(gateway.GetRangeConfig("10.10.0.0/16").Priority, 100)
+	is.Equal(gateway.GetRangeConfig("10.20.0.0/16").Priority, 50) // no priority of its own
+	is.Equal(gateway.GetRangeConfig("10.30.0.0/16").Priority, 50) // no config
+	gateway.NodeID = uuid.NewString()
+	gateway.RangesWithConfig[1].Priority = -1
+	is.True(ValidateEgressGateway(gateway) != nil)
+}
+
+func TestUpdateEgressHA(t *testing.T) {
+	database.InitializeDatabase()
+	// the gateways are removed by cleanups, which run after deferred calls
+	t.Cleanup(database.CloseDB)
+	network := "egresshanet"
+	defer ResetEgressHA(network)
+	newGateway := func(priority int) models.Node {
+		node := models.Node{}
+		node.ID = uuid.New()
+		node.Network = network
+		node.Connected = true
+		node.LastCheckIn = time.Now()
+		node.IsEgressGateway = true
+		node.EgressGatewayRanges = []string{"10.40.0.0/16"}
+		node.EgressGatewayRequest = models.EgressGatewayRequest{Ranges: node.EgressGatewayRanges, Priority: priority}
+		if err := upsertNode(&node); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			database.DeleteRecord(database.NODES_TABLE_NAME, node.ID.String())
+			DeleteMetrics(node.ID.String())
+			forgetEgressGateway(node.ID.String())
+		})
+		return node
+	}
+	primary := newGateway(100)
+	standby := newGateway(50)
+
+	t.Run("initial selection", func(t *testing.T) {
+		is := is.New(t)
+		_, err := UpdateEgressHA(network)
+		is.NoErr(err)
+		is.True(IsActiveEgressGateway(&primary, "10.40.0.0/16"))
+		is.True(!IsActiveEgressGateway(&standby, "10.40.0.0/16"))
+	})
+	t.Run("metrics of other gateways are read on their own check-in", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(UpdateMetrics(primary.ID.String(), &models.Metrics{Connectivity: map[string]models.Metric{
+			uuid.NewString(): {Connected: false},
+		}}))
+		changed, err := UpdateEgressHA(network)
+		is.NoErr(err)
+		is.True(!changed)
+		is.True(IsActiveEgressGateway(&primary, "10.40.0.0/16"))
+		RefreshEgressGatewayHealth(&primary)
+		changed, err = UpdateEgressHA(network)
+		is.NoErr(err)
+		is.True(changed)
+		is.True(IsActiveEgressGateway(&standby, "10.40.0.0/16"))
+	})
+	t.Run("concurrent updates report a change once", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(DeleteMetrics(primary.ID.String()))
+		RefreshEgressGatewayHealth(&primary)
+		var wg sync.WaitGroup
+		var mutex sync.Mutex
+		changes := 0
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				changed, err := UpdateEgressHA(network)
+				if err == nil && changed {
+					mutex.Lock()
+					changes++
+					mutex.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		is.Equal(changes, 1)
+		is.True(IsActiveEgressGateway(&primary, "10.40.0.0/16"))
+	})
+}
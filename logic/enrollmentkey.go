@@ -1,7 +1,9 @@
 package logic
 
 import (
+	"crypto/sha256"
 	b64 "encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -237,3 +239,9 @@ func UserHasNetworksAccess(networks []string, u *models.User) bool {
 	}
 	return true
 }
+
+// enrollmentKeyRef - identifies an enrollment key in alerts and the audit log without revealing its value
+func enrollmentKeyRef(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "key-" + hex.EncodeToString(sum[:8])
+}
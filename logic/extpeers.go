@@ -59,8 +59,11 @@ func GetEgressRangesOnNetwork(client *models.ExtClient) ([]string, error) {
 			continue
 		}
 		if currentNode.IsEgressGateway { // add the egress gateway range(s) to the result
-			if len(currentNode.EgressGatewayRanges) > 0 {
-				result = append(result, currentNode.EgressGatewayRanges...)
+			for _, iprange := range currentNode.EgressGatewayRanges {
+				if currentNode.EgressGatewayRequest.IsRangeAdvertisedTo(iprange, client.ClientID, nil) {
+					rangeConfig := currentNode.EgressGatewayRequest.GetRangeConfig(iprange)
+					result = append(result, rangeConfig.AdvertisedRange())
+				}
 			}
 		}
 	}
@@ -70,6 +73,13 @@ func GetEgressRangesOnNetwork(client *models.ExtClient) ([]string, error) {
 
 // DeleteExtClient - deletes an existing ext client
 func DeleteExtClient(network string, clientid string) error {
+	if err := replaceInternetGwClient(network, clientid, ""); err != nil {
+		logger.Log(1, "failed to remove ext client", clientid, "from its internet gateway", err.Error())
+	}
+	return deleteExtClientRecord(network, clientid)
+}
+
+func deleteExtClientRecord(network string, clientid string) error {
 	key, err := GetRecordKey(clientid, network)
 	if err != nil {
 		return err
@@ -196,7 +206,8 @@ func SaveExtClient(extclient *models.ExtClient) error {
 // UpdateExtClient - updates an ext client with new values
 func UpdateExtClient(old *models.ExtClient, update *models.CustomExtClient) (*models.ExtClient, error) {
 	new := old
-	err := DeleteExtClient(old.Network, old.ClientID)
+	oldClientID := old.ClientID
+	err := deleteExtClientRecord(old.Network, old.ClientID)
 	if err != nil {
 		return new, err
 	}
@@ -213,7 +224,15 @@ func UpdateExtClient(old *models.ExtClient, update *models.CustomExtClient) (*mo
 	if update.ExtraAllowedIPs != nil && StringDifference(old.ExtraAllowedIPs, update.ExtraAllowedIPs) != nil {
 		new.ExtraAllowedIPs = update.ExtraAllowedIPs
 	}
-	return new, CreateExtClient(new)
+	if err := CreateExtClient(new); err != nil {
+		return new, err
+	}
+	if new.ClientID != oldClientID {
+		if err := replaceInternetGwClient(new.Network, oldClientID, new.ClientID); err != nil {
+			return new, err
+		}
+	}
+	return new, nil
 }
 
 // GetExtClientsByID - gets the clients of attached gateway
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/gravitl/netmaker/database"
@@ -44,10 +45,19 @@ func CreateEgressGateway(gateway models.EgressGatewayRequest) (models.Node, erro
 	if gateway.NatEnabled == "" {
 		gateway.NatEnabled = "yes"
 	}
+	if err = normalizeEgressRangeConfigs(&gateway); err != nil {
+		return models.Node{}, err
+	}
+	if node.IsInternetGateway() {
+		keepInternetGwRanges(&node, &gateway)
+	}
 	err = ValidateEgressGateway(gateway)
 	if err != nil {
 		return models.Node{}, err
 	}
+	if err = validateEgressRangesOnNetwork(&node, gateway); err != nil {
+		return models.Node{}, err
+	}
 	node.IsEgressGateway = true
 	node.EgressGatewayRanges = gateway.Ranges
 	node.EgressGatewayNatEnabled = models.ParseBool(gateway.NatEnabled)
@@ -60,6 +70,9 @@ func CreateEgressGateway(gateway models.EgressGatewayRequest) (models.Node, erro
 	if err = database.Insert(node.ID.String(), string(nodeData), database.NODES_TABLE_NAME); err != nil {
 		return models.Node{}, err
 	}
+	if _, err = UpdateEgressHA(node.Network); err != nil {
+		logger.Log(1, "failed to update egress gateway selection for network", node.Network, err.Error())
+	}
 	return node, nil
 }
 
@@ -71,7 +84,180 @@ func ValidateEgressGateway(gateway models.EgressGatewayRequest) error {
 	if empty {
 		err = errors.New("IP Ranges Cannot Be Empty")
 	}
-	return err
+	if gateway.Priority < 0 {
+		err = errors.New("egress gateway priority cannot be negative")
+	}
+	if err != nil {
+		return err
+	}
+	configured := make(map[string]struct{}, len(gateway.RangesWithConfig))
+	for _, rangeConfig := range gateway.RangesWithConfig {
+		if _, _, err := net.ParseCIDR(rangeConfig.Range); err != nil {
+			return fmt.Errorf("invalid egress range %s: %w", rangeConfig.Range, err)
+		}
+		if _, ok := configured[rangeConfig.Range]; ok {
+			return fmt.Errorf("egress range %s is configured more than once", rangeConfig.Range)
+		}
+		configured[rangeConfig.Range] = struct{}{}
+		if !StringSliceContains(gateway.Ranges, rangeConfig.Range) {
+			return fmt.Errorf("egress range %s is not part of the gateway ranges", rangeConfig.Range)
+		}
+		if rangeConfig.NatEnabled != "yes" && rangeConfig.NatEnabled != "no" {
+			return fmt.Errorf("natenabled of egress range %s must be yes or no", rangeConfig.Range)
+		}
+		if rangeConfig.Priority < 0 {
+			return fmt.Errorf("priority of egress range %s cannot be negative", rangeConfig.Range)
+		}
+		if err := validateEgressVirtualRange(rangeConfig); err != nil {
+			return err
+		}
+		for _, id := range rangeConfig.AdvertiseTo {
+			if id == "" {
+				return fmt.Errorf("egress range %s has an empty advertise filter entry", rangeConfig.Range)
+			}
+			if id == gateway.NodeID {
+				return fmt.Errorf("egress range %s cannot be advertised to its own gateway", rangeConfig.Range)
+			}
+		}
+		for _, tag := range rangeConfig.AdvertiseToTags {
+			if tag == "" {
+				return fmt.Errorf("egress range %s has an empty advertise tag", rangeConfig.Range)
+			}
+		}
+	}
+	return nil
+}
+
+// validateEgressVirtualRange - a virtual range must be a prefix of the same family and size as the range it maps to
+func validateEgressVirtualRange(rangeConfig models.EgressRangeConfig) error {
+	if rangeConfig.VirtualRange == "" {
+		return nil
+	}
+	_, realNet, err := net.ParseCIDR(rangeConfig.Range)
+	if err != nil {
+		return err
+	}
+	_, virtualNet, err := net.ParseCIDR(rangeConfig.VirtualRange)
+	if err != nil {
+		return fmt.Errorf("invalid virtual range %s: %w", rangeConfig.VirtualRange, err)
+	}
+	realOnes, realBits := realNet.Mask.Size()
+	virtualOnes, virtualBits := virtualNet.Mask.Size()
+	if realOnes != virtualOnes || realBits != virtualBits {
+		return fmt.Errorf("virtual range %s must have the same size as egress range %s", rangeConfig.VirtualRange, rangeConfig.Range)
+	}
+	if realNet.Contains(virtualNet.IP) || virtualNet.Contains(realNet.IP) {
+		return fmt.Errorf("virtual range %s overlaps egress range %s", rangeConfig.VirtualRange, rangeConfig.Range)
+	}
+	return nil
+}
+
+// validateEgressRangesOnNetwork - checks the advertised ranges of an egress gateway against the network,
+// a range may only be advertised by several gateways if they route it to the same subnet (high availability)
+func validateEgressRangesOnNetwork(node *models.Node, gateway models.EgressGatewayRequest) error {
+	network, err := GetNetwork(node.Network)
+	if err != nil {
+		return err
+	}
+	nodes, err := GetNetworkNodes(node.Network)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return err
+	}
+	for i, rangeConfig := range gateway.RangesWithConfig {
+		if isInternetGwRange(rangeConfig.Range) {
+			continue // default routes overlap everything, they are limited to the clients of the internet gateway
+		}
+		advertised := rangeConfig.AdvertisedRange()
+		kind := "egress range"
+		if rangeConfig.VirtualRange != "" {
+			kind = "virtual range"
+		}
+		_, advertisedNet, err := net.ParseCIDR(advertised)
+		if err != nil {
+			return err
+		}
+		for _, addressRange := range []string{network.AddressRange, network.AddressRange6} {
+			if _, networkNet, err := net.ParseCIDR(addressRange); err == nil && cidrsOverlap(networkNet, advertisedNet) {
+				return fmt.Errorf("%s %s overlaps network range %s", kind, advertised, addressRange)
+			}
+		}
+		for _, other := range gateway.RangesWithConfig[i+1:] {
+			if isInternetGwRange(other.Range) {
+				continue
+			}
+			if _, otherNet, err := net.ParseCIDR(other.AdvertisedRange()); err == nil && cidrsOverlap(otherNet, advertisedNet) {
+				return fmt.Errorf("%s %s overlaps range %s of the same gateway", kind, advertised, other.AdvertisedRange())
+			}
+		}
+		for i := range nodes {
+			if nodes[i].ID == node.ID || !nodes[i].IsEgressGateway {
+				continue
+			}
+			for _, iprange := range nodes[i].EgressGatewayRanges {
+				if isInternetGwRange(iprange) {
+					continue
+				}
+				other := nodes[i].EgressGatewayRequest.GetRangeConfig(iprange)
+				if other.AdvertisedRange() == advertised && other.Range == rangeConfig.Range {
+					continue // same mapping on another gateway
+				}
+				if _, otherNet, err := net.ParseCIDR(other.AdvertisedRange()); err == nil && cidrsOverlap(otherNet, advertisedNet) {
+					return fmt.Errorf("%s %s overlaps range %s advertised by node %s", kind, advertised, other.AdvertisedRange(), nodes[i].ID.String())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func cidrsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// normalizeEgressRangeConfigs - merges the plain ranges and the per range settings of an egress request,
+// so every range ends up with exactly one config
+func normalizeEgressRangeConfigs(gateway *models.EgressGatewayRequest) error {
+	configs := []models.EgressRangeConfig{}
+	for _, rangeConfig := range gateway.RangesWithConfig {
+		if rangeConfig.Range == "::/0" {
+			logger.Log(0, "currently IPv6 internet gateways are not supported", rangeConfig.Range)
+			continue
+		}
+		normalized, err := NormalizeCIDR(rangeConfig.Range)
+		if err != nil {
+			return err
+		}
+		rangeConfig.Range = normalized
+		if rangeConfig.VirtualRange != "" {
+			if rangeConfig.VirtualRange, err = NormalizeCIDR(rangeConfig.VirtualRange); err != nil {
+				return err
+			}
+		}
+		if rangeConfig.NatEnabled == "" {
+			rangeConfig.NatEnabled = gateway.NatEnabled
+		}
+		if !StringSliceContains(gateway.Ranges, normalized) {
+			gateway.Ranges = append(gateway.Ranges, normalized)
+		}
+		configs = append(configs, rangeConfig)
+	}
+	gateway.RangesWithConfig = configs
+	for _, iprange := range gateway.Ranges {
+		configured := false
+		for _, rangeConfig := range configs {
+			if rangeConfig.Range == iprange {
+				configured = true
+				break
+			}
+		}
+		if !configured {
+			gateway.RangesWithConfig = append(gateway.RangesWithConfig, models.EgressRangeConfig{
+				Range:      iprange,
+				NatEnabled: gateway.NatEnabled,
+			})
+		}
+	}
+	return nil
 }
 
 // DeleteEgressGateway - deletes egress from node
@@ -80,6 +266,9 @@ func DeleteEgressGateway(network, nodeid string) (models.Node, error) {
 	if err != nil {
 		return models.Node{}, err
 	}
+	if node.IsInternetGateway() {
+		releaseInternetGwClients(&node, nil)
+	}
 	node.IsEgressGateway = false
 	node.EgressGatewayRanges = []string{}
 	node.EgressGatewayRequest = models.EgressGatewayRequest{} // remove preserved request as the egress gateway is gone
@@ -92,6 +281,9 @@ func DeleteEgressGateway(network, nodeid string) (models.Node, error) {
 	if err = database.Insert(node.ID.String(), string(data), database.NODES_TABLE_NAME); err != nil {
 		return models.Node{}, err
 	}
+	if _, err = UpdateEgressHA(node.Network); err != nil {
+		logger.Log(1, "failed to update egress gateway selection for network", node.Network, err.Error())
+	}
 	return node, nil
 }
 
@@ -202,3 +394,259 @@ func DeleteGatewayExtClients(gatewayID string, networkName string) error {
 	}
 	return nil
 }
+
+// ValidateInetGwReq - validates the nodes and ext clients which are to route all of their traffic through a node
+func ValidateInetGwReq(inetNode models.Node, clientIDs []string, update bool) error {
+	host, err := GetHost(inetNode.HostID.String())
+	if err != nil {
+		return err
+	}
+	if host.OS != models.OS_Types.Linux {
+		return errors.New(host.OS + " is unsupported for internet gateways")
+	}
+	if host.FirewallInUse == models.FIREWALL_NONE {
+		return errors.New("internet gateways require a supported firewall on the host")
+	}
+	if inetNode.IsInternetGateway() && !update {
+		return errors.New("node is already an internet gateway")
+	}
+	if inetNode.InternetGateway != nil && inetNode.InternetGateway.IP != nil {
+		return errors.New("node is using an internet gateway, cannot become one")
+	}
+	// an empty advertise filter would route the internet traffic of every peer through the gateway
+	if len(clientIDs) == 0 {
+		return errors.New("internet gateway needs at least one node or ext client")
+	}
+	gwAddr := internetGwAddr(&inetNode)
+	for _, clientID := range clientIDs {
+		clientNode, err := GetNodeByID(clientID)
+		if err != nil {
+			client, err := GetExtClient(clientID, inetNode.Network)
+			if err != nil {
+				return fmt.Errorf("%s is neither a node nor an ext client on network %s", clientID, inetNode.Network)
+			}
+			// ext client traffic is forwarded by its ingress gateway, which has to be the internet gateway
+			if client.IngressGatewayID != inetNode.ID.String() {
+				return fmt.Errorf("ext client %s is not attached to internet gateway %s", clientID, inetNode.ID.String())
+			}
+			continue
+		}
+		if clientNode.Network != inetNode.Network {
+			return fmt.Errorf("node %s is not on network %s", clientID, inetNode.Network)
+		}
+		if clientNode.ID == inetNode.ID {
+			return errors.New("internet gateway cannot route through itself")
+		}
+		if clientNode.IsInternetGateway() {
+			return fmt.Errorf("node %s is an internet gateway", clientID)
+		}
+		if clientNode.InternetGateway != nil && clientNode.InternetGateway.IP != nil &&
+			!clientNode.InternetGateway.IP.Equal(gwAddr.IP) {
+			return fmt.Errorf("node %s is already using internet gateway %s", clientID, clientNode.InternetGateway.IP.String())
+		}
+	}
+	return nil
+}
+
+// SetInternetGw - makes a node an internet gateway, the default routes become egress ranges of the node
+// which are only advertised to the given nodes and ext clients
+func SetInternetGw(node *models.Node, clientIDs []string) error {
+	releaseInternetGwClients(node, clientIDs)
+	if !node.IsEgressGateway {
+		node.IsEgressGateway = true
+		node.EgressGatewayNatEnabled = true
+		node.EgressGatewayRanges = []string{}
+		node.EgressGatewayRequest = models.EgressGatewayRequest{
+			NodeID:     node.ID.String(),
+			NetID:      node.Network,
+			NatEnabled: "yes",
+		}
+	}
+	removeInternetGwRanges(node)
+	for _, inetRange := range internetGwRanges {
+		node.EgressGatewayRanges = append(node.EgressGatewayRanges, inetRange)
+		node.EgressGatewayRequest.Ranges = append(node.EgressGatewayRequest.Ranges, inetRange)
+		node.EgressGatewayRequest.RangesWithConfig = append(node.EgressGatewayRequest.RangesWithConfig, models.EgressRangeConfig{
+			Range:       inetRange,
+			NatEnabled:  "yes",
+			AdvertiseTo: append([]string{}, clientIDs...),
+		})
+	}
+	node.SetLastModified()
+	if err := upsertNode(node); err != nil {
+		return err
+	}
+	gwAddr := internetGwAddr(node)
+	for _, clientID := range clientIDs {
+		if _, err := GetNodeByID(clientID); err != nil {
+			continue // ext clients route through their ingress gateway
+		}
+		if err := setNodeInternetGw(clientID, gwAddr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnsetInternetGw - removes the default routes from a node's egress ranges and releases its clients
+func UnsetInternetGw(node *models.Node) error {
+	releaseInternetGwClients(node, nil)
+	removeInternetGwRanges(node)
+	if len(node.EgressGatewayRanges) == 0 {
+		node.IsEgressGateway = false
+		node.EgressGatewayNatEnabled = false
+		node.EgressGatewayRequest = models.EgressGatewayRequest{}
+	}
+	node.SetLastModified()
+	return upsertNode(node)
+}
+
+// GetInternetGwClients - returns the ids of the nodes and ext clients routing their traffic through an internet gateway
+func GetInternetGwClients(node *models.Node) []string {
+	if !node.IsInternetGateway() {
+		return []string{}
+	}
+	for _, inetRange := range internetGwRanges {
+		if StringSliceContains(node.EgressGatewayRanges, inetRange) {
+			return node.EgressGatewayRequest.GetRangeConfig(inetRange).AdvertiseTo
+		}
+	}
+	return []string{}
+}
+
+// internetGwRanges - the default routes routed through an internet gateway
+var internetGwRanges = []string{models.IPV4_DEFAULT_ROUTE, models.IPV6_DEFAULT_ROUTE}
+
+func isInternetGwRange(iprange string) bool {
+	return StringSliceContains(internetGwRanges, iprange)
+}
+
+// internetGwAddr - the address client nodes store as their internet gateway
+func internetGwAddr(node *models.Node) *net.UDPAddr {
+	return &net.UDPAddr{IP: net.ParseIP(node.PrimaryAddress())}
+}
+
+// removeInternetGwRanges - drops the default routes from a node's egress ranges
+func removeInternetGwRanges(node *models.Node) {
+	ranges := []string{}
+	for _, iprange := range node.EgressGatewayRanges {
+		if !isInternetGwRange(iprange) {
+			ranges = append(ranges, iprange)
+		}
+	}
+	node.EgressGatewayRanges = ranges
+	requestRanges := []string{}
+	for _, iprange := range node.EgressGatewayRequest.Ranges {
+		if !isInternetGwRange(iprange) {
+			requestRanges = append(requestRanges, iprange)
+		}
+	}
+	node.EgressGatewayRequest.Ranges = requestRanges
+	configs := []models.EgressRangeConfig{}
+	for _, rangeConfig := range node.EgressGatewayRequest.RangesWithConfig {
+		if !isInternetGwRange(rangeConfig.Range) {
+			configs = append(configs, rangeConfig)
+		}
+	}
+	node.EgressGatewayRequest.RangesWithConfig = configs
+}
+
+// keepInternetGwRanges - the default routes of an internet gateway are managed through the internet gateway api,
+// so an egress request replacing the ranges of the gateway keeps them
+func keepInternetGwRanges(node *models.Node, gateway *models.EgressGatewayRequest) {
+	ranges := []string{}
+	for _, iprange := range gateway.Ranges {
+		if !isInternetGwRange(iprange) {
+			ranges = append(ranges, iprange)
+		}
+	}
+	configs := []models.EgressRangeConfig{}
+	for _, rangeConfig := range gateway.RangesWithConfig {
+		if !isInternetGwRange(rangeConfig.Range) {
+			configs = append(configs, rangeConfig)
+		}
+	}
+	for _, rangeConfig := range node.EgressGatewayRequest.RangesWithConfig {
+		if isInternetGwRange(rangeConfig.Range) {
+			ranges = append(ranges, rangeConfig.Range)
+			configs = append(configs, rangeConfig)
+		}
+	}
+	gateway.Ranges = ranges
+	gateway.RangesWithConfig = configs
+}
+
+// releaseInternetGwClients - clears the internet gateway of the client nodes which are not kept
+func releaseInternetGwClients(node *models.Node, keep []string) {
+	for _, clientID := range GetInternetGwClients(node) {
+		if StringSliceContains(keep, clientID) {
+			continue
+		}
+		if _, err := GetNodeByID(clientID); err != nil {
+			continue
+		}
+		if err := setNodeInternetGw(clientID, nil); err != nil {
+			logger.Log(1, "failed to remove internet gateway from node", clientID, err.Error())
+		}
+	}
+}
+
+func setNodeInternetGw(nodeID string, gateway *net.UDPAddr) error {
+	node, err := GetNodeByID(nodeID)
+	if err != nil {
+		return err
+	}
+	node.InternetGateway = gateway
+	node.SetLastModified()
+	return upsertNode(&node)
+}
+
+// replaceInternetGwClient - replaces a client of the internet gateways on a network, an empty newID removes it.
+// A gateway left without clients stops being an internet gateway, as an empty filter advertises to every peer
+func replaceInternetGwClient(network, oldID, newID string) error {
+	nodes, err := GetNetworkNodes(network)
+	if err != nil {
+		if database.IsEmptyRecord(err) {
+			return nil
+		}
+		return err
+	}
+	for i := range nodes {
+		node := nodes[i]
+		clients := GetInternetGwClients(&node)
+		if !StringSliceContains(clients, oldID) {
+			continue
+		}
+		clients = removeString(clients, oldID)
+		if newID != "" {
+			clients = append(clients, newID)
+		}
+		if len(clients) == 0 {
+			err = UnsetInternetGw(&node)
+		} else {
+			err = SetInternetGw(&node, clients)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func upsertNode(node *models.Node) error {
+	data, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+	return database.Insert(node.ID.String(), string(data), database.NODES_TABLE_NAME)
+}
+
+func removeString(slice []string, item string) []string {
+	result := []string{}
+	for _, s := range slice {
+		if s != item {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,145 @@
+package logic
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestInternetGateway(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	is := is.New(t)
+	network := models.Network{NetID: "inetgw", AddressRange: "10.99.0.0/24"}
+	data, err := json.Marshal(&network)
+	is.NoErr(err)
+	is.NoErr(database.Insert(network.NetID, string(data), database.NETWORKS_TABLE_NAME))
+	defer database.DeleteRecord(database.NETWORKS_TABLE_NAME, network.NetID)
+	newNode := func(address string) models.Node {
+		host := models.Host{
+			ID:            uuid.New(),
+			OS:            models.OS_Types.Linux,
+			FirewallInUse: models.FIREWALL_IPTABLES,
+			EndpointIP:    net.ParseIP("203.0.113.10"),
+		}
+		is.NoErr(UpsertHost(&host))
+		node := models.Node{}
+		node.ID = uuid.New()
+		node.HostID = host.ID
+		node.Network = network.NetID
+		node.Address = net.IPNet{IP: net.ParseIP(address), Mask: net.CIDRMask(32, 32)}
+		is.NoErr(upsertNode(&node))
+		return node
+	}
+	gateway := newNode("10.99.0.1")
+	client := newNode("10.99.0.2")
+	other := newNode("10.99.0.3")
+	for _, node := range []models.Node{gateway, client, other} {
+		node := node
+		defer deleteNodeByID(&node)
+		defer RemoveHostByID(node.HostID.String())
+	}
+	extClient := models.ExtClient{ClientID: "inetgw-client", Network: network.NetID, IngressGatewayID: gateway.ID.String()}
+	is.NoErr(SaveExtClient(&extClient))
+	defer deleteExtClientRecord(network.NetID, extClient.ClientID)
+
+	t.Run("validation", func(t *testing.T) {
+		is := is.New(t)
+		is.True(ValidateInetGwReq(gateway, []string{}, false) != nil)                    // empty filter advertises to everyone
+		is.True(ValidateInetGwReq(gateway, []string{gateway.ID.String()}, false) != nil) // routes through itself
+		is.True(ValidateInetGwReq(gateway, []string{"unknown"}, false) != nil)
+		is.True(ValidateInetGwReq(client, []string{extClient.ClientID}, false) != nil) // ext client attached elsewhere
+		is.NoErr(ValidateInetGwReq(gateway, []string{client.ID.String(), extClient.ClientID}, false))
+	})
+	t.Run("set", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(SetInternetGw(&gateway, []string{client.ID.String(), extClient.ClientID}))
+		gateway, err = GetNodeByID(gateway.ID.String())
+		is.NoErr(err)
+		is.True(gateway.IsInternetGateway())
+		is.Equal(GetInternetGwClients(&gateway), []string{client.ID.String(), extClient.ClientID})
+		client, err = GetNodeByID(client.ID.String())
+		is.NoErr(err)
+		is.True(client.InternetGateway != nil)
+		is.True(client.InternetGateway.IP.Equal(gateway.Address.IP))
+		is.True(ValidateInetGwReq(gateway, []string{client.ID.String()}, false) != nil) // already a gateway
+		is.True(ValidateInetGwReq(client, []string{other.ID.String()}, false) != nil)   // uses a gateway
+	})
+	t.Run("default routes in allowed ips", func(t *testing.T) {
+		is := is.New(t)
+		hasDefaultRoute := func(node *models.Node) bool {
+			for _, ipnet := range GetAllowedIPs(node, &gateway, nil) {
+				if ipnet.String() == models.IPV4_DEFAULT_ROUTE {
+					return true
+				}
+			}
+			return false
+		}
+		is.True(hasDefaultRoute(&client))
+		is.True(!hasDefaultRoute(&other))
+		ranges, err := GetEgressRangesOnNetwork(&extClient)
+		is.NoErr(err)
+		is.True(StringSliceContains(ranges, models.IPV4_DEFAULT_ROUTE))
+	})
+	t.Run("egress update keeps the default routes", func(t *testing.T) {
+		is := is.New(t)
+		var err error
+		gateway, err = CreateEgressGateway(models.EgressGatewayRequest{
+			NodeID: gateway.ID.String(),
+			NetID:  network.NetID,
+			Ranges: []string{"192.168.50.0/24"},
+		})
+		is.NoErr(err)
+		is.True(gateway.IsInternetGateway())
+		is.True(StringSliceContains(gateway.EgressGatewayRanges, "192.168.50.0/24"))
+		is.Equal(GetInternetGwClients(&gateway), []string{client.ID.String(), extClient.ClientID})
+	})
+	t.Run("ext client rename and delete", func(t *testing.T) {
+		is := is.New(t)
+		renamed, err := UpdateExtClient(&extClient, &models.CustomExtClient{ClientID: "inetgw-renamed"})
+		is.NoErr(err)
+		gateway, err = GetNodeByID(gateway.ID.String())
+		is.NoErr(err)
+		is.Equal(GetInternetGwClients(&gateway), []string{client.ID.String(), renamed.ClientID})
+		is.NoErr(DeleteExtClient(network.NetID, renamed.ClientID))
+		gateway, err = GetNodeByID(gateway.ID.String())
+		is.NoErr(err)
+		is.Equal(GetInternetGwClients(&gateway), []string{client.ID.String()})
+	})
+	t.Run("last client removed", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(replaceInternetGwClient(network.NetID, client.ID.String(), ""))
+		gateway, err = GetNodeByID(gateway.ID.String())
+		is.NoErr(err)
+		is.True(!gateway.IsInternetGateway())
+		is.True(gateway.IsEgressGateway) // the other egress range stays
+		client, err = GetNodeByID(client.ID.String())
+		is.NoErr(err)
+		is.True(client.InternetGateway == nil)
+	})
+}
+
+func TestEgressRouteMetrics(t *testing.T) {
+	is := is.New(t)
+	peer := models.Node{}
+	peer.IsEgressGateway = true
+	peer.EgressGatewayRanges = []string{"10.10.0.0/16", "10.20.0.0/16", "10.30.0.0/16"}
+	peer.EgressGatewayRequest = models.EgressGatewayRequest{
+		Ranges: peer.EgressGatewayRanges,
+		RangesWithConfig: []models.EgressRangeConfig{
+			{Range: "10.10.0.0/16", NatEnabled: "yes", Metric: 200},
+			{Range: "10.20.0.0/16", NatEnabled: "no", Metric: 300, VirtualRange: "100.64.0.0/16"},
+			{Range: "10.30.0.0/16", NatEnabled: "yes", Metric: 400},
+		},
+	}
+	_, routed, _ := net.ParseCIDR("10.10.0.0/16")
+	_, virtual, _ := net.ParseCIDR("100.64.0.0/16")
+	metrics := make(map[string]uint32)
+	setEgressRouteMetrics(metrics, &peer, []net.IPNet{*routed, *virtual}) // 10.30.0.0/16 is not routed for the node
+	is.Equal(metrics, map[string]uint32{"10.10.0.0/16": 200, "100.64.0.0/16": 300})
+}
@@ -0,0 +1,316 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+)
+
+const (
+	// PUNCH_LEAD_TIME - time between signalling the hosts and the scheduled punch,
+	// leaves room for the signal to reach both hosts over MQ
+	PUNCH_LEAD_TIME = time.Second * 3
+	// PUNCH_INTERVAL - time between two punch attempts of a host
+	PUNCH_INTERVAL = time.Millisecond * 200
+	// PUNCH_ATTEMPTS - number of packets a host sends to every candidate of its peer
+	PUNCH_ATTEMPTS = 25
+	// PUNCH_TIMEOUT - time after the scheduled punch in which the hosts have to report a result
+	PUNCH_TIMEOUT = time.Second * 15
+)
+
+var (
+	punchMutex    = &sync.Mutex{}
+	punchSessions = make(map[string]*models.PunchSession)
+	// punchResults - session id -> host id -> reported result
+	punchResults = make(map[string]map[string]models.PunchResult)
+)
+
+// candidate priorities, a direct path over a local interface is preferred, ipv6 only after the public endpoint
+// as it is often filtered, turn only as a fallback
+var candidatePriorities = map[models.CandidateType]int{
+	models.LocalCandidate:  100,
+	models.PublicCandidate: 80,
+	models.IPv6Candidate:   75,
+	models.StunCandidate:   70,
+	models.TurnCandidate:   10,
+}
+
+// GetEndpointCandidates - collects the endpoints a host may be reachable on, highest priority first
+func GetEndpointCandidates(host *models.Host) []models.EndpointCandidate {
+	candidates := []models.EndpointCandidate{}
+	seen := make(map[netip.AddrPort]bool)
+	add := func(candidateType models.CandidateType, endpoint netip.AddrPort) {
+		if !endpoint.IsValid() || endpoint.Port() == 0 || seen[endpoint] {
+			return
+		}
+		seen[endpoint] = true
+		candidates = append(candidates, models.EndpointCandidate{
+			Type:     candidateType,
+			Endpoint: endpoint,
+			Priority: candidatePriorities[candidateType],
+		})
+	}
+	listenPort := uint16(host.ListenPort)
+	for _, iface := range host.Interfaces {
+		addr, ok := netip.AddrFromSlice(iface.Address.IP)
+		if !ok {
+			continue
+		}
+		addr = addr.Unmap()
+		if addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() {
+			continue
+		}
+		if addr.Is6() && addr.IsGlobalUnicast() && !addr.IsPrivate() {
+			add(models.IPv6Candidate, netip.AddrPortFrom(addr, listenPort))
+			continue
+		}
+		add(models.LocalCandidate, netip.AddrPortFrom(addr, listenPort))
+	}
+	if publicAddr, ok := netip.AddrFromSlice(host.EndpointIP); ok {
+		add(models.PublicCandidate, netip.AddrPortFrom(publicAddr.Unmap(), uint16(getPeerWgListenPort(host))))
+	}
+	if host.NatBehaviour != nil {
+		add(models.StunCandidate, host.NatBehaviour.MappedAddr)
+	}
+	if host.TurnEndpoint != nil {
+		add(models.TurnCandidate, *host.TurnEndpoint)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Priority > candidates[j].Priority
+	})
+	return candidates
+}
+
+// CreatePunchSession - schedules a hole punch between two hosts,
+// the session has to be published to both hosts by the caller
+func CreatePunchSession(hostA, hostB *models.Host) (*models.PunchSession, error) {
+	if hostA.ID == hostB.ID {
+		return nil, errors.New("cannot punch a path from a host to itself")
+	}
+	if !hostsShareNetwork(hostA, hostB) {
+		// the session hands each host the candidates of the other, only peers may learn them
+		return nil, errors.New("hosts do not share a network")
+	}
+	candidatesA := GetEndpointCandidates(hostA)
+	candidatesB := GetEndpointCandidates(hostB)
+	if len(candidatesA) == 0 || len(candidatesB) == 0 {
+		return nil, errors.New("hosts have no endpoint candidates")
+	}
+	punchAt := time.Now().Add(PUNCH_LEAD_TIME)
+	session := models.PunchSession{
+		ID:          uuid.New().String(),
+		HostA:       hostA.ID.String(),
+		HostB:       hostB.ID.String(),
+		CandidatesA: candidatesA,
+		CandidatesB: candidatesB,
+		PunchAt:     punchAt,
+		Interval:    PUNCH_INTERVAL,
+		Attempts:    PUNCH_ATTEMPTS,
+		ExpiresAt:   punchAt.Add(PUNCH_TIMEOUT),
+		Status:      models.PunchPending,
+	}
+	punchMutex.Lock()
+	defer punchMutex.Unlock()
+	cleanupPunchSessions()
+	for id, existing := range punchSessions {
+		if existing.Status == models.PunchPending && sameHostPair(existing, &session) && time.Now().Before(existing.ExpiresAt) {
+			return nil, errors.New("a punch between the hosts is already in progress: " + id)
+		}
+	}
+	punchSessions[session.ID] = &session
+	punchResults[session.ID] = make(map[string]models.PunchResult)
+	return &session, nil
+}
+
+// GetPunchSession - fetches a hole punch session
+func GetPunchSession(sessionID string) (models.PunchSession, error) {
+	punchMutex.Lock()
+	defer punchMutex.Unlock()
+	session, ok := punchSessions[sessionID]
+	if !ok {
+		return models.PunchSession{}, errors.New("punch session not found")
+	}
+	return *session, nil
+}
+
+// RecordPunchResult - stores the result a host reported for a session,
+// returns the session and true once the outcome of the session is decided
+func RecordPunchResult(result models.PunchResult) (models.PunchSession, bool, error) {
+	punchMutex.Lock()
+	defer punchMutex.Unlock()
+	session, ok := punchSessions[result.SessionID]
+	if !ok {
+		return models.PunchSession{}, false, errors.New("punch session not found")
+	}
+	if result.HostID != session.HostA && result.HostID != session.HostB {
+		return *session, false, errors.New("host is not part of the punch session")
+	}
+	if session.Status != models.PunchPending {
+		return *session, false, nil
+	}
+	punchResults[session.ID][result.HostID] = result
+	if result.Success {
+		session.Status = models.PunchSucceeded
+		if err := savePeerPath(session, punchResults[session.ID]); err != nil {
+			return *session, true, err
+		}
+		cleanupPunchSessions()
+		return *session, true, nil
+	}
+	if len(punchResults[session.ID]) == 2 {
+		// both hosts gave up without finding a direct path
+		session.Status = models.PunchFailed
+		err := savePeerPath(session, punchResults[session.ID])
+		cleanupPunchSessions()
+		return *session, true, err
+	}
+	return *session, false, nil
+}
+
+// ExpirePunchSession - fails a session none of the hosts reported success for in time,
+// returns the session and true if the session was still pending
+func ExpirePunchSession(sessionID string) (models.PunchSession, bool, error) {
+	punchMutex.Lock()
+	defer punchMutex.Unlock()
+	session, ok := punchSessions[sessionID]
+	if !ok || session.Status != models.PunchPending {
+		return models.PunchSession{}, false, nil
+	}
+	session.Status = models.PunchFailed
+	err := savePeerPath(session, punchResults[session.ID])
+	cleanupPunchSessions()
+	return *session, true, err
+}
+
+// GetPeerPath - fetches the path last found between two hosts
+func GetPeerPath(hostA, hostB string) (models.PeerPath, error) {
+	var path models.PeerPath
+	record, err := database.FetchRecord(database.PEER_PATHS_TABLE_NAME, peerPathKey(hostA, hostB))
+	if err != nil {
+		return path, err
+	}
+	err = json.Unmarshal([]byte(record), &path)
+	return path, err
+}
+
+// GetHostPeerPaths - fetches the paths found between a host and its peers
+func GetHostPeerPaths(hostID string) ([]models.PeerPath, error) {
+	paths := []models.PeerPath{}
+	records, err := database.FetchRecords(database.PEER_PATHS_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return paths, err
+	}
+	for _, record := range records {
+		var path models.PeerPath
+		if err := json.Unmarshal([]byte(record), &path); err != nil {
+			continue
+		}
+		if path.HostA == hostID || path.HostB == hostID {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// DeleteHostPeerPaths - removes the recorded paths of a deleted host
+func DeleteHostPeerPaths(hostID string) error {
+	paths, err := GetHostPeerPaths(hostID)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := database.DeleteRecord(database.PEER_PATHS_TABLE_NAME, peerPathKey(path.HostA, path.HostB)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// savePeerPath - records the outcome of a session, a successful result reported by
+// either host describes the direct path
+func savePeerPath(session *models.PunchSession, results map[string]models.PunchResult) error {
+	path := models.PeerPath{
+		HostA:     session.HostA,
+		HostB:     session.HostB,
+		UpdatedAt: time.Now(),
+	}
+	for _, result := range results {
+		if !result.Success {
+			continue
+		}
+		path.Direct = true
+		path.CandidateType = result.CandidateType
+		path.Latency = result.Latency
+		if result.HostID == session.HostA {
+			path.EndpointA, path.EndpointB = result.Local, result.Remote
+		} else {
+			path.EndpointA, path.EndpointB = result.Remote, result.Local
+		}
+		break
+	}
+	if !path.Direct {
+		// a failed punch does not prove a path found earlier broke, keep it
+		if current, err := GetPeerPath(session.HostA, session.HostB); err == nil && current.Direct {
+			return nil
+		}
+	}
+	data, err := json.Marshal(&path)
+	if err != nil {
+		return err
+	}
+	return database.Insert(peerPathKey(session.HostA, session.HostB), string(data), database.PEER_PATHS_TABLE_NAME)
+}
+
+// cleanupPunchSessions - drops decided sessions past their expiry and pending sessions
+// whose expiry was missed, called with punchMutex held
+func cleanupPunchSessions() {
+	now := time.Now()
+	for id, session := range punchSessions {
+		if (session.Status != models.PunchPending && now.After(session.ExpiresAt)) ||
+			now.After(session.ExpiresAt.Add(PUNCH_TIMEOUT)) {
+			delete(punchSessions, id)
+			delete(punchResults, id)
+		}
+	}
+}
+
+// hostsShareNetwork - checks if two hosts have nodes in a common network
+func hostsShareNetwork(hostA, hostB *models.Host) bool {
+	networks := make(map[string]struct{})
+	for _, nodeID := range hostA.Nodes {
+		node, err := GetNodeByID(nodeID)
+		if err != nil {
+			continue
+		}
+		networks[node.Network] = struct{}{}
+	}
+	for _, nodeID := range hostB.Nodes {
+		node, err := GetNodeByID(nodeID)
+		if err != nil {
+			continue
+		}
+		if _, ok := networks[node.Network]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func sameHostPair(a, b *models.PunchSession) bool {
+	return peerPathKey(a.HostA, a.HostB) == peerPathKey(b.HostA, b.HostB)
+}
+
+// peerPathKey - order independent key of a pair of hosts
+func peerPathKey(hostA, hostB string) string {
+	if hostA > hostB {
+		hostA, hostB = hostB, hostA
+	}
+	return hostA + "-" + hostB
+}
@@ -0,0 +1,177 @@
+package logic
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestGetEndpointCandidates(t *testing.T) {
+	turn := netip.MustParseAddrPort("198.51.100.1:49152")
+	host := models.Host{
+		ID:                 uuid.New(),
+		ListenPort:         51821,
+		WgPublicListenPort: 40000,
+		EndpointIP:         net.ParseIP("203.0.113.7"),
+		Interfaces: []models.Iface{
+			{Name: "lo", Address: net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)}},
+			{Name: "eth0", Address: net.IPNet{IP: net.ParseIP("192.168.1.10"), Mask: net.CIDRMask(24, 32)}},
+			{Name: "eth0", Address: net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)}},
+			{Name: "eth0", Address: net.IPNet{IP: net.ParseIP("2001:db8::10"), Mask: net.CIDRMask(64, 128)}},
+		},
+		NatBehaviour: &models.NatBehaviour{
+			MappedAddr: netip.MustParseAddrPort("203.0.113.7:40000"),
+		},
+		TurnEndpoint: &turn,
+	}
+	t.Run("ordered by priority", func(t *testing.T) {
+		is := is.New(t)
+		candidates := GetEndpointCandidates(&host)
+		is.Equal(len(candidates), 4)
+		is.Equal(candidates[0].Type, models.LocalCandidate)
+		is.Equal(candidates[0].Endpoint, netip.MustParseAddrPort("192.168.1.10:51821"))
+		is.Equal(candidates[1].Type, models.PublicCandidate)
+		is.Equal(candidates[1].Endpoint, netip.MustParseAddrPort("203.0.113.7:40000"))
+		is.Equal(candidates[2].Type, models.IPv6Candidate)
+		is.Equal(candidates[3].Type, models.TurnCandidate)
+	})
+	t.Run("stun mapping differing from the public endpoint", func(t *testing.T) {
+		is := is.New(t)
+		h := host
+		h.NatBehaviour = &models.NatBehaviour{
+			MappedAddr: netip.MustParseAddrPort("203.0.113.7:40001"),
+		}
+		candidates := GetEndpointCandidates(&h)
+		is.Equal(len(candidates), 5)
+		is.Equal(candidates[3].Type, models.StunCandidate)
+	})
+	t.Run("public endpoint uses the wireguard port, not the proxy port", func(t *testing.T) {
+		is := is.New(t)
+		h := host
+		h.ProxyEnabled = true
+		h.PublicListenPort = 51722
+		candidates := GetEndpointCandidates(&h)
+		is.Equal(candidates[1].Type, models.PublicCandidate)
+		is.Equal(candidates[1].Endpoint, netip.MustParseAddrPort("203.0.113.7:40000"))
+	})
+	t.Run("no endpoints", func(t *testing.T) {
+		is := is.New(t)
+		is.Equal(len(GetEndpointCandidates(&models.Host{})), 0)
+	})
+}
+
+func TestCreatePunchSession(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	newHost := func(ip string, networks ...string) models.Host {
+		host := models.Host{
+			ID:         uuid.New(),
+			ListenPort: 51821,
+			EndpointIP: net.ParseIP(ip),
+		}
+		for _, network := range networks {
+			node := models.Node{}
+			node.ID = uuid.New()
+			node.HostID = host.ID
+			node.Network = network
+			if err := upsertNode(&node); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { database.DeleteRecord(database.NODES_TABLE_NAME, node.ID.String()) })
+			host.Nodes = append(host.Nodes, node.ID.String())
+		}
+		return host
+	}
+	hostA := newHost("203.0.113.7", "punch-net")
+	hostB := newHost("198.51.100.20", "punch-net")
+	t.Run("punch to itself", func(t *testing.T) {
+		is := is.New(t)
+		_, err := CreatePunchSession(&hostA, &hostA)
+		is.True(err != nil)
+	})
+	t.Run("hosts without a common network", func(t *testing.T) {
+		is := is.New(t)
+		stranger := newHost("192.0.2.50", "other-net")
+		_, err := CreatePunchSession(&hostA, &stranger)
+		is.True(err != nil)
+	})
+	t.Run("one session per pair", func(t *testing.T) {
+		is := is.New(t)
+		session, err := CreatePunchSession(&hostA, &hostB)
+		is.NoErr(err)
+		is.Equal(session.Status, models.PunchPending)
+		is.True(session.ExpiresAt.After(session.PunchAt))
+		_, err = CreatePunchSession(&hostB, &hostA)
+		is.True(err != nil)
+	})
+	t.Run("result of a host outside the session", func(t *testing.T) {
+		is := is.New(t)
+		hostC := newHost("192.0.2.1", "punch-net")
+		session, err := CreatePunchSession(&hostA, &hostC)
+		is.NoErr(err)
+		_, decided, err := RecordPunchResult(models.PunchResult{
+			SessionID: session.ID,
+			HostID:    hostB.ID.String(),
+			Success:   true,
+		})
+		is.True(err != nil)
+		is.True(!decided)
+	})
+	t.Run("failed punch keeps a working path", func(t *testing.T) {
+		is := is.New(t)
+		hostC := newHost("192.0.2.2", "punch-net")
+		hostD := newHost("192.0.2.3", "punch-net")
+		defer DeleteHostPeerPaths(hostC.ID.String())
+		session, err := CreatePunchSession(&hostC, &hostD)
+		is.NoErr(err)
+		_, decided, err := RecordPunchResult(models.PunchResult{
+			SessionID:     session.ID,
+			HostID:        hostC.ID.String(),
+			Success:       true,
+			Local:         netip.MustParseAddrPort("192.0.2.2:51821"),
+			Remote:        netip.MustParseAddrPort("192.0.2.3:51821"),
+			CandidateType: models.PublicCandidate,
+		})
+		is.NoErr(err)
+		is.True(decided)
+		punchMutex.Lock()
+		punchSessions[session.ID].ExpiresAt = time.Now().Add(-time.Second)
+		punchMutex.Unlock()
+		session, err = CreatePunchSession(&hostD, &hostC)
+		is.NoErr(err)
+		_, pending, err := ExpirePunchSession(session.ID)
+		is.NoErr(err)
+		is.True(pending)
+		path, err := GetPeerPath(hostC.ID.String(), hostD.ID.String())
+		is.NoErr(err)
+		is.True(path.Direct)
+		is.Equal(path.EndpointA, netip.MustParseAddrPort("192.0.2.2:51821"))
+	})
+	t.Run("expired sessions are cleaned up", func(t *testing.T) {
+		is := is.New(t)
+		hostC, hostD := newHost("192.0.2.4", "punch-net"), newHost("192.0.2.5", "punch-net")
+		hostE, hostF := newHost("192.0.2.6", "punch-net"), newHost("192.0.2.7", "punch-net")
+		decided, err := CreatePunchSession(&hostC, &hostD)
+		is.NoErr(err)
+		missed, err := CreatePunchSession(&hostE, &hostF)
+		is.NoErr(err)
+		punchMutex.Lock()
+		punchSessions[decided.ID].Status = models.PunchFailed
+		punchSessions[decided.ID].ExpiresAt = time.Now().Add(-time.Second)
+		// the expiry of a pending session was never recorded
+		punchSessions[missed.ID].ExpiresAt = time.Now().Add(-PUNCH_TIMEOUT - time.Second)
+		punchMutex.Unlock()
+		_, err = CreatePunchSession(&hostC, &hostF)
+		is.NoErr(err)
+		_, err = GetPunchSession(decided.ID)
+		is.True(err != nil)
+		_, err = GetPunchSession(missed.ID)
+		is.True(err != nil)
+	})
+}
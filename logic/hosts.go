@@ -200,6 +200,10 @@ func UpdateHostFromClient(newHost, currHost *models.Host) (sendPeerUpdate bool)
 	if newHost.Name != "" {
 		currHost.Name = newHost.Name
 	}
+	if IsNatBehaviourObserved(newHost.NatBehaviour) {
+		newHost.NatType = ClassifyNatType(newHost.NatBehaviour, currHost.NatType)
+		currHost.NatBehaviour = newHost.NatBehaviour
+	}
 	if len(newHost.NatType) > 0 && newHost.NatType != currHost.NatType {
 		currHost.NatType = newHost.NatType
 		sendPeerUpdate = true
@@ -226,6 +230,12 @@ func RemoveHost(h *models.Host) error {
 	if servercfg.IsUsingTurn() {
 		DeRegisterHostWithTurn(h.ID.String())
 	}
+	if err := DeleteHostPeerPaths(h.ID.String()); err != nil {
+		logger.Log(1, "failed to remove peer paths of host", h.ID.String(), err.Error())
+	}
+	if err := DeleteHostPeerEndpoints(h.ID.String()); err != nil {
+		logger.Log(1, "failed to remove peer endpoints of host", h.ID.String(), err.Error())
+	}
 
 	return database.DeleteRecord(database.HOSTS_TABLE_NAME, h.ID.String())
 }
@@ -235,6 +245,12 @@ func RemoveHostByID(hostID string) error {
 	if servercfg.IsUsingTurn() {
 		DeRegisterHostWithTurn(hostID)
 	}
+	if err := DeleteHostPeerPaths(hostID); err != nil {
+		logger.Log(1, "failed to remove peer paths of host", hostID, err.Error())
+	}
+	if err := DeleteHostPeerEndpoints(hostID); err != nil {
+		logger.Log(1, "failed to remove peer endpoints of host", hostID, err.Error())
+	}
 	return database.DeleteRecord(database.HOSTS_TABLE_NAME, hostID)
 }
 
@@ -465,6 +481,14 @@ func ConvHostPassToHash(hostPass string) string {
 
 // RegisterHostWithTurn - registers the host with the given turn server
 func RegisterHostWithTurn(hostID, hostPass string) error {
+	if servercfg.IsUsingEmbeddedTurn() {
+		// stored as a json string, the database only takes json values
+		data, err := json.Marshal(ConvHostPassToHash(hostPass))
+		if err != nil {
+			return err
+		}
+		return database.Insert(hostID, string(data), database.TURN_HOSTS_TABLE_NAME)
+	}
 	auth := servercfg.GetTurnUserName() + ":" + servercfg.GetTurnPassword()
 	api := httpclient.JSONEndpoint[models.SuccessResponse, models.ErrorResponse]{
 		URL:           servercfg.GetTurnApiHost(),
@@ -490,6 +514,9 @@ func RegisterHostWithTurn(hostID, hostPass string) error {
 
 // DeRegisterHostWithTurn - to be called when host need to be deregistered from a turn server
 func DeRegisterHostWithTurn(hostID string) error {
+	if servercfg.IsUsingEmbeddedTurn() {
+		return database.DeleteRecord(database.TURN_HOSTS_TABLE_NAME, hostID)
+	}
 	auth := servercfg.GetTurnUserName() + ":" + servercfg.GetTurnPassword()
 	api := httpclient.JSONEndpoint[models.SuccessResponse, models.ErrorResponse]{
 		URL:           servercfg.GetTurnApiHost(),
@@ -509,6 +536,19 @@ func DeRegisterHostWithTurn(hostID string) error {
 	return nil
 }
 
+// GetTurnHostPassHash - fetches the password hash a host registered with the embedded turn server
+func GetTurnHostPassHash(hostID string) (string, error) {
+	record, err := database.FetchRecord(database.TURN_HOSTS_TABLE_NAME, hostID)
+	if err != nil {
+		return "", err
+	}
+	var passHash string
+	if err = json.Unmarshal([]byte(record), &passHash); err != nil {
+		return "", err
+	}
+	return passHash, nil
+}
+
 // SortApiHosts - Sorts slice of ApiHosts by their ID alphabetically with numbers first
 func SortApiHosts(unsortedHosts []models.ApiHost) {
 	sort.Slice(unsortedHosts, func(i, j int) bool {
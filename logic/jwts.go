@@ -52,9 +52,9 @@ func CreateJWT(uuid string, macAddress string, network string) (response string,
 	return "", err
 }
 
-// CreateProUserJWT - creates a user jwt token
-func CreateProUserJWT(username string, networks, groups []string, isadmin bool) (response string, err error) {
-	expirationTime := time.Now().Add(60 * 12 * time.Minute)
+// CreateProUserJWT - creates a short lived user jwt token bound to a session
+func CreateProUserJWT(username, sessionID string, networks, groups []string, isadmin bool) (response string, err error) {
+	expirationTime := time.Now().Add(servercfg.GetUserTokenValidity())
 	claims := &models.UserClaims{
 		UserName: username,
 		Networks: networks,
@@ -63,6 +63,7 @@ func CreateProUserJWT(username string, networks, groups []string, isadmin bool)
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "Netmaker",
 			Subject:   fmt.Sprintf("user|%s", username),
+			ID:        sessionID,
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
@@ -76,27 +77,9 @@ func CreateProUserJWT(username string, networks, groups []string, isadmin bool)
 	return "", err
 }
 
-// CreateUserJWT - creates a user jwt token
-func CreateUserJWT(username string, networks []string, isadmin bool) (response string, err error) {
-	expirationTime := time.Now().Add(60 * 12 * time.Minute)
-	claims := &models.UserClaims{
-		UserName: username,
-		Networks: networks,
-		IsAdmin:  isadmin,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "Netmaker",
-			Subject:   fmt.Sprintf("user|%s", username),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecretKey)
-	if err == nil {
-		return tokenString, nil
-	}
-	return "", err
+// CreateUserJWT - creates a short lived user jwt token bound to a session
+func CreateUserJWT(username, sessionID string, networks []string, isadmin bool) (response string, err error) {
+	return CreateProUserJWT(username, sessionID, networks, nil, isadmin)
 }
 
 // VerifyJWT verifies Auth Header
@@ -118,25 +101,44 @@ func VerifyUserToken(tokenString string) (username string, networks []string, is
 	if tokenString == servercfg.GetMasterKey() && servercfg.GetMasterKey() != "" {
 		return "masteradministrator", nil, true, nil
 	}
+	if IsAccessToken(tokenString) {
+		return verifyAccessTokenUser(tokenString)
+	}
 
+	user, err := verifyUserJWT(tokenString, claims)
+	if err != nil {
+		return "", nil, false, err
+	}
+	return user.UserName, claims.Networks, claims.IsAdmin, nil
+}
+
+// verifyUserJWT - checks a user jwt and its session, returns the user it was issued to
+func verifyUserJWT(tokenString string, claims *models.UserClaims) (*models.User, error) {
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecretKey, nil
 	})
-
-	if token != nil && token.Valid {
-		var user *models.User
-		// check that user exists
-		user, err = GetUser(claims.UserName)
-		if err != nil {
-			return "", nil, false, err
+	if token == nil || !token.Valid {
+		if err == nil {
+			err = errors.New("invalid token")
 		}
-
-		if user.UserName != "" {
-			return claims.UserName, claims.Networks, claims.IsAdmin, nil
-		}
-		err = errors.New("user does not exist")
+		return nil, err
+	}
+	// check that user exists
+	user, err := GetUser(claims.UserName)
+	if err != nil {
+		return nil, err
+	}
+	if user.UserName == "" {
+		return nil, errors.New("user does not exist")
 	}
-	return "", nil, false, err
+	if err = verifyUserSession(claims.ID, claims.UserName); err != nil {
+		return nil, err
+	}
+	// tokens issued before the user lost admin rights are no longer valid
+	if claims.IsAdmin && !user.IsAdmin {
+		return nil, errors.New("user is no longer an admin")
+	}
+	return user, nil
 }
 
 // VerifyHostToken - [hosts] Only
@@ -153,8 +155,13 @@ func VerifyHostToken(tokenString string) (hostID string, mac string, network str
 		return jwtSecretKey, nil
 	})
 
-	if token != nil {
-		return claims.ID, claims.MacAddress, claims.Network, nil
+	if err != nil {
+		return "", "", "", err
+	}
+	// tokens with a bad signature or past their expiry are parsed too, only valid ones identify a host.
+	// user tokens are signed with the same secret, their sessions are checked on the user path only
+	if token == nil || !token.Valid || claims.ID == "" || claims.Subject != fmt.Sprintf("node|%s", claims.ID) {
+		return "", "", "", errors.New("invalid host token")
 	}
-	return "", "", "", err
+	return claims.ID, claims.MacAddress, claims.Network, nil
 }
@@ -0,0 +1,85 @@
+package logic
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestVerifyHostToken(t *testing.T) {
+	SetJWTSecret()
+	hostID := uuid.NewString()
+	sign := func(claims *models.Claims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecretKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		is := is.New(t)
+		token, err := CreateJWT(hostID, "", "")
+		is.NoErr(err)
+		id, _, _, err := VerifyHostToken(token)
+		is.NoErr(err)
+		is.Equal(id, hostID)
+	})
+	t.Run("tampered", func(t *testing.T) {
+		is := is.New(t)
+		token, err := CreateJWT(hostID, "", "")
+		is.NoErr(err)
+		// swap a character of the signature
+		last := token[len(token)-2]
+		replacement := "A"
+		if last == 'A' {
+			replacement = "B"
+		}
+		id, _, _, err := VerifyHostToken(token[:len(token)-2] + replacement + token[len(token)-1:])
+		is.True(err != nil)
+		is.Equal(id, "")
+	})
+	t.Run("expired", func(t *testing.T) {
+		is := is.New(t)
+		token := sign(&models.Claims{
+			ID: hostID,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   fmt.Sprintf("node|%s", hostID),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+			},
+		})
+		id, _, _, err := VerifyHostToken(token)
+		is.True(err != nil)
+		is.Equal(id, "")
+	})
+	t.Run("user token", func(t *testing.T) {
+		is := is.New(t)
+		token, err := CreateUserJWT("alice", uuid.NewString(), nil, false)
+		is.NoErr(err)
+		_, _, _, err = VerifyHostToken(token)
+		is.True(err != nil)
+		// user claims under a host id are no host either
+		token = sign(&models.Claims{
+			ID: hostID,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "user|alice",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		})
+		_, _, _, err = VerifyHostToken(token)
+		is.True(err != nil)
+	})
+	t.Run("without host id", func(t *testing.T) {
+		is := is.New(t)
+		token := sign(&models.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+		})
+		_, _, _, err := VerifyHostToken(token)
+		is.True(err != nil)
+	})
+}
@@ -0,0 +1,147 @@
+package logic
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+const (
+	// ldapTimeout - time allowed to connect to and get an answer from the ldap server
+	ldapTimeout = time.Second * 10
+	// LDAPProviderName - provider the sso mappings of ldap groups are made for
+	LDAPProviderName = "ldap"
+)
+
+// IsLDAPUser - checks if a user logs in with its directory password
+func IsLDAPUser(user *models.User) bool {
+	return user.LDAPDN != ""
+}
+
+// VerifyLDAPCredentials - binds to the ldap server as the user, creating the user on its first login.
+// The directory groups of the user are mapped to netmaker access when sso group sync is enabled.
+func VerifyLDAPCredentials(username, password string) (*models.User, error) {
+	entry, err := authenticateLDAPUser(username, password)
+	if err != nil {
+		return nil, err
+	}
+	user, err := GetUser(username)
+	if err != nil {
+		// the password is never used, the user logs in through ldap
+		user = &models.User{UserName: username, Password: RandomString(32), LDAPDN: entry.DN}
+		if err = CreateUser(user); err != nil {
+			return nil, err
+		}
+		logger.Log(0, "user", username, "created from ldap entry", entry.DN)
+	} else if user.LDAPDN != entry.DN {
+		user.LDAPDN = entry.DN
+		if err = saveUser(user); err != nil {
+			return nil, err
+		}
+	}
+	if !IsUserActive(username) {
+		return nil, ErrUserDeactivated
+	}
+	if servercfg.IsSSOGroupSyncEnabled() {
+		if err = ApplySSOMappings(LDAPProviderName, username, ldapGroups(entry)); err != nil {
+			logger.Log(0, "failed to apply sso mappings to user", username, err.Error())
+		}
+		return GetUser(username)
+	}
+	return user, nil
+}
+
+// CheckLDAPConnection - connects to the ldap server and binds with the search account
+func CheckLDAPConnection() error {
+	conn, err := dialLDAP()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return bindLDAPSearchAccount(conn)
+}
+
+// authenticateLDAPUser - searches the entry of a user and binds as it with its password
+func authenticateLDAPUser(username, password string) (*ldap.Entry, error) {
+	if password == "" {
+		return nil, errors.New("password can't be empty")
+	}
+	conn, err := dialLDAP()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err = bindLDAPSearchAccount(conn); err != nil {
+		return nil, err
+	}
+	// the filter may name the user more than once, e.g. (|(uid=%s)(mail=%s))
+	filter := strings.ReplaceAll(servercfg.GetLDAPUserFilter(), "%s", ldap.EscapeFilter(username))
+	result, err := conn.Search(ldap.NewSearchRequest(
+		servercfg.GetLDAPBaseDN(),
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(ldapTimeout.Seconds()), false,
+		filter,
+		[]string{servercfg.GetLDAPGroupAttribute()},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search ldap user: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, errors.New("incorrect credentials")
+	}
+	entry := result.Entries[0]
+	if err = conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, errors.New("incorrect credentials")
+		}
+		return nil, fmt.Errorf("failed to bind as ldap user: %w", err)
+	}
+	return entry, nil
+}
+
+func dialLDAP() (*ldap.Conn, error) {
+	serverURL := servercfg.GetLDAPURL()
+	tlsConfig := &tls.Config{InsecureSkipVerify: servercfg.IsLDAPSkipTLSVerify()}
+	if parsed, err := url.Parse(serverURL); err == nil {
+		tlsConfig.ServerName = parsed.Hostname()
+	}
+	conn, err := ldap.DialURL(serverURL, ldap.DialWithTLSConfig(tlsConfig), ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ldap server: %w", err)
+	}
+	conn.SetTimeout(ldapTimeout)
+	if servercfg.IsLDAPStartTLS() {
+		if err = conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start tls with ldap server: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// bindLDAPSearchAccount - binds with the account users are searched with, searches are anonymous without one
+func bindLDAPSearchAccount(conn *ldap.Conn) error {
+	bindDN := servercfg.GetLDAPBindDN()
+	if bindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(bindDN, servercfg.GetLDAPBindPassword()); err != nil {
+		return fmt.Errorf("failed to bind to ldap server: %w", err)
+	}
+	return nil
+}
+
+// ldapGroups - groups listed by the entry of a user by their dn. Mappings name the whole dn, a cn is
+// only unique within its ou, so groups of the same name elsewhere in the directory would match it too
+func ldapGroups(entry *ldap.Entry) []string {
+	return entry.GetEqualFoldAttributeValues(servercfg.GetLDAPGroupAttribute())
+}
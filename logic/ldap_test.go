@@ -0,0 +1,203 @@
+package logic
+
+import (
+	"net"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic/pro"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/models/promodels"
+	"github.com/matryer/is"
+)
+
+const (
+	testLDAPBindDN       = "cn=admin,dc=example,dc=org"
+	testLDAPBindPassword = "adminpass"
+)
+
+type testLDAPEntry struct {
+	dn       string
+	password string
+	groups   []string
+}
+
+// testLDAPServer - answers the simple binds and uid searches netmaker sends to an ldap server
+type testLDAPServer struct {
+	listener net.Listener
+	entries  map[string]testLDAPEntry
+}
+
+func startTestLDAPServer(t *testing.T, entries map[string]testLDAPEntry) *testLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &testLDAPServer{listener: listener, entries: entries}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *testLDAPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	boundDN := ""
+	for {
+		request, err := ber.ReadPacket(conn)
+		if err != nil || len(request.Children) < 2 {
+			return
+		}
+		id := request.Children[0].Value
+		op := request.Children[1]
+		switch op.Tag {
+		case 0: // bind
+			dn, password := op.Children[1].Value.(string), op.Children[2].Data.String()
+			code := int64(49) // invalid credentials
+			if s.checkPassword(dn, password) {
+				boundDN, code = dn, 0
+			}
+			conn.Write(ldapMessage(id, ldapResult(1, code)))
+		case 3: // search
+			if boundDN != testLDAPBindDN {
+				conn.Write(ldapMessage(id, ldapResult(5, 50))) // insufficient access rights
+				continue
+			}
+			filter := op.Children[6]
+			if entry, ok := s.entries[filter.Children[1].Value.(string)]; ok && filter.Children[0].Value.(string) == "uid" {
+				found := ber.Encode(ber.ClassApplication, ber.TypeConstructed, 4, nil, "search result entry")
+				found.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "object name"))
+				attributes := ber.NewSequence("attributes")
+				attribute := ber.NewSequence("attribute")
+				attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "memberOf", "type"))
+				values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "values")
+				for _, group := range entry.groups {
+					values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, group, "value"))
+				}
+				attribute.AppendChild(values)
+				attributes.AppendChild(attribute)
+				found.AppendChild(attributes)
+				conn.Write(ldapMessage(id, found))
+			}
+			conn.Write(ldapMessage(id, ldapResult(5, 0)))
+		default: // unbind
+			return
+		}
+	}
+}
+
+func (s *testLDAPServer) checkPassword(dn, password string) bool {
+	if dn == testLDAPBindDN {
+		return password == testLDAPBindPassword
+	}
+	for _, entry := range s.entries {
+		if entry.dn == dn {
+			return password == entry.password
+		}
+	}
+	return false
+}
+
+func ldapMessage(id interface{}, op *ber.Packet) []byte {
+	message := ber.NewSequence("ldap message")
+	message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "message id"))
+	message.AppendChild(op)
+	return message.Bytes()
+}
+
+func ldapResult(tag ber.Tag, code int64) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "ldap result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "result code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matched dn"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnostic message"))
+	return result
+}
+
+func TestLDAPLogin(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	server := startTestLDAPServer(t, map[string]testLDAPEntry{
+		"ldapalice": {dn: "uid=ldapalice,ou=people,dc=example,dc=org", password: "alicepass", groups: []string{"cn=devs,ou=groups,dc=example,dc=org"}},
+		"ldaplocal": {dn: "uid=ldaplocal,ou=people,dc=example,dc=org", password: "directorypass"},
+		"ldapbob":   {dn: "uid=ldapbob,ou=people,dc=example,dc=org", password: "bobpass", groups: []string{"cn=devs,ou=contractors,dc=example,dc=org"}},
+	})
+	defer server.listener.Close()
+	t.Setenv("LDAP_URL", "ldap://"+server.listener.Addr().String())
+	t.Setenv("LDAP_BIND_DN", testLDAPBindDN)
+	t.Setenv("LDAP_BIND_PASSWORD", testLDAPBindPassword)
+	t.Setenv("LDAP_BASE_DN", "dc=example,dc=org")
+	t.Setenv("SSO_GROUP_SYNC", "yes")
+	is := is.New(t)
+	is.NoErr(pro.InsertUserGroup(promodels.UserGroupName("ldap-devs")))
+	defer pro.DeleteUserGroup(promodels.UserGroupName("ldap-devs"))
+	mapping := models.SSOMapping{Provider: LDAPProviderName, IdPGroup: "CN=devs,ou=groups,dc=example,dc=org", UserGroups: []string{"ldap-devs"}}
+	is.NoErr(CreateSSOMapping(&mapping))
+	defer DeleteSSOMapping(mapping.ID)
+	defer DeleteUser("ldapalice")
+	defer DeleteUser("ldaplocal")
+	defer DeleteUser("ldapbob")
+
+	t.Run("check connection", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(CheckLDAPConnection())
+	})
+	t.Run("first login creates the user", func(t *testing.T) {
+		is := is.New(t)
+		user, err := VerifyUserCredentials(models.UserAuthParams{UserName: "ldapalice", Password: "alicepass"})
+		is.NoErr(err)
+		is.Equal(user.UserName, "ldapalice")
+		is.True(IsLDAPUser(user))
+		stored, err := GetUser("ldapalice")
+		is.NoErr(err)
+		is.True(IsLDAPUser(stored))
+		is.True(!user.IsAdmin)
+		is.True(StringSliceContains(user.Groups, "ldap-devs"))
+	})
+	t.Run("groups are mapped by dn", func(t *testing.T) {
+		is := is.New(t)
+		// a group of the same cn in another ou is another group
+		user, err := VerifyUserCredentials(models.UserAuthParams{UserName: "ldapbob", Password: "bobpass"})
+		is.NoErr(err)
+		is.True(!StringSliceContains(user.Groups, "ldap-devs"))
+	})
+	t.Run("wrong password", func(t *testing.T) {
+		is := is.New(t)
+		_, err := VerifyUserCredentials(models.UserAuthParams{UserName: "ldapalice", Password: "wrong"})
+		is.True(err != nil)
+	})
+	t.Run("unknown user", func(t *testing.T) {
+		is := is.New(t)
+		_, err := VerifyUserCredentials(models.UserAuthParams{UserName: "ldapnobody", Password: "secret"})
+		is.True(err != nil)
+		_, err = GetUser("ldapnobody")
+		is.True(err != nil)
+	})
+	t.Run("local users keep their password", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(CreateUser(&models.User{UserName: "ldaplocal", Password: "localpass"}))
+		_, err := VerifyUserCredentials(models.UserAuthParams{UserName: "ldaplocal", Password: "directorypass"})
+		is.True(err != nil)
+		user, err := VerifyUserCredentials(models.UserAuthParams{UserName: "ldaplocal", Password: "localpass"})
+		is.NoErr(err)
+		is.True(!IsLDAPUser(user))
+	})
+	t.Run("deleting the user unlinks it", func(t *testing.T) {
+		is := is.New(t)
+		_, err := DeleteUser("ldapalice")
+		is.NoErr(err)
+		_, err = GetUser("ldapalice")
+		is.True(err != nil)
+	})
+	t.Run("wrong search account", func(t *testing.T) {
+		is := is.New(t)
+		t.Setenv("LDAP_BIND_PASSWORD", "wrong")
+		is.True(CheckLDAPConnection() != nil)
+	})
+}
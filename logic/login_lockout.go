@@ -0,0 +1,205 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+const (
+	// maxLoginLockout - longest a user or source ip is locked out for, however often its logins failed
+	maxLoginLockout = time.Hour
+	// loginAttemptsWindow - time without failed logins after which the failures of a user or source ip are forgotten
+	loginAttemptsWindow = time.Hour * 24
+)
+
+// ErrLoginLockedOut - the user or source ip failed to log in too often
+var ErrLoginLockedOut = errors.New("too many failed logins")
+
+var loginAttemptsMutex = &sync.Mutex{}
+
+// VerifyUserLogin - checks the password and second factor of a user logging in from a source ip,
+// a successful login clears the failed logins of the user
+func VerifyUserLogin(authRequest models.UserAuthParams, sourceIP string) (*models.User, error) {
+	user, err := VerifyUserPassword(authRequest, sourceIP)
+	if err != nil {
+		return nil, err
+	}
+	if err = VerifyUserSecondFactor(user, authRequest.TOTP, sourceIP); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// VerifyUserSecondFactor - checks the mfa code of a user whose password was checked, refusing locked out
+// users and source ips and counting wrong codes towards their lockout, a valid code clears the failed
+// logins of the user
+func VerifyUserSecondFactor(user *models.User, code, sourceIP string) error {
+	if err := CheckLoginLockout(user.UserName, sourceIP); err != nil {
+		return err
+	}
+	if err := VerifyUserMFA(user, code); err != nil {
+		if errors.Is(err, ErrMFAInvalidCode) {
+			RecordLoginFailure(user.UserName, sourceIP)
+		}
+		return err
+	}
+	ClearLoginLockout(user.UserName)
+	return nil
+}
+
+// VerifyUserPassword - checks the password of a user logging in from a source ip, refusing locked out
+// users and source ips and counting wrong credentials towards their lockout
+func VerifyUserPassword(authRequest models.UserAuthParams, sourceIP string) (*models.User, error) {
+	if err := CheckLoginLockout(authRequest.UserName, sourceIP); err != nil {
+		return nil, err
+	}
+	user, err := VerifyUserCredentials(authRequest)
+	if err != nil {
+		if authRequest.UserName != "" && authRequest.Password != "" && !errors.Is(err, ErrUserDeactivated) {
+			RecordLoginFailure(authRequest.UserName, sourceIP)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// CheckLoginLockout - checks that neither the user nor the source ip are locked out, either may be empty
+func CheckLoginLockout(username, sourceIP string) error {
+	now := time.Now()
+	lockedUntil := now
+	for _, key := range loginAttemptsKeys(username, sourceIP) {
+		attempts, err := getLoginAttempts(key)
+		if err == nil && attempts.LockedUntil.After(lockedUntil) {
+			lockedUntil = attempts.LockedUntil
+		}
+	}
+	if lockedUntil.After(now) {
+		return fmt.Errorf("%w, try again in %s", ErrLoginLockedOut, lockedUntil.Sub(now).Round(time.Second))
+	}
+	return nil
+}
+
+// RecordLoginFailure - counts a failed login of a user from a source ip, locking either out once it failed
+// too often. Each further failure doubles the lockout. Failures for unknown users only count towards the
+// source ip, so guessing user names does not fill the database.
+func RecordLoginFailure(username, sourceIP string) {
+	if username != "" {
+		if _, err := GetUser(username); err != nil {
+			username = ""
+		}
+	}
+	loginAttemptsMutex.Lock()
+	defer loginAttemptsMutex.Unlock()
+	thresholds := []int{servercfg.GetLoginLockoutThreshold(), servercfg.GetLoginLockoutIPThreshold()}
+	for i, key := range []string{loginAttemptsUserKey(username), loginAttemptsIPKey(sourceIP)} {
+		if key == "" || thresholds[i] == 0 {
+			continue
+		}
+		if err := recordLoginFailure(key, thresholds[i]); err != nil {
+			logger.Log(0, "failed to record failed login of", key, err.Error())
+		}
+	}
+}
+
+// ClearLoginLockout - forgets the failed logins of a user, lifting its lockout
+func ClearLoginLockout(username string) {
+	loginAttemptsMutex.Lock()
+	defer loginAttemptsMutex.Unlock()
+	if err := database.DeleteRecord(database.LOGIN_ATTEMPTS_TABLE_NAME, loginAttemptsUserKey(username)); err != nil && !database.IsEmptyRecord(err) {
+		logger.Log(0, "failed to clear failed logins of user", username, err.Error())
+	}
+}
+
+// pruneLoginAttempts - removes the failed logins of users and source ips which are no longer locked out
+// and have not failed within the attempts window
+func pruneLoginAttempts() error {
+	loginAttemptsMutex.Lock()
+	defer loginAttemptsMutex.Unlock()
+	records, err := database.FetchRecords(database.LOGIN_ATTEMPTS_TABLE_NAME)
+	if err != nil {
+		if database.IsEmptyRecord(err) {
+			return nil
+		}
+		return err
+	}
+	now := time.Now()
+	for key, record := range records {
+		var attempts models.LoginAttempts
+		if err := json.Unmarshal([]byte(record), &attempts); err == nil &&
+			(now.Sub(attempts.LastFailure) <= loginAttemptsWindow || attempts.LockedUntil.After(now)) {
+			continue
+		}
+		if err := database.DeleteRecord(database.LOGIN_ATTEMPTS_TABLE_NAME, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recordLoginFailure(key string, threshold int) error {
+	now := time.Now()
+	attempts, err := getLoginAttempts(key)
+	if err != nil || now.Sub(attempts.LastFailure) > loginAttemptsWindow {
+		attempts = models.LoginAttempts{Key: key}
+	}
+	attempts.Failures++
+	attempts.LastFailure = now
+	if attempts.Failures >= threshold {
+		lockout := maxLoginLockout
+		// stop doubling before the duration overflows
+		if doublings := attempts.Failures - threshold; doublings < 32 {
+			if backoff := servercfg.GetLoginLockoutDuration() << doublings; backoff > 0 && backoff < maxLoginLockout {
+				lockout = backoff
+			}
+		}
+		attempts.LockedUntil = now.Add(lockout)
+		logger.Log(1, key, "locked out for", lockout.String(), "after", fmt.Sprint(attempts.Failures), "failed logins")
+	}
+	data, err := json.Marshal(&attempts)
+	if err != nil {
+		return err
+	}
+	return database.Insert(key, string(data), database.LOGIN_ATTEMPTS_TABLE_NAME)
+}
+
+func getLoginAttempts(key string) (models.LoginAttempts, error) {
+	var attempts models.LoginAttempts
+	record, err := database.FetchRecord(database.LOGIN_ATTEMPTS_TABLE_NAME, key)
+	if err != nil {
+		return attempts, err
+	}
+	err = json.Unmarshal([]byte(record), &attempts)
+	return attempts, err
+}
+
+func loginAttemptsKeys(username, sourceIP string) []string {
+	keys := []string{}
+	for _, key := range []string{loginAttemptsUserKey(username), loginAttemptsIPKey(sourceIP)} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func loginAttemptsUserKey(username string) string {
+	if username == "" {
+		return ""
+	}
+	return "user:" + username
+}
+
+func loginAttemptsIPKey(sourceIP string) string {
+	if sourceIP == "" {
+		return ""
+	}
+	return "ip:" + sourceIP
+}
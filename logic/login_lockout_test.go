@@ -0,0 +1,115 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestLoginLockout(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	t.Setenv("LOGIN_LOCKOUT_THRESHOLD", "3")
+	t.Setenv("LOGIN_LOCKOUT_IP_THRESHOLD", "5")
+	t.Setenv("LOGIN_LOCKOUT_DURATION", "60")
+	is := is.New(t)
+	is.NoErr(CreateUser(&models.User{UserName: "lockuser", Password: "right-pass"}))
+	defer DeleteUser("lockuser")
+	database.DeleteAllRecords(database.LOGIN_ATTEMPTS_TABLE_NAME)
+	defer database.DeleteAllRecords(database.LOGIN_ATTEMPTS_TABLE_NAME)
+	login := func(password, sourceIP string) error {
+		_, err := VerifyUserLogin(models.UserAuthParams{UserName: "lockuser", Password: password}, sourceIP)
+		return err
+	}
+
+	t.Run("success clears failures", func(t *testing.T) {
+		is := is.New(t)
+		is.True(login("wrong", "192.0.2.10") != nil)
+		is.True(login("wrong", "192.0.2.10") != nil)
+		is.NoErr(login("right-pass", "192.0.2.10"))
+		_, err := getLoginAttempts(loginAttemptsUserKey("lockuser"))
+		is.True(err != nil)
+	})
+	t.Run("user lockout", func(t *testing.T) {
+		is := is.New(t)
+		for i := 0; i < 3; i++ {
+			is.True(login("wrong", "192.0.2.11") != nil)
+		}
+		err := login("right-pass", "192.0.2.12")
+		is.True(errors.Is(err, ErrLoginLockedOut))
+		attempts, err := getLoginAttempts(loginAttemptsUserKey("lockuser"))
+		is.NoErr(err)
+		is.True(attempts.LockedUntil.Sub(attempts.LastFailure) == time.Minute)
+	})
+	t.Run("backoff", func(t *testing.T) {
+		is := is.New(t)
+		RecordLoginFailure("lockuser", "")
+		RecordLoginFailure("lockuser", "")
+		attempts, err := getLoginAttempts(loginAttemptsUserKey("lockuser"))
+		is.NoErr(err)
+		is.Equal(attempts.Failures, 5)
+		is.True(attempts.LockedUntil.Sub(attempts.LastFailure) == time.Minute*4)
+		for i := 0; i < 40; i++ {
+			RecordLoginFailure("lockuser", "")
+		}
+		attempts, err = getLoginAttempts(loginAttemptsUserKey("lockuser"))
+		is.NoErr(err)
+		is.True(attempts.LockedUntil.Sub(attempts.LastFailure) == maxLoginLockout)
+	})
+	t.Run("unlock", func(t *testing.T) {
+		is := is.New(t)
+		ClearLoginLockout("lockuser")
+		is.NoErr(login("right-pass", "192.0.2.12"))
+	})
+	t.Run("source ip lockout", func(t *testing.T) {
+		is := is.New(t)
+		// the ip already failed 3 times in the user lockout
+		is.True(login("wrong", "192.0.2.11") != nil)
+		is.True(login("wrong", "192.0.2.11") != nil)
+		ClearLoginLockout("lockuser")
+		err := login("right-pass", "192.0.2.11")
+		is.True(errors.Is(err, ErrLoginLockedOut))
+		is.NoErr(login("right-pass", "192.0.2.13"))
+	})
+	t.Run("unknown users count towards the source ip only", func(t *testing.T) {
+		is := is.New(t)
+		RecordLoginFailure("lockunknown", "192.0.2.20")
+		_, err := getLoginAttempts(loginAttemptsUserKey("lockunknown"))
+		is.True(err != nil)
+		attempts, err := getLoginAttempts(loginAttemptsIPKey("192.0.2.20"))
+		is.NoErr(err)
+		is.Equal(attempts.Failures, 1)
+	})
+	t.Run("forwarded addresses of untrusted clients are ignored", func(t *testing.T) {
+		is := is.New(t)
+		t.Setenv("TRUSTED_PROXIES", "")
+		r := httptest.NewRequest(http.MethodPost, "/api/users/adm/authenticate", nil)
+		r.RemoteAddr = "192.0.2.30:1234"
+		r.Header.Set("X-Forwarded-For", "198.51.100.30")
+		_, err := VerifyAuthRequest(models.UserAuthParams{UserName: "lockuser", Password: "wrong"}, r)
+		is.True(err != nil)
+		_, err = getLoginAttempts(loginAttemptsIPKey("192.0.2.30"))
+		is.NoErr(err)
+		_, err = getLoginAttempts(loginAttemptsIPKey("198.51.100.30"))
+		is.True(err != nil)
+	})
+	t.Run("prune", func(t *testing.T) {
+		is := is.New(t)
+		stale := models.LoginAttempts{Key: loginAttemptsIPKey("192.0.2.40"), Failures: 2, LastFailure: time.Now().Add(-loginAttemptsWindow - time.Minute)}
+		data, _ := json.Marshal(&stale)
+		is.NoErr(database.Insert(stale.Key, string(data), database.LOGIN_ATTEMPTS_TABLE_NAME))
+		RecordLoginFailure("", "192.0.2.41")
+		is.NoErr(pruneLoginAttempts())
+		_, err := getLoginAttempts(stale.Key)
+		is.True(err != nil)
+		_, err = getLoginAttempts(loginAttemptsIPKey("192.0.2.41"))
+		is.NoErr(err)
+	})
+}
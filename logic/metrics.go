@@ -30,10 +30,15 @@ func UpdateMetrics(nodeid string, metrics *models.Metrics) error {
 	if err != nil {
 		return err
 	}
-	return database.Insert(nodeid, string(data), database.METRICS_TABLE_NAME)
+	if err = database.Insert(nodeid, string(data), database.METRICS_TABLE_NAME); err != nil {
+		return err
+	}
+	setPeerMetrics(nodeid, metrics)
+	return nil
 }
 
 // DeleteMetrics - deletes metrics of a given node
 func DeleteMetrics(nodeid string) error {
+	deletePeerMetrics(nodeid)
 	return database.DeleteRecord(database.METRICS_TABLE_NAME, nodeid)
 }
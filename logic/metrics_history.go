@@ -0,0 +1,407 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+// MAX_METRICS_POINTS - maximum number of points a range query returns, the step is widened to stay below
+const MAX_METRICS_POINTS = 1000
+
+// metricsResolution - a resolution of the history with the length of its steps and of the stored chunks
+type metricsResolution struct {
+	resolution models.MetricsResolution
+	step       time.Duration
+	chunk      time.Duration
+	table      string
+}
+
+// metricsResolutions - resolutions the history is rolled up to, finest first
+var metricsResolutions = []metricsResolution{
+	{models.MetricsMinute, time.Minute, time.Hour, database.METRICS_HISTORY_1M_TABLE_NAME},
+	{models.MetricsHour, time.Hour, time.Hour * 24, database.METRICS_HISTORY_1H_TABLE_NAME},
+	{models.MetricsDay, time.Hour * 24, time.Hour * 24 * 30, database.METRICS_HISTORY_1D_TABLE_NAME},
+}
+
+// metricsHistoryState - open chunks of a node and the byte counters of its last report
+type metricsHistoryState struct {
+	chunks   map[models.MetricsResolution]*models.MetricsHistoryChunk
+	received map[string]int64
+	sent     map[string]int64
+}
+
+var (
+	metricsHistoryMutex = &sync.Mutex{}
+	// metricsHistoryCache - node id -> state, keeps the open chunks from being read back on every report
+	metricsHistoryCache = make(map[string]*metricsHistoryState)
+)
+
+// RecordMetricsHistory - adds a metrics report of a node to the finest resolution of its history,
+// the coarser resolutions are rolled up once a chunk is complete so a report mostly writes a single chunk
+func RecordMetricsHistory(nodeID, network string, metrics *models.Metrics, now time.Time) error {
+	now = now.UTC()
+	metricsHistoryMutex.Lock()
+	defer metricsHistoryMutex.Unlock()
+	state := getMetricsHistoryState(nodeID)
+	peers := make(map[string]models.PeerMetricsPoint, len(metrics.Connectivity))
+	for peerID, metric := range metrics.Connectivity {
+		point := models.PeerMetricsPoint{
+			NodeName: metric.NodeName,
+			Samples:  1,
+		}
+		if metric.Connected {
+			point.ConnectedSamples = 1
+			point.Latency = float64(metric.Latency)
+			point.LatencyMax = metric.Latency
+		}
+		// the reported counters are totals, a counter going backwards was reset
+		if last, ok := state.received[peerID]; ok && metric.TotalReceived >= last {
+			point.Received = metric.TotalReceived - last
+		}
+		if last, ok := state.sent[peerID]; ok && metric.TotalSent >= last {
+			point.Sent = metric.TotalSent - last
+		}
+		state.received[peerID] = metric.TotalReceived
+		state.sent[peerID] = metric.TotalSent
+		peers[peerID] = point
+	}
+	res := metricsResolutions[0]
+	start := now.Truncate(res.chunk)
+	chunk := state.chunks[res.resolution]
+	if chunk == nil {
+		// first report since the server started, the previous chunk may not have been rolled up
+		if previous := loadMetricsChunk(res, nodeID, start.Add(-res.chunk)); previous != nil {
+			if err := rollUpMetricsChunk(state, 0, previous); err != nil {
+				return err
+			}
+		}
+		chunk = loadMetricsChunk(res, nodeID, start)
+	}
+	if chunk != nil && !chunk.Start.Equal(start) {
+		if err := rollUpMetricsChunk(state, 0, chunk); err != nil {
+			return err
+		}
+		chunk = nil
+	}
+	if chunk == nil {
+		chunk = &models.MetricsHistoryChunk{
+			NodeID:     nodeID,
+			Network:    network,
+			Resolution: res.resolution,
+			Start:      start,
+		}
+	}
+	state.chunks[res.resolution] = chunk
+	addMetricsPoint(chunk, now.Truncate(res.step), peers)
+	return saveMetricsChunk(res, chunk)
+}
+
+// rollUpMetricsChunk - merges the points of a complete chunk of the resolution at level into the point
+// of the next coarser resolution, a chunk spans exactly one step of the next resolution,
+// the point is replaced so rolling up a chunk again does not count it twice
+func rollUpMetricsChunk(state *metricsHistoryState, level int, closed *models.MetricsHistoryChunk) error {
+	if level+1 >= len(metricsResolutions) {
+		return nil
+	}
+	res := metricsResolutions[level+1]
+	start := closed.Start.Truncate(res.chunk)
+	chunk := state.chunks[res.resolution]
+	if chunk == nil || !chunk.Start.Equal(start) {
+		if chunk == nil {
+			// first roll up since the server started, the previous chunk may not have been rolled up
+			if previous := loadMetricsChunk(res, closed.NodeID, start.Add(-res.chunk)); previous != nil {
+				if err := rollUpMetricsChunk(state, level+1, previous); err != nil {
+					return err
+				}
+			}
+		} else if chunk.Start.Before(start) {
+			if err := rollUpMetricsChunk(state, level+1, chunk); err != nil {
+				return err
+			}
+		}
+		chunk = loadMetricsChunk(res, closed.NodeID, start)
+		if chunk == nil {
+			chunk = &models.MetricsHistoryChunk{
+				NodeID:     closed.NodeID,
+				Network:    closed.Network,
+				Resolution: res.resolution,
+				Start:      start,
+			}
+		}
+		state.chunks[res.resolution] = chunk
+	}
+	point := models.MetricsPoint{
+		Timestamp: closed.Start.Truncate(res.step),
+		Peers:     make(map[string]models.PeerMetricsPoint),
+	}
+	for i := range closed.Points {
+		mergeMetricsPeers(&point, closed.Points[i].Peers)
+	}
+	chunk.Points[metricsPointIndex(chunk, point.Timestamp)] = point
+	return saveMetricsChunk(res, chunk)
+}
+
+// GetMetricsHistory - fetches the history of a node between from and to in steps of step,
+// a step of 0 picks one fitting the range, the resolution is the coarsest one not exceeding the step
+func GetMetricsHistory(nodeID string, from, to time.Time, step time.Duration) (models.MetricsHistory, error) {
+	from, to = from.UTC(), to.UTC()
+	history := models.MetricsHistory{
+		NodeID: nodeID,
+		Points: []models.MetricsPoint{},
+	}
+	if !to.After(from) {
+		return history, errors.New("end of the range has to be after its start")
+	}
+	if step < 0 {
+		return history, errors.New("step cannot be negative")
+	}
+	span := to.Sub(from)
+	if step == 0 {
+		step = span / 300
+	}
+	if minStep := span / MAX_METRICS_POINTS; step < minStep {
+		step = minStep
+	}
+	res := pickMetricsResolution(step, from, time.Now())
+	if step < res.step {
+		step = res.step
+	}
+	step = step.Truncate(res.step)
+	history.Resolution = res.resolution
+	history.Step = step
+	history.From = from
+	history.To = to
+	records, err := database.FetchRecordsInRange(res.table, metricsChunkKey(nodeID, from.Truncate(res.chunk)), metricsChunkKey(nodeID, to))
+	if err != nil {
+		if database.IsEmptyRecord(err) {
+			return history, nil
+		}
+		return history, err
+	}
+	buckets := make(map[time.Time]*models.MetricsPoint)
+	for _, record := range records {
+		var chunk models.MetricsHistoryChunk
+		if err := json.Unmarshal([]byte(record), &chunk); err != nil {
+			continue
+		}
+		history.Network = chunk.Network
+		for _, point := range chunk.Points {
+			if point.Timestamp.Before(from) || !point.Timestamp.Before(to) {
+				continue
+			}
+			bucketStart := from.Add(point.Timestamp.Sub(from) / step * step)
+			bucket, ok := buckets[bucketStart]
+			if !ok {
+				bucket = &models.MetricsPoint{
+					Timestamp: bucketStart,
+					Peers:     make(map[string]models.PeerMetricsPoint),
+				}
+				buckets[bucketStart] = bucket
+			}
+			mergeMetricsPeers(bucket, point.Peers)
+		}
+	}
+	for _, bucket := range buckets {
+		history.Points = append(history.Points, *bucket)
+	}
+	sort.Slice(history.Points, func(i, j int) bool {
+		return history.Points[i].Timestamp.Before(history.Points[j].Timestamp)
+	})
+	return history, nil
+}
+
+// ManageMetricsHistory - removes history past its retention once an hour
+func ManageMetricsHistory(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := PruneMetricsHistory(time.Now()); err != nil {
+				logger.Log(0, "failed to prune metrics history", err.Error())
+			}
+		}
+	}
+}
+
+// PruneMetricsHistory - removes the chunks that ended before the retention of their resolution
+func PruneMetricsHistory(now time.Time) error {
+	now = now.UTC()
+	metricsHistoryMutex.Lock()
+	nodeIDs := make(map[string]struct{}, len(metricsHistoryCache))
+	// forget nodes that stopped reporting, e.g. deleted ones, after rolling up their last chunks
+	for nodeID, state := range metricsHistoryCache {
+		nodeIDs[nodeID] = struct{}{}
+		chunk := state.chunks[models.MetricsMinute]
+		if chunk != nil && now.Sub(chunk.Start) <= metricsResolutions[0].chunk*2 {
+			continue
+		}
+		for level, res := range metricsResolutions {
+			if chunk := state.chunks[res.resolution]; chunk != nil && !chunk.Start.Add(res.chunk).After(now) {
+				if err := rollUpMetricsChunk(state, level, chunk); err != nil {
+					logger.Log(1, "failed to roll up metrics history of node", nodeID, err.Error())
+				}
+			}
+		}
+		delete(metricsHistoryCache, nodeID)
+	}
+	metricsHistoryMutex.Unlock()
+	nodes, err := GetAllNodes()
+	if err != nil {
+		return err
+	}
+	for i := range nodes {
+		nodeIDs[nodes[i].ID.String()] = struct{}{}
+	}
+	for _, res := range metricsResolutions {
+		cutoff := now.Add(-metricsRetention(res)).Add(-res.chunk)
+		removed := 0
+		for nodeID := range nodeIDs {
+			// chunks starting at or before the cutoff ended before the retention
+			records, err := database.FetchRecordsInRange(res.table, nodeID+"-", metricsChunkKey(nodeID, cutoff.Add(time.Second)))
+			if err != nil {
+				if database.IsEmptyRecord(err) {
+					continue
+				}
+				return err
+			}
+			for key := range records {
+				if err := database.DeleteRecord(res.table, key); err != nil {
+					return err
+				}
+				removed++
+			}
+		}
+		if removed > 0 {
+			logger.Log(2, "removed", fmt.Sprint(removed), "chunks of", string(res.resolution), "metrics history")
+		}
+	}
+	return nil
+}
+
+// DeleteMetricsHistory - removes the history of a deleted node
+func DeleteMetricsHistory(nodeID string) error {
+	metricsHistoryMutex.Lock()
+	delete(metricsHistoryCache, nodeID)
+	metricsHistoryMutex.Unlock()
+	for _, res := range metricsResolutions {
+		// keys of the node are its id, a dash and the unix start of the chunk, '.' sorts right after '-'
+		records, err := database.FetchRecordsInRange(res.table, nodeID+"-", nodeID+".")
+		if err != nil {
+			if database.IsEmptyRecord(err) {
+				continue
+			}
+			return err
+		}
+		for key := range records {
+			if err := database.DeleteRecord(res.table, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pickMetricsResolution - coarsest resolution with a step not exceeding the requested one,
+// coarser if the finer one no longer holds the start of the range
+func pickMetricsResolution(step time.Duration, from, now time.Time) metricsResolution {
+	picked := 0
+	for i, res := range metricsResolutions {
+		if res.step <= step {
+			picked = i
+		}
+	}
+	for picked < len(metricsResolutions)-1 && from.Before(now.Add(-metricsRetention(metricsResolutions[picked]))) {
+		picked++
+	}
+	return metricsResolutions[picked]
+}
+
+func metricsRetention(res metricsResolution) time.Duration {
+	return time.Duration(servercfg.GetMetricsRetention(string(res.resolution))) * time.Hour * 24
+}
+
+// addMetricsPoint - merges the peers of a report into the point of its step
+func addMetricsPoint(chunk *models.MetricsHistoryChunk, timestamp time.Time, peers map[string]models.PeerMetricsPoint) {
+	mergeMetricsPeers(&chunk.Points[metricsPointIndex(chunk, timestamp)], peers)
+}
+
+// metricsPointIndex - index of the point of a chunk at timestamp, inserting an empty one if there is none,
+// reports arrive in order so the point is searched from the end
+func metricsPointIndex(chunk *models.MetricsHistoryChunk, timestamp time.Time) int {
+	i := len(chunk.Points) - 1
+	for i >= 0 && chunk.Points[i].Timestamp.After(timestamp) {
+		i--
+	}
+	if i >= 0 && chunk.Points[i].Timestamp.Equal(timestamp) {
+		return i
+	}
+	point := models.MetricsPoint{
+		Timestamp: timestamp,
+		Peers:     make(map[string]models.PeerMetricsPoint),
+	}
+	chunk.Points = append(chunk.Points, models.MetricsPoint{})
+	copy(chunk.Points[i+2:], chunk.Points[i+1:])
+	chunk.Points[i+1] = point
+	return i + 1
+}
+
+func mergeMetricsPeers(point *models.MetricsPoint, peers map[string]models.PeerMetricsPoint) {
+	for peerID, peer := range peers {
+		merged := point.Peers[peerID]
+		merged.Merge(peer)
+		point.Peers[peerID] = merged
+	}
+}
+
+func getMetricsHistoryState(nodeID string) *metricsHistoryState {
+	state, ok := metricsHistoryCache[nodeID]
+	if !ok {
+		state = &metricsHistoryState{
+			chunks:   make(map[models.MetricsResolution]*models.MetricsHistoryChunk),
+			received: make(map[string]int64),
+			sent:     make(map[string]int64),
+		}
+		metricsHistoryCache[nodeID] = state
+	}
+	return state
+}
+
+// loadMetricsChunk - reads a stored chunk of a node, nil if there is none
+func loadMetricsChunk(res metricsResolution, nodeID string, start time.Time) *models.MetricsHistoryChunk {
+	key := metricsChunkKey(nodeID, start)
+	records, err := database.FetchRecordsInRange(res.table, key, metricsChunkKey(nodeID, start.Add(time.Second)))
+	if err != nil || records[key] == "" {
+		return nil
+	}
+	var chunk models.MetricsHistoryChunk
+	if err := json.Unmarshal([]byte(records[key]), &chunk); err != nil {
+		logger.Log(1, "failed to read metrics history of node", nodeID, err.Error())
+		return nil
+	}
+	return &chunk
+}
+
+func saveMetricsChunk(res metricsResolution, chunk *models.MetricsHistoryChunk) error {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+	return database.Insert(metricsChunkKey(chunk.NodeID, chunk.Start), string(data), res.table)
+}
+
+func metricsChunkKey(nodeID string, start time.Time) string {
+	return fmt.Sprintf("%s-%d", nodeID, start.Unix())
+}
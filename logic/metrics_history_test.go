@@ -0,0 +1,112 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestMetricsHistory(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	nodeID := uuid.New().String()
+	peerID := uuid.New().String()
+	start := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour * 2)
+	report := func(at time.Time, connected bool, latency, received int64) {
+		t.Helper()
+		metrics := models.Metrics{
+			Connectivity: map[string]models.Metric{
+				peerID: {NodeName: "peer", Connected: connected, Latency: latency, TotalReceived: received},
+			},
+		}
+		if err := RecordMetricsHistory(nodeID, "skynet", &metrics, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report(start, true, 10, 1000)
+	report(start.Add(time.Second*30), true, 30, 1500)
+	report(start.Add(time.Minute), false, 0, 2500)
+	report(start.Add(time.Hour), true, 20, 3000)
+	report(start.Add(time.Hour*2), true, 20, 3200)
+
+	t.Run("minute resolution", func(t *testing.T) {
+		is := is.New(t)
+		history, err := GetMetricsHistory(nodeID, start, start.Add(time.Minute*2), time.Minute)
+		is.NoErr(err)
+		is.Equal(history.Resolution, models.MetricsMinute)
+		is.Equal(history.Network, "skynet")
+		is.Equal(len(history.Points), 2)
+		first := history.Points[0].Peers[peerID]
+		is.Equal(first.Samples, 2)
+		is.Equal(first.Latency, float64(20))
+		is.Equal(first.LatencyMax, int64(30))
+		is.Equal(first.Received, int64(500))
+		second := history.Points[1].Peers[peerID]
+		is.Equal(second.ConnectedSamples, 0)
+		is.Equal(second.Received, int64(1000))
+	})
+	t.Run("rolled up to hours", func(t *testing.T) {
+		is := is.New(t)
+		history, err := GetMetricsHistory(nodeID, start, start.Add(time.Hour*2), time.Hour)
+		is.NoErr(err)
+		is.Equal(history.Resolution, models.MetricsHour)
+		is.Equal(len(history.Points), 2)
+		is.Equal(history.Points[0].Peers[peerID].Samples, 3)
+		is.Equal(history.Points[0].Peers[peerID].Received, int64(1500))
+		is.Equal(history.Points[1].Peers[peerID].Received, int64(500))
+	})
+	t.Run("open hour is not rolled up yet", func(t *testing.T) {
+		is := is.New(t)
+		history, err := GetMetricsHistory(nodeID, start.Add(time.Hour*2), start.Add(time.Hour*3), time.Hour)
+		is.NoErr(err)
+		is.Equal(len(history.Points), 0)
+	})
+	t.Run("previous hour is rolled up after a restart", func(t *testing.T) {
+		is := is.New(t)
+		metricsHistoryMutex.Lock()
+		metricsHistoryCache = make(map[string]*metricsHistoryState)
+		metricsHistoryMutex.Unlock()
+		report(start.Add(time.Hour*3), true, 20, 3300)
+		history, err := GetMetricsHistory(nodeID, start, start.Add(time.Hour*3), time.Hour)
+		is.NoErr(err)
+		is.Equal(len(history.Points), 3)
+		// rolling up again replaces the points instead of adding to them
+		is.Equal(history.Points[0].Peers[peerID].Samples, 3)
+		is.Equal(history.Points[2].Peers[peerID].Samples, 1)
+	})
+	t.Run("steps wider than the resolution", func(t *testing.T) {
+		is := is.New(t)
+		history, err := GetMetricsHistory(nodeID, start, start.Add(time.Hour*2), time.Minute*90)
+		is.NoErr(err)
+		is.Equal(history.Resolution, models.MetricsHour)
+		is.Equal(history.Step, time.Hour)
+	})
+	t.Run("invalid range", func(t *testing.T) {
+		is := is.New(t)
+		_, err := GetMetricsHistory(nodeID, start, start, time.Minute)
+		is.True(err != nil)
+	})
+	t.Run("pruned past retention", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(PruneMetricsHistory(start.Add(time.Hour * 24 * 400)))
+		history, err := GetMetricsHistory(nodeID, start.Add(-time.Hour*24*30), start.Add(time.Hour*24*30), time.Hour*24)
+		is.NoErr(err)
+		is.Equal(len(history.Points), 0)
+	})
+	t.Run("deleted with the node", func(t *testing.T) {
+		is := is.New(t)
+		report(start, true, 10, 1000)
+		report(start.Add(time.Hour), true, 10, 1000)
+		is.NoErr(DeleteMetricsHistory(nodeID))
+		history, err := GetMetricsHistory(nodeID, start, start.Add(time.Hour*2), time.Minute)
+		is.NoErr(err)
+		is.Equal(len(history.Points), 0)
+		history, err = GetMetricsHistory(nodeID, start, start.Add(time.Hour*2), time.Hour)
+		is.NoErr(err)
+		is.Equal(len(history.Points), 0)
+	})
+}
@@ -0,0 +1,100 @@
+package logic
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+// stunObservationTTL - time a source address answered by the stun server is trusted as a mapped address
+const stunObservationTTL = time.Minute * 5
+
+// stunObservation - server ips a source address was answered on
+type stunObservation struct {
+	seen      time.Time
+	serverIPs uint8
+}
+
+var (
+	stunObservationsMutex sync.Mutex
+	stunObservations      = make(map[netip.AddrPort]stunObservation)
+	stunObservationsPrune time.Time
+)
+
+// RecordStunMapping - records the source address of a binding request the stun server answered
+// on its ip of the given index (0 primary, 1 alternate)
+func RecordStunMapping(mapped netip.AddrPort, serverIP int, now time.Time) {
+	stunObservationsMutex.Lock()
+	defer stunObservationsMutex.Unlock()
+	if now.Sub(stunObservationsPrune) >= stunObservationTTL {
+		for addr, observation := range stunObservations {
+			if now.Sub(observation.seen) >= stunObservationTTL {
+				delete(stunObservations, addr)
+			}
+		}
+		stunObservationsPrune = now
+	}
+	observation := stunObservations[mapped]
+	observation.seen = now
+	observation.serverIPs |= 1 << serverIP
+	stunObservations[mapped] = observation
+}
+
+// getStunObservation - returns the recent observation of a mapped address
+func getStunObservation(mapped netip.AddrPort, now time.Time) (stunObservation, bool) {
+	stunObservationsMutex.Lock()
+	defer stunObservationsMutex.Unlock()
+	observation, ok := stunObservations[mapped]
+	if !ok || now.Sub(observation.seen) >= stunObservationTTL {
+		return stunObservation{}, false
+	}
+	return observation, true
+}
+
+// IsNatBehaviourObserved - checks that the mapped address a host reports was recently answered
+// by this server's stun server running behaviour discovery, other results are not trusted
+func IsNatBehaviourObserved(behaviour *models.NatBehaviour) bool {
+	if behaviour == nil || !behaviour.MappedAddr.IsValid() || servercfg.GetStunAltIP() == "" {
+		return false
+	}
+	_, ok := getStunObservation(behaviour.MappedAddr, time.Now())
+	return ok
+}
+
+// ClassifyNatType - derives the NAT type of a host from its RFC 5780 behaviour discovery results,
+// the current type is kept when the results do not allow a classification or were not observed by the stun server
+func ClassifyNatType(behaviour *models.NatBehaviour, current string) string {
+	if behaviour == nil || behaviour.Mapping == "" || !IsNatBehaviourObserved(behaviour) {
+		return current
+	}
+	mapping := behaviour.Mapping
+	if observation, _ := getStunObservation(behaviour.MappedAddr, time.Now()); observation.serverIPs != 0b11 {
+		// an endpoint independent mapping is seen on both server ips, do not take the host's word for it
+		mapping = models.NAT_Behaviours.AddressDependent
+	}
+	// an empty filtering means the host could not run the filtering tests, only the mapping is known
+	openFiltering := behaviour.Filtering == "" || behaviour.Filtering == models.NAT_Behaviours.EndpointIndependent
+	if behaviour.LocalAddr.IsValid() && behaviour.LocalAddr.Addr() == behaviour.MappedAddr.Addr() {
+		if openFiltering {
+			return models.NAT_Types.Public
+		}
+		// a public address behind a filtering firewall still needs peers to punch through
+		return models.NAT_Types.Asymmetric
+	}
+	if mapping != models.NAT_Behaviours.EndpointIndependent {
+		// the mapping changes per destination, peers cannot reuse the endpoint seen by the stun server
+		return models.NAT_Types.Symmetric
+	}
+	// a second NAT layer is invisible to a single stun server, keep a double NAT reported by the host
+	if current == models.NAT_Types.Double {
+		return current
+	}
+	if behaviour.Filtering == models.NAT_Behaviours.EndpointIndependent {
+		// a full cone NAT forwards any inbound traffic to the mapped address, peers reach it like a public host
+		return models.NAT_Types.Public
+	}
+	return models.NAT_Types.Asymmetric
+}
@@ -0,0 +1,98 @@
+package logic
+
+import (
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestClassifyNatType(t *testing.T) {
+	local := netip.MustParseAddrPort("192.168.1.10:51821")
+	mapped := netip.MustParseAddrPort("203.0.113.7:40000")
+	unobserved := netip.MustParseAddrPort("203.0.113.8:40000")
+	oneIP := netip.MustParseAddrPort("203.0.113.9:40000")
+	os.Setenv("STUN_ALT_IP", "198.51.100.2")
+	defer os.Unsetenv("STUN_ALT_IP")
+	RecordStunMapping(mapped, 0, time.Now())
+	RecordStunMapping(mapped, 1, time.Now())
+	RecordStunMapping(oneIP, 0, time.Now())
+	t.Run("no results keeps current", func(t *testing.T) {
+		is := is.New(t)
+		is.Equal(ClassifyNatType(nil, models.NAT_Types.Double), models.NAT_Types.Double)
+		is.Equal(ClassifyNatType(&models.NatBehaviour{}, models.NAT_Types.Public), models.NAT_Types.Public)
+	})
+	t.Run("public", func(t *testing.T) {
+		is := is.New(t)
+		behaviour := &models.NatBehaviour{
+			LocalAddr:  mapped,
+			MappedAddr: mapped,
+			Mapping:    models.NAT_Behaviours.EndpointIndependent,
+			Filtering:  models.NAT_Behaviours.EndpointIndependent,
+		}
+		is.Equal(ClassifyNatType(behaviour, ""), models.NAT_Types.Public)
+		behaviour.Filtering = models.NAT_Behaviours.AddressAndPortDependent
+		is.Equal(ClassifyNatType(behaviour, ""), models.NAT_Types.Asymmetric)
+	})
+	t.Run("symmetric", func(t *testing.T) {
+		is := is.New(t)
+		behaviour := &models.NatBehaviour{
+			LocalAddr:  local,
+			MappedAddr: mapped,
+			Mapping:    models.NAT_Behaviours.AddressAndPortDependent,
+			Filtering:  models.NAT_Behaviours.AddressAndPortDependent,
+		}
+		is.Equal(ClassifyNatType(behaviour, models.NAT_Types.Asymmetric), models.NAT_Types.Symmetric)
+	})
+	t.Run("asymmetric", func(t *testing.T) {
+		is := is.New(t)
+		behaviour := &models.NatBehaviour{
+			LocalAddr:  local,
+			MappedAddr: mapped,
+			Mapping:    models.NAT_Behaviours.EndpointIndependent,
+			Filtering:  models.NAT_Behaviours.AddressDependent,
+		}
+		is.Equal(ClassifyNatType(behaviour, models.NAT_Types.Symmetric), models.NAT_Types.Asymmetric)
+		is.Equal(ClassifyNatType(behaviour, models.NAT_Types.Double), models.NAT_Types.Double)
+	})
+	t.Run("full cone", func(t *testing.T) {
+		is := is.New(t)
+		behaviour := &models.NatBehaviour{
+			LocalAddr:  local,
+			MappedAddr: mapped,
+			Mapping:    models.NAT_Behaviours.EndpointIndependent,
+			Filtering:  models.NAT_Behaviours.EndpointIndependent,
+		}
+		is.Equal(ClassifyNatType(behaviour, models.NAT_Types.Symmetric), models.NAT_Types.Public)
+	})
+	t.Run("unobserved results are ignored", func(t *testing.T) {
+		is := is.New(t)
+		behaviour := &models.NatBehaviour{
+			LocalAddr:  local,
+			MappedAddr: unobserved,
+			Mapping:    models.NAT_Behaviours.EndpointIndependent,
+			Filtering:  models.NAT_Behaviours.EndpointIndependent,
+		}
+		is.True(!IsNatBehaviourObserved(behaviour))
+		is.Equal(ClassifyNatType(behaviour, models.NAT_Types.Symmetric), models.NAT_Types.Symmetric)
+	})
+	t.Run("mapping seen on one server ip", func(t *testing.T) {
+		is := is.New(t)
+		behaviour := &models.NatBehaviour{
+			LocalAddr:  local,
+			MappedAddr: oneIP,
+			Mapping:    models.NAT_Behaviours.EndpointIndependent,
+			Filtering:  models.NAT_Behaviours.AddressDependent,
+		}
+		is.Equal(ClassifyNatType(behaviour, models.NAT_Types.Asymmetric), models.NAT_Types.Symmetric)
+	})
+	t.Run("observations expire", func(t *testing.T) {
+		is := is.New(t)
+		expired := netip.MustParseAddrPort("203.0.113.10:40000")
+		RecordStunMapping(expired, 0, time.Now().Add(-stunObservationTTL))
+		is.True(!IsNatBehaviourObserved(&models.NatBehaviour{MappedAddr: expired}))
+	})
+}
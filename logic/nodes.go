@@ -139,6 +139,15 @@ func DeleteNode(node *models.Node, purge bool) error {
 func deleteNodeByID(node *models.Node) error {
 	var err error
 	var key = node.ID.String()
+	if node.IsInternetGateway() {
+		if err := UnsetInternetGw(node); err != nil {
+			logger.Log(0, "failed to remove internet gateway", node.ID.String(), err.Error())
+		}
+	} else if node.InternetGateway != nil && node.InternetGateway.IP != nil {
+		if err := replaceInternetGwClient(node.Network, node.ID.String(), ""); err != nil {
+			logger.Log(1, "failed to remove node", node.ID.String(), "from its internet gateway", err.Error())
+		}
+	}
 	//delete any ext clients as required
 	if node.IsIngressGateway {
 		if err := DeleteGatewayExtClients(node.ID.String(), node.Network); err != nil {
@@ -153,6 +162,12 @@ func deleteNodeByID(node *models.Node) error {
 	if servercfg.IsDNSMode() {
 		SetDNS()
 	}
+	if node.IsEgressGateway {
+		forgetEgressGateway(node.ID.String())
+		if _, err = UpdateEgressHA(node.Network); err != nil {
+			logger.Log(1, "failed to update egress gateway selection for network", node.Network, err.Error())
+		}
+	}
 	if node.OwnerID != "" {
 		err = pro.DissociateNetworkUserNode(node.OwnerID, node.Network, node.ID.String())
 		if err != nil {
@@ -168,6 +183,9 @@ func deleteNodeByID(node *models.Node) error {
 	if err = DeleteMetrics(node.ID.String()); err != nil {
 		logger.Log(1, "unable to remove metrics from DB for node", node.ID.String(), err.Error())
 	}
+	if err = DeleteMetricsHistory(node.ID.String()); err != nil {
+		logger.Log(1, "unable to remove metrics history from DB for node", node.ID.String(), err.Error())
+	}
 	return nil
 }
 
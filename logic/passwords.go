@@ -0,0 +1,199 @@
+package logic
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidPasswordResetToken - the password reset token is wrong, was used before or expired
+var ErrInvalidPasswordResetToken = errors.New("invalid or expired password reset token")
+
+var passwordResetMutex = &sync.Mutex{}
+
+// ValidatePassword - checks a password against the password policy of local users
+func ValidatePassword(username, password string) error {
+	if minLength := servercfg.GetPasswordMinLength(); utf8.RuneCountInString(password) < minLength {
+		return fmt.Errorf("password has to be at least %d characters long", minLength)
+	}
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, used := range []bool{lower, upper, digit, symbol} {
+		if used {
+			classes++
+		}
+	}
+	if required := servercfg.GetPasswordCharacterClasses(); classes < required {
+		return fmt.Errorf("password has to mix at least %d of lowercase letters, uppercase letters, digits and symbols", required)
+	}
+	if strings.EqualFold(password, username) {
+		return errors.New("password cannot be the username")
+	}
+	return nil
+}
+
+// CreatePasswordResetToken - creates a one-time token the user can set a new password with,
+// replacing any outstanding one. The token is not stored and cannot be fetched again.
+func CreatePasswordResetToken(username string) (models.PasswordResetToken, error) {
+	var resetToken models.PasswordResetToken
+	user, err := GetUser(username)
+	if err != nil {
+		return resetToken, err
+	}
+	if servercfg.IsLDAPEnabled() && IsLDAPUser(user) {
+		return resetToken, fmt.Errorf("the password of %s is managed by the ldap server", username)
+	}
+	token := RandomString(40)
+	if token == "" {
+		return resetToken, errors.New("failed to generate password reset token")
+	}
+	reset := models.PasswordReset{
+		UserName:  username,
+		TokenHash: hashPasswordResetToken(token),
+		ExpiresAt: time.Now().Add(servercfg.GetPasswordResetTokenValidity()),
+	}
+	data, err := json.Marshal(&reset)
+	if err != nil {
+		return resetToken, err
+	}
+	if err = database.Insert(username, string(data), database.PASSWORD_RESETS_TABLE_NAME); err != nil {
+		return resetToken, err
+	}
+	resetToken.UserName = username
+	resetToken.Token = token
+	resetToken.ExpiresAt = reset.ExpiresAt
+	return resetToken, nil
+}
+
+// ResetUserPassword - sets a new password of a user with its one-time reset token, ending the sessions
+// of the user and lifting its lockout. The token stays valid if the password is rejected by the policy.
+func ResetUserPassword(request models.PasswordResetRequest) error {
+	passwordResetMutex.Lock()
+	defer passwordResetMutex.Unlock()
+	record, err := database.FetchRecord(database.PASSWORD_RESETS_TABLE_NAME, request.UserName)
+	if err != nil {
+		return ErrInvalidPasswordResetToken
+	}
+	var reset models.PasswordReset
+	if err = json.Unmarshal([]byte(record), &reset); err != nil {
+		return err
+	}
+	if time.Now().After(reset.ExpiresAt) {
+		deletePasswordReset(request.UserName)
+		return ErrInvalidPasswordResetToken
+	}
+	if subtle.ConstantTimeCompare([]byte(reset.TokenHash), []byte(hashPasswordResetToken(request.Token))) != 1 {
+		return ErrInvalidPasswordResetToken
+	}
+	user, err := GetUser(request.UserName)
+	if err != nil {
+		return err
+	}
+	if _, err = UpdateUser(&models.User{Password: request.Password}, user); err != nil {
+		return err
+	}
+	deletePasswordReset(request.UserName)
+	if err = DeleteUserSessions(request.UserName); err != nil {
+		logger.Log(0, "failed to end sessions of user", request.UserName, err.Error())
+	}
+	ClearLoginLockout(request.UserName)
+	return nil
+}
+
+// checkNewPassword - checks a password a user is about to set against the policy and its previous passwords
+func checkNewPassword(user *models.User, password string) error {
+	if servercfg.IsLDAPEnabled() && IsLDAPUser(user) {
+		return fmt.Errorf("the password of %s is managed by the ldap server", user.UserName)
+	}
+	if err := ValidatePassword(user.UserName, password); err != nil {
+		return err
+	}
+	history := servercfg.GetPasswordHistory()
+	if history == 0 {
+		return nil
+	}
+	hashes := append([]string{user.Password}, getPasswordHistory(user.UserName).Hashes...)
+	if len(hashes) > history {
+		hashes = hashes[:history]
+	}
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return fmt.Errorf("password cannot be one of the last %d passwords", history)
+		}
+	}
+	return nil
+}
+
+// recordPasswordHistory - remembers the hash of a password a user no longer has
+func recordPasswordHistory(username, hash string) {
+	history := servercfg.GetPasswordHistory()
+	if history == 0 || hash == "" {
+		return
+	}
+	passwordHistory := getPasswordHistory(username)
+	passwordHistory.UserName = username
+	passwordHistory.Hashes = append([]string{hash}, passwordHistory.Hashes...)
+	if len(passwordHistory.Hashes) > history {
+		passwordHistory.Hashes = passwordHistory.Hashes[:history]
+	}
+	data, err := json.Marshal(&passwordHistory)
+	if err == nil {
+		err = database.Insert(username, string(data), database.PASSWORD_HISTORY_TABLE_NAME)
+	}
+	if err != nil {
+		logger.Log(0, "failed to record password history of user", username, err.Error())
+	}
+}
+
+func getPasswordHistory(username string) models.PasswordHistory {
+	var passwordHistory models.PasswordHistory
+	if record, err := database.FetchRecord(database.PASSWORD_HISTORY_TABLE_NAME, username); err == nil {
+		json.Unmarshal([]byte(record), &passwordHistory)
+	}
+	return passwordHistory
+}
+
+// deletePasswordRecords - removes the password history and any outstanding reset token of a deleted user
+func deletePasswordRecords(username string) {
+	if err := database.DeleteRecord(database.PASSWORD_HISTORY_TABLE_NAME, username); err != nil && !database.IsEmptyRecord(err) {
+		logger.Log(0, "failed to remove password history of user", username, err.Error())
+	}
+	deletePasswordReset(username)
+}
+
+func deletePasswordReset(username string) {
+	if err := database.DeleteRecord(database.PASSWORD_RESETS_TABLE_NAME, username); err != nil && !database.IsEmptyRecord(err) {
+		logger.Log(0, "failed to remove password reset token of user", username, err.Error())
+	}
+}
+
+func hashPasswordResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
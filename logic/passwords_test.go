@@ -0,0 +1,112 @@
+package logic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestValidatePassword(t *testing.T) {
+	t.Setenv("PASSWORD_MIN_LENGTH", "8")
+	t.Setenv("PASSWORD_CHARACTER_CLASSES", "3")
+	tests := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{name: "too short", password: "Ab1!"},
+		{name: "too few classes", password: "abcdefgh1"},
+		{name: "three classes", password: "abcdefG1", valid: true},
+		{name: "symbols count", password: "abcdef!1", valid: true},
+		{name: "username", password: "Pwuser12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			err := ValidatePassword("pwuser12", tt.password)
+			is.Equal(err == nil, tt.valid)
+		})
+	}
+}
+
+func TestPasswordHistory(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	t.Setenv("PASSWORD_HISTORY", "2")
+	is := is.New(t)
+	is.NoErr(CreateUser(&models.User{UserName: "pwhistory", Password: "first-pass"}))
+	defer DeleteUser("pwhistory")
+	setPassword := func(password string) error {
+		user, err := GetUser("pwhistory")
+		is.NoErr(err)
+		_, err = UpdateUser(&models.User{Password: password}, user)
+		return err
+	}
+
+	is.True(setPassword("first-pass") != nil) // current password
+	is.NoErr(setPassword("second-pass"))
+	is.True(setPassword("first-pass") != nil) // previous password
+	is.NoErr(setPassword("third-pass"))
+	is.NoErr(setPassword("first-pass")) // no longer one of the last 2
+	_, err := DeleteUser("pwhistory")
+	is.NoErr(err)
+	is.Equal(len(getPasswordHistory("pwhistory").Hashes), 0)
+}
+
+func TestPasswordReset(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	SetJWTSecret()
+	is := is.New(t)
+	user := models.User{UserName: "pwreset", Password: "old-password"}
+	is.NoErr(CreateUser(&user))
+	defer DeleteUser("pwreset")
+
+	t.Run("wrong token", func(t *testing.T) {
+		is := is.New(t)
+		_, err := CreatePasswordResetToken("pwreset")
+		is.NoErr(err)
+		err = ResetUserPassword(models.PasswordResetRequest{UserName: "pwreset", Token: "guess", Password: "new-password"})
+		is.Equal(err, ErrInvalidPasswordResetToken)
+	})
+	t.Run("reset", func(t *testing.T) {
+		is := is.New(t)
+		stored, err := GetUser("pwreset")
+		is.NoErr(err)
+		login, err := CreateUserSession(stored, "", "")
+		is.NoErr(err)
+		token, err := CreatePasswordResetToken("pwreset")
+		is.NoErr(err)
+		is.True(token.Token != "")
+		// a password refused by the policy leaves the token valid
+		err = ResetUserPassword(models.PasswordResetRequest{UserName: "pwreset", Token: token.Token, Password: "abc"})
+		is.True(err != nil)
+		is.NoErr(ResetUserPassword(models.PasswordResetRequest{UserName: "pwreset", Token: token.Token, Password: "new-password"}))
+		_, err = VerifyUserCredentials(models.UserAuthParams{UserName: "pwreset", Password: "new-password"})
+		is.NoErr(err)
+		_, _, _, err = VerifyUserToken(login.AuthToken)
+		is.True(err != nil) // sessions end with the reset
+		// the token is one-time
+		err = ResetUserPassword(models.PasswordResetRequest{UserName: "pwreset", Token: token.Token, Password: "other-password"})
+		is.Equal(err, ErrInvalidPasswordResetToken)
+	})
+	t.Run("expired token", func(t *testing.T) {
+		is := is.New(t)
+		token, err := CreatePasswordResetToken("pwreset")
+		is.NoErr(err)
+		// a zero expiry lies in the past
+		record := models.PasswordReset{UserName: "pwreset", TokenHash: hashPasswordResetToken(token.Token)}
+		data, _ := json.Marshal(&record)
+		is.NoErr(database.Insert("pwreset", string(data), database.PASSWORD_RESETS_TABLE_NAME))
+		err = ResetUserPassword(models.PasswordResetRequest{UserName: "pwreset", Token: token.Token, Password: "other-password"})
+		is.Equal(err, ErrInvalidPasswordResetToken)
+	})
+	t.Run("unknown user", func(t *testing.T) {
+		is := is.New(t)
+		_, err := CreatePasswordResetToken("pwnobody")
+		is.True(err != nil)
+	})
+}
@@ -0,0 +1,366 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/netip"
+	"sort"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+)
+
+const (
+	// ENDPOINT_SWITCH_HOLDDOWN - time an endpoint is used before its metrics are trusted,
+	// metrics reported right after a switch still describe the previous endpoint
+	ENDPOINT_SWITCH_HOLDDOWN = time.Minute * 3
+	// ENDPOINT_FAILURE_TTL - time after which an endpoint that failed is tried again
+	ENDPOINT_FAILURE_TTL = time.Minute * 30
+	// ENDPOINT_SWITCH_MARGIN - score a candidate has to exceed a working endpoint by to replace it
+	ENDPOINT_SWITCH_MARGIN = 15
+	// punchedPathBonus - score of the candidate a hole punch found a direct path over
+	punchedPathBonus = 50
+)
+
+// GetPeerEndpoint - picks the endpoint a host uses to reach a peer host out of the candidates of the peer,
+// selection is the stored selection of the pair and punched the peer's end of a punched direct path,
+// nothing is written here, selections only change when metrics are reported
+func GetPeerEndpoint(host, peerHost *models.Host, selection models.PeerEndpoint, punched netip.AddrPort) *net.UDPAddr {
+	if selection.CandidateType == models.TurnCandidate {
+		// a turn relay address only works through the turn protocol, never as a plain endpoint
+		selection = models.PeerEndpoint{}
+	}
+	if !selection.Pinned {
+		selection, _ = selectPeerEndpoint(reachableCandidates(host, peerHost), selection, nil, punched, time.Now())
+	}
+	if !selection.Endpoint.IsValid() {
+		return &net.UDPAddr{
+			IP:   peerHost.EndpointIP,
+			Port: getPeerWgListenPort(peerHost),
+		}
+	}
+	return net.UDPAddrFromAddrPort(selection.Endpoint)
+}
+
+// UpdatePeerEndpoints - feeds the connectivity a node reported into the endpoint selections of its host,
+// returns true if an endpoint the host uses changed
+func UpdatePeerEndpoints(node *models.Node, metrics *models.Metrics) (bool, error) {
+	if metrics == nil || len(metrics.Connectivity) == 0 {
+		return false, nil
+	}
+	hosts, err := GetAllHosts()
+	if err != nil {
+		return false, err
+	}
+	hostMap := make(map[string]*models.Host, len(hosts))
+	for i := range hosts {
+		hostMap[hosts[i].ID.String()] = &hosts[i]
+	}
+	host, ok := hostMap[node.HostID.String()]
+	if !ok {
+		return false, errors.New("host of node " + node.ID.String() + " not found")
+	}
+	peers, err := GetNetworkNodes(node.Network)
+	if err != nil {
+		return false, err
+	}
+	selections := getHostEndpointSelections(host.ID.String())
+	punched := getHostPunchedEndpoints(host.ID.String())
+	now := time.Now()
+	changed := false
+	for _, peer := range peers {
+		metric, ok := metrics.Connectivity[peer.ID.String()]
+		if !ok || peer.HostID == node.HostID {
+			continue
+		}
+		peerHost, ok := hostMap[peer.HostID.String()]
+		if !ok {
+			continue
+		}
+		current := selections[peerHost.ID.String()]
+		current.HostID = host.ID.String()
+		current.PeerHostID = peerHost.ID.String()
+		selection, selectionChanged := selectPeerEndpoint(reachableCandidates(host, peerHost), current, &metric, punched[peerHost.ID.String()], now)
+		if !selectionChanged {
+			continue
+		}
+		if err := savePeerEndpoint(&selection); err != nil {
+			return changed, err
+		}
+		selections[peerHost.ID.String()] = selection
+		if selection.Endpoint != current.Endpoint {
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// getHostEndpointSelections - endpoint selections of a host keyed by the peer host
+func getHostEndpointSelections(hostID string) map[string]models.PeerEndpoint {
+	selections := make(map[string]models.PeerEndpoint)
+	stored, err := GetHostPeerEndpoints(hostID)
+	if err != nil {
+		logger.Log(1, "failed to fetch endpoint selections of host", hostID, err.Error())
+	}
+	for _, selection := range stored {
+		selections[selection.PeerHostID] = selection
+	}
+	return selections
+}
+
+// getHostPunchedEndpoints - the peer's end of the direct paths punched by a host keyed by the peer host
+func getHostPunchedEndpoints(hostID string) map[string]netip.AddrPort {
+	punched := make(map[string]netip.AddrPort)
+	paths, err := GetHostPeerPaths(hostID)
+	if err != nil {
+		logger.Log(1, "failed to fetch peer paths of host", hostID, err.Error())
+	}
+	for _, path := range paths {
+		if !path.Direct {
+			continue
+		}
+		if path.HostA == hostID {
+			punched[path.HostB] = path.EndpointB
+		} else {
+			punched[path.HostA] = path.EndpointA
+		}
+	}
+	return punched
+}
+
+// GetPeerEndpointSelection - fetches the endpoint selected for a host to reach a peer host
+func GetPeerEndpointSelection(hostID, peerHostID string) (models.PeerEndpoint, error) {
+	var selection models.PeerEndpoint
+	record, err := database.FetchRecord(database.PEER_ENDPOINTS_TABLE_NAME, peerEndpointKey(hostID, peerHostID))
+	if err != nil {
+		return selection, err
+	}
+	err = json.Unmarshal([]byte(record), &selection)
+	return selection, err
+}
+
+// GetHostPeerEndpoints - fetches the endpoints selected for a host to reach its peers
+func GetHostPeerEndpoints(hostID string) ([]models.PeerEndpoint, error) {
+	selections := []models.PeerEndpoint{}
+	records, err := database.FetchRecords(database.PEER_ENDPOINTS_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return selections, err
+	}
+	for _, record := range records {
+		var selection models.PeerEndpoint
+		if err := json.Unmarshal([]byte(record), &selection); err != nil {
+			continue
+		}
+		if selection.HostID == hostID {
+			selections = append(selections, selection)
+		}
+	}
+	sort.Slice(selections, func(i, j int) bool {
+		return selections[i].PeerHostID < selections[j].PeerHostID
+	})
+	return selections, nil
+}
+
+// PinPeerEndpoint - makes a host use the given endpoint to reach a peer host regardless of metrics
+func PinPeerEndpoint(hostID, peerHostID string, endpoint netip.AddrPort) (models.PeerEndpoint, error) {
+	if hostID == peerHostID {
+		return models.PeerEndpoint{}, errors.New("cannot pin an endpoint of a host to itself")
+	}
+	if !endpoint.IsValid() || endpoint.Port() == 0 {
+		return models.PeerEndpoint{}, errors.New("invalid endpoint " + endpoint.String())
+	}
+	selection, err := GetPeerEndpointSelection(hostID, peerHostID)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return selection, err
+	}
+	selection.HostID = hostID
+	selection.PeerHostID = peerHostID
+	selection.Endpoint = endpoint
+	selection.CandidateType = ""
+	if peerHost, err := GetHost(peerHostID); err == nil {
+		for _, candidate := range GetEndpointCandidates(peerHost) {
+			if candidate.Endpoint == endpoint {
+				selection.CandidateType = candidate.Type
+				break
+			}
+		}
+	}
+	if selection.CandidateType == models.TurnCandidate {
+		return models.PeerEndpoint{}, errors.New("a turn relay address cannot be used as a peer endpoint")
+	}
+	selection.Pinned = true
+	selection.UpdatedAt = time.Now()
+	return selection, savePeerEndpoint(&selection)
+}
+
+// UnpinPeerEndpoint - hands the endpoint a host uses to reach a peer host back to the automatic selection
+func UnpinPeerEndpoint(hostID, peerHostID string) error {
+	selection, err := GetPeerEndpointSelection(hostID, peerHostID)
+	if err != nil {
+		return err
+	}
+	if !selection.Pinned {
+		return errors.New("endpoint is not pinned")
+	}
+	return database.DeleteRecord(database.PEER_ENDPOINTS_TABLE_NAME, peerEndpointKey(hostID, peerHostID))
+}
+
+// DeleteHostPeerEndpoints - removes the endpoint selections of and towards a deleted host
+func DeleteHostPeerEndpoints(hostID string) error {
+	records, err := database.FetchRecords(database.PEER_ENDPOINTS_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return err
+	}
+	for _, record := range records {
+		var selection models.PeerEndpoint
+		if err := json.Unmarshal([]byte(record), &selection); err != nil {
+			continue
+		}
+		if selection.HostID != hostID && selection.PeerHostID != hostID {
+			continue
+		}
+		if err := database.DeleteRecord(database.PEER_ENDPOINTS_TABLE_NAME, peerEndpointKey(selection.HostID, selection.PeerHostID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reachableCandidates - candidates of a peer host the host can be expected to reach,
+// local addresses are only used by hosts behind the same NAT sharing a subnet,
+// turn relay addresses are left to the turn fallback
+func reachableCandidates(host, peerHost *models.Host) []models.EndpointCandidate {
+	sameNAT := host.EndpointIP != nil && host.EndpointIP.Equal(peerHost.EndpointIP)
+	hasIPv6 := false
+	for _, candidate := range GetEndpointCandidates(host) {
+		if candidate.Type == models.IPv6Candidate {
+			hasIPv6 = true
+			break
+		}
+	}
+	candidates := []models.EndpointCandidate{}
+	for _, candidate := range GetEndpointCandidates(peerHost) {
+		switch candidate.Type {
+		case models.TurnCandidate:
+			continue
+		case models.LocalCandidate:
+			if !sameNAT || !sharesSubnet(host, candidate.Endpoint.Addr()) {
+				continue
+			}
+		case models.IPv6Candidate:
+			if !hasIPv6 {
+				continue
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// sharesSubnet - checks if an address lies in the subnet of an interface of the host without being its own
+func sharesSubnet(host *models.Host, addr netip.Addr) bool {
+	ip := net.IP(addr.AsSlice())
+	shared := false
+	for _, iface := range host.Interfaces {
+		if iface.Address.IP.Equal(ip) {
+			return false
+		}
+		if iface.Address.Mask != nil && iface.Address.Contains(ip) {
+			shared = true
+		}
+	}
+	return shared
+}
+
+// selectPeerEndpoint - records the metric of the endpoint in use and switches to the best scoring candidate
+// if the endpoint failed or a candidate beats it by ENDPOINT_SWITCH_MARGIN, returns true if the selection changed
+func selectPeerEndpoint(candidates []models.EndpointCandidate, current models.PeerEndpoint, metric *models.Metric, punched netip.AddrPort, now time.Time) (models.PeerEndpoint, bool) {
+	if current.Pinned {
+		return current, false
+	}
+	selection := current
+	selection.Latencies = make(map[string]int64)
+	for endpoint, latency := range current.Latencies {
+		selection.Latencies[endpoint] = latency
+	}
+	selection.Failed = make(map[string]time.Time)
+	for endpoint, failedAt := range current.Failed {
+		if now.Sub(failedAt) < ENDPOINT_FAILURE_TTL {
+			selection.Failed[endpoint] = failedAt
+		}
+	}
+	changed := len(selection.Failed) != len(current.Failed)
+	inUse := selection.Endpoint.String()
+	working := selection.Endpoint.IsValid()
+	if working && metric != nil && now.Sub(selection.UpdatedAt) >= ENDPOINT_SWITCH_HOLDDOWN {
+		if metric.Connected {
+			if selection.Latencies[inUse] != metric.Latency {
+				selection.Latencies[inUse] = metric.Latency
+				changed = true
+			}
+		} else {
+			selection.Failed[inUse] = now
+			working = false
+			changed = true
+		}
+	}
+	score := func(candidate models.EndpointCandidate) int {
+		s := candidate.Priority
+		if candidate.Endpoint == punched {
+			s += punchedPathBonus
+		}
+		if latency, ok := selection.Latencies[candidate.Endpoint.String()]; ok {
+			s -= int(latency / 10)
+		}
+		return s
+	}
+	var best *models.EndpointCandidate
+	currentScore, found := 0, false
+	for i := range candidates {
+		candidate := candidates[i]
+		if candidate.Endpoint == selection.Endpoint {
+			currentScore, found = score(candidate), true
+		}
+		if _, failed := selection.Failed[candidate.Endpoint.String()]; failed {
+			continue
+		}
+		if best == nil || score(candidate) > score(*best) {
+			best = &candidates[i]
+		}
+	}
+	if best == nil && len(candidates) > 0 {
+		// every candidate failed, start over with the best one
+		best = &candidates[0]
+		for i := range candidates {
+			if score(candidates[i]) > score(*best) {
+				best = &candidates[i]
+			}
+		}
+	}
+	if best == nil || best.Endpoint == selection.Endpoint {
+		return selection, changed
+	}
+	if working && found && score(*best) <= currentScore+ENDPOINT_SWITCH_MARGIN {
+		return selection, changed
+	}
+	selection.Endpoint = best.Endpoint
+	selection.CandidateType = best.Type
+	selection.UpdatedAt = now
+	return selection, true
+}
+
+func savePeerEndpoint(selection *models.PeerEndpoint) error {
+	data, err := json.Marshal(selection)
+	if err != nil {
+		return err
+	}
+	return database.Insert(peerEndpointKey(selection.HostID, selection.PeerHostID), string(data), database.PEER_ENDPOINTS_TABLE_NAME)
+}
+
+// peerEndpointKey - key of the endpoint a host uses to reach a peer host
+func peerEndpointKey(hostID, peerHostID string) string {
+	return hostID + "-" + peerHostID
+}
@@ -0,0 +1,205 @@
+package logic
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestSelectPeerEndpoint(t *testing.T) {
+	local := models.EndpointCandidate{Type: models.LocalCandidate, Endpoint: netip.MustParseAddrPort("192.168.1.20:51821"), Priority: 100}
+	public := models.EndpointCandidate{Type: models.PublicCandidate, Endpoint: netip.MustParseAddrPort("203.0.113.7:51821"), Priority: 80}
+	ipv6 := models.EndpointCandidate{Type: models.IPv6Candidate, Endpoint: netip.MustParseAddrPort("[2001:db8::20]:51821"), Priority: 75}
+	turn := models.EndpointCandidate{Type: models.TurnCandidate, Endpoint: netip.MustParseAddrPort("198.51.100.1:49152"), Priority: 10}
+	candidates := []models.EndpointCandidate{local, public, turn}
+	now := time.Now()
+	settled := now.Add(-ENDPOINT_SWITCH_HOLDDOWN)
+	t.Run("highest priority first", func(t *testing.T) {
+		is := is.New(t)
+		selection, changed := selectPeerEndpoint(candidates, models.PeerEndpoint{}, nil, netip.AddrPort{}, now)
+		is.True(changed)
+		is.Equal(selection.Endpoint, local.Endpoint)
+		is.Equal(selection.CandidateType, models.LocalCandidate)
+	})
+	t.Run("punched path preferred", func(t *testing.T) {
+		is := is.New(t)
+		selection, _ := selectPeerEndpoint(candidates, models.PeerEndpoint{}, nil, public.Endpoint, now)
+		is.Equal(selection.Endpoint, public.Endpoint)
+	})
+	t.Run("switch on disconnect", func(t *testing.T) {
+		is := is.New(t)
+		current := models.PeerEndpoint{Endpoint: local.Endpoint, UpdatedAt: settled}
+		selection, changed := selectPeerEndpoint(candidates, current, &models.Metric{Connected: false}, netip.AddrPort{}, now)
+		is.True(changed)
+		is.Equal(selection.Endpoint, public.Endpoint)
+		_, failed := selection.Failed[local.Endpoint.String()]
+		is.True(failed)
+	})
+	t.Run("metrics ignored during holddown", func(t *testing.T) {
+		is := is.New(t)
+		current := models.PeerEndpoint{Endpoint: local.Endpoint, UpdatedAt: now}
+		selection, changed := selectPeerEndpoint(candidates, current, &models.Metric{Connected: false}, netip.AddrPort{}, now)
+		is.True(!changed)
+		is.Equal(selection.Endpoint, local.Endpoint)
+	})
+	t.Run("working endpoint kept within margin", func(t *testing.T) {
+		is := is.New(t)
+		current := models.PeerEndpoint{Endpoint: public.Endpoint, UpdatedAt: settled}
+		selection, _ := selectPeerEndpoint([]models.EndpointCandidate{ipv6, public}, current, &models.Metric{Connected: true, Latency: 30}, netip.AddrPort{}, now)
+		is.Equal(selection.Endpoint, public.Endpoint)
+		is.Equal(selection.Latencies[public.Endpoint.String()], int64(30))
+	})
+	t.Run("failed endpoint retried after ttl", func(t *testing.T) {
+		is := is.New(t)
+		current := models.PeerEndpoint{
+			Endpoint:  public.Endpoint,
+			UpdatedAt: settled,
+			Failed:    map[string]time.Time{local.Endpoint.String(): now.Add(-ENDPOINT_FAILURE_TTL)},
+		}
+		selection, changed := selectPeerEndpoint(candidates, current, nil, netip.AddrPort{}, now)
+		is.True(changed)
+		is.Equal(selection.Endpoint, local.Endpoint)
+		is.Equal(len(selection.Failed), 0)
+	})
+	t.Run("all failed starts over", func(t *testing.T) {
+		is := is.New(t)
+		current := models.PeerEndpoint{
+			Endpoint:  turn.Endpoint,
+			UpdatedAt: settled,
+			Failed: map[string]time.Time{
+				local.Endpoint.String():  now,
+				public.Endpoint.String(): now,
+			},
+		}
+		selection, _ := selectPeerEndpoint(candidates, current, &models.Metric{Connected: false}, netip.AddrPort{}, now)
+		is.Equal(selection.Endpoint, local.Endpoint)
+	})
+	t.Run("pinned endpoint kept", func(t *testing.T) {
+		is := is.New(t)
+		current := models.PeerEndpoint{Endpoint: turn.Endpoint, Pinned: true, UpdatedAt: settled}
+		selection, changed := selectPeerEndpoint(candidates, current, &models.Metric{Connected: false}, netip.AddrPort{}, now)
+		is.True(!changed)
+		is.Equal(selection.Endpoint, turn.Endpoint)
+	})
+}
+
+func TestReachableCandidates(t *testing.T) {
+	lan := func(ip string) models.Iface {
+		return models.Iface{Name: "eth0", Address: net.IPNet{IP: net.ParseIP(ip), Mask: net.CIDRMask(24, 32)}}
+	}
+	host := models.Host{
+		ID:         uuid.New(),
+		ListenPort: 51821,
+		EndpointIP: net.ParseIP("203.0.113.7"),
+		Interfaces: []models.Iface{lan("192.168.1.10"), lan("172.17.0.1")},
+	}
+	peer := models.Host{
+		ID:         uuid.New(),
+		ListenPort: 51821,
+		EndpointIP: net.ParseIP("203.0.113.7"),
+		Interfaces: []models.Iface{
+			lan("192.168.1.20"),
+			lan("172.17.0.1"),
+			lan("10.0.0.5"),
+			{Name: "eth0", Address: net.IPNet{IP: net.ParseIP("2001:db8::20"), Mask: net.CIDRMask(64, 128)}},
+		},
+	}
+	t.Run("same nat", func(t *testing.T) {
+		is := is.New(t)
+		candidates := reachableCandidates(&host, &peer)
+		is.Equal(len(candidates), 2)
+		is.Equal(candidates[0].Endpoint, netip.MustParseAddrPort("192.168.1.20:51821"))
+		is.Equal(candidates[1].Type, models.PublicCandidate)
+	})
+	t.Run("different nat", func(t *testing.T) {
+		is := is.New(t)
+		h := host
+		h.EndpointIP = net.ParseIP("198.51.100.20")
+		h.Interfaces = append(h.Interfaces, models.Iface{Name: "eth0", Address: net.IPNet{IP: net.ParseIP("2001:db8:1::10"), Mask: net.CIDRMask(64, 128)}})
+		candidates := reachableCandidates(&h, &peer)
+		is.Equal(len(candidates), 2)
+		is.Equal(candidates[0].Type, models.PublicCandidate)
+		is.Equal(candidates[1].Type, models.IPv6Candidate)
+	})
+	t.Run("turn relay addresses are never endpoints", func(t *testing.T) {
+		is := is.New(t)
+		h := host
+		h.EndpointIP = net.ParseIP("198.51.100.20")
+		p := peer
+		turn := netip.MustParseAddrPort("198.51.100.1:49152")
+		p.TurnEndpoint = &turn
+		for _, candidate := range reachableCandidates(&h, &p) {
+			is.True(candidate.Type != models.TurnCandidate)
+		}
+		endpoint := GetPeerEndpoint(&h, &p, models.PeerEndpoint{Endpoint: turn, CandidateType: models.TurnCandidate, Pinned: true}, netip.AddrPort{})
+		is.Equal(endpoint.String(), "203.0.113.7:51821")
+	})
+}
+
+func TestUpdatePeerEndpoints(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	is := is.New(t)
+	newNode := func(hostIP, lanIP string) (models.Host, models.Node) {
+		host := models.Host{
+			ID:         uuid.New(),
+			ListenPort: 51821,
+			EndpointIP: net.ParseIP(hostIP),
+			Interfaces: []models.Iface{{Name: "eth0", Address: net.IPNet{IP: net.ParseIP(lanIP), Mask: net.CIDRMask(24, 32)}}},
+		}
+		node := models.Node{}
+		node.ID = uuid.New()
+		node.HostID = host.ID
+		node.Network = "endpoints"
+		host.Nodes = []string{node.ID.String()}
+		is.NoErr(UpsertHost(&host))
+		is.NoErr(upsertNode(&node))
+		return host, node
+	}
+	host, node := newNode("203.0.113.7", "192.168.1.10")
+	peerHost, peer := newNode("203.0.113.7", "192.168.1.20")
+	for _, n := range []models.Node{node, peer} {
+		n := n
+		defer database.DeleteRecord(database.NODES_TABLE_NAME, n.ID.String())
+		defer RemoveHostByID(n.HostID.String())
+	}
+	defer DeleteHostPeerEndpoints(host.ID.String())
+	local := netip.MustParseAddrPort("192.168.1.20:51821")
+
+	t.Run("peer updates do not write", func(t *testing.T) {
+		is := is.New(t)
+		_, err := GetPeerUpdateForHost(context.Background(), "", &host, nil, nil)
+		is.NoErr(err)
+		_, err = GetPeerEndpointSelection(host.ID.String(), peerHost.ID.String())
+		is.True(database.IsEmptyRecord(err))
+		endpoint := GetPeerEndpoint(&host, &peerHost, models.PeerEndpoint{}, netip.AddrPort{})
+		is.Equal(endpoint.String(), local.String())
+	})
+	t.Run("metrics switch a failed endpoint", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(savePeerEndpoint(&models.PeerEndpoint{
+			HostID:        host.ID.String(),
+			PeerHostID:    peerHost.ID.String(),
+			Endpoint:      local,
+			CandidateType: models.LocalCandidate,
+			UpdatedAt:     time.Now().Add(-ENDPOINT_SWITCH_HOLDDOWN),
+		}))
+		changed, err := UpdatePeerEndpoints(&node, &models.Metrics{Connectivity: map[string]models.Metric{
+			peer.ID.String(): {Connected: false},
+		}})
+		is.NoErr(err)
+		is.True(changed)
+		selection, err := GetPeerEndpointSelection(host.ID.String(), peerHost.ID.String())
+		is.NoErr(err)
+		is.Equal(selection.CandidateType, models.PublicCandidate)
+		endpoint := GetPeerEndpoint(&host, &peerHost, selection, netip.AddrPort{})
+		is.Equal(endpoint.String(), "203.0.113.7:51821")
+	})
+}
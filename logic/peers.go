@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"net/netip"
+	"time"
 
 	"github.com/gravitl/netmaker/database"
 	"github.com/gravitl/netmaker/logger"
@@ -145,6 +146,7 @@ func GetPeerUpdateForHost(ctx context.Context, network string, host *models.Host
 	if host == nil {
 		return models.HostPeerUpdate{}, errors.New("host is nil")
 	}
+	defer observePeerUpdate(time.Now())
 	allNodes, err := GetAllNodes()
 	if err != nil {
 		return models.HostPeerUpdate{}, err
@@ -160,15 +162,20 @@ func GetPeerUpdateForHost(ctx context.Context, network string, host *models.Host
 		IngressInfo: models.IngressInfo{
 			ExtPeers: make(map[string]models.ExtClientInfo),
 		},
-		EgressInfo:      make(map[string]models.EgressInfo),
-		PeerIDs:         make(models.PeerMap, 0),
-		Peers:           []wgtypes.PeerConfig{},
-		NodePeers:       []wgtypes.PeerConfig{},
-		HostNetworkInfo: models.HostInfoMap{},
+		EgressInfo:         make(map[string]models.EgressInfo),
+		PeerIDs:            make(models.PeerMap, 0),
+		Peers:              []wgtypes.PeerConfig{},
+		NodePeers:          []wgtypes.PeerConfig{},
+		HostNetworkInfo:    models.HostInfoMap{},
+		EgressRouteMetrics: make(map[string]uint32),
 	}
 
 	logger.Log(1, "peer update for host", host.ID.String())
 	peerIndexMap := make(map[string]int)
+	// endpoint selected per peer host, a peer sharing several networks gets the same endpoint
+	peerEndpoints := make(map[string]*net.UDPAddr)
+	endpointSelections := getHostEndpointSelections(host.ID.String())
+	punchedEndpoints := getHostPunchedEndpoints(host.ID.String())
 	for _, nodeID := range host.Nodes {
 		nodeID := nodeID
 		node, err := GetNodeByID(nodeID)
@@ -205,27 +212,11 @@ func GetPeerUpdateForHost(ctx context.Context, network string, host *models.Host
 				peerConfig.PublicKey = peerHost.PublicKey
 				peerConfig.PersistentKeepaliveInterval = &peer.PersistentKeepalive
 				peerConfig.ReplaceAllowedIPs = true
-				uselocal := false
-				if host.EndpointIP.String() == peerHost.EndpointIP.String() {
-					// peer is on same network
-					// set to localaddress
-					uselocal = true
-					if node.LocalAddress.IP == nil {
-						// use public endpint
-						uselocal = false
-					}
-					if node.LocalAddress.String() == peer.LocalAddress.String() {
-						uselocal = false
-					}
-				}
-				peerConfig.Endpoint = &net.UDPAddr{
-					IP:   peerHost.EndpointIP,
-					Port: getPeerWgListenPort(peerHost),
-				}
-
-				if uselocal {
-					peerConfig.Endpoint.IP = peer.LocalAddress.IP
-					peerConfig.Endpoint.Port = peerHost.ListenPort
+				if endpoint, ok := peerEndpoints[peerHost.ID.String()]; ok {
+					peerConfig.Endpoint = endpoint
+				} else {
+					peerConfig.Endpoint = GetPeerEndpoint(host, peerHost, endpointSelections[peerHost.ID.String()], punchedEndpoints[peerHost.ID.String()])
+					peerEndpoints[peerHost.ID.String()] = peerConfig.Endpoint
 				}
 				allowedips := GetAllowedIPs(&node, &peer, nil)
 				if peer.IsIngressGateway {
@@ -237,7 +228,9 @@ func GetPeerUpdateForHost(ctx context.Context, network string, host *models.Host
 					}
 				}
 				if peer.IsEgressGateway {
-					allowedips = append(allowedips, getEgressIPs(&node, &peer)...)
+					egressIPs := getEgressIPs(&node, &peer)
+					allowedips = append(allowedips, egressIPs...)
+					setEgressRouteMetrics(hostPeerUpdate.EgressRouteMetrics, &peer, egressIPs)
 				}
 				if peer.Action != models.NODE_DELETE &&
 					!peer.PendingDelete &&
@@ -387,8 +380,9 @@ func GetPeerUpdateForHost(ctx context.Context, network string, host *models.Host
 					IP:   net.ParseIP(node.PrimaryAddress()),
 					Mask: getCIDRMaskFromAddr(node.PrimaryAddress()),
 				},
-				GwPeers:     nodePeerMap,
-				EgressGWCfg: node.EgressGatewayRequest,
+				GwPeers:       nodePeerMap,
+				EgressGWCfg:   node.EgressGatewayRequest,
+				RangeMappings: getEgressRangeMappings(&node),
 			}
 		}
 	}
@@ -627,6 +621,34 @@ func GetAllowedIPs(node, peer *models.Node, metrics *models.Metrics) []net.IPNet
 	return allowedips
 }
 
+// setEgressRouteMetrics - records the configured metric of the egress ranges a peer routes for the node
+func setEgressRouteMetrics(metrics map[string]uint32, peer *models.Node, egressIPs []net.IPNet) {
+	for _, egressRange := range peer.EgressGatewayRanges {
+		rangeConfig := peer.EgressGatewayRequest.GetRangeConfig(egressRange)
+		if rangeConfig.Metric == 0 {
+			continue
+		}
+		advertised := rangeConfig.AdvertisedRange()
+		for _, egressIP := range egressIPs {
+			if egressIP.String() == advertised {
+				metrics[advertised] = rangeConfig.Metric
+				break
+			}
+		}
+	}
+}
+
+// getEgressRangeMappings - returns the virtual -> real range mappings of an egress gateway
+func getEgressRangeMappings(node *models.Node) map[string]string {
+	mappings := make(map[string]string)
+	for _, rangeConfig := range node.EgressGatewayRequest.RangesWithConfig {
+		if rangeConfig.VirtualRange != "" {
+			mappings[rangeConfig.VirtualRange] = rangeConfig.Range
+		}
+	}
+	return mappings
+}
+
 func getEgressIPs(node, peer *models.Node) []net.IPNet {
 	host, err := GetHost(node.HostID.String())
 	if err != nil {
@@ -643,8 +665,10 @@ func getEgressIPs(node, peer *models.Node) []net.IPNet {
 		internetGateway = true
 	}
 	allowedips := []net.IPNet{}
-	for _, iprange := range peer.EgressGatewayRanges { // go through each cidr for egress gateway
-		_, ipnet, err := net.ParseCIDR(iprange) // confirming it's valid cidr
+	for _, egressRange := range peer.EgressGatewayRanges { // go through each cidr for egress gateway
+		rangeConfig := peer.EgressGatewayRequest.GetRangeConfig(egressRange)
+		iprange := rangeConfig.AdvertisedRange() // peers route the virtual range if the range is mapped
+		_, ipnet, err := net.ParseCIDR(iprange)  // confirming it's valid cidr
 		if err != nil {
 			logger.Log(1, "could not parse gateway IP range. Not adding ", iprange)
 			continue // if can't parse CIDR
@@ -654,6 +678,14 @@ func getEgressIPs(node, peer *models.Node) []net.IPNet {
 			logger.Log(2, "egress IP range of ", iprange, " overlaps with ", host.EndpointIP.String(), ", omitting")
 			continue // skip adding egress range if overlaps with node's ip
 		}
+		if !peer.EgressGatewayRequest.IsRangeAdvertisedTo(egressRange, node.ID.String(), node.Tags) {
+			logger.Log(3, "egress IP range of ", iprange, " is not advertised to ", node.ID.String(), ", omitting")
+			continue
+		}
+		if !IsActiveEgressGateway(peer, iprange) { // another gateway currently routes this range
+			logger.Log(3, "egress IP range of ", iprange, " is routed by another gateway, omitting for ", peer.ID.String())
+			continue
+		}
 		// TODO: Could put in a lot of great logic to avoid conflicts / bad routes
 		if ipnet.Contains(node.LocalAddress.IP) && !internetGateway { // ensuring egress gateway range does not contain public ip of node
 			logger.Log(2, "egress IP range of ", iprange, " overlaps with ", node.LocalAddress.String(), ", omitting")
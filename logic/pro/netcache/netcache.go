@@ -21,6 +21,7 @@ type CValue struct {
 	Pass       string      `json:"pass,omitempty"`
 	User       string      `json:"user,omitempty"`
 	ALL        bool        `json:"all,omitempty"`
+	Provider   string      `json:"provider,omitempty"`
 	Expiration time.Time   `json:"expiration"`
 }
 
@@ -0,0 +1,81 @@
+package logic
+
+import (
+	"time"
+
+	"github.com/gravitl/netmaker/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	peerUpdateDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "netmaker",
+		Name:      "peer_update_duration_seconds",
+		Help:      "Time taken to calculate the peer update of a host.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	zombieNodes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "netmaker",
+		Name:      "zombie_nodes",
+		Help:      "Nodes in the zombie quarantine list.",
+	})
+	zombieHosts = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "netmaker",
+		Name:      "zombie_hosts",
+		Help:      "Hosts and their nodes in the zombie quarantine list.",
+	})
+)
+
+// labels of the node to peer connectivity metrics
+var peerMetricLabels = []string{"network", "node_id", "node_name", "peer_id", "peer_name"}
+
+// node to peer connectivity, set when a node reports its metrics so scrapes do not read the database
+var (
+	peerConnected     = newPeerGauge("connected", "Whether the node reported the peer as connected.")
+	peerLatency       = newPeerGauge("latency_milliseconds", "Latency from the node to the peer.")
+	peerReceived      = newPeerGauge("received_bytes", "Bytes the node received from the peer.")
+	peerSent          = newPeerGauge("sent_bytes", "Bytes the node sent to the peer.")
+	peerUptime        = newPeerGauge("uptime_seconds", "Time the peer has been connected to the node.")
+	peerUptimePercent = newPeerGauge("uptime_percent", "Share of the checks the peer was connected to the node.")
+	peerGauges        = []*prometheus.GaugeVec{peerConnected, peerLatency, peerReceived, peerSent, peerUptime, peerUptimePercent}
+)
+
+func newPeerGauge(name, help string) *prometheus.GaugeVec {
+	return promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "netmaker",
+		Subsystem: "peer",
+		Name:      name,
+		Help:      help,
+	}, peerMetricLabels)
+}
+
+// setPeerMetrics - replaces the exported connectivity of a node with the metrics it reported
+func setPeerMetrics(nodeID string, metrics *models.Metrics) {
+	deletePeerMetrics(nodeID)
+	for peerID, metric := range metrics.Connectivity {
+		labels := []string{metrics.Network, nodeID, metrics.NodeName, peerID, metric.NodeName}
+		connected := 0.0
+		if metric.Connected {
+			connected = 1
+		}
+		peerConnected.WithLabelValues(labels...).Set(connected)
+		peerLatency.WithLabelValues(labels...).Set(float64(metric.Latency))
+		peerReceived.WithLabelValues(labels...).Set(float64(metric.TotalReceived))
+		peerSent.WithLabelValues(labels...).Set(float64(metric.TotalSent))
+		peerUptime.WithLabelValues(labels...).Set(metric.ActualUptime.Seconds())
+		peerUptimePercent.WithLabelValues(labels...).Set(metric.PercentUp)
+	}
+}
+
+// deletePeerMetrics - drops the exported connectivity of a node
+func deletePeerMetrics(nodeID string) {
+	for _, gauge := range peerGauges {
+		gauge.DeletePartialMatch(prometheus.Labels{"node_id": nodeID})
+	}
+}
+
+// observePeerUpdate - records the duration of a peer update calculation started at start
+func observePeerUpdate(start time.Time) {
+	peerUpdateDuration.Observe(time.Since(start).Seconds())
+}
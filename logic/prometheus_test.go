@@ -0,0 +1,39 @@
+package logic
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPeerMetrics(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	is := is.New(t)
+	nodeID, peerID := uuid.NewString(), uuid.NewString()
+	labels := []string{"prom-net", nodeID, "node", peerID, "peer"}
+	is.NoErr(UpdateMetrics(nodeID, &models.Metrics{
+		Network:  "prom-net",
+		NodeID:   nodeID,
+		NodeName: "node",
+		Connectivity: map[string]models.Metric{
+			peerID: {NodeName: "peer", Connected: true, Latency: 12},
+		},
+	}))
+	is.Equal(testutil.ToFloat64(peerConnected.WithLabelValues(labels...)), 1.0)
+	is.Equal(testutil.ToFloat64(peerLatency.WithLabelValues(labels...)), 12.0)
+	// a report without the peer drops its series
+	is.NoErr(UpdateMetrics(nodeID, &models.Metrics{Network: "prom-net", NodeID: nodeID, NodeName: "node"}))
+	is.True(!peerLatency.DeleteLabelValues(labels...))
+	is.NoErr(UpdateMetrics(nodeID, &models.Metrics{
+		Network:      "prom-net",
+		NodeName:     "node",
+		Connectivity: map[string]models.Metric{peerID: {NodeName: "peer"}},
+	}))
+	is.NoErr(DeleteMetrics(nodeID))
+	is.True(!peerConnected.DeleteLabelValues(labels...))
+}
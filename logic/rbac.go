@@ -0,0 +1,467 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic/pro"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/models/promodels"
+)
+
+// builtinRoles - roles shipped with the server, the network roles match the access levels of network users
+var builtinRoles = []models.Role{
+	{
+		ID:          models.AdminRole,
+		Description: "manages everything",
+		Permissions: []models.RbacPermission{
+			{Resource: models.AllResources, Action: models.AllActions},
+		},
+		BuiltIn: true,
+	},
+	{
+		ID:          models.NetworkAdminRole,
+		Description: "manages the nodes, ext clients, dns and acls of a network",
+		Permissions: []models.RbacPermission{
+			{Resource: models.NetworkResource, Action: models.ReadAction},
+			{Resource: models.NodeResource, Action: models.AllActions},
+			{Resource: models.ExtClientResource, Action: models.AllActions},
+			{Resource: models.DNSResource, Action: models.AllActions},
+			{Resource: models.AclResource, Action: models.AllActions},
+			{Resource: models.EnrollmentKeyResource, Action: models.ReadAction},
+			{Resource: models.HostResource, Action: models.ReadAction},
+		},
+		BuiltIn: true,
+	},
+	{
+		ID:          models.NodeAccessRole,
+		Description: "views the nodes of a network and manages its ext clients",
+		Permissions: []models.RbacPermission{
+			{Resource: models.NetworkResource, Action: models.ReadAction},
+			{Resource: models.NodeResource, Action: models.ReadAction},
+			{Resource: models.ExtClientResource, Action: models.AllActions},
+			{Resource: models.DNSResource, Action: models.ReadAction},
+		},
+		BuiltIn: true,
+	},
+	{
+		ID:          models.ClientAccessRole,
+		Description: "manages its ext clients in a network",
+		Permissions: []models.RbacPermission{
+			{Resource: models.NetworkResource, Action: models.ReadAction},
+			{Resource: models.ExtClientResource, Action: models.AllActions},
+		},
+		BuiltIn: true,
+	},
+}
+
+// rbacResources - resources permissions can be given on
+var rbacResources = []models.RbacResource{
+	models.AllResources,
+	models.NetworkResource,
+	models.NodeResource,
+	models.HostResource,
+	models.ExtClientResource,
+	models.DNSResource,
+	models.EnrollmentKeyResource,
+	models.AclResource,
+	models.UserResource,
+	models.ServerResource,
+}
+
+var rbacActions = []models.RbacAction{
+	models.AllActions,
+	models.ReadAction,
+	models.CreateAction,
+	models.UpdateAction,
+	models.DeleteAction,
+}
+
+var roleIDRegex = regexp.MustCompile(`^[a-z0-9_-]{1,40}$`)
+
+// GetRoles - fetches the built-in and custom roles
+func GetRoles() ([]models.Role, error) {
+	roles := append([]models.Role{}, builtinRoles...)
+	records, err := database.FetchRecords(database.ROLES_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return roles, err
+	}
+	custom := []models.Role{}
+	for _, record := range records {
+		var role models.Role
+		if err := json.Unmarshal([]byte(record), &role); err != nil {
+			continue
+		}
+		custom = append(custom, role)
+	}
+	sort.Slice(custom, func(i, j int) bool {
+		return custom[i].ID < custom[j].ID
+	})
+	return append(roles, custom...), nil
+}
+
+// GetRole - fetches a built-in or custom role
+func GetRole(id string) (models.Role, error) {
+	for _, role := range builtinRoles {
+		if role.ID == id {
+			return role, nil
+		}
+	}
+	var role models.Role
+	record, err := database.FetchRecord(database.ROLES_TABLE_NAME, id)
+	if err != nil {
+		return role, err
+	}
+	err = json.Unmarshal([]byte(record), &role)
+	return role, err
+}
+
+// CreateRole - creates a custom role
+func CreateRole(role *models.Role) error {
+	if !roleIDRegex.MatchString(role.ID) {
+		return errors.New("role id has to be 1 to 40 lower case letters, digits, dashes or underscores")
+	}
+	if _, err := GetRole(role.ID); err == nil {
+		return fmt.Errorf("role %s already exists", role.ID)
+	}
+	return saveRole(role)
+}
+
+// UpdateRole - replaces the description and permissions of a custom role
+func UpdateRole(role *models.Role) error {
+	current, err := GetRole(role.ID)
+	if err != nil {
+		return err
+	}
+	if current.BuiltIn {
+		return fmt.Errorf("built-in role %s cannot be changed", role.ID)
+	}
+	return saveRole(role)
+}
+
+// DeleteRole - deletes a custom role which is not bound to anyone
+func DeleteRole(id string) error {
+	role, err := GetRole(id)
+	if err != nil {
+		return err
+	}
+	if role.BuiltIn {
+		return fmt.Errorf("built-in role %s cannot be deleted", id)
+	}
+	bindings, err := GetRoleBindings()
+	if err != nil {
+		return err
+	}
+	for _, binding := range bindings {
+		if binding.RoleID == id {
+			return fmt.Errorf("role %s is still bound by %s", id, binding.ID)
+		}
+	}
+	return database.DeleteRecord(database.ROLES_TABLE_NAME, id)
+}
+
+// GetRoleBindings - fetches the role bindings stored on the server
+func GetRoleBindings() ([]models.RoleBinding, error) {
+	bindings := []models.RoleBinding{}
+	records, err := database.FetchRecords(database.ROLE_BINDINGS_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return bindings, err
+	}
+	for _, record := range records {
+		var binding models.RoleBinding
+		if err := json.Unmarshal([]byte(record), &binding); err != nil {
+			continue
+		}
+		bindings = append(bindings, binding)
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindings[i].ID < bindings[j].ID
+	})
+	return bindings, nil
+}
+
+// CreateRoleBinding - binds a role to a user or a user group, on one or all networks
+func CreateRoleBinding(binding *models.RoleBinding) error {
+	if _, err := GetRole(binding.RoleID); err != nil {
+		return fmt.Errorf("role %s does not exist", binding.RoleID)
+	}
+	if (binding.UserName == "") == (binding.Group == "") {
+		return errors.New("a role binding needs either a user or a group")
+	}
+	if binding.UserName != "" {
+		if _, err := GetUser(binding.UserName); err != nil {
+			return fmt.Errorf("user %s does not exist", binding.UserName)
+		}
+	}
+	if binding.Group != "" && !pro.DoesUserGroupExist(promodels.UserGroupName(binding.Group)) {
+		return fmt.Errorf("group %s does not exist", binding.Group)
+	}
+	if binding.Network != "" {
+		if exists, err := NetworkExists(binding.Network); err != nil || !exists {
+			return fmt.Errorf("network %s does not exist", binding.Network)
+		}
+	}
+	binding.ID = uuid.New().String()
+	data, err := json.Marshal(binding)
+	if err != nil {
+		return err
+	}
+	return database.Insert(binding.ID, string(data), database.ROLE_BINDINGS_TABLE_NAME)
+}
+
+// DeleteRoleBinding - deletes a role binding
+func DeleteRoleBinding(id string) error {
+	record, err := database.FetchRecord(database.ROLE_BINDINGS_TABLE_NAME, id)
+	if err != nil {
+		return err
+	}
+	var binding models.RoleBinding
+	if err := json.Unmarshal([]byte(record), &binding); err != nil {
+		return err
+	}
+	if err := database.DeleteRecord(database.ROLE_BINDINGS_TABLE_NAME, id); err != nil {
+		return err
+	}
+	if binding.RoleID == models.AdminRole {
+		// demoted admins have to log in again
+		endRoleBindingSessions(&binding)
+	}
+	return nil
+}
+
+// endRoleBindingSessions - ends the sessions of the user or the users of the group a role binding was for
+func endRoleBindingSessions(binding *models.RoleBinding) {
+	usernames := []string{binding.UserName}
+	if binding.Group != "" {
+		usernames = []string{}
+		users, _ := GetGroupUsers(binding.Group)
+		for _, user := range users {
+			usernames = append(usernames, user.UserName)
+		}
+	}
+	for _, username := range usernames {
+		if err := DeleteUserSessions(username); err != nil {
+			logger.Log(0, "failed to end sessions of user", username, err.Error())
+		}
+	}
+}
+
+// DeleteUserRoleBindings - deletes the role bindings of a deleted user
+func DeleteUserRoleBindings(username string) error {
+	bindings, err := GetRoleBindings()
+	if err != nil {
+		return err
+	}
+	for _, binding := range bindings {
+		if binding.UserName != username {
+			continue
+		}
+		if err := database.DeleteRecord(database.ROLE_BINDINGS_TABLE_NAME, binding.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetUserRoleBindings - role bindings of a user, directly or through its groups, along with
+// the bindings implied by its admin flag and network access levels
+func GetUserRoleBindings(user *models.User) ([]models.RoleBinding, error) {
+	bindings := []models.RoleBinding{}
+	if user.IsAdmin {
+		bindings = append(bindings, models.RoleBinding{RoleID: models.AdminRole, UserName: user.UserName})
+	}
+	var networkUsers map[string]string
+	if len(user.Networks) > 0 {
+		// one read of the network users of all networks instead of one per network
+		records, err := database.FetchRecords(database.NETWORK_USER_TABLE_NAME)
+		if err != nil && !database.IsEmptyRecord(err) {
+			return bindings, err
+		}
+		networkUsers = records
+	}
+	for _, network := range user.Networks {
+		var userMap promodels.NetworkUserMap
+		if err := json.Unmarshal([]byte(networkUsers[network]), &userMap); err != nil {
+			continue
+		}
+		netUser, ok := userMap[promodels.NetworkUserID(user.UserName)]
+		if !ok || netUser.ID == "" {
+			continue
+		}
+		var roleID string
+		switch netUser.AccessLevel {
+		case pro.NET_ADMIN:
+			roleID = models.NetworkAdminRole
+		case pro.NODE_ACCESS:
+			roleID = models.NodeAccessRole
+		case pro.CLIENT_ACCESS:
+			roleID = models.ClientAccessRole
+		default:
+			continue
+		}
+		bindings = append(bindings, models.RoleBinding{RoleID: roleID, UserName: user.UserName, Network: network})
+	}
+	stored, err := GetRoleBindings()
+	if err != nil {
+		return bindings, err
+	}
+	for _, binding := range stored {
+		if binding.UserName == user.UserName || (binding.Group != "" && StringSliceContains(user.Groups, binding.Group)) {
+			bindings = append(bindings, binding)
+		}
+	}
+	return bindings, nil
+}
+
+// IsAllowed - checks if role bindings grant an action on a resource of a network, network is empty
+// for requests not bound to one: reads then need a binding on any network, anything else a binding
+// on all networks. Users and the server are never granted by bindings on a single network
+func IsAllowed(bindings []models.RoleBinding, resource models.RbacResource, action models.RbacAction, network string) bool {
+	return isAllowed(bindings, bindingRoles(bindings), resource, action, network)
+}
+
+// AllowedNetworks - networks role bindings grant an action on a resource on,
+// ALL_NETWORK_ACCESS if granted on all networks
+func AllowedNetworks(bindings []models.RoleBinding, resource models.RbacResource, action models.RbacAction) []string {
+	return allowedNetworks(bindings, bindingRoles(bindings), resource, action)
+}
+
+// IsSuperAdmin - checks if role bindings grant everything on all networks
+func IsSuperAdmin(bindings []models.RoleBinding) bool {
+	return IsAllowed(bindings, models.AllResources, models.AllActions, "")
+}
+
+func isAllowed(bindings []models.RoleBinding, roles map[string]models.Role, resource models.RbacResource, action models.RbacAction, network string) bool {
+	if resource == models.SelfResource {
+		return true
+	}
+	for _, binding := range bindings {
+		if !roleGrants(roles[binding.RoleID], resource, action) {
+			continue
+		}
+		if binding.Network == "" {
+			return true
+		}
+		if isGlobalResource(resource) {
+			continue
+		}
+		if binding.Network == network || (network == "" && action == models.ReadAction) {
+			return true
+		}
+	}
+	return false
+}
+
+func allowedNetworks(bindings []models.RoleBinding, roles map[string]models.Role, resource models.RbacResource, action models.RbacAction) []string {
+	networks := []string{}
+	for _, binding := range bindings {
+		if !roleGrants(roles[binding.RoleID], resource, action) {
+			continue
+		}
+		if binding.Network == "" {
+			return []string{ALL_NETWORK_ACCESS}
+		}
+		if !isGlobalResource(resource) && !StringSliceContains(networks, binding.Network) {
+			networks = append(networks, binding.Network)
+		}
+	}
+	return networks
+}
+
+// bindingRoles - the roles of role bindings keyed by id, custom roles are read once for all bindings
+func bindingRoles(bindings []models.RoleBinding) map[string]models.Role {
+	roles := make(map[string]models.Role)
+	for _, role := range builtinRoles {
+		roles[role.ID] = role
+	}
+	custom := false
+	for _, binding := range bindings {
+		if _, ok := roles[binding.RoleID]; !ok {
+			custom = true
+		}
+	}
+	if !custom {
+		return roles
+	}
+	all, err := GetRoles()
+	if err != nil {
+		logger.Log(1, "failed to fetch roles", err.Error())
+	}
+	for _, role := range all {
+		roles[role.ID] = role
+	}
+	return roles
+}
+
+// narrowRoleBindings - limits role bindings to the given networks
+func narrowRoleBindings(bindings []models.RoleBinding, networks []string) []models.RoleBinding {
+	narrowed := []models.RoleBinding{}
+	for _, binding := range bindings {
+		for _, network := range networks {
+			if binding.Network == "" || binding.Network == network {
+				b := binding
+				b.Network = network
+				narrowed = append(narrowed, b)
+			}
+		}
+	}
+	return narrowed
+}
+
+func roleGrants(role models.Role, resource models.RbacResource, action models.RbacAction) bool {
+	for _, permission := range role.Permissions {
+		if (permission.Resource == models.AllResources || permission.Resource == resource) &&
+			(permission.Action == models.AllActions || permission.Action == action) {
+			return true
+		}
+	}
+	return false
+}
+
+func isGlobalResource(resource models.RbacResource) bool {
+	return resource == models.UserResource || resource == models.ServerResource || resource == models.AllResources
+}
+
+func saveRole(role *models.Role) error {
+	if len(role.Permissions) == 0 {
+		return errors.New("a role needs at least one permission")
+	}
+	for _, permission := range role.Permissions {
+		if !rbacResourceExists(permission.Resource) {
+			return fmt.Errorf("unknown resource %s", permission.Resource)
+		}
+		if !rbacActionExists(permission.Action) {
+			return fmt.Errorf("unknown action %s", permission.Action)
+		}
+	}
+	role.BuiltIn = false
+	data, err := json.Marshal(role)
+	if err != nil {
+		return err
+	}
+	return database.Insert(role.ID, string(data), database.ROLES_TABLE_NAME)
+}
+
+func rbacResourceExists(resource models.RbacResource) bool {
+	for _, r := range rbacResources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+func rbacActionExists(action models.RbacAction) bool {
+	for _, a := range rbacActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package logic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic/pro"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/models/promodels"
+	"github.com/matryer/is"
+)
+
+func TestRoles(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	t.Run("builtin roles", func(t *testing.T) {
+		is := is.New(t)
+		roles, err := GetRoles()
+		is.NoErr(err)
+		is.True(len(roles) >= len(builtinRoles))
+		is.True(DeleteRole(models.AdminRole) != nil)
+		is.True(UpdateRole(&models.Role{ID: models.NetworkAdminRole, Permissions: []models.RbacPermission{{Resource: models.DNSResource, Action: models.ReadAction}}}) != nil)
+		is.True(CreateRole(&models.Role{ID: models.AdminRole, Permissions: []models.RbacPermission{{Resource: models.DNSResource, Action: models.ReadAction}}}) != nil)
+	})
+	t.Run("invalid roles", func(t *testing.T) {
+		is := is.New(t)
+		is.True(CreateRole(&models.Role{ID: "Bad Name", Permissions: []models.RbacPermission{{Resource: models.DNSResource, Action: models.ReadAction}}}) != nil)
+		is.True(CreateRole(&models.Role{ID: "empty"}) != nil)
+		is.True(CreateRole(&models.Role{ID: "bogus", Permissions: []models.RbacPermission{{Resource: "bogus", Action: models.ReadAction}}}) != nil)
+		is.True(CreateRole(&models.Role{ID: "bogus", Permissions: []models.RbacPermission{{Resource: models.DNSResource, Action: "bogus"}}}) != nil)
+	})
+	t.Run("bound roles are kept", func(t *testing.T) {
+		is := is.New(t)
+		user := models.User{UserName: "roleuser", Networks: []string{}}
+		data, _ := json.Marshal(&user)
+		is.NoErr(database.Insert(user.UserName, string(data), database.USERS_TABLE_NAME))
+		defer database.DeleteRecord(database.USERS_TABLE_NAME, user.UserName)
+		role := models.Role{ID: "dns-reader", BuiltIn: true, Permissions: []models.RbacPermission{{Resource: models.DNSResource, Action: models.ReadAction}}}
+		is.NoErr(CreateRole(&role))
+		stored, err := GetRole(role.ID)
+		is.NoErr(err)
+		is.True(!stored.BuiltIn)
+		binding := models.RoleBinding{RoleID: role.ID, UserName: user.UserName}
+		is.NoErr(CreateRoleBinding(&binding))
+		is.True(DeleteRole(role.ID) != nil)
+		is.NoErr(DeleteUserRoleBindings(user.UserName))
+		is.NoErr(DeleteRole(role.ID))
+	})
+	t.Run("invalid bindings", func(t *testing.T) {
+		is := is.New(t)
+		is.True(CreateRoleBinding(&models.RoleBinding{RoleID: "missing", UserName: "roleuser"}) != nil)
+		is.True(CreateRoleBinding(&models.RoleBinding{RoleID: models.AdminRole}) != nil)
+		is.True(CreateRoleBinding(&models.RoleBinding{RoleID: models.AdminRole, UserName: "nobody"}) != nil)
+		is.True(CreateRoleBinding(&models.RoleBinding{RoleID: models.AdminRole, UserName: "a", Group: "b"}) != nil)
+	})
+}
+
+func TestGetUserRoleBindings(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	is := is.New(t)
+	network := &models.Network{NetID: "rbacnet"}
+	is.NoErr(pro.InitializeNetworkUsers(network.NetID))
+	defer database.DeleteRecord(database.NETWORK_USER_TABLE_NAME, network.NetID)
+	is.NoErr(pro.CreateNetworkUser(network, &promodels.NetworkUser{ID: "netuser", AccessLevel: pro.NODE_ACCESS}))
+	is.NoErr(pro.InitializeGroups())
+	is.NoErr(pro.InsertUserGroup("ops"))
+	defer pro.DeleteUserGroup("ops")
+	user := models.User{UserName: "netuser", Networks: []string{network.NetID}, Groups: []string{"ops"}}
+	data, _ := json.Marshal(&user)
+	is.NoErr(database.Insert(user.UserName, string(data), database.USERS_TABLE_NAME))
+	defer database.DeleteRecord(database.USERS_TABLE_NAME, user.UserName)
+	groupBinding := models.RoleBinding{RoleID: models.ClientAccessRole, Group: "ops"}
+	is.NoErr(CreateRoleBinding(&groupBinding))
+	defer DeleteRoleBinding(groupBinding.ID)
+
+	bindings, err := GetUserRoleBindings(&user)
+	is.NoErr(err)
+	is.Equal(len(bindings), 2)
+	is.Equal(bindings[0].RoleID, models.NodeAccessRole)
+	is.Equal(bindings[0].Network, network.NetID)
+	is.Equal(bindings[1].ID, groupBinding.ID)
+	// node access on the network, ext clients everywhere through the group
+	is.True(IsAllowed(bindings, models.NodeResource, models.ReadAction, network.NetID))
+	is.True(!IsAllowed(bindings, models.NodeResource, models.UpdateAction, network.NetID))
+	is.True(!IsAllowed(bindings, models.NodeResource, models.ReadAction, "othernet"))
+	is.True(IsAllowed(bindings, models.ExtClientResource, models.CreateAction, "othernet"))
+	is.True(!IsSuperAdmin(bindings))
+	is.Equal(AllowedNetworks(bindings, models.NodeResource, models.ReadAction), []string{network.NetID})
+	is.Equal(AllowedNetworks(bindings, models.ExtClientResource, models.ReadAction), []string{ALL_NETWORK_ACCESS})
+
+	user.IsAdmin = true
+	bindings, err = GetUserRoleBindings(&user)
+	is.NoErr(err)
+	is.True(IsSuperAdmin(bindings))
+}
+
+func TestIsAllowed(t *testing.T) {
+	bindings := []models.RoleBinding{
+		{RoleID: models.NetworkAdminRole, Network: "skynet"},
+	}
+	cases := []struct {
+		name     string
+		resource models.RbacResource
+		action   models.RbacAction
+		network  string
+		allowed  bool
+	}{
+		{"update nodes of bound network", models.NodeResource, models.UpdateAction, "skynet", true},
+		{"update nodes of other network", models.NodeResource, models.UpdateAction, "othernet", false},
+		{"list nodes", models.NodeResource, models.ReadAction, "", true},
+		{"push nodes of all networks", models.NodeResource, models.UpdateAction, "", false},
+		{"delete network", models.NetworkResource, models.DeleteAction, "skynet", false},
+		{"self", models.SelfResource, models.CreateAction, "", true},
+		{"server", models.ServerResource, models.ReadAction, "skynet", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			is.Equal(IsAllowed(bindings, tc.resource, tc.action, tc.network), tc.allowed)
+		})
+	}
+	t.Run("narrowed admin", func(t *testing.T) {
+		is := is.New(t)
+		narrowed := narrowRoleBindings([]models.RoleBinding{{RoleID: models.AdminRole}}, []string{"skynet"})
+		is.True(!IsSuperAdmin(narrowed))
+		is.True(IsAllowed(narrowed, models.NetworkResource, models.DeleteAction, "skynet"))
+		is.True(!IsAllowed(narrowed, models.NetworkResource, models.DeleteAction, "othernet"))
+		is.True(!IsAllowed(narrowed, models.UserResource, models.ReadAction, ""))
+	})
+}
@@ -163,6 +163,11 @@ func validateHostRelay(relay models.HostRelayRequest) error {
 	if len(relay.RelayedHosts) == 0 {
 		return errors.New("relayed hosts are empty")
 	}
+	for _, hostID := range append([]string{relay.HostID}, relay.RelayedHosts...) {
+		if err := CheckHostNotInRelayGroup(hostID); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,526 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+)
+
+// RELAY_HA_CHECKIN_TIMEOUT - time without a check-in after which a relay host is considered down
+const RELAY_HA_CHECKIN_TIMEOUT = time.Minute * 5
+
+var relayGroupMutex = &sync.Mutex{}
+
+// relayGroupHosts - hosts loaded and modified during a single relay group operation
+type relayGroupHosts struct {
+	hosts   map[string]*models.Host
+	changed map[string]struct{}
+}
+
+func newRelayGroupHosts() *relayGroupHosts {
+	return &relayGroupHosts{
+		hosts:   make(map[string]*models.Host),
+		changed: make(map[string]struct{}),
+	}
+}
+
+// CreateRelayGroup - creates a relay group and assigns its members to relay hosts
+// returns the hosts which have been changed by the assignment
+func CreateRelayGroup(req models.RelayGroupRequest) (*models.RelayGroup, []models.Host, error) {
+	relayGroupMutex.Lock()
+	defer relayGroupMutex.Unlock()
+	group := models.RelayGroup{
+		ID:          uuid.New().String(),
+		Assignments: make(map[string]string),
+	}
+	applyRelayGroupRequest(&group, req)
+	if err := validateRelayGroup(&group); err != nil {
+		return nil, nil, err
+	}
+	hosts := newRelayGroupHosts()
+	assignRelayGroup(&group, hosts, group.Members)
+	if err := saveRelayGroup(&group, hosts); err != nil {
+		return nil, nil, err
+	}
+	return &group, hosts.changedHosts(), nil
+}
+
+// UpdateRelayGroup - updates the relay hosts and members of a relay group
+// returns the hosts which have been changed by the update
+func UpdateRelayGroup(groupID string, req models.RelayGroupRequest) (*models.RelayGroup, []models.Host, error) {
+	relayGroupMutex.Lock()
+	defer relayGroupMutex.Unlock()
+	group, err := GetRelayGroup(groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+	oldRelays := group.RelayHosts
+	applyRelayGroupRequest(group, req)
+	if err := validateRelayGroup(group); err != nil {
+		return nil, nil, err
+	}
+	hosts := newRelayGroupHosts()
+	// release members and relay hosts which are no longer part of the group
+	for memberID, relayID := range group.Assignments {
+		if !StringSliceContains(group.Members, memberID) || !StringSliceContains(group.RelayHosts, relayID) {
+			hosts.setAssignment(memberID, relayID, "")
+			delete(group.Assignments, memberID)
+		}
+	}
+	for _, relayID := range oldRelays {
+		if !StringSliceContains(group.RelayHosts, relayID) {
+			hosts.releaseRelay(relayID)
+		}
+	}
+	assignRelayGroup(group, hosts, group.Members)
+	if err := saveRelayGroup(group, hosts); err != nil {
+		return nil, nil, err
+	}
+	return group, hosts.changedHosts(), nil
+}
+
+// DeleteRelayGroup - deletes a relay group and releases its relay hosts and members
+// returns the hosts which have been changed by the deletion
+func DeleteRelayGroup(groupID string) ([]models.Host, error) {
+	relayGroupMutex.Lock()
+	defer relayGroupMutex.Unlock()
+	group, err := GetRelayGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+	hosts := newRelayGroupHosts()
+	for memberID, relayID := range group.Assignments {
+		hosts.setAssignment(memberID, relayID, "")
+	}
+	for _, relayID := range group.RelayHosts {
+		hosts.releaseRelay(relayID)
+	}
+	if err := hosts.save(); err != nil {
+		return nil, err
+	}
+	if err := database.DeleteRecord(database.RELAY_GROUPS_TABLE_NAME, groupID); err != nil {
+		return nil, err
+	}
+	return hosts.changedHosts(), nil
+}
+
+// GetRelayGroup - fetches a relay group
+func GetRelayGroup(groupID string) (*models.RelayGroup, error) {
+	record, err := database.FetchRecord(database.RELAY_GROUPS_TABLE_NAME, groupID)
+	if err != nil {
+		return nil, err
+	}
+	var group models.RelayGroup
+	if err = json.Unmarshal([]byte(record), &group); err != nil {
+		return nil, err
+	}
+	if group.Assignments == nil {
+		group.Assignments = make(map[string]string)
+	}
+	return &group, nil
+}
+
+// GetRelayGroups - fetches all relay groups
+func GetRelayGroups() ([]models.RelayGroup, error) {
+	groups := []models.RelayGroup{}
+	records, err := database.FetchRecords(database.RELAY_GROUPS_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return groups, err
+	}
+	for _, record := range records {
+		var group models.RelayGroup
+		if err := json.Unmarshal([]byte(record), &group); err != nil {
+			continue
+		}
+		if group.Assignments == nil {
+			group.Assignments = make(map[string]string)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// UpdateRelayGroupsForHost - re-evaluates the relay assignment of a member host,
+// called on host check-in so members move away from relays which stopped checking in
+// returns the hosts which have been changed
+func UpdateRelayGroupsForHost(hostID string) ([]models.Host, error) {
+	group, err := GetHostRelayGroup(hostID)
+	if err != nil || group == nil || !StringSliceContains(group.Members, hostID) {
+		// relays need no re-evaluation on their own check-in, their members check in too
+		return nil, err
+	}
+	relayGroupMutex.Lock()
+	defer relayGroupMutex.Unlock()
+	// re-read under the lock, the group may have changed meanwhile
+	if group, err = GetRelayGroup(group.ID); err != nil {
+		return nil, err
+	}
+	hosts := newRelayGroupHosts()
+	if !assignRelayGroup(group, hosts, []string{hostID}) && len(hosts.changed) == 0 {
+		return nil, nil
+	}
+	if err := saveRelayGroup(group, hosts); err != nil {
+		return nil, err
+	}
+	return hosts.changedHosts(), nil
+}
+
+// GetHostRelayGroup - returns the relay group a host is a relay or member of, nil if it is in none
+func GetHostRelayGroup(hostID string) (*models.RelayGroup, error) {
+	groups, err := GetRelayGroups()
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		if groups[i].HasHost(hostID) {
+			return &groups[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// RemoveHostFromRelayGroups - removes a deleted host from the relay groups it belongs to
+// returns the hosts which have been changed
+func RemoveHostFromRelayGroups(hostID string) ([]models.Host, error) {
+	relayGroupMutex.Lock()
+	defer relayGroupMutex.Unlock()
+	groups, err := GetRelayGroups()
+	if err != nil {
+		return nil, err
+	}
+	hosts := newRelayGroupHosts()
+	for i := range groups {
+		group := &groups[i]
+		if !group.HasHost(hostID) {
+			continue
+		}
+		for memberID, relayID := range group.Assignments {
+			if relayID == hostID {
+				hosts.setAssignment(memberID, relayID, "")
+			}
+			if memberID == hostID || relayID == hostID {
+				delete(group.Assignments, memberID)
+			}
+		}
+		group.RelayHosts = removeString(group.RelayHosts, hostID)
+		group.Members = removeString(group.Members, hostID)
+		assignRelayGroup(group, hosts, group.Members)
+		if err := saveRelayGroup(group, hosts); err != nil {
+			return nil, err
+		}
+	}
+	delete(hosts.changed, hostID)
+	return hosts.changedHosts(), nil
+}
+
+// CheckHostNotInRelayGroup - relays of hosts in a relay group are managed by the group,
+// returns an error if the host is part of one
+func CheckHostNotInRelayGroup(hostID string) error {
+	group, err := GetHostRelayGroup(hostID)
+	if err != nil {
+		return err
+	}
+	if group != nil {
+		return fmt.Errorf("host %s is managed by relay group %s", hostID, group.Name)
+	}
+	return nil
+}
+
+// IsRelayHostHealthy - checks if any node of a relay host has checked in recently
+func IsRelayHostHealthy(host *models.Host) bool {
+	for _, nodeID := range host.Nodes {
+		node, err := GetNodeByID(nodeID)
+		if err != nil {
+			continue
+		}
+		if !node.Connected || node.PendingDelete || node.Action == models.NODE_DELETE {
+			continue
+		}
+		if time.Since(node.LastCheckIn) <= RELAY_HA_CHECKIN_TIMEOUT {
+			return true
+		}
+	}
+	return false
+}
+
+// getHostLatency - returns the lowest latency measured between the nodes of two hosts,
+// math.MaxInt64 if no connected pair of nodes reported metrics
+func getHostLatency(host, peerHost *models.Host) int64 {
+	latency := int64(math.MaxInt64)
+	for _, nodeID := range host.Nodes {
+		metrics, err := GetMetrics(nodeID)
+		if err != nil || metrics == nil || metrics.Connectivity == nil {
+			continue
+		}
+		for _, peerNodeID := range peerHost.Nodes {
+			metric, ok := metrics.Connectivity[peerNodeID]
+			if ok && metric.Connected && metric.Latency < latency {
+				latency = metric.Latency
+			}
+		}
+	}
+	return latency
+}
+
+// assignRelayGroup - (re)assigns the given members of a group to relay hosts,
+// returns whether any assignment changed, the changed hosts are tracked in hosts
+func assignRelayGroup(group *models.RelayGroup, hosts *relayGroupHosts, memberIDs []string) bool {
+	relays := []models.Host{}
+	for _, relayID := range group.RelayHosts {
+		relay := hosts.get(relayID)
+		if relay == nil {
+			continue
+		}
+		if !relay.IsRelay || !relay.ProxyEnabled {
+			relay.IsRelay = true
+			relay.ProxyEnabled = true
+			hosts.markChanged(relay)
+		}
+		relays = append(relays, *relay)
+	}
+	// health is only looked up for the relays a selection actually considers
+	healthy := make(map[string]bool)
+	isHealthy := func(h *models.Host) bool {
+		hostID := h.ID.String()
+		if _, ok := healthy[hostID]; !ok {
+			healthy[hostID] = IsRelayHostHealthy(h)
+		}
+		return healthy[hostID]
+	}
+	load := make(map[string]int)
+	for _, relayID := range group.Assignments {
+		load[relayID]++
+	}
+	changed := false
+	for _, memberID := range memberIDs {
+		member := hosts.get(memberID)
+		if member == nil {
+			continue
+		}
+		current := group.Assignments[memberID]
+		selected := ""
+		if group.RelayAll || member.NatType == models.NAT_Types.Symmetric {
+			selected = selectRelayHost(relays, current, isHealthy,
+				func(h *models.Host) int64 { return getHostLatency(member, h) },
+				load)
+		}
+		if selected == current {
+			continue
+		}
+		logger.Log(0, "relay group", group.Name, "moving host", member.Name, "from relay", current, "to", selected)
+		if current != "" {
+			load[current]--
+		}
+		if selected != "" {
+			load[selected]++
+			group.Assignments[memberID] = selected
+		} else {
+			delete(group.Assignments, memberID)
+		}
+		hosts.setAssignment(memberID, current, selected)
+		changed = true
+	}
+	return changed
+}
+
+// saveRelayGroup - saves the hosts changed by a relay group operation and the group
+func saveRelayGroup(group *models.RelayGroup, hosts *relayGroupHosts) error {
+	if err := hosts.save(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(group)
+	if err != nil {
+		return err
+	}
+	return database.Insert(group.ID, string(data), database.RELAY_GROUPS_TABLE_NAME)
+}
+
+// selectRelayHost - picks the relay for a host, the current relay is kept while it is healthy,
+// otherwise relays behind a symmetric NAT are avoided and the lowest latency, then the lowest load wins
+func selectRelayHost(relays []models.Host, current string, isHealthy func(*models.Host) bool, latency func(*models.Host) int64, load map[string]int) string {
+	candidates := []models.Host{}
+	for i := range relays {
+		if !isHealthy(&relays[i]) {
+			continue
+		}
+		if relays[i].ID.String() == current {
+			return current
+		}
+		candidates = append(candidates, relays[i])
+	}
+	if len(candidates) == 0 {
+		// every relay is down, keep the current one until one recovers
+		return current
+	}
+	latencies := make(map[string]int64, len(candidates))
+	for i := range candidates {
+		latencies[candidates[i].ID.String()] = latency(&candidates[i])
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		iSymmetric := candidates[i].NatType == models.NAT_Types.Symmetric
+		jSymmetric := candidates[j].NatType == models.NAT_Types.Symmetric
+		if iSymmetric != jSymmetric {
+			return !iSymmetric
+		}
+		iID, jID := candidates[i].ID.String(), candidates[j].ID.String()
+		if latencies[iID] != latencies[jID] {
+			return latencies[iID] < latencies[jID]
+		}
+		if load[iID] != load[jID] {
+			return load[iID] < load[jID]
+		}
+		return iID < jID
+	})
+	return candidates[0].ID.String()
+}
+
+// isGroupRelay - checks if a host is a relay of the given stored group
+func isGroupRelay(groups []models.RelayGroup, groupID, hostID string) bool {
+	for i := range groups {
+		if groups[i].ID == groupID {
+			return StringSliceContains(groups[i].RelayHosts, hostID)
+		}
+	}
+	return false
+}
+
+func applyRelayGroupRequest(group *models.RelayGroup, req models.RelayGroupRequest) {
+	group.Name = req.Name
+	group.RelayHosts = req.RelayHosts
+	group.Members = req.Members
+	group.RelayAll = req.RelayAll
+}
+
+func validateRelayGroup(group *models.RelayGroup) error {
+	if group.Name == "" {
+		return errors.New("relay group name cannot be empty")
+	}
+	if len(group.RelayHosts) == 0 {
+		return errors.New("relay group needs at least one relay host")
+	}
+	if len(group.Members) == 0 {
+		return errors.New("relay group members are empty")
+	}
+	groups, err := GetRelayGroups()
+	if err != nil {
+		return err
+	}
+	for _, relayID := range group.RelayHosts {
+		relay, err := GetHost(relayID)
+		if err != nil {
+			return fmt.Errorf("failed to get relay host %s: %w", relayID, err)
+		}
+		if relay.OS != models.OS_Types.Linux {
+			return fmt.Errorf("relay host %s: only linux machines can be relays", relay.Name)
+		}
+		if StringSliceContains(group.Members, relayID) {
+			return fmt.Errorf("host %s cannot be a relay and a member of the same group", relay.Name)
+		}
+		if relay.IsRelay && !isGroupRelay(groups, group.ID, relayID) {
+			return fmt.Errorf("host %s is already a relay outside of relay groups", relay.Name)
+		}
+	}
+	for _, memberID := range group.Members {
+		member, err := GetHost(memberID)
+		if err != nil {
+			return fmt.Errorf("failed to get member host %s: %w", memberID, err)
+		}
+		if member.IsRelayed && group.Assignments[memberID] != member.RelayedBy {
+			return fmt.Errorf("host %s is already relayed by %s", member.Name, member.RelayedBy)
+		}
+	}
+	for i := range groups {
+		if groups[i].ID == group.ID {
+			continue
+		}
+		for _, hostID := range append(append([]string{}, group.RelayHosts...), group.Members...) {
+			if groups[i].HasHost(hostID) {
+				return fmt.Errorf("host %s is already part of relay group %s", hostID, groups[i].Name)
+			}
+		}
+	}
+	return nil
+}
+
+// relayGroupHosts.get - fetches a host once per operation so earlier changes are not overwritten
+func (r *relayGroupHosts) get(hostID string) *models.Host {
+	if host, ok := r.hosts[hostID]; ok {
+		return host
+	}
+	host, err := GetHost(hostID)
+	if err != nil {
+		logger.Log(1, "failed to get relay group host", hostID, err.Error())
+		return nil
+	}
+	r.hosts[hostID] = host
+	return host
+}
+
+func (r *relayGroupHosts) markChanged(host *models.Host) {
+	r.changed[host.ID.String()] = struct{}{}
+}
+
+// relayGroupHosts.setAssignment - moves a member host from one relay host to another, an empty relay id unrelays
+func (r *relayGroupHosts) setAssignment(memberID, oldRelayID, newRelayID string) {
+	if oldRelayID != "" {
+		if relay := r.get(oldRelayID); relay != nil {
+			relay.RelayedHosts = removeString(relay.RelayedHosts, memberID)
+			r.markChanged(relay)
+		}
+	}
+	member := r.get(memberID)
+	if member == nil {
+		return
+	}
+	if newRelayID == "" {
+		member.IsRelayed = false
+		member.RelayedBy = ""
+		r.markChanged(member)
+		return
+	}
+	if relay := r.get(newRelayID); relay != nil && !StringSliceContains(relay.RelayedHosts, memberID) {
+		relay.RelayedHosts = append(relay.RelayedHosts, memberID)
+		r.markChanged(relay)
+	}
+	member.IsRelayed = true
+	member.RelayedBy = newRelayID
+	member.ProxyEnabled = true
+	r.markChanged(member)
+}
+
+// relayGroupHosts.releaseRelay - removes the relay role from a relay host leaving a group
+func (r *relayGroupHosts) releaseRelay(relayID string) {
+	relay := r.get(relayID)
+	if relay == nil {
+		return
+	}
+	relay.IsRelay = false
+	relay.RelayedHosts = []string{}
+	r.markChanged(relay)
+}
+
+func (r *relayGroupHosts) save() error {
+	for hostID := range r.changed {
+		if err := UpsertHost(r.hosts[hostID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *relayGroupHosts) changedHosts() []models.Host {
+	changed := []models.Host{}
+	for hostID := range r.changed {
+		changed = append(changed, *r.hosts[hostID])
+	}
+	return changed
+}
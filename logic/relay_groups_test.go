@@ -0,0 +1,148 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestSelectRelayHost(t *testing.T) {
+	newRelay := func(natType string) models.Host {
+		return models.Host{
+			ID:      uuid.New(),
+			NatType: natType,
+		}
+	}
+	near := newRelay(models.NAT_Types.Public)
+	far := newRelay(models.NAT_Types.Public)
+	symmetric := newRelay(models.NAT_Types.Symmetric)
+	down := map[string]bool{}
+	isHealthy := func(host *models.Host) bool {
+		return !down[host.ID.String()]
+	}
+	latencies := map[string]int64{
+		near.ID.String():      10,
+		far.ID.String():       80,
+		symmetric.ID.String(): 1,
+	}
+	latency := func(host *models.Host) int64 {
+		return latencies[host.ID.String()]
+	}
+	t.Run("lowest latency wins", func(t *testing.T) {
+		is := is.New(t)
+		relays := []models.Host{far, near}
+		is.Equal(selectRelayHost(relays, "", isHealthy, latency, map[string]int{}), near.ID.String())
+	})
+	t.Run("symmetric nat relays are avoided", func(t *testing.T) {
+		is := is.New(t)
+		relays := []models.Host{symmetric, far}
+		is.Equal(selectRelayHost(relays, "", isHealthy, latency, map[string]int{}), far.ID.String())
+	})
+	t.Run("healthy current relay is kept", func(t *testing.T) {
+		is := is.New(t)
+		relays := []models.Host{near, far}
+		is.Equal(selectRelayHost(relays, far.ID.String(), isHealthy, latency, map[string]int{}), far.ID.String())
+	})
+	t.Run("failover when current relay is down", func(t *testing.T) {
+		is := is.New(t)
+		down[near.ID.String()] = true
+		defer delete(down, near.ID.String())
+		relays := []models.Host{near, far}
+		is.Equal(selectRelayHost(relays, near.ID.String(), isHealthy, latency, map[string]int{}), far.ID.String())
+	})
+	t.Run("load breaks latency ties", func(t *testing.T) {
+		is := is.New(t)
+		unmeasured := func(host *models.Host) int64 { return 0 }
+		load := map[string]int{near.ID.String(): 3, far.ID.String(): 1}
+		relays := []models.Host{near, far}
+		is.Equal(selectRelayHost(relays, "", isHealthy, unmeasured, load), far.ID.String())
+	})
+	t.Run("all down keeps current", func(t *testing.T) {
+		is := is.New(t)
+		down[near.ID.String()] = true
+		down[far.ID.String()] = true
+		defer delete(down, near.ID.String())
+		defer delete(down, far.ID.String())
+		relays := []models.Host{near, far}
+		is.Equal(selectRelayHost(relays, near.ID.String(), isHealthy, latency, map[string]int{}), near.ID.String())
+	})
+}
+
+func TestRelayGroupCheckIn(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	is := is.New(t)
+	newHost := func(name string) *models.Host {
+		node := models.Node{}
+		node.ID = uuid.New()
+		node.Connected = true
+		node.LastCheckIn = time.Now()
+		host := &models.Host{
+			ID:      uuid.New(),
+			Name:    name,
+			OS:      models.OS_Types.Linux,
+			NatType: models.NAT_Types.Symmetric,
+			Nodes:   []string{node.ID.String()},
+		}
+		node.HostID = host.ID
+		is.NoErr(upsertNode(&node))
+		is.NoErr(UpsertHost(host))
+		return host
+	}
+	checkIn := func(host *models.Host, at time.Time) {
+		node, err := GetNodeByID(host.Nodes[0])
+		is.NoErr(err)
+		node.LastCheckIn = at
+		is.NoErr(upsertNode(&node))
+	}
+	relayA, relayB, member := newHost("relay-a"), newHost("relay-b"), newHost("member")
+	for _, host := range []*models.Host{relayA, relayB, member} {
+		defer database.DeleteRecord(database.NODES_TABLE_NAME, host.Nodes[0])
+		defer RemoveHostByID(host.ID.String())
+	}
+	group, _, err := CreateRelayGroup(models.RelayGroupRequest{
+		Name:       "ha",
+		RelayHosts: []string{relayA.ID.String(), relayB.ID.String()},
+		Members:    []string{member.ID.String()},
+	})
+	is.NoErr(err)
+	defer DeleteRelayGroup(group.ID)
+	current := group.Assignments[member.ID.String()]
+	is.True(current != "")
+
+	t.Run("no change no write", func(t *testing.T) {
+		is := is.New(t)
+		changed, err := UpdateRelayGroupsForHost(member.ID.String())
+		is.NoErr(err)
+		is.Equal(len(changed), 0)
+		changed, err = UpdateRelayGroupsForHost(current) // relays do not trigger a re-evaluation
+		is.NoErr(err)
+		is.Equal(len(changed), 0)
+	})
+	t.Run("member moves away from a silent relay", func(t *testing.T) {
+		is := is.New(t)
+		silent, _ := GetHost(current)
+		checkIn(silent, time.Now().Add(-2*RELAY_HA_CHECKIN_TIMEOUT))
+		changed, err := UpdateRelayGroupsForHost(member.ID.String())
+		is.NoErr(err)
+		is.True(len(changed) > 0)
+		stored, err := GetRelayGroup(group.ID)
+		is.NoErr(err)
+		is.True(stored.Assignments[member.ID.String()] != current)
+		relayed, err := GetHost(member.ID.String())
+		is.NoErr(err)
+		is.Equal(relayed.RelayedBy, stored.Assignments[member.ID.String()])
+	})
+	t.Run("manual relays of group hosts are rejected", func(t *testing.T) {
+		is := is.New(t)
+		is.True(CheckHostNotInRelayGroup(member.ID.String()) != nil)
+		is.True(CheckHostNotInRelayGroup(relayA.ID.String()) != nil)
+		is.NoErr(CheckHostNotInRelayGroup(uuid.NewString()))
+		_, _, err := CreateHostRelay(models.HostRelayRequest{HostID: relayA.ID.String(), RelayedHosts: []string{uuid.NewString()}})
+		is.True(err != nil)
+	})
+}
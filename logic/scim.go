@@ -0,0 +1,438 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic/pro"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/models/promodels"
+)
+
+// ErrUserDeactivated - the identity provider of the user deactivated it
+var ErrUserDeactivated = errors.New("user is deactivated")
+
+// GetSCIMUserState - fetches the provisioning state of a user managed through scim
+func GetSCIMUserState(username string) (models.SCIMUserState, error) {
+	var state models.SCIMUserState
+	record, err := database.FetchRecord(database.SCIM_USERS_TABLE_NAME, username)
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal([]byte(record), &state)
+	return state, err
+}
+
+// IsUserActive - checks that a user was not deactivated by its identity provider,
+// users not provisioned through scim are always active
+func IsUserActive(username string) bool {
+	state, err := GetSCIMUserState(username)
+	if err != nil {
+		if !database.IsEmptyRecord(err) {
+			logger.Log(0, "failed to fetch scim state of user", username, err.Error())
+		}
+		return database.IsEmptyRecord(err)
+	}
+	return state.Active
+}
+
+// GetSCIMUsers - fetches all users as scim users
+func GetSCIMUsers() ([]models.SCIMUser, error) {
+	scimUsers := []models.SCIMUser{}
+	users, err := GetUsers()
+	if err != nil && !database.IsEmptyRecord(err) {
+		return scimUsers, err
+	}
+	for _, user := range users {
+		scimUsers = append(scimUsers, toSCIMUser(user.UserName, user.Groups))
+	}
+	sort.Slice(scimUsers, func(i, j int) bool {
+		return scimUsers[i].UserName < scimUsers[j].UserName
+	})
+	return scimUsers, nil
+}
+
+// GetSCIMUser - fetches a user as scim user
+func GetSCIMUser(username string) (models.SCIMUser, error) {
+	user, err := GetUser(username)
+	if err != nil {
+		return models.SCIMUser{}, err
+	}
+	return toSCIMUser(user.UserName, user.Groups), nil
+}
+
+// CreateSCIMUser - provisions a user pushed by an identity provider, users without a password
+// are given a random one and log in through sso
+func CreateSCIMUser(scimUser *models.SCIMUser) error {
+	password := scimUser.Password
+	if password == "" {
+		password = RandomString(32)
+	} else if err := ValidatePassword(scimUser.UserName, password); err != nil {
+		return err
+	}
+	user := models.User{
+		UserName: scimUser.UserName,
+		Password: password,
+	}
+	if err := CreateUser(&user); err != nil {
+		return err
+	}
+	active := scimUser.Active == nil || *scimUser.Active
+	if err := saveSCIMUserState(&models.SCIMUserState{UserName: user.UserName, ExternalID: scimUser.ExternalID, Active: true}); err != nil {
+		return err
+	}
+	if !active {
+		if err := SetUserActive(user.UserName, false); err != nil {
+			return err
+		}
+	}
+	*scimUser = toSCIMUser(user.UserName, user.Groups)
+	return nil
+}
+
+// UpdateSCIMUser - sets the external id and active state of a user pushed by an identity provider
+func UpdateSCIMUser(username, externalID string, active bool) error {
+	if _, err := GetUser(username); err != nil {
+		return err
+	}
+	state, err := GetSCIMUserState(username)
+	if err != nil {
+		if !database.IsEmptyRecord(err) {
+			return err
+		}
+		state = models.SCIMUserState{UserName: username, Active: true}
+	}
+	if externalID != "" {
+		state.ExternalID = externalID
+	}
+	if err := saveSCIMUserState(&state); err != nil {
+		return err
+	}
+	if state.Active != active {
+		return SetUserActive(username, active)
+	}
+	return nil
+}
+
+// SetUserActive - deactivates a user, ending its sessions, revoking its access tokens and taking away
+// its access to networks, or activates it again with the access to networks it had before
+func SetUserActive(username string, active bool) error {
+	user, err := GetUser(username)
+	if err != nil {
+		return err
+	}
+	state, err := GetSCIMUserState(username)
+	if err != nil {
+		if !database.IsEmptyRecord(err) {
+			return err
+		}
+		state = models.SCIMUserState{UserName: username, Active: true}
+	}
+	networks, err := GetNetworks()
+	if err != nil && !database.IsEmptyRecord(err) {
+		return err
+	}
+	wasActive := state.Active
+	previousAccess := state.NetworkAccess
+	networkUsers := map[string]promodels.NetworkUser{}
+	for i := range networks {
+		networkUser, err := pro.GetNetworkUser(networks[i].NetID, promodels.NetworkUserID(username))
+		if err != nil {
+			continue
+		}
+		networkUsers[networks[i].NetID] = *networkUser
+	}
+	state.Active = active
+	if !active && wasActive {
+		// remember the access to restore when the user is activated again
+		state.NetworkAccess = map[string]models.SCIMNetworkAccess{}
+		for netID, networkUser := range networkUsers {
+			state.NetworkAccess[netID] = models.SCIMNetworkAccess{
+				AccessLevel: networkUser.AccessLevel,
+				NodeLimit:   networkUser.NodeLimit,
+				ClientLimit: networkUser.ClientLimit,
+			}
+		}
+	} else if active {
+		state.NetworkAccess = nil
+	}
+	if err := saveSCIMUserState(&state); err != nil {
+		return err
+	}
+	if !active {
+		if err := DeleteUserSessions(username); err != nil {
+			logger.Log(0, "failed to end sessions of user", username, err.Error())
+		}
+		if err := DeleteUserAccessTokens(username); err != nil {
+			logger.Log(0, "failed to revoke access tokens of user", username, err.Error())
+		}
+	}
+	for i := range networks {
+		networkUser, ok := networkUsers[networks[i].NetID]
+		if !ok {
+			continue
+		}
+		if active {
+			if access, ok := previousAccess[networks[i].NetID]; ok {
+				networkUser.AccessLevel = access.AccessLevel
+				networkUser.NodeLimit = access.NodeLimit
+				networkUser.ClientLimit = access.ClientLimit
+			} else {
+				defaultUser := defaultNetworkUser(&networks[i], user)
+				networkUser.AccessLevel = defaultUser.AccessLevel
+				networkUser.NodeLimit = defaultUser.NodeLimit
+				networkUser.ClientLimit = defaultUser.ClientLimit
+			}
+		} else {
+			networkUser.AccessLevel = pro.NO_ACCESS
+			networkUser.NodeLimit = 0
+			networkUser.ClientLimit = 0
+		}
+		if err := pro.UpdateNetworkUser(networks[i].NetID, &networkUser); err != nil {
+			logger.Log(0, "failed to update network user", username, "on network", networks[i].NetID, err.Error())
+		}
+	}
+	return nil
+}
+
+// GetSCIMGroups - fetches the user groups with their members as scim groups
+func GetSCIMGroups() ([]models.SCIMGroup, error) {
+	scimGroups := []models.SCIMGroup{}
+	groups, err := pro.GetUserGroups()
+	if err != nil {
+		return scimGroups, err
+	}
+	users, err := GetUsers()
+	if err != nil && !database.IsEmptyRecord(err) {
+		return scimGroups, err
+	}
+	for group := range groups {
+		if group == "" || group == pro.DEFAULT_ALLOWED_GROUPS {
+			continue
+		}
+		scimGroups = append(scimGroups, toSCIMGroup(string(group), users))
+	}
+	sort.Slice(scimGroups, func(i, j int) bool {
+		return scimGroups[i].DisplayName < scimGroups[j].DisplayName
+	})
+	return scimGroups, nil
+}
+
+// GetSCIMGroup - fetches a user group with its members as scim group
+func GetSCIMGroup(name string) (models.SCIMGroup, error) {
+	if name == pro.DEFAULT_ALLOWED_GROUPS || !pro.DoesUserGroupExist(promodels.UserGroupName(name)) {
+		return models.SCIMGroup{}, fmt.Errorf("group %s does not exist", name)
+	}
+	users, err := GetUsers()
+	if err != nil && !database.IsEmptyRecord(err) {
+		return models.SCIMGroup{}, err
+	}
+	return toSCIMGroup(name, users), nil
+}
+
+// CreateSCIMGroup - creates a user group pushed by an identity provider with its members
+func CreateSCIMGroup(group *models.SCIMGroup) error {
+	name := strings.TrimSpace(group.DisplayName)
+	if name == "" || name == pro.DEFAULT_ALLOWED_GROUPS {
+		return errors.New("invalid group name")
+	}
+	if pro.DoesUserGroupExist(promodels.UserGroupName(name)) {
+		return fmt.Errorf("group %s already exists", name)
+	}
+	if err := pro.InsertUserGroup(promodels.UserGroupName(name)); err != nil {
+		return err
+	}
+	if err := SetSCIMGroupMembers(name, scimMemberValues(group.Members)); err != nil {
+		return err
+	}
+	created, err := GetSCIMGroup(name)
+	if err != nil {
+		return err
+	}
+	*group = created
+	return nil
+}
+
+// SetSCIMGroupMembers - makes the given users the only members of a user group
+func SetSCIMGroupMembers(name string, members []string) error {
+	users, err := GetUsers()
+	if err != nil && !database.IsEmptyRecord(err) {
+		return err
+	}
+	for _, user := range users {
+		isMember := StringSliceContains(user.Groups, name)
+		if StringSliceContains(members, user.UserName) != isMember {
+			if err := setUserGroup(user.UserName, name, !isMember); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AddSCIMGroupMembers - adds users to a user group
+func AddSCIMGroupMembers(name string, members []string) error {
+	for _, member := range members {
+		if err := setUserGroup(member, name, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveSCIMGroupMembers - removes users from a user group
+func RemoveSCIMGroupMembers(name string, members []string) error {
+	for _, member := range members {
+		if err := setUserGroup(member, name, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenameSCIMGroup - renames a user group, moving its members to the new name
+func RenameSCIMGroup(name, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if newName == name {
+		return nil
+	}
+	if newName == "" || newName == pro.DEFAULT_ALLOWED_GROUPS {
+		return errors.New("invalid group name")
+	}
+	if pro.DoesUserGroupExist(promodels.UserGroupName(newName)) {
+		return fmt.Errorf("group %s already exists", newName)
+	}
+	group, err := GetSCIMGroup(name)
+	if err != nil {
+		return err
+	}
+	if err := pro.InsertUserGroup(promodels.UserGroupName(newName)); err != nil {
+		return err
+	}
+	if err := AddSCIMGroupMembers(newName, scimMemberValues(group.Members)); err != nil {
+		return err
+	}
+	return DeleteSCIMGroup(name)
+}
+
+// DeleteSCIMGroup - removes the members of a user group and deletes it
+func DeleteSCIMGroup(name string) error {
+	if _, err := GetSCIMGroup(name); err != nil {
+		return err
+	}
+	if err := SetSCIMGroupMembers(name, []string{}); err != nil {
+		return err
+	}
+	return pro.DeleteUserGroup(promodels.UserGroupName(name))
+}
+
+// setUserGroup - adds a user to or removes it from a group, adjusting its access to the networks of the group
+func setUserGroup(username, group string, member bool) error {
+	user, err := GetUser(username)
+	if err != nil {
+		return err
+	}
+	groups := []string{}
+	for _, current := range user.Groups {
+		if current != group {
+			groups = append(groups, current)
+		}
+	}
+	if member {
+		groups = append(groups, group)
+	}
+	if !user.IsAdmin {
+		currentUser := models.ReturnUser{UserName: user.UserName, Networks: user.Networks, Groups: user.Groups}
+		adjustUserNetworks(&currentUser, user.Networks, groups)
+	}
+	user.Groups = groups
+	return saveUser(user)
+}
+
+func toSCIMUser(username string, groups []string) models.SCIMUser {
+	active := IsUserActive(username)
+	scimUser := models.SCIMUser{
+		Schemas:  []string{models.SCIMUserSchema},
+		ID:       username,
+		UserName: username,
+		Active:   &active,
+		Groups:   []models.SCIMMember{},
+		Meta:     &models.SCIMMeta{ResourceType: "User"},
+	}
+	if state, err := GetSCIMUserState(username); err == nil {
+		scimUser.ExternalID = state.ExternalID
+	}
+	for _, group := range groups {
+		if group != pro.DEFAULT_ALLOWED_GROUPS {
+			scimUser.Groups = append(scimUser.Groups, models.SCIMMember{Value: group, Display: group})
+		}
+	}
+	return scimUser
+}
+
+func toSCIMGroup(name string, users []models.ReturnUser) models.SCIMGroup {
+	group := models.SCIMGroup{
+		Schemas:     []string{models.SCIMGroupSchema},
+		ID:          name,
+		DisplayName: name,
+		Members:     []models.SCIMMember{},
+		Meta:        &models.SCIMMeta{ResourceType: "Group"},
+	}
+	for _, user := range users {
+		if StringSliceContains(user.Groups, name) {
+			group.Members = append(group.Members, models.SCIMMember{Value: user.UserName, Display: user.UserName})
+		}
+	}
+	sort.Slice(group.Members, func(i, j int) bool {
+		return group.Members[i].Value < group.Members[j].Value
+	})
+	return group
+}
+
+// SetSCIMDisabledExtClients - records the ext clients disabled when a user was deactivated
+func SetSCIMDisabledExtClients(username string, keys []string) error {
+	state, err := GetSCIMUserState(username)
+	if err != nil {
+		return err
+	}
+	state.DisabledExtClients = keys
+	return saveSCIMUserState(&state)
+}
+
+// TakeSCIMDisabledExtClients - returns and forgets the ext clients disabled when a user was deactivated
+func TakeSCIMDisabledExtClients(username string) ([]string, error) {
+	state, err := GetSCIMUserState(username)
+	if err != nil {
+		if database.IsEmptyRecord(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	keys := state.DisabledExtClients
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	state.DisabledExtClients = nil
+	return keys, saveSCIMUserState(&state)
+}
+
+func scimMemberValues(members []models.SCIMMember) []string {
+	values := []string{}
+	for _, member := range members {
+		values = append(values, member.Value)
+	}
+	return values
+}
+
+func saveSCIMUserState(state *models.SCIMUserState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return database.Insert(state.UserName, string(data), database.SCIM_USERS_TABLE_NAME)
+}
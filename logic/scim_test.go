@@ -0,0 +1,167 @@
+package logic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic/pro"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/models/promodels"
+	"github.com/matryer/is"
+)
+
+func TestSCIMUsers(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	SetJWTSecret()
+	network := models.Network{NetID: "scimnet"}
+	pro.AddProNetDefaults(&network)
+	network.ProSettings.DefaultAccessLevel = pro.NODE_ACCESS
+	data, _ := json.Marshal(&network)
+	database.Insert(network.NetID, string(data), database.NETWORKS_TABLE_NAME)
+	defer database.DeleteRecord(database.NETWORKS_TABLE_NAME, network.NetID)
+	pro.InitializeNetworkUsers(network.NetID)
+	defer database.DeleteRecord(database.NETWORK_USER_TABLE_NAME, network.NetID)
+	defer DeleteUser("scimuser")
+
+	t.Run("provision", func(t *testing.T) {
+		is := is.New(t)
+		user := models.SCIMUser{UserName: "scimuser", ExternalID: "ext-1"}
+		is.NoErr(CreateSCIMUser(&user))
+		is.Equal(user.ID, "scimuser")
+		is.True(*user.Active)
+		is.Equal(user.ExternalID, "ext-1")
+		is.True(CreateSCIMUser(&models.SCIMUser{UserName: "scimuser"}) != nil)
+		is.True(CreateSCIMUser(&models.SCIMUser{UserName: "x"}) != nil)
+		netUser, err := pro.GetNetworkUser(network.NetID, "scimuser")
+		is.NoErr(err)
+		is.Equal(netUser.AccessLevel, pro.NODE_ACCESS)
+	})
+	t.Run("deactivate", func(t *testing.T) {
+		is := is.New(t)
+		netUser, err := pro.GetNetworkUser(network.NetID, "scimuser")
+		is.NoErr(err)
+		netUser.AccessLevel = pro.CLIENT_ACCESS
+		netUser.ClientLimit = 7
+		is.NoErr(pro.UpdateNetworkUser(network.NetID, netUser))
+		stored, err := GetUser("scimuser")
+		is.NoErr(err)
+		login, err := CreateUserSession(stored, "", "")
+		is.NoErr(err)
+		is.NoErr(UpdateSCIMUser("scimuser", "", false))
+		is.True(!IsUserActive("scimuser"))
+		_, _, _, err = VerifyUserToken(login.AuthToken)
+		is.True(err != nil)
+		netUser, err = pro.GetNetworkUser(network.NetID, "scimuser")
+		is.NoErr(err)
+		is.Equal(netUser.AccessLevel, pro.NO_ACCESS)
+		is.Equal(netUser.ClientLimit, 0)
+		user, err := GetSCIMUser("scimuser")
+		is.NoErr(err)
+		is.True(!*user.Active)
+		is.Equal(user.ExternalID, "ext-1")
+	})
+	t.Run("activate", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(UpdateSCIMUser("scimuser", "ext-2", true))
+		is.True(IsUserActive("scimuser"))
+		// the access the user had before it was deactivated is restored
+		netUser, err := pro.GetNetworkUser(network.NetID, "scimuser")
+		is.NoErr(err)
+		is.Equal(netUser.AccessLevel, pro.CLIENT_ACCESS)
+		is.Equal(netUser.ClientLimit, 7)
+		state, err := GetSCIMUserState("scimuser")
+		is.NoErr(err)
+		is.Equal(len(state.NetworkAccess), 0)
+		user, err := GetSCIMUser("scimuser")
+		is.NoErr(err)
+		is.Equal(user.ExternalID, "ext-2")
+	})
+	t.Run("deprovision", func(t *testing.T) {
+		is := is.New(t)
+		_, err := DeleteUser("scimuser")
+		is.NoErr(err)
+		_, err = GetSCIMUserState("scimuser")
+		is.True(err != nil)
+		_, err = pro.GetNetworkUser(network.NetID, "scimuser")
+		is.True(err != nil)
+	})
+	t.Run("users without scim state are active", func(t *testing.T) {
+		is := is.New(t)
+		is.True(IsUserActive("nobody"))
+	})
+	t.Run("unreadable scim state is not active", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(database.Insert("brokenstate", `{"active":"yes"}`, database.SCIM_USERS_TABLE_NAME))
+		defer database.DeleteRecord(database.SCIM_USERS_TABLE_NAME, "brokenstate")
+		is.True(!IsUserActive("brokenstate"))
+	})
+}
+
+func TestSCIMGroups(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	is := is.New(t)
+	is.NoErr(pro.InitializeGroups())
+	for _, username := range []string{"scimalice", "scimbob"} {
+		user := models.User{UserName: username, Networks: []string{}, Groups: []string{pro.DEFAULT_ALLOWED_GROUPS}}
+		data, _ := json.Marshal(&user)
+		is.NoErr(database.Insert(user.UserName, string(data), database.USERS_TABLE_NAME))
+		defer database.DeleteRecord(database.USERS_TABLE_NAME, user.UserName)
+	}
+	defer pro.DeleteUserGroup("scim-devs")
+	defer pro.DeleteUserGroup("scim-engineers")
+	members := func(name string) []string {
+		group, err := GetSCIMGroup(name)
+		is.NoErr(err)
+		values := []string{}
+		for _, member := range group.Members {
+			values = append(values, member.Value)
+		}
+		return values
+	}
+
+	t.Run("create", func(t *testing.T) {
+		is := is.New(t)
+		group := models.SCIMGroup{DisplayName: "scim-devs", Members: []models.SCIMMember{{Value: "scimalice"}}}
+		is.NoErr(CreateSCIMGroup(&group))
+		is.Equal(group.ID, "scim-devs")
+		is.Equal(len(group.Members), 1)
+		is.True(CreateSCIMGroup(&models.SCIMGroup{DisplayName: "scim-devs"}) != nil)
+		is.True(CreateSCIMGroup(&models.SCIMGroup{DisplayName: pro.DEFAULT_ALLOWED_GROUPS}) != nil)
+		_, err := GetSCIMGroup(pro.DEFAULT_ALLOWED_GROUPS)
+		is.True(err != nil)
+		user, err := GetSCIMUser("scimalice")
+		is.NoErr(err)
+		is.Equal(user.Groups, []models.SCIMMember{{Value: "scim-devs", Display: "scim-devs"}})
+	})
+	t.Run("members", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(AddSCIMGroupMembers("scim-devs", []string{"scimbob"}))
+		is.Equal(members("scim-devs"), []string{"scimalice", "scimbob"})
+		is.NoErr(RemoveSCIMGroupMembers("scim-devs", []string{"scimalice"}))
+		is.Equal(members("scim-devs"), []string{"scimbob"})
+		is.NoErr(SetSCIMGroupMembers("scim-devs", []string{"scimalice"}))
+		is.Equal(members("scim-devs"), []string{"scimalice"})
+		is.True(AddSCIMGroupMembers("scim-devs", []string{"nobody"}) != nil)
+		// other groups of the user are kept
+		user, err := GetUser("scimalice")
+		is.NoErr(err)
+		is.Equal(user.Groups, []string{pro.DEFAULT_ALLOWED_GROUPS, "scim-devs"})
+	})
+	t.Run("rename", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(RenameSCIMGroup("scim-devs", "scim-engineers"))
+		is.True(!pro.DoesUserGroupExist(promodels.UserGroupName("scim-devs")))
+		is.Equal(members("scim-engineers"), []string{"scimalice"})
+	})
+	t.Run("delete", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(DeleteSCIMGroup("scim-engineers"))
+		is.True(!pro.DoesUserGroupExist(promodels.UserGroupName("scim-engineers")))
+		user, err := GetUser("scimalice")
+		is.NoErr(err)
+		is.Equal(user.Groups, []string{pro.DEFAULT_ALLOWED_GROUPS})
+	})
+}
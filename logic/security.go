@@ -7,15 +7,18 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/gravitl/netmaker/database"
-	"github.com/gravitl/netmaker/logic/pro"
+	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/models"
-	"github.com/gravitl/netmaker/models/promodels"
 	"github.com/gravitl/netmaker/servercfg"
 )
 
 const (
 	// ALL_NETWORK_ACCESS - represents all networks
 	ALL_NETWORK_ACCESS = "THIS_USER_HAS_ALL"
+	// HostIDHeader - header the id of a host calling the api is passed on in
+	HostIDHeader = "host-id"
+	// AccessTokenHeader - header the id of an access token calling the api is passed on in
+	AccessTokenHeader = "access-token-id"
 
 	master_uname     = "masteradministrator"
 	Forbidden_Msg    = "forbidden"
@@ -24,20 +27,26 @@ const (
 	Unauthorized_Err = models.Error(Unauthorized_Msg)
 )
 
-// SecurityCheck - Check if user has appropriate permissions
-func SecurityCheck(reqAdmin bool, next http.Handler) http.HandlerFunc {
-
+// Authorize - authorization middleware of the api, checks that the caller holds the permission
+// for the action on the resource on the network of the route, host tokens pass if hostAllowed.
+// The networks the permission is held on are passed on in the networks header, changes are audited
+func Authorize(hostAllowed bool, resource models.RbacResource, action models.RbacAction, next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var errorResponse = models.ErrorResponse{
-			Code: http.StatusForbidden, Message: Forbidden_Msg,
+			Code: http.StatusUnauthorized, Message: Unauthorized_Msg,
 		}
+		// identity headers are only ever set from verified credentials, never taken from the client
 		r.Header.Set("ismaster", "no")
+		r.Header.Del(AccessTokenHeader)
+		r.Header.Del(HostIDHeader)
+		r.Header.Del("user")
+		r.Header.Del("networks")
 
 		var params = mux.Vars(r)
 		bearerToken := r.Header.Get("Authorization")
 		// to have a custom DNS service adding entries
 		// we should refactor this, but is for the special case of an external service to query the DNS api
-		if strings.Contains(r.RequestURI, "/dns") && strings.ToUpper(r.Method) == "GET" && authenticateDNSToken(bearerToken) {
+		if resource == models.DNSResource && action == models.ReadAction && authenticateDNSToken(bearerToken) {
 			// do dns stuff
 			r.Header.Set("user", "nameserver")
 			networks, _ := json.Marshal([]string{ALL_NETWORK_ACCESS})
@@ -45,135 +54,98 @@ func SecurityCheck(reqAdmin bool, next http.Handler) http.HandlerFunc {
 			next.ServeHTTP(w, r)
 			return
 		}
-		var networkName = params["networkname"]
-		if len(networkName) == 0 {
-			networkName = params["network"]
-		}
-		networks, username, err := UserPermissions(reqAdmin, networkName, bearerToken)
-		if err != nil {
+		var tokenSplit = strings.Split(bearerToken, " ")
+		if len(tokenSplit) < 2 {
 			ReturnErrorResponse(w, r, errorResponse)
 			return
 		}
-		// detect masteradmin
-		if len(networks) > 0 && networks[0] == ALL_NETWORK_ACCESS {
+		authToken := tokenSplit[1]
+		if authenticateMaster(authToken) {
+			// TODO log in as an actual admin user
 			r.Header.Set("ismaster", "yes")
-		}
-		networksJson, err := json.Marshal(&networks)
-		if err != nil {
-			ReturnErrorResponse(w, r, errorResponse)
+			r.Header.Set("user", master_uname)
+			networks, _ := json.Marshal([]string{ALL_NETWORK_ACCESS})
+			r.Header.Set("networks", string(networks))
+			serveAudited(resource, action, next, w, r)
 			return
 		}
-		r.Header.Set("user", username)
-		r.Header.Set("networks", string(networksJson))
-		next.ServeHTTP(w, r)
-	}
-}
-
-// NetUserSecurityCheck - Check if network user has appropriate permissions
-func NetUserSecurityCheck(isNodes, isClients bool, next http.Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var errorResponse = models.ErrorResponse{
-			Code: http.StatusForbidden, Message: Forbidden_Msg,
+		if hostAllowed {
+			if hostID, _, _, err := VerifyHostToken(authToken); err == nil {
+				r.Header.Set(HostIDHeader, hostID)
+				serveAudited(resource, action, next, w, r)
+				return
+			}
 		}
-		r.Header.Set("ismaster", "no")
-
-		var params = mux.Vars(r)
-		var netUserName = params["networkuser"]
-		var network = params["network"]
-
-		bearerToken := r.Header.Get("Authorization")
-
-		var tokenSplit = strings.Split(bearerToken, " ")
-		var authToken = ""
-
-		if len(tokenSplit) < 2 {
-			ReturnErrorResponse(w, r, errorResponse)
-			return
+		// the user, bindings and roles of the caller are looked up once for the whole request
+		var user *models.User
+		var accessToken *models.AccessToken
+		var err error
+		if IsAccessToken(authToken) {
+			if accessToken, err = VerifyAccessToken(authToken); err == nil {
+				user, err = GetUser(accessToken.UserName)
+			}
 		} else {
-			authToken = tokenSplit[1]
+			user, err = verifyUserJWT(authToken, &models.UserClaims{})
 		}
-
-		isMasterAuthenticated := authenticateMaster(authToken)
-		if isMasterAuthenticated {
-			r.Header.Set("user", "master token user")
-			r.Header.Set("ismaster", "yes")
-			next.ServeHTTP(w, r)
+		if err != nil {
+			ReturnErrorResponse(w, r, errorResponse)
 			return
 		}
-
-		userName, _, isadmin, err := VerifyUserToken(authToken)
+		username := user.UserName
+		bindings, err := callerRoleBindings(user, accessToken)
 		if err != nil {
 			ReturnErrorResponse(w, r, errorResponse)
 			return
 		}
-		r.Header.Set("user", userName)
-
-		if isadmin {
-			next.ServeHTTP(w, r)
-			return
+		roles := bindingRoles(bindings)
+		errorResponse = models.ErrorResponse{
+			Code: http.StatusForbidden, Message: Forbidden_Msg,
 		}
-
-		if isNodes || isClients {
-			necessaryAccess := pro.NET_ADMIN
-			if isClients {
-				necessaryAccess = pro.CLIENT_ACCESS
-			}
-			if isNodes {
-				necessaryAccess = pro.NODE_ACCESS
-			}
-			u, err := pro.GetNetworkUser(network, promodels.NetworkUserID(userName))
-			if err != nil {
-				ReturnErrorResponse(w, r, errorResponse)
-				return
-			}
-			if u.AccessLevel > necessaryAccess {
+		var networkName = params["networkname"]
+		if len(networkName) == 0 {
+			networkName = params["network"]
+		}
+		if accessToken != nil {
+			if err := CheckAccessTokenScope(accessToken, resource, action, networkName); err != nil {
+				logger.Log(1, "access token", accessToken.Name, "of user", accessToken.UserName, "denied:", err.Error())
 				ReturnErrorResponse(w, r, errorResponse)
 				return
 			}
-		} else if netUserName != userName {
+			r.Header.Set(AccessTokenHeader, accessToken.ID)
+		}
+		isSuperAdmin := isAllowed(bindings, roles, models.AllResources, models.AllActions, "")
+		if len(networkName) > 0 && !isSuperAdmin && !authenticateNetworkUser(networkName) {
 			ReturnErrorResponse(w, r, errorResponse)
 			return
 		}
-
-		next.ServeHTTP(w, r)
+		if !isAllowed(bindings, roles, resource, action, networkName) {
+			ReturnErrorResponse(w, r, errorResponse)
+			return
+		}
+		if isSuperAdmin {
+			r.Header.Set("ismaster", "yes")
+		}
+		networksJson, err := json.Marshal(allowedNetworks(bindings, roles, resource, action))
+		if err != nil {
+			ReturnErrorResponse(w, r, errorResponse)
+			return
+		}
+		r.Header.Set("user", username)
+		r.Header.Set("networks", string(networksJson))
+		serveAudited(resource, action, next, w, r)
 	}
 }
 
-// UserPermissions - checks token stuff
-func UserPermissions(reqAdmin bool, netname string, token string) ([]string, string, error) {
-	var tokenSplit = strings.Split(token, " ")
-	var authToken = ""
-	userNetworks := []string{}
-
-	if len(tokenSplit) < 2 {
-		return userNetworks, "", Unauthorized_Err
-	} else {
-		authToken = tokenSplit[1]
-	}
-	//all endpoints here require master so not as complicated
-	if authenticateMaster(authToken) {
-		// TODO log in as an actual admin user
-		return []string{ALL_NETWORK_ACCESS}, master_uname, nil
-	}
-	username, networks, isadmin, err := VerifyUserToken(authToken)
+// callerRoleBindings - role bindings of a user, limited to the networks of the access token the user calls with
+func callerRoleBindings(user *models.User, accessToken *models.AccessToken) ([]models.RoleBinding, error) {
+	bindings, err := GetUserRoleBindings(user)
 	if err != nil {
-		return nil, username, Unauthorized_Err
-	}
-	if !isadmin && reqAdmin {
-		return nil, username, Forbidden_Err
+		return nil, err
 	}
-	userNetworks = networks
-	if isadmin {
-		return []string{ALL_NETWORK_ACCESS}, username, nil
+	if accessToken != nil && len(accessToken.Scope.Networks) > 0 {
+		bindings = narrowRoleBindings(bindings, accessToken.Scope.Networks)
 	}
-	// check network admin access
-	if len(netname) > 0 && (len(userNetworks) == 0 || !authenticateNetworkUser(netname, userNetworks)) {
-		return nil, username, Forbidden_Err
-	}
-	if isEE && len(netname) > 0 && !pro.IsUserNetAdmin(netname, username) {
-		return nil, "", Forbidden_Err
-	}
-	return userNetworks, username, nil
+	return bindings, nil
 }
 
 // Consider a more secure way of setting master key
@@ -181,12 +153,9 @@ func authenticateMaster(tokenString string) bool {
 	return tokenString == servercfg.GetMasterKey() && servercfg.GetMasterKey() != ""
 }
 
-func authenticateNetworkUser(network string, userNetworks []string) bool {
+func authenticateNetworkUser(network string) bool {
 	networkexists, err := NetworkExists(network)
-	if (err != nil && !database.IsEmptyRecord(err)) || !networkexists {
-		return false
-	}
-	return StringSliceContains(userNetworks, network)
+	return (err == nil || database.IsEmptyRecord(err)) && networkexists
 }
 
 // Consider a more secure way of setting master key
@@ -198,6 +167,7 @@ func authenticateDNSToken(tokenString string) bool {
 	return len(servercfg.GetDNSKey()) > 0 && tokens[1] == servercfg.GetDNSKey()
 }
 
+// ContinueIfUserMatch - lets admins and the user named in the route through
 func ContinueIfUserMatch(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var errorResponse = models.ErrorResponse{
@@ -205,7 +175,10 @@ func ContinueIfUserMatch(next http.Handler) http.HandlerFunc {
 		}
 		var params = mux.Vars(r)
 		var requestedUser = params["username"]
-		if requestedUser != r.Header.Get("user") {
+		if len(requestedUser) == 0 {
+			requestedUser = params["networkuser"]
+		}
+		if requestedUser != r.Header.Get("user") && r.Header.Get("ismaster") != "yes" {
 			ReturnErrorResponse(w, r, errorResponse)
 			return
 		}
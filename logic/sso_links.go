@@ -0,0 +1,103 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+)
+
+var ssoUserLinksMutex = &sync.Mutex{}
+
+// GetSSOUserLinks - the sso providers a user is linked to, empty for users never linked
+func GetSSOUserLinks(username string) (models.SSOUserLinks, error) {
+	links := models.SSOUserLinks{UserName: username, Providers: []string{}}
+	record, err := database.FetchRecord(database.SSO_USER_LINKS_TABLE_NAME, username)
+	if err != nil {
+		if database.IsEmptyRecord(err) {
+			return links, nil
+		}
+		return links, err
+	}
+	err = json.Unmarshal([]byte(record), &links)
+	return links, err
+}
+
+// IsSSOUserLinked - checks if a user may sign in through an sso provider. Users without links, such as
+// those created before several providers could be configured, belong to the default provider.
+func IsSSOUserLinked(username, provider, defaultProvider string) bool {
+	links, err := GetSSOUserLinks(username)
+	if err != nil {
+		logger.Log(0, "failed to get sso providers of user", username, err.Error())
+		return false
+	}
+	if len(links.Providers) == 0 {
+		return provider == defaultProvider
+	}
+	return StringSliceContains(links.Providers, provider)
+}
+
+// LinkSSOUser - lets a user sign in through an sso provider. A user without links keeps signing in through
+// the default provider, which is linked along, defaultProvider is empty for users new to sso.
+func LinkSSOUser(username, provider, defaultProvider string) error {
+	if _, err := GetUser(username); err != nil {
+		return err
+	}
+	ssoUserLinksMutex.Lock()
+	defer ssoUserLinksMutex.Unlock()
+	links, err := GetSSOUserLinks(username)
+	if err != nil {
+		return err
+	}
+	if StringSliceContains(links.Providers, provider) {
+		return nil
+	}
+	if len(links.Providers) == 0 && defaultProvider != "" && defaultProvider != provider {
+		links.Providers = append(links.Providers, defaultProvider)
+	}
+	links.Providers = append(links.Providers, provider)
+	return saveSSOUserLinks(&links)
+}
+
+// UnlinkSSOUser - stops a user from signing in through an sso provider, a user left without links
+// falls back to the default provider
+func UnlinkSSOUser(username, provider string) error {
+	ssoUserLinksMutex.Lock()
+	defer ssoUserLinksMutex.Unlock()
+	links, err := GetSSOUserLinks(username)
+	if err != nil {
+		return err
+	}
+	if !StringSliceContains(links.Providers, provider) {
+		return fmt.Errorf("user %s is not linked to sso provider %s", username, provider)
+	}
+	providers := []string{}
+	for _, linked := range links.Providers {
+		if linked != provider {
+			providers = append(providers, linked)
+		}
+	}
+	links.Providers = providers
+	if len(links.Providers) == 0 {
+		return database.DeleteRecord(database.SSO_USER_LINKS_TABLE_NAME, username)
+	}
+	return saveSSOUserLinks(&links)
+}
+
+// deleteSSOUserLinks - removes the sso providers of a deleted user
+func deleteSSOUserLinks(username string) {
+	if err := database.DeleteRecord(database.SSO_USER_LINKS_TABLE_NAME, username); err != nil && !database.IsEmptyRecord(err) {
+		logger.Log(0, "failed to remove sso providers of user", username, err.Error())
+	}
+}
+
+func saveSSOUserLinks(links *models.SSOUserLinks) error {
+	data, err := json.Marshal(links)
+	if err != nil {
+		return err
+	}
+	return database.Insert(links.UserName, string(data), database.SSO_USER_LINKS_TABLE_NAME)
+}
@@ -0,0 +1,65 @@
+package logic
+
+import (
+	"testing"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestSSOUserLinks(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	is := is.New(t)
+	is.NoErr(CreateUser(&models.User{UserName: "ssolinked", Password: "password"}))
+	defer DeleteUser("ssolinked")
+
+	t.Run("unlinked users belong to the default provider", func(t *testing.T) {
+		is := is.New(t)
+		is.True(IsSSOUserLinked("ssolinked", "azure-ad", "azure-ad"))
+		is.True(!IsSSOUserLinked("ssolinked", "contractors", "azure-ad"))
+	})
+	t.Run("linking keeps the default provider", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(LinkSSOUser("ssolinked", "contractors", "azure-ad"))
+		is.NoErr(LinkSSOUser("ssolinked", "contractors", "azure-ad")) // linking twice is a no-op
+		links, err := GetSSOUserLinks("ssolinked")
+		is.NoErr(err)
+		is.Equal(links.Providers, []string{"azure-ad", "contractors"})
+		is.True(IsSSOUserLinked("ssolinked", "contractors", "azure-ad"))
+		is.True(IsSSOUserLinked("ssolinked", "azure-ad", "azure-ad"))
+	})
+	t.Run("unknown user", func(t *testing.T) {
+		is := is.New(t)
+		is.True(LinkSSOUser("ssonobody", "contractors", "azure-ad") != nil)
+	})
+	t.Run("unlink", func(t *testing.T) {
+		is := is.New(t)
+		is.True(UnlinkSSOUser("ssolinked", "okta") != nil)
+		is.NoErr(UnlinkSSOUser("ssolinked", "azure-ad"))
+		is.True(!IsSSOUserLinked("ssolinked", "azure-ad", "azure-ad"))
+		is.True(IsSSOUserLinked("ssolinked", "contractors", "azure-ad"))
+		is.NoErr(UnlinkSSOUser("ssolinked", "contractors"))
+		links, err := GetSSOUserLinks("ssolinked")
+		is.NoErr(err)
+		is.Equal(len(links.Providers), 0)
+		is.True(IsSSOUserLinked("ssolinked", "azure-ad", "azure-ad"))
+	})
+	t.Run("users new to sso are only linked to their provider", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(LinkSSOUser("ssolinked", "contractors", ""))
+		links, err := GetSSOUserLinks("ssolinked")
+		is.NoErr(err)
+		is.Equal(links.Providers, []string{"contractors"})
+		is.True(!IsSSOUserLinked("ssolinked", "azure-ad", "azure-ad"))
+	})
+	t.Run("deleting the user unlinks it", func(t *testing.T) {
+		is := is.New(t)
+		_, err := DeleteUser("ssolinked")
+		is.NoErr(err)
+		links, err := GetSSOUserLinks("ssolinked")
+		is.NoErr(err)
+		is.Equal(len(links.Providers), 0)
+	})
+}
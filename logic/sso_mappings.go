@@ -0,0 +1,230 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic/pro"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/models/promodels"
+)
+
+// GetSSOMappings - fetches the mappings of identity provider groups to netmaker access
+func GetSSOMappings() ([]models.SSOMapping, error) {
+	mappings := []models.SSOMapping{}
+	records, err := database.FetchRecords(database.SSO_MAPPINGS_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return mappings, err
+	}
+	for _, record := range records {
+		var mapping models.SSOMapping
+		if err := json.Unmarshal([]byte(record), &mapping); err != nil {
+			continue
+		}
+		mappings = append(mappings, mapping)
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		if mappings[i].Provider != mappings[j].Provider {
+			return mappings[i].Provider < mappings[j].Provider
+		}
+		if mappings[i].IdPGroup != mappings[j].IdPGroup {
+			return mappings[i].IdPGroup < mappings[j].IdPGroup
+		}
+		return mappings[i].ID < mappings[j].ID
+	})
+	return mappings, nil
+}
+
+// GetSSOMapping - fetches a mapping of an identity provider group
+func GetSSOMapping(id string) (models.SSOMapping, error) {
+	var mapping models.SSOMapping
+	record, err := database.FetchRecord(database.SSO_MAPPINGS_TABLE_NAME, id)
+	if err != nil {
+		return mapping, err
+	}
+	err = json.Unmarshal([]byte(record), &mapping)
+	return mapping, err
+}
+
+// CreateSSOMapping - maps an identity provider group to user groups, networks and admin status
+func CreateSSOMapping(mapping *models.SSOMapping) error {
+	mapping.ID = uuid.New().String()
+	return saveSSOMapping(mapping)
+}
+
+// UpdateSSOMapping - replaces a mapping of an identity provider group
+func UpdateSSOMapping(mapping *models.SSOMapping) error {
+	if _, err := GetSSOMapping(mapping.ID); err != nil {
+		return err
+	}
+	return saveSSOMapping(mapping)
+}
+
+// DeleteSSOMapping - deletes a mapping of an identity provider group, its users keep their access until they log in again
+func DeleteSSOMapping(id string) error {
+	if _, err := GetSSOMapping(id); err != nil {
+		return err
+	}
+	return database.DeleteRecord(database.SSO_MAPPINGS_TABLE_NAME, id)
+}
+
+// ApplySSOMappings - sets the user groups, networks and admin status of an sso user to the ones mapped
+// to its groups at the provider it logged in through, access not mapped to any of them is taken away.
+// Groups of the same name at other providers are different groups. Nothing changes if no mappings
+// exist for the provider or the groups of the user are unknown (nil).
+func ApplySSOMappings(provider, username string, idpGroups []string) error {
+	if idpGroups == nil {
+		return nil
+	}
+	mappings, err := getProviderSSOMappings(provider)
+	if err != nil || len(mappings) == 0 {
+		return err
+	}
+	user, err := GetUser(username)
+	if err != nil {
+		return err
+	}
+	isAdmin, groups, networks := mapSSOGroups(mappings, idpGroups)
+	if user.IsAdmin && !isAdmin {
+		lastAdmin, err := isLastAdmin(user.UserName)
+		if err != nil {
+			return err
+		}
+		if lastAdmin {
+			logger.Log(0, "not removing admin status from", user.UserName, "as it is the last admin")
+			isAdmin = true
+		}
+	}
+	wasAdmin := user.IsAdmin
+	if isAdmin {
+		user.IsAdmin = true
+		user.Networks = nil
+		user.Groups = groups
+	} else {
+		currentUser := models.ReturnUser{UserName: user.UserName, Groups: user.Groups}
+		if !wasAdmin {
+			currentUser.Networks = user.Networks
+		}
+		adjustUserNetworks(&currentUser, networks, groups)
+		user.IsAdmin = false
+		user.Networks = currentUser.Networks
+		user.Groups = currentUser.Groups
+	}
+	if err = saveUser(user); err != nil {
+		return err
+	}
+	if wasAdmin && !user.IsAdmin {
+		logger.Log(0, "removed admin status from", user.UserName, "as none of its identity provider groups is mapped to it")
+		// tokens of the former admin must not outlive the demotion
+		if err = DeleteUserSessions(user.UserName); err != nil {
+			logger.Log(0, "failed to end sessions of user", user.UserName, err.Error())
+		}
+	}
+	return nil
+}
+
+// getProviderSSOMappings - fetches the mappings of the groups of a provider
+func getProviderSSOMappings(provider string) ([]models.SSOMapping, error) {
+	mappings, err := GetSSOMappings()
+	if err != nil {
+		return nil, err
+	}
+	providerMappings := []models.SSOMapping{}
+	for _, mapping := range mappings {
+		if mapping.Provider == provider {
+			providerMappings = append(providerMappings, mapping)
+		}
+	}
+	return providerMappings, nil
+}
+
+// mapSSOGroups - merges the access of the mappings matching any of the given identity provider groups,
+// user groups and networks which no longer exist are left out
+func mapSSOGroups(mappings []models.SSOMapping, idpGroups []string) (bool, []string, []string) {
+	isAdmin := false
+	groups, networks := []string{}, []string{}
+	for _, mapping := range mappings {
+		matched := false
+		for _, idpGroup := range idpGroups {
+			if strings.EqualFold(mapping.IdPGroup, idpGroup) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		isAdmin = isAdmin || mapping.Admin
+		for _, group := range mapping.UserGroups {
+			if !StringSliceContains(groups, group) && pro.DoesUserGroupExist(promodels.UserGroupName(group)) {
+				groups = append(groups, group)
+			}
+		}
+		for _, network := range mapping.Networks {
+			if StringSliceContains(networks, network) {
+				continue
+			}
+			if exists, err := NetworkExists(network); err == nil && exists {
+				networks = append(networks, network)
+			}
+		}
+	}
+	sort.Strings(groups)
+	sort.Strings(networks)
+	return isAdmin, groups, networks
+}
+
+// isLastAdmin - checks if a user is the only admin of the server
+func isLastAdmin(username string) (bool, error) {
+	users, err := GetUsers()
+	if err != nil {
+		return false, err
+	}
+	for _, user := range users {
+		if user.IsAdmin && user.UserName != username {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func saveSSOMapping(mapping *models.SSOMapping) error {
+	mapping.Provider = strings.ToLower(strings.TrimSpace(mapping.Provider))
+	if mapping.Provider == "" {
+		return errors.New("a mapping needs the provider of its group")
+	}
+	mapping.IdPGroup = strings.TrimSpace(mapping.IdPGroup)
+	if mapping.IdPGroup == "" {
+		return errors.New("a mapping needs an identity provider group")
+	}
+	if !mapping.Admin && len(mapping.UserGroups) == 0 && len(mapping.Networks) == 0 {
+		return errors.New("a mapping needs user groups, networks or admin status to grant")
+	}
+	if mapping.UserGroups == nil {
+		mapping.UserGroups = []string{}
+	}
+	if mapping.Networks == nil {
+		mapping.Networks = []string{}
+	}
+	for _, group := range mapping.UserGroups {
+		if !pro.DoesUserGroupExist(promodels.UserGroupName(group)) {
+			return fmt.Errorf("group %s does not exist", group)
+		}
+	}
+	for _, network := range mapping.Networks {
+		if exists, err := NetworkExists(network); err != nil || !exists {
+			return fmt.Errorf("network %s does not exist", network)
+		}
+	}
+	data, err := json.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+	return database.Insert(mapping.ID, string(data), database.SSO_MAPPINGS_TABLE_NAME)
+}
@@ -0,0 +1,127 @@
+package logic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic/pro"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/models/promodels"
+	"github.com/matryer/is"
+)
+
+func TestApplySSOMappings(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	SetJWTSecret()
+	is := is.New(t)
+	network := models.Network{NetID: "ssonet"}
+	data, _ := json.Marshal(&network)
+	is.NoErr(database.Insert(network.NetID, string(data), database.NETWORKS_TABLE_NAME))
+	defer database.DeleteRecord(database.NETWORKS_TABLE_NAME, network.NetID)
+	is.NoErr(pro.InitializeNetworkUsers(network.NetID))
+	defer database.DeleteRecord(database.NETWORK_USER_TABLE_NAME, network.NetID)
+	is.NoErr(pro.CreateNetworkUser(&network, &promodels.NetworkUser{ID: "ssouser", AccessLevel: pro.NO_ACCESS}))
+	is.NoErr(pro.InitializeGroups())
+	is.NoErr(pro.InsertUserGroup("sso-ops"))
+	defer pro.DeleteUserGroup("sso-ops")
+	// another admin, so that the sso user can be demoted
+	for _, user := range []models.User{{UserName: "ssouser", Networks: []string{}}, {UserName: "ssoadmin", IsAdmin: true}} {
+		data, _ := json.Marshal(&user)
+		is.NoErr(database.Insert(user.UserName, string(data), database.USERS_TABLE_NAME))
+		defer database.DeleteRecord(database.USERS_TABLE_NAME, user.UserName)
+	}
+	defer DeleteUserSessions("ssouser")
+
+	t.Run("no mappings", func(t *testing.T) {
+		is := is.New(t)
+		mappings, err := GetSSOMappings()
+		is.NoErr(err)
+		if len(mappings) > 0 {
+			t.Skip("sso mappings left in the database")
+		}
+		is.NoErr(ApplySSOMappings("azure-ad", "ssouser", []string{}))
+		user, err := GetUser("ssouser")
+		is.NoErr(err)
+		is.Equal(len(user.Networks), 0)
+	})
+	t.Run("invalid mappings", func(t *testing.T) {
+		is := is.New(t)
+		is.True(CreateSSOMapping(&models.SSOMapping{Provider: "azure-ad", Networks: []string{network.NetID}}) != nil)
+		is.True(CreateSSOMapping(&models.SSOMapping{IdPGroup: "eng", Networks: []string{network.NetID}}) != nil)
+		is.True(CreateSSOMapping(&models.SSOMapping{Provider: "azure-ad", IdPGroup: "eng"}) != nil)
+		is.True(CreateSSOMapping(&models.SSOMapping{Provider: "azure-ad", IdPGroup: "eng", Networks: []string{"missingnet"}}) != nil)
+		is.True(CreateSSOMapping(&models.SSOMapping{Provider: "azure-ad", IdPGroup: "eng", UserGroups: []string{"missing"}}) != nil)
+		is.True(UpdateSSOMapping(&models.SSOMapping{ID: "missing", Provider: "azure-ad", IdPGroup: "eng", Admin: true}) != nil)
+	})
+
+	engineers := models.SSOMapping{Provider: "azure-ad", IdPGroup: "engineers", UserGroups: []string{"sso-ops"}, Networks: []string{network.NetID}}
+	is.NoErr(CreateSSOMapping(&engineers))
+	defer DeleteSSOMapping(engineers.ID)
+	admins := models.SSOMapping{Provider: "azure-ad", IdPGroup: "acme/admins", Admin: true}
+	is.NoErr(CreateSSOMapping(&admins))
+	defer DeleteSSOMapping(admins.ID)
+
+	t.Run("grants mapped access", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(ApplySSOMappings("azure-ad", "ssouser", []string{"Engineers", "unmapped"}))
+		user, err := GetUser("ssouser")
+		is.NoErr(err)
+		is.True(!user.IsAdmin)
+		is.Equal(user.Groups, []string{"sso-ops"})
+		is.Equal(user.Networks, []string{network.NetID})
+		netUser, err := pro.GetNetworkUser(network.NetID, "ssouser")
+		is.NoErr(err)
+		is.Equal(netUser.AccessLevel, pro.NET_ADMIN)
+	})
+	t.Run("unknown groups change nothing", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(ApplySSOMappings("azure-ad", "ssouser", nil))
+		user, err := GetUser("ssouser")
+		is.NoErr(err)
+		is.Equal(user.Networks, []string{network.NetID})
+	})
+	t.Run("promotes and demotes", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(ApplySSOMappings("azure-ad", "ssouser", []string{"acme/admins"}))
+		user, err := GetUser("ssouser")
+		is.NoErr(err)
+		is.True(user.IsAdmin)
+		login, err := CreateUserSession(user, "", "")
+		is.NoErr(err)
+		is.NoErr(ApplySSOMappings("azure-ad", "ssouser", []string{"engineers"}))
+		user, err = GetUser("ssouser")
+		is.NoErr(err)
+		is.True(!user.IsAdmin)
+		is.Equal(user.Networks, []string{network.NetID})
+		_, _, _, err = VerifyUserToken(login.AuthToken)
+		is.True(err != nil)
+	})
+	t.Run("groups of other providers", func(t *testing.T) {
+		is := is.New(t)
+		contractors := models.SSOMapping{Provider: "contractors", IdPGroup: "acme/admins", Networks: []string{network.NetID}}
+		is.NoErr(CreateSSOMapping(&contractors))
+		defer DeleteSSOMapping(contractors.ID)
+		// the group of the same name at the other provider grants no admin status
+		is.NoErr(ApplySSOMappings("contractors", "ssouser", []string{"acme/admins"}))
+		user, err := GetUser("ssouser")
+		is.NoErr(err)
+		is.True(!user.IsAdmin)
+		is.Equal(user.Networks, []string{network.NetID})
+		// providers without mappings leave the access as it is
+		is.NoErr(ApplySSOMappings("okta", "ssouser", []string{"engineers"}))
+		user, err = GetUser("ssouser")
+		is.NoErr(err)
+		is.Equal(len(user.Groups), 0)
+		is.Equal(user.Networks, []string{network.NetID})
+	})
+	t.Run("revokes unmapped access", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(ApplySSOMappings("azure-ad", "ssouser", []string{}))
+		user, err := GetUser("ssouser")
+		is.NoErr(err)
+		is.Equal(len(user.Groups), 0)
+		is.Equal(len(user.Networks), 0)
+	})
+}
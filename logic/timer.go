@@ -46,6 +46,8 @@ func AddHook(ifaceToAdd interface{}) {
 var timeHooks = []interface{}{
 	loggerDump,
 	sendTelemetry,
+	pruneUserSessions,
+	pruneLoginAttempts,
 }
 
 func loggerDump() error {
@@ -0,0 +1,286 @@
+package logic
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic/acls/nodeacls"
+	"github.com/gravitl/netmaker/models"
+)
+
+// GetNetworkTopology - builds the graph of a network out of its nodes and the paths stored between their hosts,
+// the edges carry the connectivity last reported in the metrics of the nodes
+func GetNetworkTopology(network string) (models.NetworkTopology, error) {
+	topology := models.NetworkTopology{
+		Network:     network,
+		GeneratedAt: time.Now(),
+		Vertices:    []models.TopologyVertex{},
+		Edges:       []models.TopologyEdge{},
+	}
+	nodes, err := GetNetworkNodes(network)
+	if err != nil {
+		return topology, err
+	}
+	allHosts, err := GetAllHosts()
+	if err != nil {
+		return topology, err
+	}
+	hostsByID := make(map[string]*models.Host, len(allHosts))
+	for i := range allHosts {
+		hostsByID[allHosts[i].ID.String()] = &allHosts[i]
+	}
+	networkNodes := []models.Node{}
+	hosts := make(map[string]*models.Host)
+	for _, node := range nodes {
+		if node.PendingDelete {
+			continue
+		}
+		host, ok := hostsByID[node.HostID.String()]
+		if !ok {
+			logger.Log(1, "no host found for node", node.ID.String(), "in topology of network", network)
+			continue
+		}
+		networkNodes = append(networkNodes, node)
+		hosts[host.ID.String()] = host
+		topology.Vertices = append(topology.Vertices, models.TopologyVertex{
+			ID:                node.ID.String(),
+			Name:              host.Name,
+			Type:              models.NodeVertex,
+			HostID:            host.ID.String(),
+			Address:           ipString(node.Address.IP),
+			Address6:          ipString(node.Address6.IP),
+			Connected:         node.Connected,
+			IsRelay:           host.IsRelay,
+			IsRelayed:         host.IsRelayed,
+			IsIngressGateway:  node.IsIngressGateway,
+			IsEgressGateway:   node.IsEgressGateway,
+			IsInternetGateway: node.IsInternetGateway(),
+		})
+		for _, egressRange := range node.EgressGatewayRanges {
+			if !containsVertex(topology.Vertices, egressVertexID(egressRange)) {
+				topology.Vertices = append(topology.Vertices, models.TopologyVertex{
+					ID:        egressVertexID(egressRange),
+					Name:      egressRange,
+					Type:      models.EgressRangeVertex,
+					Address:   egressRange,
+					Connected: true,
+				})
+			}
+			topology.Edges = append(topology.Edges, models.TopologyEdge{
+				From:      node.ID.String(),
+				To:        egressVertexID(egressRange),
+				Reported:  true,
+				Connected: node.Connected,
+				Path:      models.DirectPath,
+			})
+		}
+	}
+	// relay node of a relay host in this network
+	relayNodes := make(map[string]string)
+	for _, node := range networkNodes {
+		if hosts[node.HostID.String()].IsRelay {
+			relayNodes[node.HostID.String()] = node.ID.String()
+		}
+	}
+	for _, node := range networkNodes {
+		nodeID := node.ID.String()
+		host := hosts[node.HostID.String()]
+		metrics, err := GetMetrics(nodeID)
+		if err != nil {
+			metrics = &models.Metrics{}
+		}
+		if node.Connected {
+			// selections and paths of the host are loaded once instead of per peer
+			selections := getHostEndpointSelections(host.ID.String())
+			paths := getHostPeerPathMap(host.ID.String())
+			for _, peer := range networkNodes {
+				peerID := peer.ID.String()
+				if peerID == nodeID || peer.HostID == node.HostID || !peer.Connected ||
+					!nodeacls.AreNodesAllowed(nodeacls.NetworkID(network), nodeacls.NodeID(nodeID), nodeacls.NodeID(peerID)) {
+					continue
+				}
+				peerHost := hosts[peer.HostID.String()]
+				path := paths[peerHost.ID.String()]
+				var punched netip.AddrPort
+				if path.Direct {
+					if path.HostA == host.ID.String() {
+						punched = path.EndpointB
+					} else {
+						punched = path.EndpointA
+					}
+				}
+				edge := models.TopologyEdge{
+					From:     nodeID,
+					To:       peerID,
+					Endpoint: GetPeerEndpoint(host, peerHost, selections[peerHost.ID.String()], punched).String(),
+				}
+				edge.Path, edge.Via = topologyPath(host, peerHost, relayNodes, path)
+				setEdgeMetric(&edge, metrics, peerID)
+				topology.Edges = append(topology.Edges, edge)
+			}
+		}
+		if !node.IsIngressGateway {
+			continue
+		}
+		clients, err := GetExtClientsByID(nodeID, network)
+		if err != nil && !database.IsEmptyRecord(err) {
+			return topology, err
+		}
+		for _, client := range clients {
+			topology.Vertices = append(topology.Vertices, models.TopologyVertex{
+				ID:        client.ClientID,
+				Name:      client.ClientID,
+				Type:      models.ExtClientVertex,
+				Address:   client.Address,
+				Address6:  client.Address6,
+				Connected: client.Enabled,
+			})
+			edge := models.TopologyEdge{
+				From: nodeID,
+				To:   client.ClientID,
+				Path: models.DirectPath,
+			}
+			setEdgeMetric(&edge, metrics, client.ClientID)
+			topology.Edges = append(topology.Edges, edge)
+		}
+	}
+	sort.Slice(topology.Vertices, func(i, j int) bool {
+		return topology.Vertices[i].ID < topology.Vertices[j].ID
+	})
+	sort.Slice(topology.Edges, func(i, j int) bool {
+		if topology.Edges[i].From != topology.Edges[j].From {
+			return topology.Edges[i].From < topology.Edges[j].From
+		}
+		return topology.Edges[i].To < topology.Edges[j].To
+	})
+	return topology, nil
+}
+
+// TopologyToDOT - renders a topology as a Graphviz digraph, edges are colored by connectivity
+// and dashed when the traffic does not take a direct path
+func TopologyToDOT(topology *models.NetworkTopology) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", topology.Network)
+	b.WriteString("\tnode [fontname=\"Helvetica\"];\n")
+	for _, vertex := range topology.Vertices {
+		shape := "box"
+		switch vertex.Type {
+		case models.ExtClientVertex:
+			shape = "ellipse"
+		case models.EgressRangeVertex:
+			shape = "cloud"
+		}
+		label := vertex.Name
+		if vertex.Address != "" && vertex.Address != vertex.Name {
+			label += "\n" + vertex.Address
+		}
+		var roles []string
+		if vertex.IsRelay {
+			roles = append(roles, "relay")
+		}
+		if vertex.IsIngressGateway {
+			roles = append(roles, "ingress")
+		}
+		if vertex.IsEgressGateway {
+			roles = append(roles, "egress")
+		}
+		if vertex.IsInternetGateway {
+			roles = append(roles, "internet gateway")
+		}
+		if len(roles) > 0 {
+			label += "\n[" + strings.Join(roles, ", ") + "]"
+		}
+		color := "black"
+		if !vertex.Connected {
+			color = "gray"
+		}
+		fmt.Fprintf(&b, "\t%q [label=%q, shape=%s, color=%s];\n", vertex.ID, label, shape, color)
+	}
+	for _, edge := range topology.Edges {
+		color := "gray"
+		label := string(edge.Path)
+		if edge.Reported {
+			color = "red"
+			if edge.Connected {
+				color = "green"
+				label = fmt.Sprintf("%s %dms", edge.Path, edge.Latency)
+			}
+		}
+		style := "solid"
+		if edge.Path != models.DirectPath {
+			style = "dashed"
+		}
+		fmt.Fprintf(&b, "\t%q -> %q [label=%q, color=%s, style=%s];\n", edge.From, edge.To, label, color, style)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// topologyPath - path the traffic of a host to a peer host takes and the relay node forwarding it,
+// path is the one stored between the hosts, empty if none was punched
+func topologyPath(host, peerHost *models.Host, relayNodes map[string]string, path models.PeerPath) (models.TopologyPath, string) {
+	if host.IsRelayed && host.RelayedBy != peerHost.ID.String() {
+		return models.RelayPath, relayNodes[host.RelayedBy]
+	}
+	if peerHost.IsRelayed && peerHost.RelayedBy != host.ID.String() {
+		return models.RelayPath, relayNodes[peerHost.RelayedBy]
+	}
+	if path.HostA != "" && !path.Direct {
+		return models.TurnPath, ""
+	}
+	return models.DirectPath, ""
+}
+
+// getHostPeerPathMap - the paths stored between a host and its peers keyed by the peer host
+func getHostPeerPathMap(hostID string) map[string]models.PeerPath {
+	paths := make(map[string]models.PeerPath)
+	stored, err := GetHostPeerPaths(hostID)
+	if err != nil {
+		logger.Log(1, "failed to fetch peer paths of host", hostID, err.Error())
+	}
+	for _, path := range stored {
+		if path.HostA == hostID {
+			paths[path.HostB] = path
+		} else {
+			paths[path.HostA] = path
+		}
+	}
+	return paths
+}
+
+func setEdgeMetric(edge *models.TopologyEdge, metrics *models.Metrics, peerID string) {
+	metric, ok := metrics.Connectivity[peerID]
+	if !ok {
+		return
+	}
+	edge.Reported = true
+	edge.Connected = metric.Connected
+	edge.Latency = metric.Latency
+	edge.PercentUp = metric.PercentUp
+}
+
+func containsVertex(vertices []models.TopologyVertex, id string) bool {
+	for _, vertex := range vertices {
+		if vertex.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func egressVertexID(egressRange string) string {
+	return "egress:" + egressRange
+}
+
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
@@ -0,0 +1,166 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic/acls"
+	"github.com/gravitl/netmaker/logic/acls/nodeacls"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestTopologyPath(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	newHost := func() *models.Host {
+		return &models.Host{ID: uuid.New()}
+	}
+	relay := newHost()
+	relay.IsRelay = true
+	relayNodes := map[string]string{relay.ID.String(): "relay-node"}
+	t.Run("direct", func(t *testing.T) {
+		is := is.New(t)
+		path, via := topologyPath(newHost(), newHost(), relayNodes, models.PeerPath{})
+		is.Equal(path, models.DirectPath)
+		is.Equal(via, "")
+	})
+	t.Run("relayed host", func(t *testing.T) {
+		is := is.New(t)
+		host := newHost()
+		host.IsRelayed = true
+		host.RelayedBy = relay.ID.String()
+		path, via := topologyPath(host, newHost(), relayNodes, models.PeerPath{})
+		is.Equal(path, models.RelayPath)
+		is.Equal(via, "relay-node")
+		path, _ = topologyPath(newHost(), host, relayNodes, models.PeerPath{})
+		is.Equal(path, models.RelayPath)
+	})
+	t.Run("relayed host to its relay", func(t *testing.T) {
+		is := is.New(t)
+		host := newHost()
+		host.IsRelayed = true
+		host.RelayedBy = relay.ID.String()
+		path, _ := topologyPath(host, relay, relayNodes, models.PeerPath{})
+		is.Equal(path, models.DirectPath)
+	})
+	t.Run("failed punch", func(t *testing.T) {
+		is := is.New(t)
+		host, peerHost := newHost(), newHost()
+		path, _ := topologyPath(host, peerHost, relayNodes, models.PeerPath{HostA: host.ID.String(), HostB: peerHost.ID.String()})
+		is.Equal(path, models.TurnPath)
+	})
+}
+
+func TestGetNetworkTopology(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	network := "topo-net"
+	hosts := make([]models.Host, 2)
+	nodes := make([]models.Node, 2)
+	for i := range hosts {
+		hosts[i] = models.Host{
+			ID:         uuid.New(),
+			Name:       fmt.Sprintf("topohost%d", i),
+			EndpointIP: net.ParseIP(fmt.Sprintf("203.0.113.%d", 10+i)),
+			ListenPort: 51821,
+		}
+		nodes[i] = models.Node{}
+		nodes[i].ID = uuid.New()
+		nodes[i].HostID = hosts[i].ID
+		nodes[i].Network = network
+		nodes[i].Connected = true
+		nodes[i].Address = net.IPNet{IP: net.ParseIP(fmt.Sprintf("10.0.0.%d", 10+i)), Mask: net.CIDRMask(32, 32)}
+		if err := upsertNode(&nodes[i]); err != nil {
+			t.Fatal(err)
+		}
+		defer database.DeleteRecord(database.NODES_TABLE_NAME, nodes[i].ID.String())
+		hosts[i].Nodes = []string{nodes[i].ID.String()}
+		if err := UpsertHost(&hosts[i]); err != nil {
+			t.Fatal(err)
+		}
+		defer RemoveHostByID(hosts[i].ID.String())
+		if _, err := nodeacls.CreateNodeACL(nodeacls.NetworkID(network), nodeacls.NodeID(nodes[i].ID.String()), acls.Allowed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer nodeacls.DeleteACLContainer(nodeacls.NetworkID(network))
+	if err := UpdateMetrics(nodes[0].ID.String(), &models.Metrics{
+		Connectivity: map[string]models.Metric{
+			nodes[1].ID.String(): {Connected: true, Latency: 25},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer DeleteMetrics(nodes[0].ID.String())
+
+	t.Run("edges between connected nodes", func(t *testing.T) {
+		is := is.New(t)
+		topology, err := GetNetworkTopology(network)
+		is.NoErr(err)
+		is.Equal(len(topology.Vertices), 2)
+		is.Equal(len(topology.Edges), 2)
+		for _, edge := range topology.Edges {
+			is.Equal(edge.Path, models.DirectPath)
+			if edge.From == nodes[0].ID.String() {
+				is.True(edge.Reported)
+				is.Equal(edge.Latency, int64(25))
+				is.Equal(edge.Endpoint, "203.0.113.11:51821")
+			} else {
+				is.True(!edge.Reported)
+			}
+		}
+	})
+	t.Run("stored paths", func(t *testing.T) {
+		is := is.New(t)
+		path := models.PeerPath{HostA: hosts[0].ID.String(), HostB: hosts[1].ID.String()}
+		data, err := json.Marshal(&path)
+		is.NoErr(err)
+		is.NoErr(database.Insert(peerPathKey(path.HostA, path.HostB), string(data), database.PEER_PATHS_TABLE_NAME))
+		defer DeleteHostPeerPaths(hosts[0].ID.String())
+		topology, err := GetNetworkTopology(network)
+		is.NoErr(err)
+		is.Equal(len(topology.Edges), 2)
+		for _, edge := range topology.Edges {
+			is.Equal(edge.Path, models.TurnPath)
+		}
+	})
+	t.Run("disconnected peer", func(t *testing.T) {
+		is := is.New(t)
+		nodes[1].Connected = false
+		is.NoErr(upsertNode(&nodes[1]))
+		topology, err := GetNetworkTopology(network)
+		is.NoErr(err)
+		is.Equal(len(topology.Vertices), 2)
+		is.Equal(len(topology.Edges), 0)
+	})
+}
+
+func TestTopologyToDOT(t *testing.T) {
+	is := is.New(t)
+	topology := models.NetworkTopology{
+		Network: "skynet",
+		Vertices: []models.TopologyVertex{
+			{ID: "a", Name: "alpha", Type: models.NodeVertex, Address: "10.0.0.1", Connected: true, IsRelay: true},
+			{ID: "b", Name: "beta", Type: models.NodeVertex, Address: "10.0.0.2", Connected: true},
+			{ID: "c", Name: "laptop", Type: models.ExtClientVertex, Connected: true},
+		},
+		Edges: []models.TopologyEdge{
+			{From: "a", To: "b", Reported: true, Connected: true, Latency: 12, Path: models.DirectPath},
+			{From: "b", To: "a", Reported: true, Path: models.TurnPath},
+			{From: "a", To: "c", Path: models.DirectPath},
+		},
+	}
+	dot := TopologyToDOT(&topology)
+	is.True(strings.HasPrefix(dot, `digraph "skynet" {`))
+	is.True(strings.Contains(dot, `"a" [label="alpha\n10.0.0.1\n[relay]", shape=box, color=black];`))
+	is.True(strings.Contains(dot, `"c" [label="laptop", shape=ellipse, color=black];`))
+	is.True(strings.Contains(dot, `"a" -> "b" [label="direct 12ms", color=green, style=solid];`))
+	is.True(strings.Contains(dot, `"b" -> "a" [label="turn", color=red, style=dashed];`))
+	is.True(strings.Contains(dot, `"a" -> "c" [label="direct", color=gray, style=solid];`))
+}
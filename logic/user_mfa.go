@@ -0,0 +1,240 @@
+package logic
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+const (
+	// totpPeriod - seconds a totp code is valid for
+	totpPeriod = 30
+	totpDigits = 6
+	// totpSkew - time steps before and after the current one a code is accepted for, to allow for clock drift
+	totpSkew             = 1
+	mfaRecoveryCodeCount = 10
+	mfaIssuer            = "Netmaker"
+)
+
+var (
+	// ErrMFARequired - the user has mfa enabled and gave no code
+	ErrMFARequired = errors.New("mfa code required")
+	// ErrMFAEnrollmentRequired - mfa is enforced and the user has no authenticator enrolled
+	ErrMFAEnrollmentRequired = errors.New("mfa is enforced, enroll a totp authenticator first")
+	// ErrMFAInvalidCode - the given code is wrong or was used before
+	ErrMFAInvalidCode = errors.New("invalid mfa code")
+)
+
+var userMFAMutex = &sync.Mutex{}
+
+// GetUserMFA - fetches the mfa enrollment of a user
+func GetUserMFA(username string) (models.UserMFA, error) {
+	var mfa models.UserMFA
+	record, err := database.FetchRecord(database.USER_MFA_TABLE_NAME, username)
+	if err != nil {
+		return mfa, err
+	}
+	err = json.Unmarshal([]byte(record), &mfa)
+	return mfa, err
+}
+
+// GetUserMFAStatus - whether a user has mfa enabled and how many recovery codes it has left
+func GetUserMFAStatus(username string) models.MFAStatus {
+	status := models.MFAStatus{Enforced: servercfg.IsMFAEnforced()}
+	if mfa, err := GetUserMFA(username); err == nil && mfa.Enabled {
+		status.Enabled = true
+		status.RecoveryCodesLeft = len(mfa.RecoveryCodes)
+	}
+	return status
+}
+
+// EnrollUserMFA - creates a totp secret and recovery codes for a user, which are only used once the
+// enrollment is confirmed with a code, a pending enrollment is replaced
+func EnrollUserMFA(username string) (models.MFAEnrollment, error) {
+	var enrollment models.MFAEnrollment
+	if _, err := GetUser(username); err != nil {
+		return enrollment, err
+	}
+	userMFAMutex.Lock()
+	defer userMFAMutex.Unlock()
+	if mfa, err := GetUserMFA(username); err == nil && mfa.Enabled {
+		return enrollment, errors.New("mfa is already enabled, disable it first")
+	}
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return enrollment, err
+	}
+	mfa := models.UserMFA{
+		UserName:  username,
+		Secret:    base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret),
+		CreatedAt: time.Now(),
+	}
+	codes, hashes, err := newMFARecoveryCodes()
+	if err != nil {
+		return enrollment, err
+	}
+	mfa.RecoveryCodes = hashes
+	if err := saveUserMFA(&mfa); err != nil {
+		return enrollment, err
+	}
+	label := url.PathEscape(mfaIssuer + ":" + username)
+	query := url.Values{}
+	query.Set("secret", mfa.Secret)
+	query.Set("issuer", mfaIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+	enrollment.Secret = mfa.Secret
+	enrollment.URI = "otpauth://totp/" + label + "?" + query.Encode()
+	enrollment.RecoveryCodes = codes
+	return enrollment, nil
+}
+
+// ConfirmUserMFA - enables a pending mfa enrollment of a user with a code of its authenticator
+func ConfirmUserMFA(username, code string) error {
+	userMFAMutex.Lock()
+	defer userMFAMutex.Unlock()
+	mfa, err := GetUserMFA(username)
+	if err != nil {
+		return errors.New("no mfa enrollment to confirm")
+	}
+	if mfa.Enabled {
+		return errors.New("mfa is already enabled")
+	}
+	// recovery codes cannot confirm that the authenticator works
+	if err := checkTOTPCode(&mfa, code); err != nil {
+		return err
+	}
+	mfa.Enabled = true
+	return saveUserMFA(&mfa)
+}
+
+// RegenerateMFARecoveryCodes - replaces the recovery codes of a user with mfa enabled
+func RegenerateMFARecoveryCodes(username string) ([]string, error) {
+	userMFAMutex.Lock()
+	defer userMFAMutex.Unlock()
+	mfa, err := GetUserMFA(username)
+	if err != nil || !mfa.Enabled {
+		return nil, errors.New("mfa is not enabled")
+	}
+	codes, hashes, err := newMFARecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	mfa.RecoveryCodes = hashes
+	return codes, saveUserMFA(&mfa)
+}
+
+// DisableUserMFA - removes the mfa enrollment of a user
+func DisableUserMFA(username string) error {
+	if _, err := GetUserMFA(username); err != nil {
+		return errors.New("mfa is not enabled")
+	}
+	return database.DeleteRecord(database.USER_MFA_TABLE_NAME, username)
+}
+
+// VerifyUserMFA - checks the second factor of a user logging in with a password, a code is
+// either the current totp code or an unused recovery code
+func VerifyUserMFA(user *models.User, code string) error {
+	userMFAMutex.Lock()
+	defer userMFAMutex.Unlock()
+	mfa, err := GetUserMFA(user.UserName)
+	if err != nil || !mfa.Enabled {
+		if servercfg.IsMFAEnforced() {
+			return ErrMFAEnrollmentRequired
+		}
+		return nil
+	}
+	if code == "" {
+		return ErrMFARequired
+	}
+	if err := checkTOTPCode(&mfa, code); err == nil {
+		return saveUserMFA(&mfa)
+	}
+	hash := hashAccessTokenSecret(normalizeMFARecoveryCode(code))
+	for i, recoveryCode := range mfa.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(recoveryCode), []byte(hash)) == 1 {
+			mfa.RecoveryCodes = append(mfa.RecoveryCodes[:i], mfa.RecoveryCodes[i+1:]...)
+			return saveUserMFA(&mfa)
+		}
+	}
+	return ErrMFAInvalidCode
+}
+
+// checkTOTPCode - checks a totp code against the secret of an enrollment and records its time step,
+// the caller saves the enrollment
+func checkTOTPCode(mfa *models.UserMFA, code string) error {
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(mfa.Secret)
+	if err != nil {
+		return err
+	}
+	code = strings.TrimSpace(code)
+	current := time.Now().Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= mfa.LastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, step)), []byte(code)) == 1 {
+			mfa.LastStep = step
+			return nil
+		}
+	}
+	return ErrMFAInvalidCode
+}
+
+// totpCode - code of a time step as of RFC 6238, with HMAC-SHA1
+func totpCode(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulo)
+}
+
+// newMFARecoveryCodes - generates recovery codes, returning them and their hashes
+func newMFARecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	hashes := make([]string, 0, mfaRecoveryCodeCount)
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		code := strings.ToLower(RandomString(10))
+		if code == "" {
+			return nil, nil, errors.New("failed to generate recovery codes")
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashAccessTokenSecret(code))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeMFARecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+func saveUserMFA(mfa *models.UserMFA) error {
+	data, err := json.Marshal(mfa)
+	if err != nil {
+		return err
+	}
+	return database.Insert(mfa.UserName, string(data), database.USER_MFA_TABLE_NAME)
+}
@@ -0,0 +1,90 @@
+package logic
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestTOTPCode(t *testing.T) {
+	is := is.New(t)
+	// test vectors of RFC 6238, truncated to six digits
+	secret := []byte("12345678901234567890")
+	is.Equal(totpCode(secret, 59/totpPeriod), "287082")
+	is.Equal(totpCode(secret, 1111111109/totpPeriod), "081804")
+	is.Equal(totpCode(secret, 2000000000/totpPeriod), "279037")
+}
+
+func TestUserMFA(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	user := models.User{UserName: "mfauser", Networks: []string{}}
+	data, _ := json.Marshal(&user)
+	database.Insert(user.UserName, string(data), database.USERS_TABLE_NAME)
+	defer database.DeleteRecord(database.USERS_TABLE_NAME, user.UserName)
+	defer database.DeleteRecord(database.USER_MFA_TABLE_NAME, user.UserName)
+	currentCode := func(secret string, offset int64) string {
+		key, _ := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+		return totpCode(key, time.Now().Unix()/totpPeriod+offset)
+	}
+	var enrollment models.MFAEnrollment
+	var confirmCode string
+
+	t.Run("not enrolled", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(VerifyUserMFA(&user, ""))
+		t.Setenv("MFA_ENFORCED", "yes")
+		is.Equal(VerifyUserMFA(&user, ""), ErrMFAEnrollmentRequired)
+	})
+	t.Run("enroll", func(t *testing.T) {
+		is := is.New(t)
+		var err error
+		enrollment, err = EnrollUserMFA(user.UserName)
+		is.NoErr(err)
+		is.True(strings.HasPrefix(enrollment.URI, "otpauth://totp/Netmaker:mfauser?"))
+		is.True(strings.Contains(enrollment.URI, "secret="+enrollment.Secret))
+		is.Equal(len(enrollment.RecoveryCodes), mfaRecoveryCodeCount)
+		// pending enrollments are not asked for
+		is.NoErr(VerifyUserMFA(&user, ""))
+		is.Equal(ConfirmUserMFA(user.UserName, "000000x"), ErrMFAInvalidCode)
+		confirmCode = currentCode(enrollment.Secret, 0)
+		is.NoErr(ConfirmUserMFA(user.UserName, confirmCode))
+		is.True(GetUserMFAStatus(user.UserName).Enabled)
+		_, err = EnrollUserMFA(user.UserName)
+		is.True(err != nil)
+	})
+	t.Run("verify", func(t *testing.T) {
+		is := is.New(t)
+		is.Equal(VerifyUserMFA(&user, ""), ErrMFARequired)
+		is.Equal(VerifyUserMFA(&user, "bogus"), ErrMFAInvalidCode)
+		// the code used to confirm cannot be used again
+		is.Equal(VerifyUserMFA(&user, confirmCode), ErrMFAInvalidCode)
+		code := currentCode(enrollment.Secret, 1)
+		is.NoErr(VerifyUserMFA(&user, code))
+		is.Equal(VerifyUserMFA(&user, code), ErrMFAInvalidCode)
+	})
+	t.Run("recovery codes", func(t *testing.T) {
+		is := is.New(t)
+		code := enrollment.RecoveryCodes[0]
+		is.NoErr(VerifyUserMFA(&user, strings.ToUpper(code)))
+		is.Equal(VerifyUserMFA(&user, code), ErrMFAInvalidCode)
+		is.Equal(GetUserMFAStatus(user.UserName).RecoveryCodesLeft, mfaRecoveryCodeCount-1)
+		codes, err := RegenerateMFARecoveryCodes(user.UserName)
+		is.NoErr(err)
+		is.Equal(VerifyUserMFA(&user, enrollment.RecoveryCodes[1]), ErrMFAInvalidCode)
+		is.NoErr(VerifyUserMFA(&user, codes[0]))
+	})
+	t.Run("disable", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr(DisableUserMFA(user.UserName))
+		is.True(!GetUserMFAStatus(user.UserName).Enabled)
+		is.NoErr(VerifyUserMFA(&user, ""))
+		is.True(DisableUserMFA(user.UserName) != nil)
+	})
+}
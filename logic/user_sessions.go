@@ -0,0 +1,273 @@
+package logic
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+// REFRESH_TOKEN_PREFIX - prefix of the refresh tokens of user sessions
+const REFRESH_TOKEN_PREFIX = "nmrt_"
+
+// userLoginCodeValidity - time the one time code handed to the frontend after an sso login can be exchanged
+const userLoginCodeValidity = time.Minute
+
+// userLoginCode - one time code of a session opened through sso, only a hash of the code is stored
+type userLoginCode struct {
+	SessionID string    `json:"session_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+var userSessionMutex = &sync.Mutex{}
+
+// CreateUserSession - logs a user in, returning a short lived access token and the refresh token of a new session
+func CreateUserSession(user *models.User, sourceIP, userAgent string) (models.SuccessfulUserLoginResponse, error) {
+	session := models.UserSession{
+		ID:        uuid.New().String(),
+		UserName:  user.UserName,
+		CreatedAt: time.Now(),
+		SourceIP:  sourceIP,
+		UserAgent: userAgent,
+	}
+	userSessionMutex.Lock()
+	defer userSessionMutex.Unlock()
+	return issueUserSessionTokens(user, &session)
+}
+
+// RefreshUserSession - exchanges a refresh token for new tokens of its session, a refresh token can only
+// be used once and using it again ends the session as it may have been stolen
+func RefreshUserSession(refreshToken string) (models.SuccessfulUserLoginResponse, error) {
+	var response models.SuccessfulUserLoginResponse
+	id, secret, found := strings.Cut(strings.TrimPrefix(refreshToken, REFRESH_TOKEN_PREFIX), ".")
+	if !strings.HasPrefix(refreshToken, REFRESH_TOKEN_PREFIX) || !found {
+		return response, Unauthorized_Err
+	}
+	userSessionMutex.Lock()
+	defer userSessionMutex.Unlock()
+	session, err := GetUserSession(id)
+	if err != nil {
+		return response, Unauthorized_Err
+	}
+	hash := hashAccessTokenSecret(secret)
+	if subtle.ConstantTimeCompare([]byte(session.RefreshHash), []byte(hash)) != 1 {
+		if session.PreviousRefreshHash != "" && subtle.ConstantTimeCompare([]byte(session.PreviousRefreshHash), []byte(hash)) == 1 {
+			logger.Log(0, "refresh token of a session of user", session.UserName, "was reused, ending the session")
+			if err := database.DeleteRecord(database.USER_SESSIONS_TABLE_NAME, session.ID); err != nil {
+				logger.Log(0, "failed to end session", session.ID, err.Error())
+			}
+		}
+		return response, Unauthorized_Err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		database.DeleteRecord(database.USER_SESSIONS_TABLE_NAME, session.ID)
+		return response, errors.New("session expired")
+	}
+	user, err := GetUser(session.UserName)
+	if err != nil {
+		return response, Unauthorized_Err
+	}
+	return issueUserSessionTokens(user, &session)
+}
+
+// CreateUserLoginCode - creates a short lived code which can be exchanged once for the tokens of a session,
+// so that sso logins do not have to pass the tokens to the frontend in the redirect url
+func CreateUserLoginCode(sessionID string) (string, error) {
+	code := RandomString(40)
+	if code == "" {
+		return "", errors.New("failed to generate login code")
+	}
+	data, err := json.Marshal(userLoginCode{
+		SessionID: sessionID,
+		ExpiresAt: time.Now().Add(userLoginCodeValidity),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := database.Insert(userLoginCodeKey(code), string(data), database.CACHE_TABLE_NAME); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// ExchangeUserLoginCode - exchanges a login code for new tokens of its session, the code can only be used once
+func ExchangeUserLoginCode(code string) (models.SuccessfulUserLoginResponse, error) {
+	var response models.SuccessfulUserLoginResponse
+	if code == "" {
+		return response, Unauthorized_Err
+	}
+	userSessionMutex.Lock()
+	defer userSessionMutex.Unlock()
+	key := userLoginCodeKey(code)
+	record, err := database.FetchRecord(database.CACHE_TABLE_NAME, key)
+	if err != nil {
+		return response, Unauthorized_Err
+	}
+	if err := database.DeleteRecord(database.CACHE_TABLE_NAME, key); err != nil {
+		return response, err
+	}
+	var loginCode userLoginCode
+	if err := json.Unmarshal([]byte(record), &loginCode); err != nil || time.Now().After(loginCode.ExpiresAt) {
+		return response, Unauthorized_Err
+	}
+	session, err := GetUserSession(loginCode.SessionID)
+	if err != nil || time.Now().After(session.ExpiresAt) {
+		return response, Unauthorized_Err
+	}
+	user, err := GetUser(session.UserName)
+	if err != nil {
+		return response, Unauthorized_Err
+	}
+	return issueUserSessionTokens(user, &session)
+}
+
+func userLoginCodeKey(code string) string {
+	return "logincode-" + hashAccessTokenSecret(code)
+}
+
+// GetUserSession - fetches a session
+func GetUserSession(id string) (models.UserSession, error) {
+	var session models.UserSession
+	record, err := database.FetchRecord(database.USER_SESSIONS_TABLE_NAME, id)
+	if err != nil {
+		return session, err
+	}
+	err = json.Unmarshal([]byte(record), &session)
+	return session, err
+}
+
+// GetUserSessions - fetches the active sessions of a user, of all users if username is empty,
+// the refresh token hashes are left out
+func GetUserSessions(username string) ([]models.UserSession, error) {
+	sessions := []models.UserSession{}
+	records, err := database.FetchRecords(database.USER_SESSIONS_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return sessions, err
+	}
+	now := time.Now()
+	for _, record := range records {
+		var session models.UserSession
+		if err := json.Unmarshal([]byte(record), &session); err != nil {
+			continue
+		}
+		if now.After(session.ExpiresAt) {
+			continue
+		}
+		if username != "" && session.UserName != username {
+			continue
+		}
+		session.RefreshHash = ""
+		session.PreviousRefreshHash = ""
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+// DeleteUserSession - ends a session, its access and refresh tokens stop working immediately
+func DeleteUserSession(id string) error {
+	if _, err := GetUserSession(id); err != nil {
+		return err
+	}
+	return database.DeleteRecord(database.USER_SESSIONS_TABLE_NAME, id)
+}
+
+// DeleteUserSessions - ends all sessions of a user, e.g. when it is deleted or loses admin rights
+func DeleteUserSessions(username string) error {
+	return deleteUserSessionsWhere(func(session models.UserSession) bool {
+		return session.UserName == username
+	})
+}
+
+// pruneUserSessions - removes the records of expired sessions
+func pruneUserSessions() error {
+	now := time.Now()
+	return deleteUserSessionsWhere(func(session models.UserSession) bool {
+		return now.After(session.ExpiresAt)
+	})
+}
+
+func deleteUserSessionsWhere(match func(models.UserSession) bool) error {
+	records, err := database.FetchRecords(database.USER_SESSIONS_TABLE_NAME)
+	if err != nil {
+		if database.IsEmptyRecord(err) {
+			return nil
+		}
+		return err
+	}
+	for id, record := range records {
+		var session models.UserSession
+		if err := json.Unmarshal([]byte(record), &session); err != nil || !match(session) {
+			continue
+		}
+		if err := database.DeleteRecord(database.USER_SESSIONS_TABLE_NAME, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyUserSession - checks that the session a user access token was issued for is still active
+func verifyUserSession(id, username string) error {
+	if id == "" {
+		return errors.New("token does not belong to a session")
+	}
+	session, err := GetUserSession(id)
+	if err != nil {
+		return errors.New("session has ended")
+	}
+	if session.UserName != username {
+		return errors.New("session belongs to another user")
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return errors.New("session expired")
+	}
+	return nil
+}
+
+// issueUserSessionTokens - rotates the refresh token of a session and signs a new access token for it
+func issueUserSessionTokens(user *models.User, session *models.UserSession) (models.SuccessfulUserLoginResponse, error) {
+	var response models.SuccessfulUserLoginResponse
+	secret := RandomString(40)
+	if secret == "" {
+		return response, errors.New("failed to generate refresh token")
+	}
+	now := time.Now()
+	authToken, err := CreateProUserJWT(user.UserName, session.ID, user.Networks, user.Groups, user.IsAdmin)
+	if err != nil {
+		return response, err
+	}
+	session.PreviousRefreshHash = session.RefreshHash
+	session.RefreshHash = hashAccessTokenSecret(secret)
+	session.RefreshedAt = now
+	// refreshing keeps a session alive, but never past its maximum lifetime
+	session.ExpiresAt = now.Add(servercfg.GetUserSessionValidity())
+	if end := session.CreatedAt.Add(servercfg.GetUserSessionLifetime()); session.ExpiresAt.After(end) {
+		session.ExpiresAt = end
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return response, err
+	}
+	if err := database.Insert(session.ID, string(data), database.USER_SESSIONS_TABLE_NAME); err != nil {
+		return response, err
+	}
+	return models.SuccessfulUserLoginResponse{
+		UserName:     user.UserName,
+		AuthToken:    authToken,
+		RefreshToken: REFRESH_TOKEN_PREFIX + session.ID + "." + secret,
+		SessionID:    session.ID,
+		ExpiresAt:    now.Add(servercfg.GetUserTokenValidity()),
+	}, nil
+}
@@ -0,0 +1,155 @@
+package logic
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestUserSessions(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	SetJWTSecret()
+	user := models.User{UserName: "sessionuser", IsAdmin: true, Networks: []string{}}
+	data, _ := json.Marshal(&user)
+	database.Insert(user.UserName, string(data), database.USERS_TABLE_NAME)
+	defer database.DeleteRecord(database.USERS_TABLE_NAME, user.UserName)
+	defer DeleteUserSessions(user.UserName)
+
+	t.Run("login", func(t *testing.T) {
+		is := is.New(t)
+		login, err := CreateUserSession(&user, "10.0.0.1", "test")
+		is.NoErr(err)
+		username, _, isadmin, err := VerifyUserToken(login.AuthToken)
+		is.NoErr(err)
+		is.Equal(username, user.UserName)
+		is.True(isadmin)
+		sessions, err := GetUserSessions(user.UserName)
+		is.NoErr(err)
+		is.Equal(len(sessions), 1)
+		is.Equal(sessions[0].ID, login.SessionID)
+		is.Equal(sessions[0].SourceIP, "10.0.0.1")
+		is.Equal(sessions[0].RefreshHash, "")
+	})
+	t.Run("refresh rotates", func(t *testing.T) {
+		is := is.New(t)
+		login, err := CreateUserSession(&user, "", "")
+		is.NoErr(err)
+		refreshed, err := RefreshUserSession(login.RefreshToken)
+		is.NoErr(err)
+		is.Equal(refreshed.SessionID, login.SessionID)
+		is.True(refreshed.RefreshToken != login.RefreshToken)
+		_, _, _, err = VerifyUserToken(refreshed.AuthToken)
+		is.NoErr(err)
+		// the old refresh token is spent and reusing it ends the session
+		_, err = RefreshUserSession(login.RefreshToken)
+		is.True(err != nil)
+		_, err = RefreshUserSession(refreshed.RefreshToken)
+		is.True(err != nil)
+		_, _, _, err = VerifyUserToken(refreshed.AuthToken)
+		is.True(err != nil)
+	})
+	t.Run("invalid refresh tokens", func(t *testing.T) {
+		is := is.New(t)
+		for _, token := range []string{"", "bogus", REFRESH_TOKEN_PREFIX + "missing.secret", REFRESH_TOKEN_PREFIX + "nosecret"} {
+			_, err := RefreshUserSession(token)
+			is.True(err != nil)
+		}
+	})
+	t.Run("ending sessions revokes tokens", func(t *testing.T) {
+		is := is.New(t)
+		first, err := CreateUserSession(&user, "", "")
+		is.NoErr(err)
+		second, err := CreateUserSession(&user, "", "")
+		is.NoErr(err)
+		is.NoErr(DeleteUserSession(first.SessionID))
+		_, _, _, err = VerifyUserToken(first.AuthToken)
+		is.True(err != nil)
+		_, _, _, err = VerifyUserToken(second.AuthToken)
+		is.NoErr(err)
+		is.NoErr(DeleteUserSessions(user.UserName))
+		_, _, _, err = VerifyUserToken(second.AuthToken)
+		is.True(err != nil)
+		_, err = RefreshUserSession(second.RefreshToken)
+		is.True(err != nil)
+	})
+	t.Run("tokens without session", func(t *testing.T) {
+		is := is.New(t)
+		token, err := CreateUserJWT(user.UserName, "", user.Networks, true)
+		is.NoErr(err)
+		_, _, _, err = VerifyUserToken(token)
+		is.True(err != nil)
+	})
+	t.Run("demoted admin", func(t *testing.T) {
+		is := is.New(t)
+		login, err := CreateUserSession(&user, "", "")
+		is.NoErr(err)
+		demoted := user
+		demoted.IsAdmin = false
+		data, _ := json.Marshal(&demoted)
+		is.NoErr(database.Insert(demoted.UserName, string(data), database.USERS_TABLE_NAME))
+		_, _, _, err = VerifyUserToken(login.AuthToken)
+		is.True(err != nil)
+	})
+	t.Run("login code", func(t *testing.T) {
+		is := is.New(t)
+		login, err := CreateUserSession(&user, "", "")
+		is.NoErr(err)
+		code, err := CreateUserLoginCode(login.SessionID)
+		is.NoErr(err)
+		exchanged, err := ExchangeUserLoginCode(code)
+		is.NoErr(err)
+		is.Equal(exchanged.SessionID, login.SessionID)
+		is.Equal(exchanged.UserName, user.UserName)
+		_, _, _, err = VerifyUserToken(exchanged.AuthToken)
+		is.NoErr(err)
+		_, err = RefreshUserSession(exchanged.RefreshToken)
+		is.NoErr(err)
+		// a code can only be used once
+		_, err = ExchangeUserLoginCode(code)
+		is.True(err != nil)
+		_, err = ExchangeUserLoginCode("")
+		is.True(err != nil)
+	})
+	t.Run("expired sessions are pruned, not listed", func(t *testing.T) {
+		is := is.New(t)
+		login, err := CreateUserSession(&user, "", "")
+		is.NoErr(err)
+		session, err := GetUserSession(login.SessionID)
+		is.NoErr(err)
+		session.ExpiresAt = time.Now().Add(-time.Minute)
+		data, _ := json.Marshal(&session)
+		is.NoErr(database.Insert(session.ID, string(data), database.USER_SESSIONS_TABLE_NAME))
+		sessions, err := GetUserSessions(user.UserName)
+		is.NoErr(err)
+		for _, listed := range sessions {
+			is.True(listed.ID != session.ID)
+		}
+		_, err = GetUserSession(session.ID)
+		is.NoErr(err)
+		is.NoErr(pruneUserSessions())
+		_, err = GetUserSession(session.ID)
+		is.True(err != nil)
+	})
+	t.Run("maximum lifetime", func(t *testing.T) {
+		is := is.New(t)
+		t.Setenv("USER_SESSION_VALIDITY", "168")
+		t.Setenv("USER_SESSION_LIFETIME", "720")
+		login, err := CreateUserSession(&user, "", "")
+		is.NoErr(err)
+		session, err := GetUserSession(login.SessionID)
+		is.NoErr(err)
+		session.CreatedAt = time.Now().Add(-time.Hour * 719)
+		data, _ := json.Marshal(&session)
+		is.NoErr(database.Insert(session.ID, string(data), database.USER_SESSIONS_TABLE_NAME))
+		refreshed, err := RefreshUserSession(login.RefreshToken)
+		is.NoErr(err)
+		session, err = GetUserSession(refreshed.SessionID)
+		is.NoErr(err)
+		is.True(session.ExpiresAt.Before(time.Now().Add(time.Hour)))
+	})
+}
@@ -35,10 +35,10 @@ func GetGroupUsers(group string) ([]models.ReturnUser, error) {
 	}
 	for _, user := range users {
 		if StringSliceContains(user.Groups, group) {
-			users = append(users, user)
+			returnUsers = append(returnUsers, user)
 		}
 	}
-	return users, err
+	return returnUsers, err
 }
 
 // == PRO ==
@@ -127,6 +127,8 @@ func ManageZombies(ctx context.Context, peerUpdate chan *models.Node) {
 				}
 			}
 		}
+		zombieNodes.Set(float64(len(zombies)))
+		zombieHosts.Set(float64(len(hostZombies)))
 	}
 }
 
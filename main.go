@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"strings"
 	"sync"
 	"syscall"
 
@@ -26,6 +27,7 @@ import (
 	"github.com/gravitl/netmaker/servercfg"
 	"github.com/gravitl/netmaker/serverctl"
 	stunserver "github.com/gravitl/netmaker/stun-server"
+	turnserver "github.com/gravitl/netmaker/turn-server"
 )
 
 var version = "v0.20.1"
@@ -90,17 +92,25 @@ func initialize() { // Client Mode Prereq Check
 
 	logic.EnterpriseCheck()
 
-	var authProvider = auth.InitializeAuthProvider()
-	if authProvider != "" {
-		logger.Log(0, "OAuth provider,", authProvider+",", "initialized")
+	var authProviders = auth.InitializeAuthProviders()
+	if len(authProviders) > 0 {
+		logger.Log(0, "OAuth providers,", strings.Join(authProviders, ", ")+",", "initialized")
 	} else {
 		logger.Log(0, "no OAuth provider found or not configured, continuing without OAuth")
 	}
+	if servercfg.IsLDAPEnabled() {
+		if err := logic.CheckLDAPConnection(); err != nil {
+			logger.Log(0, "failed to reach LDAP server", servercfg.GetLDAPURL()+",", "LDAP logins fail until it is reachable:", err.Error())
+		} else {
+			logger.Log(0, "LDAP provider,", servercfg.GetLDAPURL()+",", "initialized")
+		}
+	}
 
 	err = serverctl.SetDefaults()
 	if err != nil {
 		logger.FatalLog("error setting defaults: ", err.Error())
 	}
+	logic.InitEgressHA()
 
 	if servercfg.IsDNSMode() {
 		err := functions.SetDNSDir()
@@ -148,6 +158,11 @@ func startControllers(wg *sync.WaitGroup, ctx context.Context) {
 	// starts the stun server
 	wg.Add(1)
 	go stunserver.Start(wg, ctx)
+	// starts the embedded turn server
+	if servercfg.IsUsingEmbeddedTurn() {
+		wg.Add(1)
+		go turnserver.Start(wg, ctx)
+	}
 }
 
 // Should we be using a context vice a waitgroup????????????
@@ -163,6 +178,8 @@ func runMessageQueue(wg *sync.WaitGroup, ctx context.Context) {
 	}
 	defer mq.CloseClient()
 	go mq.Keepalive(ctx)
+	go logic.ManageMetricsHistory(ctx)
+	go logic.ManageAlerts(ctx)
 	go func() {
 		peerUpdate := make(chan *models.Node)
 		go logic.ManageZombies(ctx, peerUpdate)
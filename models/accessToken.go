@@ -1,13 +1,38 @@
 package models
 
-// AccessToken - token used to access netmaker
+import "time"
+
+// AccessTokenScope - limits what an access token can be used for, empty lists place no limit
+type AccessTokenScope struct {
+	// Networks - networks the token can act on, it loses admin rights when set
+	Networks []string `json:"networks"`
+	// ReadOnly - the token can only be used for GET requests
+	ReadOnly bool `json:"read_only"`
+	// Resources - kinds of resources the token can access, e.g. node or dns
+	Resources []string `json:"resources"`
+}
+
+// AccessToken - named api token of a user, only a hash of the secret is stored
 type AccessToken struct {
-	APIConnString string `json:"apiconnstring"`
-	ClientConfig
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	UserName   string           `json:"user_name"`
+	Scope      AccessTokenScope `json:"scope"`
+	CreatedAt  time.Time        `json:"created_at"`
+	ExpiresAt  time.Time        `json:"expires_at"`
+	LastUsedAt time.Time        `json:"last_used_at"`
+	SecretHash string           `json:"secret_hash,omitempty"`
+}
+
+// AccessTokenRequest - request to create an access token, Expiration is a unix timestamp, 0 never expires
+type AccessTokenRequest struct {
+	Name       string           `json:"name"`
+	Scope      AccessTokenScope `json:"scope"`
+	Expiration int64            `json:"expiration"`
 }
 
-// ClientConfig - the config of the client
-type ClientConfig struct {
-	Network string `json:"network"`
-	Key     string `json:"key"`
+// AccessTokenResponse - a created access token with its value, which is only returned once
+type AccessTokenResponse struct {
+	AccessToken
+	Token string `json:"token"`
 }
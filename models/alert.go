@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// AlertRuleType - condition an alert rule checks
+type AlertRuleType string
+
+const (
+	// NodeCheckInRule - a node has not checked in for Threshold minutes
+	NodeCheckInRule AlertRuleType = "node_checkin"
+	// PeerConnectivityRule - a peer pair was connected less than Threshold percent over the last Window minutes
+	PeerConnectivityRule AlertRuleType = "peer_connectivity"
+	// EgressDownRule - an egress gateway is disconnected or has not checked in for Threshold minutes
+	EgressDownRule AlertRuleType = "egress_down"
+	// EnrollmentKeyExpiryRule - an enrollment key expires within Threshold hours
+	EnrollmentKeyExpiryRule AlertRuleType = "enrollment_key_expiry"
+)
+
+// AlertTargetType - how notifications reach a target
+type AlertTargetType string
+
+const (
+	// WebhookTarget - the alert is posted as json
+	WebhookTarget AlertTargetType = "webhook"
+	// SlackTarget - the alert is posted as a Slack compatible message
+	SlackTarget AlertTargetType = "slack"
+	// EmailTarget - the alert is mailed through the configured SMTP server
+	EmailTarget AlertTargetType = "email"
+)
+
+// AlertState - state of an alert
+type AlertState string
+
+const (
+	// AlertFiring - the condition of the rule holds
+	AlertFiring AlertState = "firing"
+	// AlertResolved - the condition of the rule no longer holds
+	AlertResolved AlertState = "resolved"
+)
+
+// AlertRule - condition on the health of the mesh notifications are sent for
+type AlertRule struct {
+	ID      string        `json:"id"`
+	Name    string        `json:"name"`
+	Type    AlertRuleType `json:"type"`
+	Enabled bool          `json:"enabled"`
+	// Network - limits the rule to one network, all networks if empty
+	Network string `json:"network"`
+	// Threshold - minutes, percent or hours depending on the type of the rule
+	Threshold float64 `json:"threshold"`
+	// Window - minutes the connectivity of peers is averaged over
+	Window int `json:"window"`
+	// RepeatInterval - minutes after which a firing alert is notified again, 0 notifies once
+	RepeatInterval int      `json:"repeat_interval"`
+	Targets        []string `json:"targets"`
+}
+
+// AlertTarget - destination of alert notifications
+type AlertTarget struct {
+	ID     string          `json:"id"`
+	Name   string          `json:"name"`
+	Type   AlertTargetType `json:"type"`
+	URL    string          `json:"url,omitempty"`
+	Emails []string        `json:"emails,omitempty"`
+}
+
+// Alert - an occurrence of a rule firing for a subject, e.g. a node or a pair of peers
+type Alert struct {
+	ID           string        `json:"id"`
+	RuleID       string        `json:"rule_id"`
+	RuleName     string        `json:"rule_name"`
+	Type         AlertRuleType `json:"type"`
+	Subject      string        `json:"subject"`
+	Network      string        `json:"network"`
+	Message      string        `json:"message"`
+	State        AlertState    `json:"state"`
+	FiredAt      time.Time     `json:"fired_at"`
+	ResolvedAt   time.Time     `json:"resolved_at,omitempty"`
+	LastNotified time.Time     `json:"last_notified"`
+}
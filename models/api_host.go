@@ -116,6 +116,7 @@ func (a *ApiHost) ConvertAPIHostToNMHost(currentHost *Host) *Host {
 	h.IsDefault = a.IsDefault
 	h.NatType = currentHost.NatType
 	h.TurnEndpoint = currentHost.TurnEndpoint
+	h.NatBehaviour = currentHost.NatBehaviour
 
 	return &h
 }
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEvent - record of a change made through the api
+type AuditEvent struct {
+	ID        string       `json:"id"`
+	Timestamp time.Time    `json:"timestamp"`
+	Actor     string       `json:"actor"`
+	Action    RbacAction   `json:"action"`
+	Resource  RbacResource `json:"resource"`
+	// ResourceID - id of the changed object, e.g. the node id or the username
+	ResourceID string `json:"resource_id"`
+	Network    string `json:"network,omitempty"`
+	SourceIP   string `json:"source_ip"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	// Status - http status the request was answered with
+	Status int             `json:"status"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+	// Diff - top level fields an update changed
+	Diff map[string]AuditChange `json:"diff,omitempty"`
+}
+
+// AuditChange - value of a field before and after a change
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditFilter - narrows a query of the audit log, zero values match everything
+type AuditFilter struct {
+	Actor    string
+	Action   RbacAction
+	Resource RbacResource
+	Network  string
+	Since    time.Time
+	Until    time.Time
+	// Limit - maximum number of most recent events returned
+	Limit int
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"net/netip"
+	"time"
+)
+
+// CandidateType - origin of an endpoint candidate of a host
+type CandidateType string
+
+const (
+	// LocalCandidate - address of a local interface of the host
+	LocalCandidate CandidateType = "local"
+	// IPv6Candidate - globally routable ipv6 address of a local interface of the host
+	IPv6Candidate CandidateType = "ipv6"
+	// PublicCandidate - public endpoint the server sees the host on
+	PublicCandidate CandidateType = "public"
+	// StunCandidate - address mapped by the stun server during NAT behaviour discovery
+	StunCandidate CandidateType = "stun"
+	// TurnCandidate - relayed address allocated on a turn server
+	TurnCandidate CandidateType = "turn"
+)
+
+// PunchStatus - state of a hole punch session
+type PunchStatus string
+
+const (
+	// PunchPending - hosts have been signalled and are punching
+	PunchPending PunchStatus = "pending"
+	// PunchSucceeded - a direct path between the hosts was found
+	PunchSucceeded PunchStatus = "succeeded"
+	// PunchFailed - no direct path was found, the hosts fall back to turn or relays
+	PunchFailed PunchStatus = "failed"
+)
+
+// EndpointCandidate - an endpoint a host may be reachable on
+type EndpointCandidate struct {
+	Type     CandidateType  `json:"type"`
+	Endpoint netip.AddrPort `json:"endpoint"`
+	Priority int            `json:"priority"`
+}
+
+// PunchSession - a coordinated hole punch between two hosts,
+// both hosts start sending to each others candidates at PunchAt
+type PunchSession struct {
+	ID          string              `json:"id"`
+	HostA       string              `json:"host_a"`
+	HostB       string              `json:"host_b"`
+	CandidatesA []EndpointCandidate `json:"candidates_a"`
+	CandidatesB []EndpointCandidate `json:"candidates_b"`
+	PunchAt     time.Time           `json:"punch_at"`
+	Interval    time.Duration       `json:"interval"`
+	Attempts    int                 `json:"attempts"`
+	ExpiresAt   time.Time           `json:"expires_at"`
+	Status      PunchStatus         `json:"status"`
+}
+
+// PunchResult - outcome of a hole punch reported by one of the hosts
+type PunchResult struct {
+	SessionID     string         `json:"session_id"`
+	HostID        string         `json:"host_id"`
+	Success       bool           `json:"success"`
+	Local         netip.AddrPort `json:"local"`
+	Remote        netip.AddrPort `json:"remote"`
+	CandidateType CandidateType  `json:"candidate_type"`
+	Latency       time.Duration  `json:"latency"`
+}
+
+// PeerPath - the path last found between two hosts
+type PeerPath struct {
+	HostA         string         `json:"host_a"`
+	HostB         string         `json:"host_b"`
+	Direct        bool           `json:"direct"`
+	EndpointA     netip.AddrPort `json:"endpoint_a"`
+	EndpointB     netip.AddrPort `json:"endpoint_b"`
+	CandidateType CandidateType  `json:"candidate_type"`
+	Latency       time.Duration  `json:"latency"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// PunchRequest - request of a host to punch a direct path to a peer host
+type PunchRequest struct {
+	PeerHostID string `json:"peer_host_id"`
+}
+
+// PeerEndpoint - the endpoint a host uses to reach a peer host
+type PeerEndpoint struct {
+	HostID        string         `json:"host_id"`
+	PeerHostID    string         `json:"peer_host_id"`
+	Endpoint      netip.AddrPort `json:"endpoint"`
+	CandidateType CandidateType  `json:"candidate_type"`
+	Pinned        bool           `json:"pinned"`
+	// Latencies - last latency in ms measured over each endpoint used so far
+	Latencies map[string]int64 `json:"latencies,omitempty"`
+	// Failed - endpoints the metrics reported as disconnected while in use and when
+	Failed    map[string]time.Time `json:"failed,omitempty"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// PeerEndpointPin - request to pin the endpoint a host uses to reach a peer host
+type PeerEndpointPin struct {
+	Endpoint string `json:"endpoint"`
+}
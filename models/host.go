@@ -3,6 +3,7 @@ package models
 import (
 	"net"
 	"net/netip"
+	"time"
 
 	"github.com/google/uuid"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
@@ -36,6 +37,25 @@ var NAT_Types = struct {
 	Double:     "double",
 }
 
+// NAT_Behaviours - RFC 5780 mapping and filtering behaviours of a NAT
+var NAT_Behaviours = struct {
+	EndpointIndependent     string
+	AddressDependent        string
+	AddressAndPortDependent string
+}{
+	EndpointIndependent:     "endpoint-independent",
+	AddressDependent:        "address-dependent",
+	AddressAndPortDependent: "address-and-port-dependent",
+}
+
+// NatBehaviour - results of the RFC 5780 behaviour discovery a host ran against the stun server
+type NatBehaviour struct {
+	LocalAddr  netip.AddrPort `json:"local_addr" yaml:"local_addr"`
+	MappedAddr netip.AddrPort `json:"mapped_addr" yaml:"mapped_addr"`
+	Mapping    string         `json:"mapping" yaml:"mapping"`
+	Filtering  string         `json:"filtering" yaml:"filtering"`
+}
+
 // WIREGUARD_INTERFACE name of wireguard interface
 const WIREGUARD_INTERFACE = "netmaker"
 
@@ -78,6 +98,7 @@ type Host struct {
 	IsDefault          bool             `json:"isdefault" yaml:"isdefault"`
 	NatType            string           `json:"nat_type,omitempty" yaml:"nat_type,omitempty"`
 	TurnEndpoint       *netip.AddrPort  `json:"turn_endpoint,omitempty" yaml:"turn_endpoint,omitempty"`
+	NatBehaviour       *NatBehaviour    `json:"nat_behaviour,omitempty" yaml:"nat_behaviour,omitempty"`
 }
 
 // FormatBool converts a boolean to a [yes|no] string
@@ -120,6 +141,8 @@ const (
 	RegisterWithTurn = "REGISTER_WITH_TURN"
 	// UpdateKeys - update wireguard private/public keys
 	UpdateKeys = "UPDATE_KEYS"
+	// ReportPunch - host reports the outcome of a hole punch session
+	ReportPunch = "REPORT_PUNCH"
 )
 
 // SignalAction - turn peer signal action
@@ -130,6 +153,8 @@ const (
 	Disconnect SignalAction = "DISCONNECT"
 	// ConnNegotiation - action to negotiate connection between peers
 	ConnNegotiation SignalAction = "CONNECTION_NEGOTIATION"
+	// HolePunch - action to punch a direct path to the peer following the punch schedule
+	HolePunch SignalAction = "HOLE_PUNCH"
 )
 
 // HostUpdate - struct for host update
@@ -146,14 +171,33 @@ type HostTurnRegister struct {
 	HostPassHash string `json:"host_pass_hash"`
 }
 
+// TurnStats - allocation stats of the embedded turn server
+type TurnStats struct {
+	Running        bool                     `json:"running"`
+	Allocations    int                      `json:"allocations"`
+	HostQuota      int                      `json:"host_quota"`
+	MaxAllocations int                      `json:"max_allocations"`
+	Hosts          map[string]TurnHostStats `json:"hosts"`
+}
+
+// TurnHostStats - allocation stats of a single host on the embedded turn server
+type TurnHostStats struct {
+	Allocations    int       `json:"allocations"`
+	BytesSent      int64     `json:"bytes_sent"`
+	BytesReceived  int64     `json:"bytes_received"`
+	LastAllocation time.Time `json:"last_allocation"`
+}
+
 // Signal - struct for signalling peer
 type Signal struct {
-	Server            string       `json:"server"`
-	FromHostPubKey    string       `json:"from_host_pubkey"`
-	TurnRelayEndpoint string       `json:"turn_relay_addr"`
-	ToHostPubKey      string       `json:"to_host_pubkey"`
-	Reply             bool         `json:"reply"`
-	Action            SignalAction `json:"action"`
+	Server            string        `json:"server"`
+	FromHostPubKey    string        `json:"from_host_pubkey"`
+	TurnRelayEndpoint string        `json:"turn_relay_addr"`
+	ToHostPubKey      string        `json:"to_host_pubkey"`
+	Reply             bool          `json:"reply"`
+	Action            SignalAction  `json:"action"`
+	Punch             *PunchSession `json:"punch,omitempty"`
+	PunchResult       *PunchResult  `json:"punch_result,omitempty"`
 }
 
 // RegisterMsg - login message struct for hosts to join via SSO login
@@ -162,5 +206,7 @@ type RegisterMsg struct {
 	Network      string `json:"network,omitempty"`
 	User         string `json:"user,omitempty"`
 	Password     string `json:"password,omitempty"`
+	TOTP         string `json:"totp,omitempty"`
 	JoinAll      bool   `json:"join_all,omitempty"`
+	Provider     string `json:"provider,omitempty"`
 }
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// MetricsResolution - resolution metrics history is rolled up to
+type MetricsResolution string
+
+const (
+	// MetricsMinute - one point per minute
+	MetricsMinute MetricsResolution = "1m"
+	// MetricsHour - one point per hour
+	MetricsHour MetricsResolution = "1h"
+	// MetricsDay - one point per day
+	MetricsDay MetricsResolution = "1d"
+)
+
+// PeerMetricsPoint - metrics of a peer aggregated over the step of a point
+type PeerMetricsPoint struct {
+	NodeName string `json:"node_name"`
+	// Samples - number of reports aggregated into the point
+	Samples int `json:"samples"`
+	// ConnectedSamples - number of reports the peer was connected in
+	ConnectedSamples int `json:"connected_samples"`
+	// Latency - average latency in ms of the reports the peer was connected in
+	Latency    float64 `json:"latency"`
+	LatencyMax int64   `json:"latency_max"`
+	// Received - bytes received from the peer during the step
+	Received int64 `json:"received"`
+	// Sent - bytes sent to the peer during the step
+	Sent int64 `json:"sent"`
+}
+
+// MetricsPoint - metrics of a node to its peers at the start of a step
+type MetricsPoint struct {
+	Timestamp time.Time                   `json:"timestamp"`
+	Peers     map[string]PeerMetricsPoint `json:"peers"`
+}
+
+// MetricsHistoryChunk - stored block of consecutive points of a node at one resolution
+type MetricsHistoryChunk struct {
+	NodeID     string            `json:"node_id"`
+	Network    string            `json:"network"`
+	Resolution MetricsResolution `json:"resolution"`
+	Start      time.Time         `json:"start"`
+	Points     []MetricsPoint    `json:"points"`
+}
+
+// MetricsHistory - response to a metrics range query
+type MetricsHistory struct {
+	NodeID     string            `json:"node_id"`
+	Network    string            `json:"network"`
+	Resolution MetricsResolution `json:"resolution"`
+	Step       time.Duration     `json:"step"`
+	From       time.Time         `json:"from"`
+	To         time.Time         `json:"to"`
+	Points     []MetricsPoint    `json:"points"`
+}
+
+// Merge - adds the aggregate of another point of the same peer
+func (p *PeerMetricsPoint) Merge(other PeerMetricsPoint) {
+	if other.NodeName != "" {
+		p.NodeName = other.NodeName
+	}
+	if connected := p.ConnectedSamples + other.ConnectedSamples; connected > 0 {
+		p.Latency = (p.Latency*float64(p.ConnectedSamples) + other.Latency*float64(other.ConnectedSamples)) / float64(connected)
+	}
+	if other.LatencyMax > p.LatencyMax {
+		p.LatencyMax = other.LatencyMax
+	}
+	p.Samples += other.Samples
+	p.ConnectedSamples += other.ConnectedSamples
+	p.Received += other.Received
+	p.Sent += other.Sent
+}
@@ -20,6 +20,8 @@ type HostPeerUpdate struct {
 	IngressInfo     IngressInfo           `json:"ingress_info" bson:"ext_peers" yaml:"ext_peers"`
 	PeerIDs         PeerMap               `json:"peerids" bson:"peerids" yaml:"peerids"`
 	HostNetworkInfo HostInfoMap           `json:"host_network_info,omitempty" bson:"host_network_info,omitempty" yaml:"host_network_info,omitempty"`
+	// EgressRouteMetrics - metric of the routes to egress ranges which have one configured, keyed by the routed range
+	EgressRouteMetrics map[string]uint32 `json:"egress_route_metrics,omitempty" bson:"egress_route_metrics,omitempty" yaml:"egress_route_metrics,omitempty"`
 }
 
 // IngressInfo - struct for ingress info
@@ -35,6 +37,8 @@ type EgressInfo struct {
 	EgressGwAddr net.IPNet                `json:"egress_gw_addr" yaml:"egress_gw_addr"`
 	GwPeers      map[string]PeerRouteInfo `json:"gateway_peers" yaml:"gateway_peers"`
 	EgressGWCfg  EgressGatewayRequest     `json:"egress_gateway_cfg" yaml:"egress_gateway_cfg"`
+	// RangeMappings - virtual range -> real range, to be programmed as 1:1 NAT (NETMAP) on the gateway
+	RangeMappings map[string]string `json:"range_mappings" yaml:"range_mappings"`
 }
 
 // PeerRouteInfo - struct for peer info for an ext. client
@@ -33,6 +33,12 @@ const (
 	FIREWALL_NFTABLES = "nftables"
 	// FIREWALL_NONE - indicates that no supported firewall in use
 	FIREWALL_NONE = "none"
+	// IPV4_DEFAULT_ROUTE - ipv4 route to the internet
+	IPV4_DEFAULT_ROUTE = "0.0.0.0/0"
+	// IPV6_DEFAULT_ROUTE - ipv6 route to the internet
+	IPV6_DEFAULT_ROUTE = "::/0"
+	// DEFAULT_INTERNET_GW_DNS - dns server handed to ext clients using an internet gateway when none is configured
+	DEFAULT_INTERNET_GW_DNS = "1.1.1.1"
 )
 
 var seededRand *rand.Rand = rand.New(
@@ -89,6 +95,7 @@ type Node struct {
 	IsRelayed               bool                 `json:"isrelayed" bson:"isrelayed" yaml:"isrelayed"`
 	IsRelay                 bool                 `json:"isrelay" bson:"isrelay" yaml:"isrelay"`
 	RelayAddrs              []string             `json:"relayaddrs" bson:"relayaddrs" yaml:"relayaddrs"`
+	Tags                    []string             `json:"tags" bson:"tags" yaml:"tags"`
 	// == PRO ==
 	DefaultACL   string    `json:"defaultacl,omitempty" bson:"defaultacl,omitempty" yaml:"defaultacl,omitempty" validate:"checkyesornoorunset"`
 	OwnerID      string    `json:"ownerid,omitempty" bson:"ownerid,omitempty" yaml:"ownerid,omitempty"`
@@ -196,6 +203,19 @@ func (node *Node) PrimaryNetworkRange() net.IPNet {
 	return node.NetworkRange6
 }
 
+// Node.IsInternetGateway - checks if the node is an egress gateway for the default routes
+func (node *Node) IsInternetGateway() bool {
+	if !node.IsEgressGateway {
+		return false
+	}
+	for _, egressRange := range node.EgressGatewayRanges {
+		if egressRange == IPV4_DEFAULT_ROUTE || egressRange == IPV6_DEFAULT_ROUTE {
+			return true
+		}
+	}
+	return false
+}
+
 // Node.SetDefaultConnected
 func (node *Node) SetDefaultConnected() {
 	node.Connected = true
@@ -380,6 +400,9 @@ func (newNode *Node) Fill(currentNode *Node) { // TODO add new field for nftable
 	if newNode.Network == "" {
 		newNode.Network = currentNode.Network
 	}
+	if newNode.Tags == nil {
+		newNode.Tags = currentNode.Tags
+	}
 	if newNode.IsEgressGateway != currentNode.IsEgressGateway {
 		newNode.IsEgressGateway = currentNode.IsEgressGateway
 	}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// LoginAttempts - failed logins of a user or from a source ip, too many of them lock it out
+type LoginAttempts struct {
+	Key         string    `json:"key"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// PasswordHistory - hashes of the previous passwords of a user
+type PasswordHistory struct {
+	UserName string   `json:"username"`
+	Hashes   []string `json:"hashes"`
+}
+
+// PasswordReset - outstanding one-time token a user can set a new password with, only its hash is stored
+type PasswordReset struct {
+	UserName  string    `json:"username"`
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PasswordResetToken - one-time token handed to the admin who initiated a password reset
+type PasswordResetToken struct {
+	UserName  string    `json:"username"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PasswordResetRequest - new password of a user, authorized by a one-time reset token
+type PasswordResetRequest struct {
+	UserName string `json:"username"`
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
@@ -0,0 +1,81 @@
+package models
+
+// RbacResource - kind of object a permission applies to
+type RbacResource string
+
+const (
+	// AllResources - wildcard matching every resource
+	AllResources RbacResource = "*"
+	// NetworkResource - networks
+	NetworkResource RbacResource = "network"
+	// NodeResource - nodes of networks, including their gateways and relays
+	NodeResource RbacResource = "node"
+	// HostResource - hosts, their keys, relays and peer paths
+	HostResource RbacResource = "host"
+	// ExtClientResource - ext clients of ingress gateways
+	ExtClientResource RbacResource = "ext_client"
+	// DNSResource - dns entries
+	DNSResource RbacResource = "dns"
+	// EnrollmentKeyResource - enrollment keys
+	EnrollmentKeyResource RbacResource = "enrollment_key"
+	// AclResource - network acls
+	AclResource RbacResource = "acl"
+	// UserResource - users, user groups, network users and roles
+	UserResource RbacResource = "user"
+	// ServerResource - server wide settings, logs, metrics and alerts
+	ServerResource RbacResource = "server"
+	// SelfResource - objects of the caller itself, e.g. its access tokens, every authenticated user holds it
+	SelfResource RbacResource = "self"
+)
+
+// RbacAction - operation on a resource
+type RbacAction string
+
+const (
+	// AllActions - wildcard matching every action
+	AllActions RbacAction = "*"
+	// ReadAction - fetching a resource
+	ReadAction RbacAction = "read"
+	// CreateAction - creating a resource
+	CreateAction RbacAction = "create"
+	// UpdateAction - changing a resource
+	UpdateAction RbacAction = "update"
+	// DeleteAction - deleting a resource
+	DeleteAction RbacAction = "delete"
+)
+
+const (
+	// AdminRole - built-in role holding every permission
+	AdminRole = "admin"
+	// NetworkAdminRole - built-in role managing everything within a network
+	NetworkAdminRole = "network-admin"
+	// NodeAccessRole - built-in role viewing the nodes of a network and managing ext clients
+	NodeAccessRole = "node-access"
+	// ClientAccessRole - built-in role managing ext clients of a network
+	ClientAccessRole = "client-access"
+)
+
+// RbacPermission - action allowed on a resource
+type RbacPermission struct {
+	Resource RbacResource `json:"resource"`
+	Action   RbacAction   `json:"action"`
+}
+
+// Role - named set of permissions
+type Role struct {
+	ID          string           `json:"id"`
+	Description string           `json:"description"`
+	Permissions []RbacPermission `json:"permissions"`
+	// BuiltIn - roles shipped with the server cannot be changed or deleted
+	BuiltIn bool `json:"builtin"`
+}
+
+// RoleBinding - grants a role to a user or to the members of a user group
+type RoleBinding struct {
+	ID       string `json:"id"`
+	RoleID   string `json:"role_id"`
+	UserName string `json:"username,omitempty"`
+	Group    string `json:"group,omitempty"`
+	// Network - limits the binding to one network, all networks if empty
+	Network string `json:"network"`
+}
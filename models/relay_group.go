@@ -0,0 +1,36 @@
+package models
+
+// RelayGroup - a set of relay hosts which share relaying the member hosts of the group
+type RelayGroup struct {
+	ID         string   `json:"id" yaml:"id"`
+	Name       string   `json:"name" yaml:"name"`
+	RelayHosts []string `json:"relay_hosts" yaml:"relay_hosts"`
+	Members    []string `json:"members" yaml:"members"`
+	// RelayAll - relay every member, otherwise only members behind a symmetric NAT are relayed
+	RelayAll bool `json:"relay_all" yaml:"relay_all"`
+	// Assignments - member host id -> id of the relay host currently relaying the member
+	Assignments map[string]string `json:"assignments" yaml:"assignments"`
+}
+
+// RelayGroupRequest - struct for relay group creation and updates
+type RelayGroupRequest struct {
+	Name       string   `json:"name"`
+	RelayHosts []string `json:"relay_hosts"`
+	Members    []string `json:"members"`
+	RelayAll   bool     `json:"relay_all"`
+}
+
+// RelayGroup.HasHost - checks if a host is a relay or a member of the group
+func (g *RelayGroup) HasHost(hostID string) bool {
+	for _, id := range g.RelayHosts {
+		if id == hostID {
+			return true
+		}
+	}
+	for _, id := range g.Members {
+		if id == hostID {
+			return true
+		}
+	}
+	return false
+}
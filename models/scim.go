@@ -0,0 +1,101 @@
+package models
+
+import "encoding/json"
+
+const (
+	// SCIMUserSchema - schema of scim users
+	SCIMUserSchema = "urn:ietf:params:scim:schemas:core:2.0:User"
+	// SCIMGroupSchema - schema of scim groups
+	SCIMGroupSchema = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	// SCIMListResponseSchema - schema of scim query results
+	SCIMListResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	// SCIMPatchOpSchema - schema of scim patch requests
+	SCIMPatchOpSchema = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	// SCIMErrorSchema - schema of scim errors
+	SCIMErrorSchema = "urn:ietf:params:scim:api:messages:2.0:Error"
+	// SCIMServiceProviderConfigSchema - schema of the scim service provider config
+	SCIMServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+// SCIMUser - user as provisioned by an identity provider, its id is the netmaker username
+type SCIMUser struct {
+	Schemas    []string `json:"schemas"`
+	ID         string   `json:"id,omitempty"`
+	ExternalID string   `json:"externalId,omitempty"`
+	UserName   string   `json:"userName"`
+	// Active - users are active unless the identity provider deactivates them
+	Active *bool `json:"active,omitempty"`
+	// Password - only set when provisioning, sso users are given a random one
+	Password string       `json:"password,omitempty"`
+	Groups   []SCIMMember `json:"groups,omitempty"`
+	Meta     *SCIMMeta    `json:"meta,omitempty"`
+}
+
+// SCIMGroup - user group as provisioned by an identity provider, its id is the netmaker group name
+type SCIMGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []SCIMMember `json:"members"`
+	Meta        *SCIMMeta    `json:"meta,omitempty"`
+}
+
+// SCIMMember - reference to a user of a group or a group of a user
+type SCIMMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// SCIMMeta - resource metadata of scim objects
+type SCIMMeta struct {
+	ResourceType string `json:"resourceType"`
+}
+
+// SCIMListResponse - page of scim objects matching a query
+type SCIMListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+// SCIMPatchRequest - partial update of a scim object
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMPatchOperation - add, remove or replace of an attribute of a scim object
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// SCIMError - error response of the scim api
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// SCIMUserState - provisioning state of a user managed through scim
+type SCIMUserState struct {
+	UserName   string `json:"username"`
+	ExternalID string `json:"external_id"`
+	Active     bool   `json:"active"`
+	// NetworkAccess - access of the user to each network before it was deactivated, restored when it is activated again
+	NetworkAccess map[string]SCIMNetworkAccess `json:"network_access,omitempty"`
+	// DisabledExtClients - record keys of the ext clients disabled when the user was deactivated, enabled again when it is activated
+	DisabledExtClients []string `json:"disabled_ext_clients,omitempty"`
+}
+
+// SCIMNetworkAccess - access of a network user kept while the user is deactivated
+type SCIMNetworkAccess struct {
+	AccessLevel int `json:"accesslevel"`
+	NodeLimit   int `json:"nodelimit"`
+	ClientLimit int `json:"clientlimit"`
+}
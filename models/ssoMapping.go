@@ -0,0 +1,15 @@
+package models
+
+// SSOMapping - grants netmaker access to the sso users who are members of a group of the identity provider,
+// re-evaluated each time such a user logs in
+type SSOMapping struct {
+	ID string `json:"id"`
+	// Provider - name of the sso provider the group belongs to, "ldap" for groups of the ldap directory
+	Provider string `json:"provider"`
+	// IdPGroup - group as named by the identity provider: a value of the groups claim for oidc,
+	// a group object id or app role for azure ad, a group email for google, "org" or "org/team" for github and a group dn for ldap
+	IdPGroup   string   `json:"idp_group"`
+	UserGroups []string `json:"user_groups"`
+	Networks   []string `json:"networks"`
+	Admin      bool     `json:"admin"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// SSOUserLinks - the sso providers a user may sign in through
+type SSOUserLinks struct {
+	UserName  string   `json:"username"`
+	Providers []string `json:"providers"`
+}
+
+// SSOProvider - a configured sso provider users can sign in through
+type SSOProvider struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Default bool   `json:"default"`
+}
@@ -8,6 +8,7 @@ const DefaultExpDuration = time.Minute * 5
 // SsoState - holds SSO sign-in session data
 type SsoState struct {
 	Value      string    `json:"value"`
+	Provider   string    `json:"provider,omitempty"`
 	Expiration time.Time `json:"expiration"`
 }
 
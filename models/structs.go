@@ -2,6 +2,7 @@ package models
 
 import (
 	"strings"
+	"time"
 
 	jwt "github.com/golang-jwt/jwt/v4"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
@@ -28,6 +29,8 @@ type User struct {
 	Networks []string `json:"networks" bson:"networks"`
 	IsAdmin  bool     `json:"isadmin" bson:"isadmin"`
 	Groups   []string `json:"groups" bson:"groups" yaml:"groups"`
+	// LDAPDN - dn of the ldap entry of a user logging in with its directory password
+	LDAPDN string `json:"ldap_dn,omitempty" bson:"ldap_dn,omitempty"`
 }
 
 // ReturnUser - return user struct
@@ -42,6 +45,8 @@ type ReturnUser struct {
 type UserAuthParams struct {
 	UserName string `json:"username"`
 	Password string `json:"password"`
+	// TOTP - current code of the authenticator app or a recovery code, if the user has mfa enabled
+	TOTP string `json:"totp,omitempty"`
 }
 
 // UserClaims - user claims struct
@@ -57,6 +62,10 @@ type UserClaims struct {
 type SuccessfulUserLoginResponse struct {
 	UserName  string
 	AuthToken string
+	// RefreshToken - single use token to get a new AuthToken before it expires
+	RefreshToken string
+	SessionID    string
+	ExpiresAt    time.Time
 }
 
 // Claims is  a struct that will be encoded to a JWT.
@@ -149,6 +158,69 @@ type EgressGatewayRequest struct {
 	NetID      string   `json:"netid" bson:"netid"`
 	NatEnabled string   `json:"natenabled" bson:"natenabled"`
 	Ranges     []string `json:"ranges" bson:"ranges"`
+	// Priority - priority of the gateway for ranges without their own, among the gateways advertising
+	// the same range the healthy one with the highest priority routes it
+	Priority int `json:"priority" bson:"priority"`
+	// RangesWithConfig - per range settings, ranges without an entry use the request level settings
+	RangesWithConfig []EgressRangeConfig `json:"ranges_with_config" bson:"ranges_with_config"`
+}
+
+// EgressRangeConfig - NAT, route metric and advertise filter of a single egress range
+type EgressRangeConfig struct {
+	Range      string `json:"range" bson:"range" yaml:"range"`
+	NatEnabled string `json:"natenabled" bson:"natenabled" yaml:"natenabled"`
+	Metric     uint32 `json:"metric" bson:"metric" yaml:"metric"`
+	// Priority - priority of the gateway for this range among the gateways advertising it, 0 uses the gateway priority
+	Priority int `json:"priority" bson:"priority" yaml:"priority"`
+	// AdvertiseTo - ids of the nodes and ext clients the range is advertised to,
+	// all peers when neither ids nor tags are given
+	AdvertiseTo []string `json:"advertise_to" bson:"advertise_to" yaml:"advertise_to"`
+	// AdvertiseToTags - the range is also advertised to the nodes carrying one of these tags
+	AdvertiseToTags []string `json:"advertise_to_tags" bson:"advertise_to_tags" yaml:"advertise_to_tags"`
+	// VirtualRange - prefix of the same size peers use to reach the range, the gateway maps it 1:1 onto Range
+	VirtualRange string `json:"virtual_range" bson:"virtual_range" yaml:"virtual_range"`
+}
+
+// AdvertisedRange - returns the range peers route to the gateway, the virtual range if one is mapped
+func (rangeConfig *EgressRangeConfig) AdvertisedRange() string {
+	if rangeConfig.VirtualRange != "" {
+		return rangeConfig.VirtualRange
+	}
+	return rangeConfig.Range
+}
+
+// GetRangeConfig - returns the settings of an egress range
+func (gateway *EgressGatewayRequest) GetRangeConfig(iprange string) EgressRangeConfig {
+	for _, rangeConfig := range gateway.RangesWithConfig {
+		if rangeConfig.Range == iprange {
+			if rangeConfig.Priority == 0 {
+				rangeConfig.Priority = gateway.Priority
+			}
+			return rangeConfig
+		}
+	}
+	return EgressRangeConfig{Range: iprange, NatEnabled: gateway.NatEnabled, Priority: gateway.Priority}
+}
+
+// IsRangeAdvertisedTo - checks if an egress range should be advertised to the given node or ext client
+func (gateway *EgressGatewayRequest) IsRangeAdvertisedTo(iprange, peerID string, peerTags []string) bool {
+	rangeConfig := gateway.GetRangeConfig(iprange)
+	if len(rangeConfig.AdvertiseTo) == 0 && len(rangeConfig.AdvertiseToTags) == 0 {
+		return true
+	}
+	for _, id := range rangeConfig.AdvertiseTo {
+		if id == peerID {
+			return true
+		}
+	}
+	for _, tag := range rangeConfig.AdvertiseToTags {
+		for _, peerTag := range peerTags {
+			if tag == peerTag {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // RelayRequest - relay request struct
@@ -243,6 +315,8 @@ type ServerConfig struct {
 	TurnDomain  string       `yaml:"turn_domain"`
 	TurnPort    int          `yaml:"turn_port"`
 	UseTurn     bool         `yaml:"use_turn"`
+	// MetricsCollection - nodes report their metrics regardless of the edition
+	MetricsCollection bool `yaml:"metrics_collection"`
 }
 
 // User.NameInCharset - returns if name is in charset below or not
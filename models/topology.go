@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// TopologyVertexType - kind of a vertex of the topology graph
+type TopologyVertexType string
+
+const (
+	// NodeVertex - a node of the network
+	NodeVertex TopologyVertexType = "node"
+	// ExtClientVertex - an ext client attached to an ingress gateway
+	ExtClientVertex TopologyVertexType = "ext_client"
+	// EgressRangeVertex - a range routed through an egress gateway
+	EgressRangeVertex TopologyVertexType = "egress_range"
+)
+
+// TopologyPath - path the traffic of an edge takes
+type TopologyPath string
+
+const (
+	// DirectPath - the peers reach each other directly
+	DirectPath TopologyPath = "direct"
+	// RelayPath - the traffic is forwarded by a relay
+	RelayPath TopologyPath = "relay"
+	// TurnPath - the traffic is forwarded by a TURN server
+	TurnPath TopologyPath = "turn"
+)
+
+// TopologyVertex - a node, ext client or egress range of a network
+type TopologyVertex struct {
+	ID                string             `json:"id"`
+	Name              string             `json:"name"`
+	Type              TopologyVertexType `json:"type"`
+	HostID            string             `json:"host_id,omitempty"`
+	Address           string             `json:"address,omitempty"`
+	Address6          string             `json:"address6,omitempty"`
+	Connected         bool               `json:"connected"`
+	IsRelay           bool               `json:"is_relay"`
+	IsRelayed         bool               `json:"is_relayed"`
+	IsIngressGateway  bool               `json:"is_ingress_gateway"`
+	IsEgressGateway   bool               `json:"is_egress_gateway"`
+	IsInternetGateway bool               `json:"is_internet_gateway"`
+}
+
+// TopologyEdge - the connection from one vertex to another as seen by the first,
+// Reported is false while the first has not sent metrics for the second
+type TopologyEdge struct {
+	From      string       `json:"from"`
+	To        string       `json:"to"`
+	Reported  bool         `json:"reported"`
+	Connected bool         `json:"connected"`
+	Latency   int64        `json:"latency"`
+	PercentUp float64      `json:"percent_up"`
+	Path      TopologyPath `json:"path"`
+	// Via - the relay node forwarding the traffic of a relay path
+	Via      string `json:"via,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// NetworkTopology - graph of a network annotated with the current connectivity
+type NetworkTopology struct {
+	Network     string           `json:"network"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Vertices    []TopologyVertex `json:"nodes"`
+	Edges       []TopologyEdge   `json:"edges"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// UserMFA - totp second factor of a local user, recovery codes are only stored as hashes
+type UserMFA struct {
+	UserName string `json:"user_name"`
+	Secret   string `json:"secret"`
+	// Enabled - the user confirmed the enrollment with a code, until then it is not asked for
+	Enabled       bool      `json:"enabled"`
+	RecoveryCodes []string  `json:"recovery_codes"`
+	CreatedAt     time.Time `json:"created_at"`
+	// LastStep - time step of the last accepted code, codes cannot be used twice
+	LastStep int64 `json:"last_step"`
+}
+
+// MFAEnrollment - secret of a new totp enrollment, returned once to be added to an authenticator app
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	// URI - otpauth uri of the secret
+	URI string `json:"uri"`
+	// QRCode - png image of the uri
+	QRCode        []byte   `json:"qrcode"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAStatus - whether a user has mfa enabled
+type MFAStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+	// Enforced - the server requires mfa of all local users
+	Enforced bool `json:"enforced"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// UserSession - login of a user, kept alive by refreshing its short lived access token,
+// only hashes of the refresh tokens are stored
+type UserSession struct {
+	ID          string    `json:"id"`
+	UserName    string    `json:"user_name"`
+	CreatedAt   time.Time `json:"created_at"`
+	RefreshedAt time.Time `json:"refreshed_at"`
+	// ExpiresAt - end of the session unless it is refreshed before
+	ExpiresAt time.Time `json:"expires_at"`
+	SourceIP  string    `json:"source_ip"`
+	UserAgent string    `json:"user_agent"`
+	// RefreshHash - hash of the refresh token which can currently be used
+	RefreshHash string `json:"refresh_hash,omitempty"`
+	// PreviousRefreshHash - hash of the refresh token last rotated out, its reuse ends the session
+	PreviousRefreshHash string `json:"previous_refresh_hash,omitempty"`
+}
+
+// RefreshTokenRequest - request to exchange a refresh token for new tokens
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LoginCodeRequest - request to exchange the one time code of an sso login for the tokens of its session
+type LoginCodeRequest struct {
+	Code string `json:"code"`
+}
@@ -158,6 +158,18 @@ func UpdateHost(client mqtt.Client, msg mqtt.Message) {
 			logger.Log(0, "failed to delete all nodes of host: ", currentHost.ID.String(), err.Error())
 			return
 		}
+		relayChanged, err := logic.RemoveHostFromRelayGroups(currentHost.ID.String())
+		if err != nil {
+			logger.Log(0, "failed to remove host from relay groups: ", currentHost.ID.String(), err.Error())
+		}
+		for i := range relayChanged {
+			if err := HostUpdate(&models.HostUpdate{
+				Action: models.UpdateHost,
+				Host:   relayChanged[i],
+			}); err != nil {
+				logger.Log(0, "failed to send host update after relay change", relayChanged[i].ID.String(), err.Error())
+			}
+		}
 		if err := logic.RemoveHostByID(currentHost.ID.String()); err != nil {
 			logger.Log(0, "failed to delete host: ", currentHost.ID.String(), err.Error())
 			return
@@ -171,7 +183,8 @@ func UpdateHost(client mqtt.Client, msg mqtt.Message) {
 				return
 			}
 		}
-
+	case models.ReportPunch:
+		handlePunchResult(hostUpdate.Signal.PunchResult, currentHost)
 	}
 
 	if sendPeerUpdate {
@@ -189,61 +202,85 @@ func UpdateHost(client mqtt.Client, msg mqtt.Message) {
 
 // UpdateMetrics  message Handler -- handles updates from client nodes for metrics
 func UpdateMetrics(client mqtt.Client, msg mqtt.Message) {
-	if servercfg.Is_EE {
-		id, err := getID(msg.Topic())
-		if err != nil {
-			logger.Log(1, "error getting node.ID sent on ", msg.Topic(), err.Error())
-			return
-		}
-		currentNode, err := logic.GetNodeByID(id)
-		if err != nil {
-			logger.Log(1, "error getting node ", id, err.Error())
-			return
-		}
-		decrypted, decryptErr := decryptMsg(&currentNode, msg.Payload())
-		if decryptErr != nil {
-			logger.Log(1, "failed to decrypt message for node ", id, decryptErr.Error())
-			return
-		}
+	if !servercfg.IsMetricsCollection() {
+		return
+	}
+	id, err := getID(msg.Topic())
+	if err != nil {
+		logger.Log(1, "error getting node.ID sent on ", msg.Topic(), err.Error())
+		return
+	}
+	currentNode, err := logic.GetNodeByID(id)
+	if err != nil {
+		logger.Log(1, "error getting node ", id, err.Error())
+		return
+	}
+	decrypted, decryptErr := decryptMsg(&currentNode, msg.Payload())
+	if decryptErr != nil {
+		logger.Log(1, "failed to decrypt message for node ", id, decryptErr.Error())
+		return
+	}
 
-		var newMetrics models.Metrics
-		if err := json.Unmarshal(decrypted, &newMetrics); err != nil {
-			logger.Log(1, "error unmarshaling payload ", err.Error())
-			return
-		}
+	var newMetrics models.Metrics
+	if err := json.Unmarshal(decrypted, &newMetrics); err != nil {
+		logger.Log(1, "error unmarshaling payload ", err.Error())
+		return
+	}
 
-		shouldUpdate := updateNodeMetrics(&currentNode, &newMetrics)
+	if newMetrics.Network == "" {
+		newMetrics.Network = currentNode.Network
+	}
+	shouldUpdate := updateNodeMetrics(&currentNode, &newMetrics)
 
-		if err = logic.UpdateMetrics(id, &newMetrics); err != nil {
-			logger.Log(1, "faield to update node metrics", id, err.Error())
-			return
+	if err = logic.UpdateMetrics(id, &newMetrics); err != nil {
+		logger.Log(1, "faield to update node metrics", id, err.Error())
+		return
+	}
+	if err = logic.RecordMetricsHistory(id, currentNode.Network, &newMetrics, time.Now()); err != nil {
+		logger.Log(1, "failed to record metrics history of node", id, err.Error())
+	}
+	if endpointChanged, err := logic.UpdatePeerEndpoints(&currentNode, &newMetrics); err != nil {
+		logger.Log(1, "failed to update peer endpoints of node", id, err.Error())
+	} else if endpointChanged {
+		shouldUpdate = true
+	}
+	if servercfg.IsMetricsExporter() {
+		if err := pushMetricsToExporter(newMetrics); err != nil {
+			logger.Log(2, fmt.Sprintf("failed to push node: [%s] metrics to exporter, err: %v",
+				currentNode.ID, err))
 		}
-		if servercfg.IsMetricsExporter() {
-			if err := pushMetricsToExporter(newMetrics); err != nil {
-				logger.Log(2, fmt.Sprintf("failed to push node: [%s] metrics to exporter, err: %v",
-					currentNode.ID, err))
-			}
+	}
+
+	// failover stays an enterprise feature
+	if servercfg.Is_EE && newMetrics.Connectivity != nil {
+		err := logic.EnterpriseFailoverFunc(&currentNode)
+		if err != nil {
+			logger.Log(0, "failed to failover for node", currentNode.ID.String(), "on network", currentNode.Network, "-", err.Error())
 		}
+	}
 
-		if newMetrics.Connectivity != nil {
-			err := logic.EnterpriseFailoverFunc(&currentNode)
-			if err != nil {
-				logger.Log(0, "failed to failover for node", currentNode.ID.String(), "on network", currentNode.Network, "-", err.Error())
-			}
+	logic.RefreshEgressGatewayHealth(&currentNode)
+	if changed, err := logic.UpdateEgressHA(currentNode.Network); err != nil {
+		logger.Log(1, "failed to update egress gateway selection for network", currentNode.Network, err.Error())
+	} else if changed {
+		logger.Log(2, "updating peers after egress gateway change on network", currentNode.Network)
+		if err = PublishPeerUpdate(); err != nil {
+			logger.Log(0, "failed to publish peer update after egress gateway change", err.Error())
 		}
+		shouldUpdate = false // every host, including this one, was just updated
+	}
 
-		if shouldUpdate {
-			logger.Log(2, "updating peers after node", currentNode.ID.String(), currentNode.Network, "detected connectivity issues")
-			host, err := logic.GetHost(currentNode.HostID.String())
-			if err == nil {
-				if err = PublishSingleHostPeerUpdate(context.Background(), host, nil, nil); err != nil {
-					logger.Log(0, "failed to publish update after failover peer change for node", currentNode.ID.String(), currentNode.Network)
-				}
+	if shouldUpdate {
+		logger.Log(2, "updating peers after node", currentNode.ID.String(), currentNode.Network, "detected connectivity issues")
+		host, err := logic.GetHost(currentNode.HostID.String())
+		if err == nil {
+			if err = PublishSingleHostPeerUpdate(context.Background(), host, nil, nil); err != nil {
+				logger.Log(0, "failed to publish update after failover peer change for node", currentNode.ID.String(), currentNode.Network)
 			}
 		}
-
-		logger.Log(1, "updated node metrics", id)
 	}
+
+	logger.Log(1, "updated node metrics", id)
 }
 
 // ClientPeerUpdate  message handler -- handles updating peers after signal from client nodes
@@ -346,6 +383,9 @@ func updateNodeMetrics(currentNode *models.Node, newMetrics *models.Metrics) boo
 
 	}
 
+	if !servercfg.Is_EE {
+		return false
+	}
 	// add nodes that need failover
 	nodes, err := logic.GetNetworkNodes(currentNode.Network)
 	if err != nil {
@@ -405,6 +445,7 @@ func handleHostCheckin(h, currentHost *models.Host) bool {
 		return false
 	}
 
+	var networks []string
 	for i := range currentHost.Nodes {
 		currNodeID := currentHost.Nodes[i]
 		node, err := logic.GetNodeByID(currNodeID)
@@ -423,6 +464,18 @@ func handleHostCheckin(h, currentHost *models.Host) bool {
 		if err := logic.UpdateNodeCheckin(&node); err != nil {
 			logger.Log(0, "error updating node", node.ID.String(), " on checkin", err.Error())
 		}
+		logic.RefreshEgressGatewayHealth(&node)
+		networks = append(networks, node.Network)
+	}
+	// check-ins drive egress gateway failover, a range moving to a standby gateway requires a peer update
+	egressChanged := false
+	for _, network := range networks {
+		changed, err := logic.UpdateEgressHA(network)
+		if err != nil {
+			logger.Log(1, "failed to update egress gateway selection for network", network, err.Error())
+			continue
+		}
+		egressChanged = egressChanged || changed
 	}
 
 	for i := range h.Interfaces {
@@ -437,6 +490,12 @@ func handleHostCheckin(h, currentHost *models.Host) bool {
 			return false
 		}
 	}
+	if !logic.IsNatBehaviourObserved(h.NatBehaviour) {
+		// behaviour results whose mapped address the stun server never answered are not trusted
+		h.NatBehaviour = nil
+	} else {
+		h.NatType = logic.ClassifyNatType(h.NatBehaviour, currentHost.NatType)
+	}
 	ifaceDelta := len(h.Interfaces) != len(currentHost.Interfaces) ||
 		!h.EndpointIP.Equal(currentHost.EndpointIP) ||
 		(len(h.NatType) > 0 && h.NatType != currentHost.NatType) ||
@@ -446,13 +505,29 @@ func handleHostCheckin(h, currentHost *models.Host) bool {
 		currentHost.Interfaces = h.Interfaces
 		currentHost.DefaultInterface = h.DefaultInterface
 		currentHost.NatType = h.NatType
+		if h.NatBehaviour != nil {
+			currentHost.NatBehaviour = h.NatBehaviour
+		}
 		if err := logic.UpsertHost(currentHost); err != nil {
 			logger.Log(0, "failed to update host after check-in", h.Name, h.ID.String(), err.Error())
 			return false
 		}
 		logger.Log(1, "updated host after check-in", currentHost.Name, currentHost.ID.String())
 	}
+	// check-ins also drive relay group reassignment, members are moved away from relays which went silent
+	relayChanged, err := logic.UpdateRelayGroupsForHost(currentHost.ID.String())
+	if err != nil {
+		logger.Log(1, "failed to update relay groups for host", currentHost.Name, err.Error())
+	}
+	for i := range relayChanged {
+		if err := HostUpdate(&models.HostUpdate{
+			Action: models.UpdateHost,
+			Host:   relayChanged[i],
+		}); err != nil {
+			logger.Log(0, "failed to send host update after relay change", relayChanged[i].ID.String(), err.Error())
+		}
+	}
 
 	logger.Log(2, "check-in processed for host", h.Name, h.ID.String())
-	return ifaceDelta
+	return ifaceDelta || egressChanged || len(relayChanged) > 0
 }
@@ -0,0 +1,78 @@
+package mq
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+// testMessage - mqtt message delivered to a handler in tests
+type testMessage struct {
+	topic   string
+	payload []byte
+}
+
+func (m *testMessage) Duplicate() bool   { return false }
+func (m *testMessage) Qos() byte         { return 0 }
+func (m *testMessage) Retained() bool    { return false }
+func (m *testMessage) Topic() string     { return m.topic }
+func (m *testMessage) MessageID() uint16 { return 0 }
+func (m *testMessage) Payload() []byte   { return m.payload }
+func (m *testMessage) Ack()              {}
+
+func TestUpdateMetricsCollection(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	// IoT hosts send their messages unencrypted
+	host := models.Host{ID: uuid.New(), OS: models.OS_Types.IoT}
+	if err := logic.UpsertHost(&host); err != nil {
+		t.Fatal(err)
+	}
+	defer logic.RemoveHostByID(host.ID.String())
+	node := models.Node{}
+	node.ID = uuid.New()
+	node.HostID = host.ID
+	node.Network = "metricsnet"
+	data, err := json.Marshal(&node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Insert(node.ID.String(), string(data), database.NODES_TABLE_NAME); err != nil {
+		t.Fatal(err)
+	}
+	defer database.DeleteRecord(database.NODES_TABLE_NAME, node.ID.String())
+	defer logic.DeleteMetrics(node.ID.String())
+	defer logic.DeleteMetricsHistory(node.ID.String())
+	payload, err := json.Marshal(&models.Metrics{
+		Connectivity: map[string]models.Metric{
+			uuid.New().String(): {NodeName: "peer", Connected: true, TotalReceived: 100},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := &testMessage{topic: "metrics/netmaker/" + node.ID.String(), payload: payload}
+
+	t.Run("off", func(t *testing.T) {
+		is := is.New(t)
+		t.Setenv("METRICS_COLLECTION", "off")
+		UpdateMetrics(nil, message)
+		metrics, err := logic.GetMetrics(node.ID.String())
+		is.NoErr(err)
+		is.Equal(len(metrics.Connectivity), 0)
+	})
+	t.Run("on", func(t *testing.T) {
+		is := is.New(t)
+		t.Setenv("METRICS_COLLECTION", "on")
+		UpdateMetrics(nil, message)
+		metrics, err := logic.GetMetrics(node.ID.String())
+		is.NoErr(err)
+		is.Equal(len(metrics.Connectivity), 1)
+		is.Equal(metrics.Network, "metricsnet")
+	})
+}
@@ -0,0 +1,102 @@
+package mq
+
+import (
+	"time"
+
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+// PublishPunchSession - sends the punch schedule to both hosts of a session
+// and fails the session if no host reported success once it expires
+func PublishPunchSession(session *models.PunchSession) error {
+	for _, hostID := range []string{session.HostA, session.HostB} {
+		host, err := logic.GetHost(hostID)
+		if err != nil {
+			return err
+		}
+		if err = HostUpdate(&models.HostUpdate{
+			Action: models.SignalHost,
+			Host:   *host,
+			Signal: models.Signal{
+				Server: servercfg.GetServer(),
+				Action: models.HolePunch,
+				Punch:  session,
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	sessionID := session.ID
+	time.AfterFunc(time.Until(session.ExpiresAt), func() {
+		expired, pending, err := logic.ExpirePunchSession(sessionID)
+		if err != nil {
+			logger.Log(0, "failed to record expired punch session", sessionID, err.Error())
+		}
+		if pending {
+			logger.Log(1, "punch session", sessionID, "expired without a direct path")
+			fallbackToTurn(&expired)
+		}
+	})
+	return nil
+}
+
+// handlePunchResult - records the result a host reported for a punch session
+func handlePunchResult(result *models.PunchResult, currentHost *models.Host) {
+	if result == nil {
+		logger.Log(1, "host", currentHost.ID.String(), "reported a punch without a result")
+		return
+	}
+	result.HostID = currentHost.ID.String()
+	session, decided, err := logic.RecordPunchResult(*result)
+	if err != nil {
+		logger.Log(0, "failed to record punch result of host", currentHost.ID.String(), err.Error())
+		return
+	}
+	if !decided {
+		return
+	}
+	logger.Log(1, "punch session", session.ID, "between", session.HostA, "and", session.HostB, "finished:", string(session.Status))
+	if session.Status == models.PunchFailed {
+		fallbackToTurn(&session)
+	}
+}
+
+// fallbackToTurn - signals the hosts of a failed session to connect through their turn allocations
+func fallbackToTurn(session *models.PunchSession) {
+	if !servercfg.IsUsingTurn() {
+		return
+	}
+	hostA, err := logic.GetHost(session.HostA)
+	if err != nil {
+		logger.Log(0, "failed to fetch host for turn fallback", session.HostA, err.Error())
+		return
+	}
+	hostB, err := logic.GetHost(session.HostB)
+	if err != nil {
+		logger.Log(0, "failed to fetch host for turn fallback", session.HostB, err.Error())
+		return
+	}
+	if hostA.TurnEndpoint == nil || hostB.TurnEndpoint == nil {
+		logger.Log(1, "no turn allocation to fall back to for punch session", session.ID)
+		return
+	}
+	for _, pair := range [][2]*models.Host{{hostA, hostB}, {hostB, hostA}} {
+		to, from := pair[0], pair[1]
+		if err := HostUpdate(&models.HostUpdate{
+			Action: models.SignalHost,
+			Host:   *to,
+			Signal: models.Signal{
+				Server:            servercfg.GetServer(),
+				Action:            models.ConnNegotiation,
+				FromHostPubKey:    from.PublicKey.String(),
+				ToHostPubKey:      to.PublicKey.String(),
+				TurnRelayEndpoint: from.TurnEndpoint.String(),
+			},
+		}); err != nil {
+			logger.Log(0, "failed to signal turn fallback to host", to.ID.String(), err.Error())
+		}
+	}
+}
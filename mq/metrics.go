@@ -0,0 +1,21 @@
+package mq
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var publishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "netmaker",
+	Name:      "mq_publish_failures_total",
+	Help:      "Messages the server failed to publish to the broker, by topic kind.",
+}, []string{"topic"})
+
+// countPublishFailure - counts a failed publish by the first segment of the topic,
+// the remaining segments hold ids and would blow up the label cardinality
+func countPublishFailure(topic string) {
+	kind, _, _ := strings.Cut(topic, "/")
+	publishFailures.WithLabelValues(kind).Inc()
+}
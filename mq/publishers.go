@@ -353,6 +353,7 @@ func pushMetricsToExporter(metrics models.Metrics) error {
 		return errors.New("failed to marshal metrics: " + err.Error())
 	}
 	if token := mqclient.Publish("metrics_exporter", 2, true, data); !token.WaitTimeout(MQ_TIMEOUT*time.Second) || token.Error() != nil {
+		countPublishFailure("metrics_exporter")
 		var err error
 		if token.Error() == nil {
 			err = errors.New("connection timeout")
@@ -78,9 +78,11 @@ func publish(host *models.Host, dest string, msg []byte) error {
 		return encryptErr
 	}
 	if mqclient == nil {
+		countPublishFailure(dest)
 		return errors.New("cannot publish ... mqclient not connected")
 	}
 	if token := mqclient.Publish(dest, 0, true, encrypted); !token.WaitTimeout(MQ_TIMEOUT*time.Second) || token.Error() != nil {
+		countPublishFailure(dest)
 		var err error
 		if token.Error() == nil {
 			err = errors.New("connection timeout")
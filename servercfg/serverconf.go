@@ -1,7 +1,9 @@
 package servercfg
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -40,6 +42,7 @@ func GetServerConfig() config.ServerConfig {
 	cfg.APIPort = GetAPIPort()
 	cfg.MasterKey = "(hidden)"
 	cfg.DNSKey = "(hidden)"
+	cfg.MetricsScrapeToken = "(hidden)"
 	cfg.AllowedOrigin = GetAllowedOrigin()
 	cfg.RestBackend = "off"
 	cfg.NodeID = GetNodeID()
@@ -85,6 +88,10 @@ func GetServerConfig() config.ServerConfig {
 		cfg.IsEE = "yes"
 	}
 	cfg.DefaultProxyMode = GetDefaultProxyMode()
+	cfg.TrustedProxies = strings.Join(GetTrustedProxies(), ",")
+	cfg.UserTokenValidity = int(GetUserTokenValidity().Minutes())
+	cfg.UserSessionValidity = int(GetUserSessionValidity().Hours())
+	cfg.UserSessionLifetime = int(GetUserSessionLifetime().Hours())
 
 	return cfg
 }
@@ -105,6 +112,7 @@ func GetServerInfo() models.ServerConfig {
 	}
 	cfg.Version = GetVersion()
 	cfg.Is_EE = Is_EE
+	cfg.MetricsCollection = IsMetricsCollection()
 	cfg.StunPort = GetStunPort()
 	cfg.StunList = GetStunList()
 	cfg.TurnDomain = GetTurnHost()
@@ -124,16 +132,70 @@ func GetTurnHost() string {
 	return turnServer
 }
 
-// IsUsingTurn - check if server has turn configured
+// IsUsingTurn - check if server has turn configured,
+// without an explicit USE_TURN setting turn is used whenever the embedded turn server runs
 func IsUsingTurn() (b bool) {
 	if os.Getenv("USE_TURN") != "" {
 		b = os.Getenv("USE_TURN") == "true"
 	} else {
-		b = config.Config.Server.UseTurn
+		b = config.Config.Server.UseTurn || IsUsingEmbeddedTurn()
 	}
 	return
 }
 
+// IsUsingEmbeddedTurn - check if the turn server embedded in the netmaker binary should run
+func IsUsingEmbeddedTurn() (b bool) {
+	if os.Getenv("EMBEDDED_TURN") != "" {
+		b = os.Getenv("EMBEDDED_TURN") == "true"
+	} else {
+		b = config.Config.Server.EmbeddedTurn
+	}
+	return
+}
+
+// GetTurnRelayIP - fetches the ip the embedded turn server hands out for relayed allocations
+func GetTurnRelayIP() string {
+	relayIP := ""
+	if os.Getenv("TURN_RELAY_IP") != "" {
+		relayIP = os.Getenv("TURN_RELAY_IP")
+	} else if config.Config.Server.TurnRelayIP != "" {
+		relayIP = config.Config.Server.TurnRelayIP
+	} else if os.Getenv("SERVER_HOST") != "" {
+		relayIP = os.Getenv("SERVER_HOST")
+	} else {
+		relayIP, _ = GetPublicIP()
+	}
+	return relayIP
+}
+
+// GetTurnHostQuota - max number of concurrent allocations a host may hold on the embedded turn server
+func GetTurnHostQuota() int {
+	quota := 10 //default
+	if os.Getenv("TURN_HOST_QUOTA") != "" {
+		quotaInt, err := strconv.Atoi(os.Getenv("TURN_HOST_QUOTA"))
+		if err == nil {
+			quota = quotaInt
+		}
+	} else if config.Config.Server.TurnHostQuota != 0 {
+		quota = config.Config.Server.TurnHostQuota
+	}
+	return quota
+}
+
+// GetTurnMaxAllocations - max number of concurrent allocations on the embedded turn server, 0 is unlimited
+func GetTurnMaxAllocations() int {
+	max := 0 //default
+	if os.Getenv("TURN_MAX_ALLOCATIONS") != "" {
+		maxInt, err := strconv.Atoi(os.Getenv("TURN_MAX_ALLOCATIONS"))
+		if err == nil {
+			max = maxInt
+		}
+	} else if config.Config.Server.TurnMaxAllocations != 0 {
+		max = config.Config.Server.TurnMaxAllocations
+	}
+	return max
+}
+
 // GetTurnApiHost - fetches the turn api host domain
 func GetTurnApiHost() string {
 	turnApiServer := ""
@@ -366,6 +428,21 @@ func IsMetricsExporter() bool {
 	return export
 }
 
+// IsMetricsCollection - checks if the metrics reported by nodes are collected, on by default
+func IsMetricsCollection() bool {
+	collect := true
+	if os.Getenv("METRICS_COLLECTION") != "" {
+		if os.Getenv("METRICS_COLLECTION") == "off" {
+			collect = false
+		}
+	} else if config.Config.Server.MetricsCollection != "" {
+		if config.Config.Server.MetricsCollection == "off" {
+			collect = false
+		}
+	}
+	return collect
+}
+
 // IsMessageQueueBackend - checks if message queue is on or off
 func IsMessageQueueBackend() bool {
 	ismessagequeue := true
@@ -595,6 +672,40 @@ func GetAuthProviderInfo() (pi []string) {
 	return []string{"", "", ""}
 }
 
+// GetAuthProviders - the named sso providers, starting with the one set by AUTH_PROVIDER named after its type
+func GetAuthProviders() ([]config.AuthProvider, error) {
+	providers := []config.AuthProvider{}
+	if authInfo := GetAuthProviderInfo(); authInfo[0] != "" {
+		provider := config.AuthProvider{
+			Name:         authInfo[0],
+			Type:         authInfo[0],
+			ClientID:     authInfo[1],
+			ClientSecret: authInfo[2],
+			AzureTenant:  GetAzureTenant(),
+		}
+		if len(authInfo) > 3 {
+			provider.OIDCIssuer = authInfo[3]
+		}
+		providers = append(providers, provider)
+	}
+	extraProviders := config.Config.Server.AuthProviders
+	if os.Getenv("AUTH_PROVIDERS") != "" {
+		extraProviders = nil
+		if err := json.Unmarshal([]byte(os.Getenv("AUTH_PROVIDERS")), &extraProviders); err != nil {
+			return providers, fmt.Errorf("invalid AUTH_PROVIDERS: %w", err)
+		}
+	}
+	for _, provider := range extraProviders {
+		provider.Name = strings.ToLower(provider.Name)
+		provider.Type = strings.ToLower(provider.Type)
+		if provider.Type == "azure-ad" && provider.AzureTenant == "" {
+			provider.AzureTenant = GetAzureTenant()
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
 // GetAzureTenant - retrieve the azure tenant ID from env variable or config file
 func GetAzureTenant() string {
 	var azureTenant = ""
@@ -644,6 +755,234 @@ func IsBasicAuthEnabled() bool {
 	return enabled
 }
 
+// IsMFAEnforced - checks if users logging in with a password have to use a second factor
+func IsMFAEnforced() bool {
+	var enforced = false //default
+	if os.Getenv("MFA_ENFORCED") != "" {
+		enforced = os.Getenv("MFA_ENFORCED") == "yes"
+	} else if config.Config.Server.MFAEnforced != "" {
+		enforced = config.Config.Server.MFAEnforced == "yes"
+	}
+	return enforced
+}
+
+// IsSSOGroupSyncEnabled - checks if the groups of sso users are fetched from the identity provider
+// and mapped to netmaker groups, networks and admin status on each login
+func IsSSOGroupSyncEnabled() bool {
+	var enabled = false //default
+	if os.Getenv("SSO_GROUP_SYNC") != "" {
+		enabled = os.Getenv("SSO_GROUP_SYNC") == "yes"
+	} else if config.Config.Server.SSOGroupSync != "" {
+		enabled = config.Config.Server.SSOGroupSync == "yes"
+	}
+	return enabled
+}
+
+// GetSSOGroupsClaim - retrieves the claim of oidc id tokens holding the groups of a user
+func GetSSOGroupsClaim() string {
+	var claim = "groups" //default
+	if os.Getenv("SSO_GROUPS_CLAIM") != "" {
+		claim = os.Getenv("SSO_GROUPS_CLAIM")
+	} else if config.Config.Server.SSOGroupsClaim != "" {
+		claim = config.Config.Server.SSOGroupsClaim
+	}
+	return claim
+}
+
+// GetLDAPURL - retrieves the url of the ldap server users log in against
+func GetLDAPURL() string {
+	var url = ""
+	if os.Getenv("LDAP_URL") != "" {
+		url = os.Getenv("LDAP_URL")
+	} else if config.Config.Server.LDAPURL != "" {
+		url = config.Config.Server.LDAPURL
+	}
+	return url
+}
+
+// IsLDAPEnabled - checks if users can log in against an ldap server
+func IsLDAPEnabled() bool {
+	return GetLDAPURL() != ""
+}
+
+// IsLDAPStartTLS - checks if the connection to the ldap server is upgraded with StartTLS
+func IsLDAPStartTLS() bool {
+	var enabled = false //default
+	if os.Getenv("LDAP_START_TLS") != "" {
+		enabled = os.Getenv("LDAP_START_TLS") == "yes"
+	} else if config.Config.Server.LDAPStartTLS != "" {
+		enabled = config.Config.Server.LDAPStartTLS == "yes"
+	}
+	return enabled
+}
+
+// IsLDAPSkipTLSVerify - checks if the certificate of the ldap server is not verified
+func IsLDAPSkipTLSVerify() bool {
+	var skip = false //default
+	if os.Getenv("LDAP_SKIP_TLS_VERIFY") != "" {
+		skip = os.Getenv("LDAP_SKIP_TLS_VERIFY") == "yes"
+	} else if config.Config.Server.LDAPSkipTLSVerify != "" {
+		skip = config.Config.Server.LDAPSkipTLSVerify == "yes"
+	}
+	return skip
+}
+
+// GetLDAPBindDN - retrieves the dn of the account ldap users are searched with
+func GetLDAPBindDN() string {
+	var dn = ""
+	if os.Getenv("LDAP_BIND_DN") != "" {
+		dn = os.Getenv("LDAP_BIND_DN")
+	} else if config.Config.Server.LDAPBindDN != "" {
+		dn = config.Config.Server.LDAPBindDN
+	}
+	return dn
+}
+
+// GetLDAPBindPassword - retrieves the password of the account ldap users are searched with
+func GetLDAPBindPassword() string {
+	var password = ""
+	if os.Getenv("LDAP_BIND_PASSWORD") != "" {
+		password = os.Getenv("LDAP_BIND_PASSWORD")
+	} else if config.Config.Server.LDAPBindPassword != "" {
+		password = config.Config.Server.LDAPBindPassword
+	}
+	return password
+}
+
+// GetLDAPBaseDN - retrieves the dn ldap users are searched under
+func GetLDAPBaseDN() string {
+	var dn = ""
+	if os.Getenv("LDAP_BASE_DN") != "" {
+		dn = os.Getenv("LDAP_BASE_DN")
+	} else if config.Config.Server.LDAPBaseDN != "" {
+		dn = config.Config.Server.LDAPBaseDN
+	}
+	return dn
+}
+
+// GetLDAPUserFilter - retrieves the filter finding an ldap user by its name, %s is replaced by the name
+func GetLDAPUserFilter() string {
+	var filter = "(uid=%s)" //default
+	if os.Getenv("LDAP_USER_FILTER") != "" {
+		filter = os.Getenv("LDAP_USER_FILTER")
+	} else if config.Config.Server.LDAPUserFilter != "" {
+		filter = config.Config.Server.LDAPUserFilter
+	}
+	return filter
+}
+
+// GetLDAPGroupAttribute - retrieves the attribute of ldap user entries listing their groups
+func GetLDAPGroupAttribute() string {
+	var attribute = "memberOf" //default
+	if os.Getenv("LDAP_GROUP_ATTRIBUTE") != "" {
+		attribute = os.Getenv("LDAP_GROUP_ATTRIBUTE")
+	} else if config.Config.Server.LDAPGroupAttribute != "" {
+		attribute = config.Config.Server.LDAPGroupAttribute
+	}
+	return attribute
+}
+
+// GetPasswordMinLength - retrieves the minimum length of the passwords of local users
+func GetPasswordMinLength() int {
+	length := 5 //default
+	if os.Getenv("PASSWORD_MIN_LENGTH") != "" {
+		lengthInt, err := strconv.Atoi(os.Getenv("PASSWORD_MIN_LENGTH"))
+		if err == nil && lengthInt > 0 {
+			length = lengthInt
+		}
+	} else if config.Config.Server.PasswordMinLength > 0 {
+		length = config.Config.Server.PasswordMinLength
+	}
+	return length
+}
+
+// GetPasswordCharacterClasses - retrieves the number of character classes a password has to mix
+func GetPasswordCharacterClasses() int {
+	classes := 1 //default
+	if os.Getenv("PASSWORD_CHARACTER_CLASSES") != "" {
+		classesInt, err := strconv.Atoi(os.Getenv("PASSWORD_CHARACTER_CLASSES"))
+		if err == nil && classesInt > 0 {
+			classes = classesInt
+		}
+	} else if config.Config.Server.PasswordClasses > 0 {
+		classes = config.Config.Server.PasswordClasses
+	}
+	if classes > 4 {
+		classes = 4
+	}
+	return classes
+}
+
+// GetPasswordHistory - retrieves the number of previous passwords a user cannot set again
+func GetPasswordHistory() int {
+	history := 0 //default
+	if os.Getenv("PASSWORD_HISTORY") != "" {
+		historyInt, err := strconv.Atoi(os.Getenv("PASSWORD_HISTORY"))
+		if err == nil && historyInt >= 0 {
+			history = historyInt
+		}
+	} else if config.Config.Server.PasswordHistory > 0 {
+		history = config.Config.Server.PasswordHistory
+	}
+	return history
+}
+
+// GetLoginLockoutThreshold - retrieves the number of failed logins after which a user is locked out, 0 if never
+func GetLoginLockoutThreshold() int {
+	threshold := 5 //default
+	if os.Getenv("LOGIN_LOCKOUT_THRESHOLD") != "" {
+		thresholdInt, err := strconv.Atoi(os.Getenv("LOGIN_LOCKOUT_THRESHOLD"))
+		if err == nil && thresholdInt >= 0 {
+			threshold = thresholdInt
+		}
+	} else if config.Config.Server.LockoutThreshold > 0 {
+		threshold = config.Config.Server.LockoutThreshold
+	}
+	return threshold
+}
+
+// GetLoginLockoutIPThreshold - retrieves the number of failed logins after which a source ip is locked out, 0 if never
+func GetLoginLockoutIPThreshold() int {
+	threshold := 20 //default
+	if os.Getenv("LOGIN_LOCKOUT_IP_THRESHOLD") != "" {
+		thresholdInt, err := strconv.Atoi(os.Getenv("LOGIN_LOCKOUT_IP_THRESHOLD"))
+		if err == nil && thresholdInt >= 0 {
+			threshold = thresholdInt
+		}
+	} else if config.Config.Server.LockoutIPThreshold > 0 {
+		threshold = config.Config.Server.LockoutIPThreshold
+	}
+	return threshold
+}
+
+// GetLoginLockoutDuration - retrieves the duration of the first lockout after too many failed logins
+func GetLoginLockoutDuration() time.Duration {
+	seconds := 60 //default
+	if os.Getenv("LOGIN_LOCKOUT_DURATION") != "" {
+		secondsInt, err := strconv.Atoi(os.Getenv("LOGIN_LOCKOUT_DURATION"))
+		if err == nil && secondsInt > 0 {
+			seconds = secondsInt
+		}
+	} else if config.Config.Server.LockoutDuration > 0 {
+		seconds = config.Config.Server.LockoutDuration
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// GetPasswordResetTokenValidity - retrieves how long a password reset token can be used for
+func GetPasswordResetTokenValidity() time.Duration {
+	minutes := 60 //default
+	if os.Getenv("PASSWORD_RESET_TOKEN_VALIDITY") != "" {
+		minutesInt, err := strconv.Atoi(os.Getenv("PASSWORD_RESET_TOKEN_VALIDITY"))
+		if err == nil && minutesInt > 0 {
+			minutes = minutesInt
+		}
+	} else if config.Config.Server.ResetTokenValidity > 0 {
+		minutes = config.Config.Server.ResetTokenValidity
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
 // GetLicenseKey - retrieves pro license value from env or conf files
 func GetLicenseKey() string {
 	licenseKeyValue := os.Getenv("LICENSE_KEY")
@@ -676,6 +1015,66 @@ func GetStunPort() int {
 	return port
 }
 
+// GetStunPrimaryIP - Get the ip the stun server binds to for NAT behaviour discovery
+func GetStunPrimaryIP() string {
+	ip := ""
+	if os.Getenv("STUN_PRIMARY_IP") != "" {
+		ip = os.Getenv("STUN_PRIMARY_IP")
+	} else if config.Config.Server.StunPrimaryIP != "" {
+		ip = config.Config.Server.StunPrimaryIP
+	}
+	return ip
+}
+
+// GetStunAltIP - Get the second ip of the stun server, enables RFC 5780 NAT behaviour discovery together with STUN_PRIMARY_IP
+func GetStunAltIP() string {
+	ip := ""
+	if os.Getenv("STUN_ALT_IP") != "" {
+		ip = os.Getenv("STUN_ALT_IP")
+	} else if config.Config.Server.StunAltIP != "" {
+		ip = config.Config.Server.StunAltIP
+	}
+	return ip
+}
+
+// GetStunAltPort - Get the second port of the stun server used for NAT behaviour discovery
+func GetStunAltPort() int {
+	port := 3480 //default
+	if os.Getenv("STUN_ALT_PORT") != "" {
+		portInt, err := strconv.Atoi(os.Getenv("STUN_ALT_PORT"))
+		if err == nil {
+			port = portInt
+		}
+	} else if config.Config.Server.StunAltPort != 0 {
+		port = config.Config.Server.StunAltPort
+	}
+	return port
+}
+
+// GetStunRateLimit - Get the number of stun requests per second answered per source ip, 0 disables the limit
+func GetStunRateLimit() int {
+	limit := 20 //default
+	if os.Getenv("STUN_RATE_LIMIT") != "" {
+		limitInt, err := strconv.Atoi(os.Getenv("STUN_RATE_LIMIT"))
+		if err == nil {
+			limit = limitInt
+		}
+	} else if config.Config.Server.StunRateLimit != 0 {
+		limit = config.Config.Server.StunRateLimit
+	}
+	return limit
+}
+
+// IsStunAllowlist - check if the stun server limits sources which are not endpoints of known hosts
+func IsStunAllowlist() (b bool) {
+	if os.Getenv("STUN_ALLOWLIST") != "" {
+		b = os.Getenv("STUN_ALLOWLIST") == "true"
+	} else {
+		b = config.Config.Server.StunAllowlist
+	}
+	return
+}
+
 // GetTurnPort - Get the port to run the turn server on
 func GetTurnPort() int {
 	port := 3479 //default
@@ -780,3 +1179,156 @@ func parseStunList(stunString string) ([]models.StunServer, error) {
 	}
 	return stunServers, err
 }
+
+// GetMetricsScrapeToken - Get the bearer token prometheus scrapes /metrics with, scraping is disabled without one
+func GetMetricsScrapeToken() string {
+	token := ""
+	if os.Getenv("METRICS_SCRAPE_TOKEN") != "" {
+		token = os.Getenv("METRICS_SCRAPE_TOKEN")
+	} else if config.Config.Server.MetricsScrapeToken != "" {
+		token = config.Config.Server.MetricsScrapeToken
+	}
+	return token
+}
+
+// GetMetricsRetention - Get the number of days the metrics history of a resolution ("1m", "1h" or "1d") is kept
+func GetMetricsRetention(resolution string) int {
+	var days, configured int
+	switch resolution {
+	case "1m":
+		days, configured = 2, config.Config.Server.MetricsRetention1m
+	case "1h":
+		days, configured = 30, config.Config.Server.MetricsRetention1h
+	default:
+		days, configured = 365, config.Config.Server.MetricsRetention1d
+	}
+	env := os.Getenv("METRICS_RETENTION_" + strings.ToUpper(resolution))
+	if env != "" {
+		daysInt, err := strconv.Atoi(env)
+		if err == nil && daysInt > 0 {
+			days = daysInt
+		}
+	} else if configured > 0 {
+		days = configured
+	}
+	return days
+}
+
+// GetSmtpHost - Get the host of the SMTP server alert emails are sent through, empty if not configured
+func GetSmtpHost() string {
+	host := ""
+	if os.Getenv("SMTP_HOST") != "" {
+		host = os.Getenv("SMTP_HOST")
+	} else if config.Config.Server.SmtpHost != "" {
+		host = config.Config.Server.SmtpHost
+	}
+	return host
+}
+
+// GetSmtpPort - Get the port of the SMTP server
+func GetSmtpPort() int {
+	port := 587 //default
+	if os.Getenv("SMTP_PORT") != "" {
+		portInt, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err == nil {
+			port = portInt
+		}
+	} else if config.Config.Server.SmtpPort != 0 {
+		port = config.Config.Server.SmtpPort
+	}
+	return port
+}
+
+// GetSmtpCredentials - Get the username and password used to authenticate with the SMTP server
+func GetSmtpCredentials() (string, string) {
+	username, password := config.Config.Server.SmtpUsername, config.Config.Server.SmtpPassword
+	if os.Getenv("SMTP_USERNAME") != "" {
+		username = os.Getenv("SMTP_USERNAME")
+	}
+	if os.Getenv("SMTP_PASSWORD") != "" {
+		password = os.Getenv("SMTP_PASSWORD")
+	}
+	return username, password
+}
+
+// GetSmtpSender - Get the address alert emails are sent from
+func GetSmtpSender() string {
+	sender := "netmaker@" + GetSmtpHost()
+	if os.Getenv("SMTP_SENDER") != "" {
+		sender = os.Getenv("SMTP_SENDER")
+	} else if config.Config.Server.SmtpSender != "" {
+		sender = config.Config.Server.SmtpSender
+	}
+	return sender
+}
+
+// GetAuditSyslog - Get the syslog server audit events are forwarded to as network://host:port, empty if not forwarded
+func GetAuditSyslog() string {
+	address := ""
+	if os.Getenv("AUDIT_SYSLOG") != "" {
+		address = os.Getenv("AUDIT_SYSLOG")
+	} else if config.Config.Server.AuditSyslog != "" {
+		address = config.Config.Server.AuditSyslog
+	}
+	return address
+}
+
+// GetTrustedProxies - Get the addresses or cidrs of the proxies in front of the server,
+// the X-Forwarded-For and X-Real-Ip headers of requests passed on by them name the client
+func GetTrustedProxies() []string {
+	proxies := ""
+	if os.Getenv("TRUSTED_PROXIES") != "" {
+		proxies = os.Getenv("TRUSTED_PROXIES")
+	} else if config.Config.Server.TrustedProxies != "" {
+		proxies = config.Config.Server.TrustedProxies
+	}
+	trusted := []string{}
+	for _, proxy := range strings.Split(proxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trusted = append(trusted, proxy)
+		}
+	}
+	return trusted
+}
+
+// GetUserTokenValidity - Get the time a user access token is valid for
+func GetUserTokenValidity() time.Duration {
+	minutes := 15 //default
+	if os.Getenv("USER_TOKEN_VALIDITY") != "" {
+		minutesInt, err := strconv.Atoi(os.Getenv("USER_TOKEN_VALIDITY"))
+		if err == nil && minutesInt > 0 {
+			minutes = minutesInt
+		}
+	} else if config.Config.Server.UserTokenValidity > 0 {
+		minutes = config.Config.Server.UserTokenValidity
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// GetUserSessionValidity - Get the time a user session lasts without being refreshed
+func GetUserSessionValidity() time.Duration {
+	hours := 168 //default
+	if os.Getenv("USER_SESSION_VALIDITY") != "" {
+		hoursInt, err := strconv.Atoi(os.Getenv("USER_SESSION_VALIDITY"))
+		if err == nil && hoursInt > 0 {
+			hours = hoursInt
+		}
+	} else if config.Config.Server.UserSessionValidity > 0 {
+		hours = config.Config.Server.UserSessionValidity
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// GetUserSessionLifetime - Get the time after which a user session ends even if it keeps being refreshed
+func GetUserSessionLifetime() time.Duration {
+	hours := 720 //default
+	if os.Getenv("USER_SESSION_LIFETIME") != "" {
+		hoursInt, err := strconv.Atoi(os.Getenv("USER_SESSION_LIFETIME"))
+		if err == nil && hoursInt > 0 {
+			hours = hoursInt
+		}
+	} else if config.Config.Server.UserSessionLifetime > 0 {
+		hours = config.Config.Server.UserSessionLifetime
+	}
+	return time.Duration(hours) * time.Hour
+}
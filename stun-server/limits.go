@@ -0,0 +1,124 @@
+package stunserver
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+)
+
+const (
+	// rateLimitWindow - window in which a source may send the configured number of requests
+	rateLimitWindow = time.Second
+	// allowlistRefresh - interval in which the known host endpoints are reloaded
+	allowlistRefresh = time.Second * 30
+	// unknownSourceLimit - requests per window answered for a source which is not a known host endpoint,
+	// enough for new hosts and hosts whose ip changed to run behaviour discovery
+	unknownSourceLimit = 10
+	// unknownSourcesMax - unknown sources answered per window, bounds what the allowlist lets through
+	unknownSourcesMax = 100
+)
+
+// rateLimiter - fixed window limit of requests per source ip
+type rateLimiter struct {
+	mutex      sync.Mutex
+	limit      int
+	maxSources int
+	window     time.Time
+	sources    map[string]int
+}
+
+// newRateLimiter - creates a limiter answering limit requests per source and window,
+// a maxSources above 0 also limits the number of sources answered per window
+func newRateLimiter(limit, maxSources int) *rateLimiter {
+	return &rateLimiter{
+		limit:      limit,
+		maxSources: maxSources,
+		sources:    make(map[string]int),
+	}
+}
+
+// allow - counts a request of the source and checks if it is within the limit,
+// a limit of 0 disables rate limiting
+func (r *rateLimiter) allow(ip net.IP, now time.Time) bool {
+	if r == nil || r.limit <= 0 {
+		return true
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if now.Sub(r.window) >= rateLimitWindow {
+		// a new window drops the counts of every source so the map does not grow unbounded
+		r.window = now
+		r.sources = make(map[string]int)
+	}
+	key := ip.String()
+	if _, ok := r.sources[key]; !ok && r.maxSources > 0 && len(r.sources) >= r.maxSources {
+		return false
+	}
+	r.sources[key]++
+	return r.sources[key] <= r.limit
+}
+
+// allowlist - endpoint ips of the known hosts, reloaded periodically,
+// unknown sources are answered within a small probation limit
+type allowlist struct {
+	mutex     sync.RWMutex
+	loaded    time.Time
+	reloading bool
+	allowed   map[string]struct{}
+	unknown   *rateLimiter
+}
+
+func newAllowlist() *allowlist {
+	return &allowlist{
+		unknown: newRateLimiter(unknownSourceLimit, unknownSourcesMax),
+	}
+}
+
+// allow - checks if a request of the ip is answered, known host endpoints are always answered
+func (a *allowlist) allow(ip net.IP, now time.Time) bool {
+	if a.contains(ip, now) {
+		return true
+	}
+	return a.unknown.allow(ip, now)
+}
+
+// contains - checks if the ip is the endpoint of a known host, a stale list is reloaded
+// by the first caller noticing while the others keep using the previous endpoints
+func (a *allowlist) contains(ip net.IP, now time.Time) bool {
+	a.mutex.Lock()
+	reload := !a.reloading && now.Sub(a.loaded) >= allowlistRefresh
+	if reload {
+		a.reloading = true
+	}
+	a.mutex.Unlock()
+	if reload {
+		a.reload(now)
+	}
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	_, ok := a.allowed[ip.String()]
+	return ok
+}
+
+func (a *allowlist) reload(now time.Time) {
+	hosts, err := logic.GetAllHosts()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.reloading = false
+	// a failed load keeps the previous endpoints until the next refresh instead of querying on every packet
+	a.loaded = now
+	if err != nil {
+		logger.Log(1, "STUN failed to load allowed host endpoints:", err.Error())
+		return
+	}
+	allowed := make(map[string]struct{}, len(hosts))
+	for i := range hosts {
+		if hosts[i].EndpointIP != nil {
+			allowed[hosts[i].EndpointIP.String()] = struct{}{}
+		}
+	}
+	a.allowed = allowed
+}
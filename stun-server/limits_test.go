@@ -0,0 +1,91 @@
+package stunserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/matryer/is"
+)
+
+func TestRateLimiter(t *testing.T) {
+	sourceA, sourceB, sourceC := net.ParseIP("198.51.100.1"), net.ParseIP("198.51.100.2"), net.ParseIP("198.51.100.3")
+	now := time.Now()
+	t.Run("disabled", func(t *testing.T) {
+		is := is.New(t)
+		var limiter *rateLimiter
+		is.True(limiter.allow(sourceA, now))
+		limiter = newRateLimiter(0, 0)
+		for i := 0; i < 100; i++ {
+			is.True(limiter.allow(sourceA, now))
+		}
+	})
+	t.Run("per source", func(t *testing.T) {
+		is := is.New(t)
+		limiter := newRateLimiter(2, 0)
+		is.True(limiter.allow(sourceA, now))
+		is.True(limiter.allow(sourceA, now))
+		is.True(!limiter.allow(sourceA, now))
+		is.True(limiter.allow(sourceB, now)) // other sources keep their own budget
+		is.True(limiter.allow(sourceA, now.Add(rateLimitWindow)))
+	})
+	t.Run("max sources", func(t *testing.T) {
+		is := is.New(t)
+		limiter := newRateLimiter(2, 2)
+		is.True(limiter.allow(sourceA, now))
+		is.True(limiter.allow(sourceB, now))
+		is.True(!limiter.allow(sourceC, now))
+		is.True(limiter.allow(sourceA, now)) // known sources of the window are still counted
+		is.True(limiter.allow(sourceC, now.Add(rateLimitWindow)))
+	})
+}
+
+func TestAllowlist(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	is := is.New(t)
+	host := models.Host{ID: uuid.New(), EndpointIP: net.ParseIP("203.0.113.20")}
+	is.NoErr(logic.UpsertHost(&host))
+	defer logic.RemoveHostByID(host.ID.String())
+	now := time.Now()
+	list := newAllowlist()
+
+	t.Run("known endpoint", func(t *testing.T) {
+		is := is.New(t)
+		for i := 0; i < unknownSourceLimit*2; i++ {
+			is.True(list.allow(host.EndpointIP, now))
+		}
+	})
+	t.Run("unknown source gets a probation limit", func(t *testing.T) {
+		is := is.New(t)
+		unknown := net.ParseIP("203.0.113.21")
+		for i := 0; i < unknownSourceLimit; i++ {
+			is.True(list.allow(unknown, now))
+		}
+		is.True(!list.allow(unknown, now))
+	})
+	t.Run("changed endpoint is picked up on refresh", func(t *testing.T) {
+		is := is.New(t)
+		host.EndpointIP = net.ParseIP("203.0.113.22")
+		is.NoErr(logic.UpsertHost(&host))
+		is.True(!list.contains(host.EndpointIP, now))
+		is.True(list.contains(host.EndpointIP, now.Add(allowlistRefresh)))
+	})
+	t.Run("a running reload is not repeated", func(t *testing.T) {
+		is := is.New(t)
+		list.mutex.Lock()
+		list.reloading = true
+		list.mutex.Unlock()
+		// the stale list is used while another reload runs
+		later := now.Add(allowlistRefresh * 2)
+		is.True(list.contains(host.EndpointIP, later))
+		list.mutex.RLock()
+		is.True(list.reloading)
+		is.True(list.loaded.Before(later))
+		list.mutex.RUnlock()
+	})
+}
@@ -0,0 +1,31 @@
+package stunserver
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// drop reasons of the stun_dropped_total counter
+const (
+	dropRateLimited = "rate_limited"
+	dropNotAllowed  = "not_allowed"
+	dropNotSTUN     = "not_stun"
+)
+
+var (
+	stunRequests = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "netmaker",
+		Name:      "stun_requests_total",
+		Help:      "Packets received by the STUN server.",
+	})
+	stunErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "netmaker",
+		Name:      "stun_errors_total",
+		Help:      "Requests the STUN server failed to process or answer.",
+	})
+	stunDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "netmaker",
+		Name:      "stun_dropped_total",
+		Help:      "Packets dropped by the STUN server without an answer.",
+	}, []string{"reason"})
+)
@@ -2,32 +2,95 @@ package stunserver
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"net"
+	"net/netip"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
 	"github.com/gravitl/netmaker/servercfg"
 	"github.com/pkg/errors"
 	"gortc.io/stun"
 )
 
-// Server is RFC 5389 basic server implementation.
+// Server is RFC 5389 basic server implementation with optional
+// RFC 5780 NAT behaviour discovery.
 //
 // Current implementation is UDP only and not utilizes FINGERPRINT mechanism,
 // nor ALTERNATE-SERVER, nor credentials mechanisms. It does not support
 // backwards compatibility with RFC 3489.
 type Server struct {
 	Addr string
+	// conns - listeners indexed by [ip][port], only [0][0] is set
+	// when behaviour discovery is disabled
+	conns [2][2]net.PacketConn
+	addrs [2][2]*net.UDPAddr
+	// limiter - per source rate limit, nil when disabled
+	limiter *rateLimiter
+	// allowlist - known host endpoints and a probation limit for unknown sources, nil when every source is answered
+	allowlist *allowlist
 }
 
+// RFC 5780 attributes
+const (
+	attrChangeRequest  stun.AttrType = 0x0003 // CHANGE-REQUEST
+	attrResponseOrigin stun.AttrType = 0x802B // RESPONSE-ORIGIN
+	attrOtherAddress   stun.AttrType = 0x802C // OTHER-ADDRESS
+
+	changeIPFlag   = 0x04
+	changePortFlag = 0x02
+)
+
 var (
 	software          = stun.NewSoftware("netmaker-stun")
 	errNotSTUNMessage = errors.New("not stun message")
+	errUnknownAddr    = errors.New("unknown addr")
+	// errChangeNotSupported - CHANGE-REQUEST received without a second ip and port to answer from
+	errChangeNotSupported = errors.New("CHANGE-REQUEST is not supported without an alternate address")
 )
 
-func basicProcess(addr net.Addr, b []byte, req, res *stun.Message) error {
+// addressAttribute - encodes an address attribute the way MAPPED-ADDRESS is encoded,
+// used for RESPONSE-ORIGIN and OTHER-ADDRESS
+type addressAttribute struct {
+	attr stun.AttrType
+	addr *net.UDPAddr
+}
+
+// AddTo adds the address attribute to the message
+func (a addressAttribute) AddTo(m *stun.Message) error {
+	family, ip := uint16(0x01), a.addr.IP.To4()
+	if ip == nil {
+		family, ip = 0x02, a.addr.IP.To16()
+	}
+	value := make([]byte, 4+len(ip))
+	binary.BigEndian.PutUint16(value[0:2], family)
+	binary.BigEndian.PutUint16(value[2:4], uint16(a.addr.Port))
+	copy(value[4:], ip)
+	m.Add(a.attr, value)
+	return nil
+}
+
+// getChangeRequest - reads the CHANGE-REQUEST flags of a request
+func getChangeRequest(req *stun.Message) (changeIP, changePort bool, err error) {
+	value, err := req.Get(attrChangeRequest)
+	if err != nil {
+		if err == stun.ErrAttributeNotFound {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+	if len(value) != 4 {
+		return false, false, errors.New("malformed CHANGE-REQUEST")
+	}
+	flags := binary.BigEndian.Uint32(value)
+	return flags&changeIPFlag != 0, flags&changePortFlag != 0, nil
+}
+
+func basicProcess(addr net.Addr, b []byte, req, res *stun.Message, extra ...stun.Setter) error {
 	if !stun.IsMessage(b) {
 		return errNotSTUNMessage
 	}
@@ -43,60 +106,133 @@ func basicProcess(addr net.Addr, b []byte, req, res *stun.Message) error {
 		ip = a.IP
 		port = a.Port
 	default:
-		panic(fmt.Sprintf("unknown addr: %v", addr))
+		return errors.Wrapf(errUnknownAddr, "%v", addr)
 	}
-	return res.Build(req,
+	setters := []stun.Setter{
+		req,
 		stun.BindingSuccess,
 		software,
 		&stun.XORMappedAddress{
 			IP:   ip,
 			Port: port,
 		},
-		stun.Fingerprint,
-	)
+	}
+	setters = append(setters, extra...)
+	setters = append(setters, stun.Fingerprint)
+	return res.Build(setters...)
 }
 
-func (s *Server) serveConn(c net.PacketConn, res, req *stun.Message, ctx context.Context) error {
-	if c == nil {
-		return nil
+// errorProcess - builds an error response to a binding request
+func errorProcess(req, res *stun.Message, code stun.ErrorCode, extra ...stun.Setter) error {
+	setters := []stun.Setter{
+		req,
+		stun.BindingError,
+		software,
+		stun.ErrorCodeAttribute{Code: code},
 	}
-	go func(ctx context.Context) {
-		<-ctx.Done()
-		if c != nil {
-			// kill connection on server shutdown
-			c.Close()
+	setters = append(setters, extra...)
+	setters = append(setters, stun.Fingerprint)
+	return res.Build(setters...)
+}
+
+// behaviourDiscovery - checks if the server listens on a second ip and port
+func (s *Server) behaviourDiscovery() bool {
+	return s.conns[1][1] != nil
+}
+
+// respond - picks the listener a response is sent from based on the CHANGE-REQUEST of the request,
+// returns the listener together with the RFC 5780 attributes of the response
+func (s *Server) respond(ipIdx, portIdx int, req *stun.Message) (net.PacketConn, []stun.Setter, error) {
+	changeIP, changePort, err := getChangeRequest(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !s.behaviourDiscovery() {
+		if changeIP || changePort {
+			return nil, nil, errChangeNotSupported
 		}
-	}(ctx)
+		return s.conns[ipIdx][portIdx], nil, nil
+	}
+	outIP, outPort := ipIdx, portIdx
+	if changeIP {
+		outIP = 1 - ipIdx
+	}
+	if changePort {
+		outPort = 1 - portIdx
+	}
+	return s.conns[outIP][outPort], []stun.Setter{
+		addressAttribute{attr: attrResponseOrigin, addr: s.addrs[outIP][outPort]},
+		addressAttribute{attr: attrOtherAddress, addr: s.addrs[1-ipIdx][1-portIdx]},
+	}, nil
+}
 
+func (s *Server) serveConn(ipIdx, portIdx int, res, req *stun.Message) error {
+	c := s.conns[ipIdx][portIdx]
 	buf := make([]byte, 1024)
 	n, addr, err := c.ReadFrom(buf) // this be blocky af
 	if err != nil {
-		if !strings.Contains(err.Error(), "use of closed network connection") {
-			logger.Log(1, "STUN read error:", err.Error())
+		if strings.Contains(err.Error(), "use of closed network connection") {
+			return err
 		}
+		logger.Log(1, "STUN read error:", err.Error())
 		return nil
 	}
 
+	stunRequests.Inc()
+	now := time.Now()
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if ok {
+		if s.allowlist != nil && !s.allowlist.allow(udpAddr.IP, now) {
+			stunDropped.WithLabelValues(dropNotAllowed).Inc()
+			return nil
+		}
+		if !s.limiter.allow(udpAddr.IP, now) {
+			stunDropped.WithLabelValues(dropRateLimited).Inc()
+			return nil
+		}
+	}
+	if !stun.IsMessage(buf[:n]) {
+		stunDropped.WithLabelValues(dropNotSTUN).Inc()
+		return nil
+	}
 	if _, err = req.Write(buf[:n]); err != nil {
+		stunErrors.Inc()
 		logger.Log(1, "STUN write error:", err.Error())
-		return err
+		return nil
 	}
-	if err = basicProcess(addr, buf[:n], req, res); err != nil {
-		if err == errNotSTUNMessage {
-			return nil
-		}
+	out, extra, err := s.respond(ipIdx, portIdx, req)
+	switch {
+	case err == errChangeNotSupported:
+		out = c
+		err = errorProcess(req, res, stun.CodeUnknownAttribute, stun.UnknownAttributes{attrChangeRequest})
+	case err != nil:
+		out = c
+		err = errorProcess(req, res, stun.CodeBadRequest)
+	default:
+		err = basicProcess(addr, buf[:n], req, res, extra...)
+	}
+	if err != nil {
+		stunErrors.Inc()
 		logger.Log(1, "STUN process error:", err.Error())
 		return nil
 	}
-	_, err = c.WriteTo(res.Raw, addr)
+	_, err = out.WriteTo(res.Raw, addr)
 	if err != nil {
+		stunErrors.Inc()
 		logger.Log(1, "STUN response write error", err.Error())
+		return nil
 	}
-	return err
+	if ok && s.behaviourDiscovery() {
+		// hosts report their behaviour discovery results to the server, only mapped addresses
+		// answered here are trusted when classifying their NAT
+		mapped := udpAddr.AddrPort()
+		logic.RecordStunMapping(netip.AddrPortFrom(mapped.Addr().Unmap(), mapped.Port()), ipIdx, now)
+	}
+	return nil
 }
 
 // Serve reads packets from connections and responds to BINDING requests.
-func (s *Server) serve(c net.PacketConn, ctx context.Context) error {
+func (s *Server) serve(ipIdx, portIdx int, ctx context.Context) error {
 	var (
 		res = new(stun.Message)
 		req = new(stun.Message)
@@ -107,9 +243,8 @@ func (s *Server) serve(c net.PacketConn, ctx context.Context) error {
 			logger.Log(0, "shut down STUN server")
 			return nil
 		default:
-			if err := s.serveConn(c, res, req, ctx); err != nil {
-				logger.Log(1, "serve: %v", err.Error())
-				continue
+			if err := s.serveConn(ipIdx, portIdx, res, req); err != nil {
+				return err
 			}
 			res.Reset()
 			req.Reset()
@@ -117,16 +252,63 @@ func (s *Server) serve(c net.PacketConn, ctx context.Context) error {
 	}
 }
 
-// listenUDPAndServe listens on laddr and process incoming packets.
-func listenUDPAndServe(ctx context.Context, serverNet, laddr string) error {
-	c, err := net.ListenPacket(serverNet, laddr)
-	if err != nil {
-		return err
-	}
+// listenUDPAndServe listens on the given addresses, indexed by [ip][port], and process incoming packets.
+func listenUDPAndServe(ctx context.Context, serverNet string, laddrs [2][2]string) error {
 	s := &Server{
-		Addr: laddr,
+		Addr:    laddrs[0][0],
+		limiter: newRateLimiter(servercfg.GetStunRateLimit(), 0),
+	}
+	if servercfg.IsStunAllowlist() {
+		s.allowlist = newAllowlist()
+	}
+	for i := range laddrs {
+		for j := range laddrs[i] {
+			if laddrs[i][j] == "" {
+				continue
+			}
+			c, err := net.ListenPacket(serverNet, laddrs[i][j])
+			if err != nil {
+				s.close()
+				return err
+			}
+			s.conns[i][j] = c
+			s.addrs[i][j], _ = c.LocalAddr().(*net.UDPAddr)
+		}
+	}
+	go func(ctx context.Context) {
+		<-ctx.Done()
+		// kill connections on server shutdown
+		s.close()
+	}(ctx)
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := range s.conns {
+		for j := range s.conns[i] {
+			if s.conns[i][j] == nil {
+				continue
+			}
+			wg.Add(1)
+			go func(i, j int) {
+				defer wg.Done()
+				if err := s.serve(i, j, ctx); err != nil {
+					errs <- err
+				}
+			}(i, j)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+func (s *Server) close() {
+	for i := range s.conns {
+		for j := range s.conns[i] {
+			if s.conns[i][j] != nil {
+				s.conns[i][j].Close()
+			}
+		}
 	}
-	return s.serve(c, ctx)
 }
 
 func normalize(address string) string {
@@ -142,9 +324,22 @@ func normalize(address string) string {
 // Start - starts the stun server
 func Start(wg *sync.WaitGroup, ctx context.Context) {
 	defer wg.Done()
-	normalized := normalize(fmt.Sprintf("0.0.0.0:%d", servercfg.GetStunPort()))
-	logger.Log(0, "netmaker-stun listening on", normalized, "via udp")
-	if err := listenUDPAndServe(ctx, "udp", normalized); err != nil {
+	var laddrs [2][2]string
+	primaryIP, altIP := servercfg.GetStunPrimaryIP(), servercfg.GetStunAltIP()
+	if primaryIP != "" && altIP != "" {
+		// RFC 5780 behaviour discovery needs the listeners bound to the individual ips
+		// so responses to a CHANGE-REQUEST leave from the requested address
+		port, altPort := servercfg.GetStunPort(), servercfg.GetStunAltPort()
+		laddrs[0][0] = net.JoinHostPort(primaryIP, fmt.Sprint(port))
+		laddrs[0][1] = net.JoinHostPort(primaryIP, fmt.Sprint(altPort))
+		laddrs[1][0] = net.JoinHostPort(altIP, fmt.Sprint(port))
+		laddrs[1][1] = net.JoinHostPort(altIP, fmt.Sprint(altPort))
+		logger.Log(0, "netmaker-stun listening on", strings.Join([]string{laddrs[0][0], laddrs[0][1], laddrs[1][0], laddrs[1][1]}, ", "), "via udp with NAT behaviour discovery")
+	} else {
+		laddrs[0][0] = normalize(fmt.Sprintf("0.0.0.0:%d", servercfg.GetStunPort()))
+		logger.Log(0, "netmaker-stun listening on", laddrs[0][0], "via udp")
+	}
+	if err := listenUDPAndServe(ctx, "udp", laddrs); err != nil {
 		if strings.Contains(err.Error(), "closed network connection") {
 			logger.Log(0, "shutdown STUN server")
 		} else {
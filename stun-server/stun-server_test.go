@@ -0,0 +1,121 @@
+package stunserver
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/matryer/is"
+	"gortc.io/stun"
+)
+
+// changeRequest - CHANGE-REQUEST setter for building test requests
+type changeRequest uint32
+
+func (c changeRequest) AddTo(m *stun.Message) error {
+	value := make([]byte, 4)
+	binary.BigEndian.PutUint32(value, uint32(c))
+	m.Add(attrChangeRequest, value)
+	return nil
+}
+
+// getAddressAttribute - decodes an address attribute encoded by addressAttribute
+func getAddressAttribute(m *stun.Message, attr stun.AttrType) (*net.UDPAddr, error) {
+	value, err := m.Get(attr)
+	if err != nil {
+		return nil, err
+	}
+	return &net.UDPAddr{
+		IP:   net.IP(value[4:]),
+		Port: int(binary.BigEndian.Uint16(value[2:4])),
+	}, nil
+}
+
+func newTestServer(t *testing.T, discovery bool) *Server {
+	s := &Server{}
+	ips := [2]string{"192.0.2.1", "192.0.2.2"}
+	ports := [2]int{3478, 3479}
+	for i := range s.conns {
+		for j := range s.conns[i] {
+			if !discovery && (i != 0 || j != 0) {
+				continue
+			}
+			c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { c.Close() })
+			s.conns[i][j] = c
+			s.addrs[i][j] = &net.UDPAddr{IP: net.ParseIP(ips[i]).To4(), Port: ports[j]}
+		}
+	}
+	return s
+}
+
+func TestChangeRequest(t *testing.T) {
+	client := &net.UDPAddr{IP: net.ParseIP("203.0.113.7").To4(), Port: 40000}
+	tests := []struct {
+		name           string
+		flags          changeRequest
+		outIP, outPort int
+	}{
+		{name: "no change", flags: 0, outIP: 0, outPort: 0},
+		{name: "change port", flags: changePortFlag, outIP: 0, outPort: 1},
+		{name: "change ip", flags: changeIPFlag, outIP: 1, outPort: 0},
+		{name: "change ip and port", flags: changeIPFlag | changePortFlag, outIP: 1, outPort: 1},
+	}
+	s := newTestServer(t, true)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			req := stun.MustBuild(stun.TransactionID, stun.BindingRequest, tt.flags)
+			out, extra, err := s.respond(0, 0, req)
+			is.NoErr(err)
+			is.True(out == s.conns[tt.outIP][tt.outPort])
+			res := new(stun.Message)
+			is.NoErr(basicProcess(client, req.Raw, new(stun.Message), res, extra...))
+			origin, err := getAddressAttribute(res, attrResponseOrigin)
+			is.NoErr(err)
+			is.Equal(origin.String(), s.addrs[tt.outIP][tt.outPort].String())
+			// OTHER-ADDRESS always points at the address differing in ip and port from the one the request reached
+			other, err := getAddressAttribute(res, attrOtherAddress)
+			is.NoErr(err)
+			is.Equal(other.String(), s.addrs[1][1].String())
+			var mapped stun.XORMappedAddress
+			is.NoErr(mapped.GetFrom(res))
+			is.Equal(mapped.String(), client.String())
+		})
+	}
+	t.Run("other address of the alternate listener", func(t *testing.T) {
+		is := is.New(t)
+		req := stun.MustBuild(stun.TransactionID, stun.BindingRequest)
+		_, extra, err := s.respond(1, 1, req)
+		is.NoErr(err)
+		res := new(stun.Message)
+		is.NoErr(basicProcess(client, req.Raw, new(stun.Message), res, extra...))
+		other, err := getAddressAttribute(res, attrOtherAddress)
+		is.NoErr(err)
+		is.Equal(other.String(), s.addrs[0][0].String())
+	})
+	t.Run("malformed", func(t *testing.T) {
+		is := is.New(t)
+		req := stun.MustBuild(stun.TransactionID, stun.BindingRequest)
+		req.Add(attrChangeRequest, []byte{0x00, 0x04})
+		_, _, err := s.respond(0, 0, req)
+		is.True(err != nil)
+	})
+}
+
+func TestChangeRequestWithoutDiscovery(t *testing.T) {
+	is := is.New(t)
+	s := newTestServer(t, false)
+	req := stun.MustBuild(stun.TransactionID, stun.BindingRequest)
+	out, extra, err := s.respond(0, 0, req)
+	is.NoErr(err)
+	is.True(out == s.conns[0][0])
+	is.Equal(len(extra), 0) // no OTHER-ADDRESS is advertised without a second ip and port
+	req = stun.MustBuild(stun.TransactionID, stun.BindingRequest, changeRequest(changeIPFlag))
+	_, _, err = s.respond(0, 0, req)
+	is.Equal(err, errChangeNotSupported)
+}
@@ -0,0 +1,282 @@
+package turnserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+	"github.com/pion/logging"
+	"github.com/pion/turn/v2"
+)
+
+// realm - realm of the long-term credentials handed out to hosts
+const realm = "netmaker"
+
+// authTimeout - time after which the authentication of a 5-tuple which did not allocate is forgotten
+const authTimeout = time.Minute
+
+var (
+	errHostQuotaExceeded   = errors.New("host exceeded its turn allocation quota")
+	errServerQuotaExceeded = errors.New("turn server reached its allocation limit")
+	errUnauthenticated     = errors.New("allocation without authenticated host")
+)
+
+// Server - turn server embedded in the netmaker binary,
+// hosts authenticate with their id and the hash of their HostPass
+type Server struct {
+	mutex sync.Mutex
+	// listener - the udp listener, it knows the 5-tuple of the request being handled
+	listener *listenerConn
+	// authenticated - host authenticated per 5-tuple, an allocation belongs to the host
+	// which authenticated the request creating it
+	authenticated map[fiveTuple]authentication
+	hosts         map[string]*models.TurnHostStats
+	hostQuota     int
+	maxAllocs     int
+	allocations   int
+	relay         *turn.RelayAddressGeneratorStatic
+}
+
+// fiveTuple - client and server address of a request, the protocol is always udp
+type fiveTuple struct {
+	src string
+	dst string
+}
+
+type authentication struct {
+	hostID string
+	at     time.Time
+}
+
+// listenerConn - udp listener of the turn server, pion reads and handles the requests of a
+// listener one by one so the source of the last read datagram is the request being handled
+type listenerConn struct {
+	net.PacketConn
+	mutex   sync.Mutex
+	current fiveTuple
+}
+
+func (l *listenerConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, addr, err := l.PacketConn.ReadFrom(p)
+	if err == nil {
+		l.mutex.Lock()
+		l.current = l.tuple(addr)
+		l.mutex.Unlock()
+	}
+	return n, addr, err
+}
+
+func (l *listenerConn) tuple(src net.Addr) fiveTuple {
+	return fiveTuple{src: src.String(), dst: l.LocalAddr().String()}
+}
+
+// listenerConn.handling - returns the 5-tuple of the request being handled
+func (l *listenerConn) handling() fiveTuple {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.current
+}
+
+// server - the running turn server, nil when not started
+var server atomic.Pointer[Server]
+
+// GetStats - returns the allocation stats of the embedded turn server
+func GetStats() models.TurnStats {
+	stats := models.TurnStats{
+		Hosts: make(map[string]models.TurnHostStats),
+	}
+	s := server.Load()
+	if s == nil {
+		return stats
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stats.Running = true
+	stats.Allocations = s.allocations
+	stats.HostQuota = s.hostQuota
+	stats.MaxAllocations = s.maxAllocs
+	for hostID, hostStats := range s.hosts {
+		stats.Hosts[hostID] = *hostStats
+	}
+	return stats
+}
+
+// authenticate - issues the long-term credential key of a host registered with the embedded turn server
+func (s *Server) authenticate(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+	tuple := s.listener.tuple(srcAddr)
+	passHash, err := logic.GetTurnHostPassHash(username)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for t, auth := range s.authenticated {
+		if time.Since(auth.at) > authTimeout {
+			delete(s.authenticated, t)
+		}
+	}
+	if err != nil {
+		logger.Log(2, "TURN auth failed for", username, "from", srcAddr.String())
+		delete(s.authenticated, tuple)
+		return nil, false
+	}
+	// pion only allocates once the message integrity matched the returned key
+	s.authenticated[tuple] = authentication{hostID: username, at: time.Now()}
+	return turn.GenerateAuthKey(username, realm, passHash), true
+}
+
+// Validate - confirms the relay address generator is properly initialized
+func (s *Server) Validate() error {
+	return s.relay.Validate()
+}
+
+// AllocatePacketConn - allocates a relay address if the authenticated host is within its quota
+func (s *Server) AllocatePacketConn(network string, requestedPort int) (net.PacketConn, net.Addr, error) {
+	tuple := s.listener.handling()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	auth, ok := s.authenticated[tuple]
+	if !ok {
+		return nil, nil, errUnauthenticated
+	}
+	delete(s.authenticated, tuple)
+	hostID := auth.hostID
+	if s.maxAllocs > 0 && s.allocations >= s.maxAllocs {
+		logger.Log(1, "TURN allocation limit reached, rejecting host", hostID)
+		return nil, nil, errServerQuotaExceeded
+	}
+	stats, ok := s.hosts[hostID]
+	if !ok {
+		stats = &models.TurnHostStats{}
+		s.hosts[hostID] = stats
+	}
+	if s.hostQuota > 0 && stats.Allocations >= s.hostQuota {
+		logger.Log(1, "TURN quota exceeded for host", hostID)
+		return nil, nil, errHostQuotaExceeded
+	}
+	conn, addr, err := s.relay.AllocatePacketConn(network, requestedPort)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.allocations++
+	stats.Allocations++
+	stats.LastAllocation = time.Now()
+	return &relayConn{PacketConn: conn, server: s, hostID: hostID}, addr, nil
+}
+
+// AllocateConn - allocates a TCP relay address
+func (s *Server) AllocateConn(network string, requestedPort int) (net.Conn, net.Addr, error) {
+	return s.relay.AllocateConn(network, requestedPort)
+}
+
+func (s *Server) release(hostID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.allocations--
+	// the stats of a host are kept once its allocations are gone, they count its total traffic
+	if stats, ok := s.hosts[hostID]; ok && stats.Allocations > 0 {
+		stats.Allocations--
+	}
+}
+
+func (s *Server) count(hostID string, in, out int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if stats, ok := s.hosts[hostID]; ok {
+		stats.BytesReceived += int64(in)
+		stats.BytesSent += int64(out)
+	}
+}
+
+// relayConn - relayed packet conn of an allocation, counts the relayed traffic of the owning host
+type relayConn struct {
+	net.PacketConn
+	server *Server
+	hostID string
+	once   sync.Once
+}
+
+func (c *relayConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(p)
+	if n > 0 {
+		c.server.count(c.hostID, n, 0)
+	}
+	return n, addr, err
+}
+
+func (c *relayConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	n, err := c.PacketConn.WriteTo(p, addr)
+	if n > 0 {
+		c.server.count(c.hostID, 0, n)
+	}
+	return n, err
+}
+
+func (c *relayConn) Close() error {
+	c.once.Do(func() {
+		c.server.release(c.hostID)
+	})
+	return c.PacketConn.Close()
+}
+
+func newServer(listener *listenerConn, relayIP net.IP, hostQuota, maxAllocs int) *Server {
+	return &Server{
+		listener:      listener,
+		authenticated: make(map[fiveTuple]authentication),
+		hosts:         make(map[string]*models.TurnHostStats),
+		hostQuota:     hostQuota,
+		maxAllocs:     maxAllocs,
+		relay: &turn.RelayAddressGeneratorStatic{
+			RelayAddress: relayIP,
+			Address:      "0.0.0.0",
+		},
+	}
+}
+
+// Start - starts the embedded turn server
+func Start(wg *sync.WaitGroup, ctx context.Context) {
+	defer wg.Done()
+	relayIP := net.ParseIP(strings.TrimSpace(servercfg.GetTurnRelayIP()))
+	if relayIP == nil {
+		logger.Log(0, "failed to start TURN server: could not determine the relay ip, set TURN_RELAY_IP")
+		return
+	}
+	address := fmt.Sprintf("0.0.0.0:%d", servercfg.GetTurnPort())
+	conn, err := net.ListenPacket("udp4", address)
+	if err != nil {
+		logger.Log(0, "failed to start TURN server:", err.Error())
+		return
+	}
+	s := newServer(&listenerConn{PacketConn: conn}, relayIP, servercfg.GetTurnHostQuota(), servercfg.GetTurnMaxAllocations())
+	t, err := turn.NewServer(turn.ServerConfig{
+		Realm:         realm,
+		AuthHandler:   s.authenticate,
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+		PacketConnConfigs: []turn.PacketConnConfig{
+			{
+				PacketConn:            s.listener,
+				RelayAddressGenerator: s,
+			},
+		},
+	})
+	if err != nil {
+		logger.Log(0, "failed to start TURN server:", err.Error())
+		conn.Close()
+		return
+	}
+	server.Store(s)
+	logger.Log(0, "netmaker-turn listening on", address, "via udp, relaying on", relayIP.String())
+	<-ctx.Done()
+	server.Store(nil)
+	if err := t.Close(); err != nil {
+		logger.Log(0, "failed to shutdown TURN server:", err.Error())
+		return
+	}
+	logger.Log(0, "shutdown TURN server")
+}
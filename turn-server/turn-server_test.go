@@ -0,0 +1,95 @@
+package turnserver
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/matryer/is"
+)
+
+func TestAllocationAttribution(t *testing.T) {
+	database.InitializeDatabase()
+	defer database.CloseDB()
+	os.Setenv("EMBEDDED_TURN", "true")
+	defer os.Unsetenv("EMBEDDED_TURN")
+	is := is.New(t)
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	is.NoErr(err)
+	defer conn.Close()
+	s := newServer(&listenerConn{PacketConn: conn}, net.ParseIP("127.0.0.1"), 1, 0)
+	is.NoErr(s.Validate())
+	hostA, hostB := uuid.NewString(), uuid.NewString()
+	for _, hostID := range []string{hostA, hostB} {
+		is.NoErr(logic.RegisterHostWithTurn(hostID, "pass-"+hostID))
+		defer logic.DeRegisterHostWithTurn(hostID)
+	}
+	addrA := &net.UDPAddr{IP: net.ParseIP("198.51.100.1"), Port: 40000}
+	addrB := &net.UDPAddr{IP: net.ParseIP("198.51.100.2"), Port: 40000}
+	// simulates pion handling the allocate request of a 5-tuple
+	allocate := func(src net.Addr) (net.PacketConn, error) {
+		s.listener.mutex.Lock()
+		s.listener.current = s.listener.tuple(src)
+		s.listener.mutex.Unlock()
+		relayed, _, err := s.AllocatePacketConn("udp4", 0)
+		return relayed, err
+	}
+
+	t.Run("unknown host", func(t *testing.T) {
+		is := is.New(t)
+		_, ok := s.authenticate(uuid.NewString(), realm, addrA)
+		is.True(!ok)
+		_, err := allocate(addrA)
+		is.Equal(err, errUnauthenticated)
+	})
+	t.Run("interleaved authentications", func(t *testing.T) {
+		is := is.New(t)
+		_, ok := s.authenticate(hostA, realm, addrA)
+		is.True(ok)
+		_, ok = s.authenticate(hostB, realm, addrB)
+		is.True(ok)
+		relayedA, err := allocate(addrA)
+		is.NoErr(err)
+		defer relayedA.Close()
+		relayedB, err := allocate(addrB)
+		is.NoErr(err)
+		defer relayedB.Close()
+		is.Equal(relayedA.(*relayConn).hostID, hostA)
+		is.Equal(relayedB.(*relayConn).hostID, hostB)
+		// the authentication is used up by the allocation
+		_, err = allocate(addrA)
+		is.Equal(err, errUnauthenticated)
+	})
+	t.Run("host quota", func(t *testing.T) {
+		is := is.New(t)
+		_, ok := s.authenticate(hostA, realm, addrA)
+		is.True(ok)
+		relayed, err := allocate(addrA)
+		is.NoErr(err)
+		_, ok = s.authenticate(hostA, realm, addrB)
+		is.True(ok)
+		_, err = allocate(addrB)
+		is.Equal(err, errHostQuotaExceeded)
+		is.NoErr(relayed.Close())
+	})
+	t.Run("stats outlive allocations", func(t *testing.T) {
+		is := is.New(t)
+		_, ok := s.authenticate(hostA, realm, addrA)
+		is.True(ok)
+		relayed, err := allocate(addrA)
+		is.NoErr(err)
+		s.count(hostA, 100, 50)
+		is.NoErr(relayed.Close())
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		stats, ok := s.hosts[hostA]
+		is.True(ok)
+		is.Equal(stats.Allocations, 0)
+		is.Equal(stats.BytesReceived, int64(100))
+		is.Equal(stats.BytesSent, int64(50))
+		is.Equal(s.allocations, 0)
+	})
+}